@@ -25,6 +25,7 @@ import (
 	buildv1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // ProwJobType specifies how the job is triggered.
@@ -47,6 +48,9 @@ type ProwJobState string
 
 // Various job states.
 const (
+	// WaitingState means the job has been created but waits for the
+	// jobs it runs after to succeed before it can be scheduled.
+	WaitingState ProwJobState = "waiting"
 	// TriggeredState means the job has been created but not yet scheduled.
 	TriggeredState ProwJobState = "triggered"
 	// PendingState means the job is scheduled but not yet running.
@@ -61,6 +65,40 @@ const (
 	ErrorState ProwJobState = "error"
 )
 
+// FailureReason classifies why a job did not succeed.
+type FailureReason string
+
+// Various failure reasons.
+const (
+	// TestFailureReason means the code under test failed.
+	TestFailureReason FailureReason = "TestFailure"
+	// TimeoutReason means the test process did not finish before its timeout.
+	TimeoutReason FailureReason = "Timeout"
+	// PodPendingTimeoutReason means the pod never started running.
+	PodPendingTimeoutReason FailureReason = "PodPendingTimeout"
+	// EvictedReason means the cluster evicted the pod running the job.
+	EvictedReason FailureReason = "Evicted"
+	// ImagePullReason means an image used by the job could not be pulled.
+	ImagePullReason FailureReason = "ImagePull"
+	// InfraErrorReason means the job tooling failed around the test process.
+	InfraErrorReason FailureReason = "InfraError"
+	// CloneFailureReason means the refs under test could not be checked out.
+	CloneFailureReason FailureReason = "CloneFailure"
+	// UploadFailureReason means the job results could not be uploaded.
+	UploadFailureReason FailureReason = "UploadFailure"
+)
+
+// IsInfra returns true if the failure was caused by the infrastructure
+// rather than by the code under test, so that a retest may pass. Clone
+// failures are not infra failures as they are mostly merge conflicts.
+func (r FailureReason) IsInfra() bool {
+	switch r {
+	case PodPendingTimeoutReason, EvictedReason, ImagePullReason, InfraErrorReason, UploadFailureReason:
+		return true
+	}
+	return false
+}
+
 // ProwJobAgent specifies the controller (such as plank or jenkins-agent) that runs the job.
 type ProwJobAgent string
 
@@ -133,6 +171,19 @@ type ProwJobSpec struct {
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// RunAfterSuccess are the names of the jobs that must
+	// succeed for the same refs before this job is started
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
+	// RetryPolicy determines whether and how often a job
+	// is re-executed when its pod fails for a reason that
+	// is unrelated to the code under test.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+	// ReporterConfig holds reporter-specific configuration
+	// for the job, e.g. which Slack channel to report to.
+	ReporterConfig *ReporterConfig `json:"reporter_config,omitempty"`
+	// Cancel requests that the job be aborted. The agent that
+	// runs the job stops it and moves it to the aborted state.
+	Cancel bool `json:"cancel,omitempty"`
 
 	// PodSpec provides the basis for running the test under
 	// a Kubernetes agent
@@ -148,6 +199,82 @@ type ProwJobSpec struct {
 	DecorationConfig *DecorationConfig `json:"decoration_config,omitempty"`
 }
 
+// RetryPolicy configures automatic re-execution of a job
+// whose pod ended in a retryable way.
+//
+// A pod termination is retryable if the state plank would
+// transition the job to is listed in States, or if the
+// failure reason of the pod (e.g. Evicted, OOMKilled or
+// ErrImagePull) is listed in Reasons.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a pod is
+	// started for the job, including the first attempt.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// States are the terminal job states that are retried.
+	States []ProwJobState `json:"states,omitempty"`
+	// Reasons are the pod or container failure reasons
+	// that are retried.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Validate ensures all the values set in the RetryPolicy are valid.
+func (r *RetryPolicy) Validate() error {
+	if r.MaxAttempts < 1 {
+		return fmt.Errorf("max_attempts must be a positive number (found %d)", r.MaxAttempts)
+	}
+	for _, state := range r.States {
+		if state != FailureState && state != ErrorState {
+			return fmt.Errorf("states may only contain %q or %q (found %q)", FailureState, ErrorState, state)
+		}
+	}
+	if len(r.States) == 0 && len(r.Reasons) == 0 {
+		return errors.New("at least one retryable state or reason must be specified")
+	}
+	return nil
+}
+
+// ShouldRetry determines if a job that has been attempted the given
+// number of times and would now transition to state because of
+// reason should be re-executed instead.
+func (r *RetryPolicy) ShouldRetry(attempts int, state ProwJobState, reason string) bool {
+	if r == nil || attempts >= r.MaxAttempts {
+		return false
+	}
+	for _, s := range r.States {
+		if s == state {
+			return true
+		}
+	}
+	if reason == "" {
+		return false
+	}
+	for _, re := range r.Reasons {
+		if re == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// ReporterConfig holds the per-job configuration of the
+// reporters run by crier.
+type ReporterConfig struct {
+	Slack *SlackReporterConfig `json:"slack,omitempty"`
+}
+
+// SlackReporterConfig configures how the Slack reporter
+// reports a job.
+type SlackReporterConfig struct {
+	// Channel is the Slack channel the job is reported to.
+	Channel string `json:"channel"`
+	// JobStatesToReport overrides the states configured
+	// for the Slack reporter in the prow config.
+	JobStatesToReport []ProwJobState `json:"job_states_to_report,omitempty"`
+	// ReportTemplate overrides the template configured
+	// for the Slack reporter in the prow config.
+	ReportTemplate string `json:"report_template,omitempty"`
+}
+
 // DecorationConfig specifies how to augment pods.
 //
 // This is primarily used to provide automatic integration with gubernator
@@ -167,7 +294,8 @@ type DecorationConfig struct {
 	// artifacts to GCS from a job.
 	GCSConfiguration *GCSConfiguration `json:"gcs_configuration,omitempty"`
 	// GCSCredentialsSecret is the name of the Kubernetes secret
-	// that holds GCS push credentials.
+	// that holds GCS push credentials, or the credentials and
+	// endpoint in s3-credentials.json for S3 buckets.
 	GCSCredentialsSecret string `json:"gcs_credentials_secret,omitempty"`
 	// SSHKeySecrets are the names of Kubernetes secrets that contain
 	// SSK keys which should be used during the cloning process.
@@ -252,7 +380,9 @@ func (d *DecorationConfig) Validate() error {
 	if d.GCSConfiguration == nil {
 		return errors.New("GCS upload configuration is not specified")
 	}
-	if d.GCSCredentialsSecret == "" {
+	// File buckets are mounted instead of being accessed
+	// with credentials.
+	if d.GCSCredentialsSecret == "" && !strings.HasPrefix(d.GCSConfiguration.Bucket, "file:") {
 		return errors.New("GCS upload credential secret is not specified")
 	}
 	if err := d.GCSConfiguration.Validate(); err != nil {
@@ -310,7 +440,9 @@ const (
 // GCSConfiguration holds options for pushing logs and
 // artifacts to GCS from a job.
 type GCSConfiguration struct {
-	// Bucket is the GCS bucket to upload to
+	// Bucket is the bucket to upload to. Buckets in other
+	// storage than GCS are given as URLs like s3://bucket
+	// or file://bucket.
 	Bucket string `json:"bucket,omitempty"`
 	// PathPrefix is an optional path that follows the
 	// bucket name and comes before any structure
@@ -362,6 +494,15 @@ func (g *GCSConfiguration) ApplyDefault(def *GCSConfiguration) *GCSConfiguration
 
 // Validate ensures all the values set in the GCSConfiguration are valid.
 func (g *GCSConfiguration) Validate() error {
+	// The schemes are those of the storage that the
+	// objectstore package supports.
+	if i := strings.Index(g.Bucket, "://"); i >= 0 {
+		switch g.Bucket[:i] {
+		case "gs", "s3", "file":
+		default:
+			return fmt.Errorf("bucket %q is in unknown storage, expected gs://, s3:// or file://", g.Bucket)
+		}
+	}
 	if g.PathStrategy != PathStrategyLegacy && g.PathStrategy != PathStrategyExplicit && g.PathStrategy != PathStrategySingle {
 		return fmt.Errorf("gcs_path_strategy must be one of %q, %q, or %q", PathStrategyLegacy, PathStrategyExplicit, PathStrategySingle)
 	}
@@ -379,6 +520,10 @@ type ProwJobStatus struct {
 	Description    string       `json:"description,omitempty"`
 	URL            string       `json:"url,omitempty"`
 
+	// FailureReason classifies why the job did not succeed
+	// when it ends in the failure or error state.
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+
 	// PodName applies only to ProwJobs fulfilled by
 	// plank. This field should always be the same as
 	// the ProwJob.ObjectMeta.Name field.
@@ -401,6 +546,55 @@ type ProwJobStatus struct {
 	// PrevReportStates stores the previous reported prowjob state per reporter
 	// So crier won't make duplicated report attempt
 	PrevReportStates map[string]ProwJobState `json:"prev_report_states,omitempty"`
+
+	// Attempts records the previous executions of this job
+	// that were discarded and retried under its RetryPolicy.
+	Attempts []ProwJobAttempt `json:"attempts,omitempty"`
+
+	// Resources applies only to ProwJobs fulfilled by plank.
+	// It records what the pod of the job requested and used
+	// and is set once the job completes.
+	Resources *ResourceUsage `json:"resources,omitempty"`
+}
+
+// ResourceUsage describes the resources the pod of a ProwJob
+// requested and consumed.
+type ResourceUsage struct {
+	// Requests are the effective resource requests of the pod,
+	// accounting for its init containers like the scheduler does.
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+	// CPUMillis is the CPU time the test process consumed, in
+	// milliseconds. It is only known for decorated jobs.
+	CPUMillis int64 `json:"cpu_millis,omitempty"`
+	// MaxMemoryBytes is the peak resident memory of the test
+	// process. It is only known for decorated jobs.
+	MaxMemoryBytes int64 `json:"max_memory_bytes,omitempty"`
+	// NodeType is the instance type of the node the pod ran on.
+	NodeType string `json:"node_type,omitempty"`
+	// DurationSeconds is the wall-clock time the pod ran for.
+	DurationSeconds int64 `json:"duration_seconds,omitempty"`
+	// PodUID is the UID of the pod the usage was recorded for.
+	PodUID types.UID `json:"pod_uid,omitempty"`
+}
+
+// ProwJobAttempt describes a single discarded execution of a ProwJob.
+type ProwJobAttempt struct {
+	// BuildID is the build identifier used by the attempt.
+	BuildID string `json:"build_id,omitempty"`
+	// PodName is the name of the pod that ran the attempt.
+	PodName string `json:"pod_name,omitempty"`
+	// PodUID is the UID of the pod that ran the attempt, which
+	// tells it apart from the pods of other attempts of the job.
+	PodUID types.UID `json:"pod_uid,omitempty"`
+	// CompletionTime is when plank observed the attempt ending.
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+	// State is the state the job would have ended in
+	// had the attempt not been retried.
+	State ProwJobState `json:"state,omitempty"`
+	// Reason is the failure reason reported for the pod.
+	Reason string `json:"reason,omitempty"`
+	// Description explains why the attempt failed.
+	Description string `json:"description,omitempty"`
 }
 
 // Complete returns true if the prow job has finished
@@ -255,3 +255,79 @@ func TestRefsToString(t *testing.T) {
 		}
 	}
 }
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		policy   *RetryPolicy
+		attempts int
+		state    ProwJobState
+		reason   string
+		expected bool
+	}{
+		{
+			name:     "no policy",
+			attempts: 1,
+			state:    ErrorState,
+		},
+		{
+			name:     "retryable state",
+			policy:   &RetryPolicy{MaxAttempts: 3, States: []ProwJobState{ErrorState}},
+			attempts: 1,
+			state:    ErrorState,
+			expected: true,
+		},
+		{
+			name:     "retryable reason",
+			policy:   &RetryPolicy{MaxAttempts: 3, Reasons: []string{"Evicted"}},
+			attempts: 2,
+			state:    FailureState,
+			reason:   "Evicted",
+			expected: true,
+		},
+		{
+			name:     "neither state nor reason are retryable",
+			policy:   &RetryPolicy{MaxAttempts: 3, States: []ProwJobState{ErrorState}, Reasons: []string{"Evicted"}},
+			attempts: 1,
+			state:    FailureState,
+			reason:   "Error",
+		},
+		{
+			name:     "out of attempts",
+			policy:   &RetryPolicy{MaxAttempts: 3, States: []ProwJobState{ErrorState}},
+			attempts: 3,
+			state:    ErrorState,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.policy.ShouldRetry(tc.attempts, tc.state, tc.reason); actual != tc.expected {
+				t.Errorf("expected ShouldRetry to return %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFailureReasonIsInfra(t *testing.T) {
+	var testCases = []struct {
+		reason   FailureReason
+		expected bool
+	}{
+		{reason: "", expected: false},
+		{reason: TestFailureReason, expected: false},
+		{reason: TimeoutReason, expected: false},
+		{reason: CloneFailureReason, expected: false},
+		{reason: PodPendingTimeoutReason, expected: true},
+		{reason: EvictedReason, expected: true},
+		{reason: ImagePullReason, expected: true},
+		{reason: InfraErrorReason, expected: true},
+		{reason: UploadFailureReason, expected: true},
+	}
+
+	for _, testCase := range testCases {
+		if actual := testCase.reason.IsInfra(); actual != testCase.expected {
+			t.Errorf("%q: expected infra %v, got %v", testCase.reason, testCase.expected, actual)
+		}
+	}
+}
@@ -111,6 +111,23 @@ func (in *ProwJob) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProwJobAttempt) DeepCopyInto(out *ProwJobAttempt) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProwJobAttempt.
+func (in *ProwJobAttempt) DeepCopy() *ProwJobAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(ProwJobAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProwJobList) DeepCopyInto(out *ProwJobList) {
 	*out = *in
@@ -159,6 +176,21 @@ func (in *ProwJobSpec) DeepCopyInto(out *ProwJobSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RunAfterSuccess != nil {
+		in, out := &in.RunAfterSuccess, &out.RunAfterSuccess
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReporterConfig != nil {
+		in, out := &in.ReporterConfig, &out.ReporterConfig
+		*out = new(ReporterConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PodSpec != nil {
 		in, out := &in.PodSpec, &out.PodSpec
 		*out = new(corev1.PodSpec)
@@ -202,6 +234,18 @@ func (in *ProwJobStatus) DeepCopyInto(out *ProwJobStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]ProwJobAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(ResourceUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -252,6 +296,97 @@ func (in *Refs) DeepCopy() *Refs {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReporterConfig) DeepCopyInto(out *ReporterConfig) {
+	*out = *in
+	if in.Slack != nil {
+		in, out := &in.Slack, &out.Slack
+		*out = new(SlackReporterConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReporterConfig.
+func (in *ReporterConfig) DeepCopy() *ReporterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ReporterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceUsage) DeepCopyInto(out *ResourceUsage) {
+	*out = *in
+	if in.Requests != nil {
+		in, out := &in.Requests, &out.Requests
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceUsage.
+func (in *ResourceUsage) DeepCopy() *ResourceUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.States != nil {
+		in, out := &in.States, &out.States
+		*out = make([]ProwJobState, len(*in))
+		copy(*out, *in)
+	}
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlackReporterConfig) DeepCopyInto(out *SlackReporterConfig) {
+	*out = *in
+	if in.JobStatesToReport != nil {
+		in, out := &in.JobStatesToReport, &out.JobStatesToReport
+		*out = make([]ProwJobState, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlackReporterConfig.
+func (in *SlackReporterConfig) DeepCopy() *SlackReporterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SlackReporterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UtilityImages) DeepCopyInto(out *UtilityImages) {
 	*out = *in
@@ -35,6 +35,7 @@ import (
 	prowjobv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 
 	prowjobscheme "k8s.io/test-infra/prow/client/clientset/versioned/scheme"
+	"k8s.io/test-infra/prow/policy"
 )
 
 var (
@@ -49,8 +50,8 @@ func init() {
 }
 
 // runServer starts an http server on :8443, responding to /validate requests
-func runServer(cert, privateKey string) {
-	http.HandleFunc("/validate", handle)
+func runServer(cert, privateKey string, pol *policy.Policy) {
+	http.HandleFunc("/validate", handle(validateProwJobs(pol)))
 	s := http.Server{
 		Addr: ":8443",
 		TLSConfig: &tls.Config{
@@ -87,19 +88,32 @@ func readRequest(r io.Reader, contentType string) (*admissionapi.AdmissionReques
 }
 
 // handle reads the request and writes the response
-func handle(w http.ResponseWriter, r *http.Request) {
-	req, err := readRequest(r.Body, r.Header.Get("Content-Type"))
-	if err != nil {
-		logrus.WithError(err).Error("read")
-	}
+func handle(decide decider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := readRequest(r.Body, r.Header.Get("Content-Type"))
+		if err != nil {
+			logrus.WithError(err).Error("read")
+		}
 
-	if err := writeResponse(*req, w, onlyUpdateStatus); err != nil {
-		logrus.WithError(err).Error("write")
+		if err := writeResponse(*req, w, decide); err != nil {
+			logrus.WithError(err).Error("write")
+		}
 	}
 }
 
 type decider func(admissionapi.AdmissionRequest) (*admissionapi.AdmissionResponse, error)
 
+// validateProwJobs returns a decider that enforces the policy, if any,
+// on new ProwJobs and only allows status updates to existing ones.
+func validateProwJobs(pol *policy.Policy) decider {
+	return func(req admissionapi.AdmissionRequest) (*admissionapi.AdmissionResponse, error) {
+		if req.Operation == admissionapi.Create {
+			return followsPolicy(pol, req)
+		}
+		return onlyUpdateStatus(req)
+	}
+}
+
 // writeResponse gets the response from onlyUpdateStatus and writes it to w.
 func writeResponse(ar admissionapi.AdmissionRequest, w io.Writer, decide decider) error {
 	response, err := decide(ar)
@@ -165,6 +179,38 @@ func onlyUpdateStatus(req admissionapi.AdmissionRequest) (*admissionapi.Admissio
 		logrus.Info("accept update with equivalent spec")
 		return &allow, nil // yes
 	}
+	// Aborting a job requests its cancellation, which is the one
+	// change to the spec that is allowed.
+	if !old.Spec.Cancel && new.Spec.Cancel {
+		cancelled := old.Spec
+		cancelled.Cancel = true
+		if equality.Semantic.DeepEqual(cancelled, new.Spec) {
+			logrus.Info("accept cancellation")
+			return &allow, nil
+		}
+	}
 	logger.Info("reject") // no
 	return &reject, nil
 }
+
+// followsPolicy returns the response to a request creating a ProwJob
+func followsPolicy(pol *policy.Policy, req admissionapi.AdmissionRequest) (*admissionapi.AdmissionResponse, error) {
+	if pol == nil {
+		return &allow, nil
+	}
+	var pj prowjobv1.ProwJob
+	if _, _, err := codecs.UniversalDeserializer().Decode(req.Object.Raw, nil, &pj); err != nil {
+		return nil, fmt.Errorf("decode new: %v", err)
+	}
+	if err := pol.ValidateProwJob(pj); err != nil {
+		logrus.WithField("name", req.Name).WithError(err).Info("reject prowjob violating the policy")
+		return &admissionapi.AdmissionResponse{
+			Result: &meta.Status{
+				Reason:  meta.StatusReasonForbidden,
+				Message: err.Error(),
+			},
+		}, nil
+	}
+	logrus.WithField("name", req.Name).Info("accept prowjob following the policy")
+	return &allow, nil
+}
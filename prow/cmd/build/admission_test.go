@@ -25,9 +25,11 @@ import (
 	"testing"
 
 	admissionapi "k8s.io/api/admission/v1beta1"
+	"k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	prowjobv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/policy"
 )
 
 func TestOnlyUpdateStatus(t *testing.T) {
@@ -87,6 +89,43 @@ func TestOnlyUpdateStatus(t *testing.T) {
 			},
 		},
 		expected: &allow,
+	}, {
+		name: "allow cancellation",
+		old: prowjobv1.ProwJob{
+			Spec: prowjobv1.ProwJobSpec{
+				MaxConcurrency: 2,
+			},
+		},
+		new: prowjobv1.ProwJob{
+			Spec: prowjobv1.ProwJobSpec{
+				MaxConcurrency: 2,
+				Cancel:         true,
+			},
+		},
+		expected: &allow,
+	}, {
+		name: "reject cancellation with other spec changes",
+		old: prowjobv1.ProwJob{
+			Spec: prowjobv1.ProwJobSpec{
+				MaxConcurrency: 1,
+			},
+		},
+		new: prowjobv1.ProwJob{
+			Spec: prowjobv1.ProwJobSpec{
+				MaxConcurrency: 2,
+				Cancel:         true,
+			},
+		},
+		expected: &reject,
+	}, {
+		name: "reject revoking cancellation",
+		old: prowjobv1.ProwJob{
+			Spec: prowjobv1.ProwJobSpec{
+				Cancel: true,
+			},
+		},
+		new:      prowjobv1.ProwJob{},
+		expected: &reject,
 	}}
 
 	for _, tc := range cases {
@@ -117,6 +156,96 @@ func TestOnlyUpdateStatus(t *testing.T) {
 	}
 }
 
+func TestValidateProwJobs(t *testing.T) {
+	pol := &policy.Policy{Rules: []policy.Rule{{
+		Name:           "no-host-path",
+		ForbidHostPath: true,
+	}}}
+	compliant := prowjobv1.ProwJob{
+		Spec: prowjobv1.ProwJobSpec{
+			Job:     "compliant",
+			PodSpec: &v1.PodSpec{},
+		},
+	}
+	violating := prowjobv1.ProwJob{
+		Spec: prowjobv1.ProwJobSpec{
+			Job: "violating",
+			PodSpec: &v1.PodSpec{
+				Volumes: []v1.Volume{{
+					Name:         "docker",
+					VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/run/docker.sock"}},
+				}},
+			},
+		},
+	}
+	cases := []struct {
+		name      string
+		pol       *policy.Policy
+		operation admissionapi.Operation
+		new       prowjobv1.ProwJob
+		old       prowjobv1.ProwJob
+		allowed   bool
+	}{{
+		name:      "allow creating a prowjob following the policy",
+		pol:       pol,
+		operation: admissionapi.Create,
+		new:       compliant,
+		allowed:   true,
+	}, {
+		name:      "reject creating a prowjob violating the policy",
+		pol:       pol,
+		operation: admissionapi.Create,
+		new:       violating,
+	}, {
+		name:      "allow creating any prowjob without a policy",
+		operation: admissionapi.Create,
+		new:       violating,
+		allowed:   true,
+	}, {
+		name:      "reject updating the spec",
+		pol:       pol,
+		operation: admissionapi.Update,
+		new:       compliant,
+		old:       violating,
+	}, {
+		name:      "allow cancelling a prowjob",
+		pol:       pol,
+		operation: admissionapi.Update,
+		new: prowjobv1.ProwJob{
+			Spec: prowjobv1.ProwJobSpec{
+				Job:     "compliant",
+				PodSpec: &v1.PodSpec{},
+				Cancel:  true,
+			},
+		},
+		old:     compliant,
+		allowed: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req admissionapi.AdmissionRequest
+			var err error
+			req.Operation = tc.operation
+			req.Object.Raw, err = json.Marshal(tc.new)
+			if err != nil {
+				t.Fatalf("encode new: %v", err)
+			}
+			req.OldObject.Raw, err = json.Marshal(tc.old)
+			if err != nil {
+				t.Fatalf("encode old: %v", err)
+			}
+			actual, err := validateProwJobs(tc.pol)(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual.Allowed != tc.allowed {
+				t.Errorf("expected allowed %t, got %#v", tc.allowed, actual)
+			}
+		})
+	}
+}
+
 func TestWriteResponse(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -30,6 +30,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/policy"
 
 	buildset "github.com/knative/build/pkg/client/clientset/versioned"
 	buildinfo "github.com/knative/build/pkg/client/informers/externalversions"
@@ -46,6 +47,7 @@ type options struct {
 	buildCluster string
 	config       string
 	kubeconfig   string
+	policyConfig string
 	totURL       string
 
 	// Create these values by following:
@@ -70,6 +72,7 @@ func (o *options) parse(flags *flag.FlagSet, args []string) error {
 	flags.StringVar(&o.buildCluster, "build-cluster", "", "Path to file containing a YAML-marshalled kube.Cluster object. If empty, uses the local cluster.")
 	flags.StringVar(&o.cert, "tls-cert-file", "", "Path to x509 certificate for HTTPS")
 	flags.StringVar(&o.privateKey, "tls-private-key-file", "", "Path to matching x509 private key.")
+	flags.StringVar(&o.policyConfig, "policy-config", "", "Path to a policy file with rules new ProwJobs must follow, see checkconfig.")
 	flags.Parse(args)
 	if (len(o.cert) == 0) != (len(o.privateKey) == 0) {
 		return errors.New("Both --tls-cert-file and --tls-private-key-file are required for HTTPS")
@@ -181,7 +184,13 @@ func main() {
 
 	// TODO(fejta): move to its own binary
 	if len(o.cert) > 0 {
-		go runServer(o.cert, o.privateKey)
+		var pol *policy.Policy
+		if o.policyConfig != "" {
+			if pol, err = policy.Load(o.policyConfig); err != nil {
+				logrus.WithError(err).Fatal("Error loading policy")
+			}
+		}
+		go runServer(o.cert, o.privateKey, pol)
 	}
 
 	controller := newController(kc, pjc, pjif.Prow().V1().ProwJobs(), buildConfigs, o.totURL, configAgent.Config, kube.RateLimiter(controllerName))
@@ -46,12 +46,14 @@ import (
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/plugins"
 	"k8s.io/test-infra/prow/plugins/lgtm"
+	"k8s.io/test-infra/prow/policy"
 )
 
 type options struct {
 	configPath    string
 	jobConfigPath string
 	pluginConfig  string
+	policyConfig  string
 
 	warnings flagutil.Strings
 	strict   bool
@@ -115,6 +117,7 @@ func gatherOptions() options {
 	flag.StringVar(&o.configPath, "config-path", "", "Path to config.yaml.")
 	flag.StringVar(&o.jobConfigPath, "job-config-path", "", "Path to prow job configs.")
 	flag.StringVar(&o.pluginConfig, "plugin-config", "", "Path to plugin config file.")
+	flag.StringVar(&o.policyConfig, "policy-config", "", "Path to a policy file with rules the pod specs of jobs must follow.")
 	flag.Var(&o.warnings, "warnings", "Comma-delimited list of warnings to validate.")
 	flag.BoolVar(&o.strict, "strict", false, "If set, consider all warnings as errors.")
 	flag.Parse()
@@ -151,6 +154,21 @@ func main() {
 		pcfg = pluginAgent.Config()
 	}
 
+	// policy violations are errors: the policy exists to keep
+	// such jobs out of the cluster.
+	if o.policyConfig != "" {
+		pol, err := policy.Load(o.policyConfig)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error loading policy.")
+		}
+		if err := pol.ValidateJobConfig(cfg.JobConfig); err != nil {
+			for _, item := range errorutil.NewAggregate(err).Strings() {
+				logrus.Error(item)
+			}
+			logrus.Fatal("Jobs violate the policy.")
+		}
+	}
+
 	// the following checks are useful in finding user errors but their
 	// presence won't lead to strictly incorrect behavior, so we can
 	// detect them here but don't necessarily want to stop config re-load
@@ -39,6 +39,9 @@ import (
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/logrusutil"
 	pubsubreporter "k8s.io/test-infra/prow/pubsub/reporter"
+	"k8s.io/test-infra/prow/slack"
+	slackreporter "k8s.io/test-infra/prow/slack/reporter"
+	webhookreporter "k8s.io/test-infra/prow/webhook/reporter"
 )
 
 const (
@@ -56,9 +59,13 @@ type options struct {
 	configPath    string
 	jobConfigPath string
 
-	gerritWorkers int
-	pubsubWorkers int
-	githubWorkers int
+	gerritWorkers  int
+	pubsubWorkers  int
+	githubWorkers  int
+	slackWorkers   int
+	webhookWorkers int
+
+	slackTokenFile string
 
 	dryrun      bool
 	reportAgent string
@@ -75,7 +82,7 @@ func (o *options) validate() error {
 		o.gerritWorkers = 1
 	}
 
-	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers <= 0 {
+	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.webhookWorkers <= 0 {
 		return errors.New("crier need to have at least one report worker to start")
 	}
 
@@ -95,6 +102,10 @@ func (o *options) validate() error {
 		}
 	}
 
+	if o.slackWorkers > 0 && o.slackTokenFile == "" {
+		return errors.New("--slack-token-file must be set")
+	}
+
 	if err := o.client.Validate(o.dryrun); err != nil {
 		return err
 	}
@@ -108,13 +119,16 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.IntVar(&o.gerritWorkers, "gerrit-workers", 0, "Number of gerrit report workers (0 means disabled)")
 	fs.IntVar(&o.pubsubWorkers, "pubsub-workers", 0, "Number of pubsub report workers (0 means disabled)")
 	fs.IntVar(&o.githubWorkers, "github-workers", 0, "Number of github report workers (0 means disabled)")
+	fs.IntVar(&o.slackWorkers, "slack-workers", 0, "Number of slack report workers (0 means disabled)")
+	fs.IntVar(&o.webhookWorkers, "webhook-workers", 0, "Number of webhook report workers (0 means disabled)")
+	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to the file containing the Slack token to use.")
 	fs.StringVar(&o.reportAgent, "report-agent", "", "Only report specified agent - empty means report to all agents (effective for github only)")
 
 	fs.StringVar(&o.configPath, "config-path", "", "Path to config.yaml.")
 	fs.StringVar(&o.jobConfigPath, "job-config-path", "", "Path to prow job configs.")
 
 	// TODO(krzyzacy): implement dryrun for gerrit/pubsub
-	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github only)")
+	fs.BoolVar(&o.dryrun, "dry-run", false, "Run in dry-run mode, not doing actual report (effective for github and slack only)")
 
 	o.github.AddFlags(fs)
 	o.client.AddFlags(fs)
@@ -217,6 +231,44 @@ func main() {
 				wg))
 	}
 
+	if o.slackWorkers > 0 {
+		secretAgent := &secret.Agent{}
+		if err := secretAgent.Start([]string{o.slackTokenFile}); err != nil {
+			logrus.WithError(err).Fatal("Error starting secrets agent")
+		}
+
+		var slackClient *slack.Client
+		if o.dryrun {
+			slackClient = slack.NewFakeClient()
+		} else {
+			slackClient = slack.NewClient(secretAgent.GetTokenGenerator(o.slackTokenFile))
+		}
+
+		slackReporter := slackreporter.NewReporter(slackClient, cfg)
+		controllers = append(
+			controllers,
+			crier.NewController(
+				prowjobClientset,
+				kube.RateLimiter(slackReporter.GetName()),
+				prowjobInformerFactory.Prow().V1().ProwJobs(),
+				slackReporter,
+				o.slackWorkers,
+				wg))
+	}
+
+	if o.webhookWorkers > 0 {
+		webhookReporter := webhookreporter.NewReporter(cfg)
+		controllers = append(
+			controllers,
+			crier.NewController(
+				prowjobClientset,
+				kube.RateLimiter(webhookReporter.GetName()),
+				prowjobInformerFactory.Prow().V1().ProwJobs(),
+				webhookReporter,
+				o.webhookWorkers,
+				wg))
+	}
+
 	if len(controllers) == 0 {
 		logrus.Fatalf("should have at least one controller to start crier.")
 	}
@@ -62,6 +62,20 @@ func TestOptions(t *testing.T) {
 				configPath: "foo",
 			},
 		},
+		{
+			name: "slack missing --slack-token-file, reject",
+			args: []string{"--slack-workers=1", "--config-path=foo"},
+		},
+		{
+			name: "slack with --slack-token-file",
+			args: []string{"--slack-workers=1", "--slack-token-file=/etc/slack/token", "--config-path=foo"},
+			expected: &options{
+				slackWorkers:   1,
+				slackTokenFile: "/etc/slack/token",
+				gerritProjects: map[string][]string{},
+				configPath:     "foo",
+			},
+		},
 	}
 
 	for _, tc := range cases {
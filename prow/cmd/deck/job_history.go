@@ -27,13 +27,15 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/api/iterator"
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/spyglass/lenses"
 )
 
 const (
@@ -49,7 +51,7 @@ const (
 )
 
 var (
-	prefixRe = regexp.MustCompile("gs://.*?/")
+	prefixRe = regexp.MustCompile("[a-z0-9]+://.*?/")
 	linkRe   = regexp.MustCompile("/([0-9]+)\\.txt$")
 )
 
@@ -73,10 +75,11 @@ type storageBucket interface {
 	readObject(key string) ([]byte, error)
 }
 
-// gcsBucket is our real implementation of storageBucket
-type gcsBucket struct {
+// blobBucket is our real implementation of storageBucket, for buckets in
+// all the storage that the objectstore package supports
+type blobBucket struct {
 	name string
-	*storage.BucketHandle
+	objectstore.Bucket
 }
 
 type jobHistoryTemplate struct {
@@ -89,16 +92,16 @@ type jobHistoryTemplate struct {
 	Builds       []buildData
 }
 
-func (bucket gcsBucket) readObject(key string) ([]byte, error) {
-	obj := bucket.Object(key)
-	rc, err := obj.NewReader(context.Background())
+func (bucket blobBucket) readObject(key string) ([]byte, error) {
+	rc, err := bucket.NewReader(context.Background(), key)
 	if err != nil {
 		return []byte{}, fmt.Errorf("failed to get reader for GCS object: %v", err)
 	}
+	defer rc.Close()
 	return ioutil.ReadAll(rc)
 }
 
-func (bucket gcsBucket) getName() string {
+func (bucket blobBucket) getName() string {
 	return bucket.name
 }
 
@@ -116,17 +119,17 @@ func readLatestBuild(bucket storageBucket, root string) (int64, error) {
 }
 
 // resolve sym links into the actual log directory for a particular test run
-func (bucket gcsBucket) resolveSymLink(symLink string) (string, error) {
+func (bucket blobBucket) resolveSymLink(symLink string) (string, error) {
 	data, err := bucket.readObject(symLink)
 	if err != nil {
 		return "", fmt.Errorf("failed to read %s: %v", symLink, err)
 	}
-	// strip gs://<bucket-name> from global address `u`
+	// strip gs://<bucket-name> (or s3://, file://) from global address `u`
 	u := string(data)
 	return prefixRe.ReplaceAllString(u, ""), nil
 }
 
-func (bucket gcsBucket) spyglassLink(root, id string) (string, error) {
+func (bucket blobBucket) spyglassLink(root, id string) (string, error) {
 	p, err := bucket.getPath(root, id, "")
 	if err != nil {
 		return "", fmt.Errorf("failed to get path: %v", err)
@@ -134,7 +137,7 @@ func (bucket gcsBucket) spyglassLink(root, id string) (string, error) {
 	return path.Join(spyglassPrefix, bucket.getName(), p), nil
 }
 
-func (bucket gcsBucket) getPath(root, id, fname string) (string, error) {
+func (bucket blobBucket) getPath(root, id, fname string) (string, error) {
 	if strings.HasPrefix(root, logsPrefix) {
 		return path.Join(root, id, fname), nil
 	}
@@ -160,51 +163,28 @@ func readJSON(bucket storageBucket, key string, data interface{}) error {
 }
 
 // Lists the GCS "directory paths" immediately under prefix.
-func (bucket gcsBucket) listSubDirs(prefix string) ([]string, error) {
+func (bucket blobBucket) listSubDirs(prefix string) ([]string, error) {
 	if !strings.HasSuffix(prefix, "/") {
 		prefix += "/"
 	}
-	dirs := []string{}
-	it := bucket.Objects(context.Background(), &storage.Query{
-		Prefix:    prefix,
-		Delimiter: "/",
-	})
-	for {
-		attrs, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return dirs, err
-		}
-		if attrs.Prefix != "" {
-			dirs = append(dirs, attrs.Prefix)
-		}
+	_, dirs, err := bucket.List(context.Background(), prefix, "/")
+	if err != nil {
+		return []string{}, err
 	}
-	return dirs, nil
+	return append([]string{}, dirs...), nil
 }
 
 // Lists all GCS keys with given prefix.
-func (bucket gcsBucket) listAll(prefix string) ([]string, error) {
-	keys := []string{}
-	it := bucket.Objects(context.Background(), &storage.Query{
-		Prefix: prefix,
-	})
-	for {
-		attrs, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return keys, err
-		}
-		keys = append(keys, attrs.Name)
+func (bucket blobBucket) listAll(prefix string) ([]string, error) {
+	keys, _, err := bucket.List(context.Background(), prefix, "")
+	if err != nil {
+		return []string{}, err
 	}
-	return keys, nil
+	return append([]string{}, keys...), nil
 }
 
 // Gets all build ids for a job.
-func (bucket gcsBucket) listBuildIDs(root string) ([]int64, error) {
+func (bucket blobBucket) listBuildIDs(root string) ([]int64, error) {
 	ids := []int64{}
 	if strings.HasPrefix(root, logsPrefix) {
 		dirs, err := bucket.listSubDirs(root)
@@ -345,7 +325,7 @@ func (a int64slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a int64slice) Less(i, j int) bool { return a[i] < a[j] }
 
 // Gets job history from the GCS bucket specified in config.
-func getJobHistory(url *url.URL, config *config.Config, gcsClient *storage.Client) (jobHistoryTemplate, error) {
+func getJobHistory(url *url.URL, config *config.Config, opener *objectstore.Opener) (jobHistoryTemplate, error) {
 	start := time.Now()
 	tmpl := jobHistoryTemplate{}
 
@@ -354,7 +334,11 @@ func getJobHistory(url *url.URL, config *config.Config, gcsClient *storage.Clien
 		return tmpl, fmt.Errorf("invalid url %s: %v", url.String(), err)
 	}
 	tmpl.Name = root
-	bucket := gcsBucket{bucketName, gcsClient.Bucket(bucketName)}
+	b, err := opener.Open(context.Background(), bucketName)
+	if err != nil {
+		return tmpl, fmt.Errorf("failed to open bucket %s: %v", bucketName, err)
+	}
+	bucket := blobBucket{bucketName, b}
 
 	latest, err := readLatestBuild(bucket, root)
 	if err != nil {
@@ -427,3 +411,117 @@ func getJobHistory(url *url.URL, config *config.Config, gcsClient *storage.Clien
 	logrus.Infof("loaded %s in %v", url.Path, elapsed)
 	return tmpl, nil
 }
+
+// jobHistoryRoot returns the root "directory" under which the results of all
+// runs of the job in dir are listed, along with the build id of the run in dir.
+func jobHistoryRoot(dir string) (root string, buildID int64, err error) {
+	parts := strings.Split(strings.Trim(dir, "/"), "/")
+	if len(parts) < 3 {
+		return "", emptyID, fmt.Errorf("invalid path for a job run: %s", dir)
+	}
+	buildID, err = strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		return "", emptyID, fmt.Errorf("invalid build id in %s: %v", dir, err)
+	}
+	job := parts[len(parts)-2]
+	switch {
+	case parts[0] == logsPrefix && len(parts) == 3:
+		return path.Join(logsPrefix, job), buildID, nil
+	case parts[0] == gcs.PRLogs && parts[1] == "pull":
+		return path.Join(gcs.PRLogs, "directory", job), buildID, nil
+	}
+	return "", emptyID, fmt.Errorf("unrecognized layout for a job run: %s", dir)
+}
+
+// lensHistory gives lenses access to the artifacts of earlier runs of a job.
+type lensHistory struct {
+	opener *objectstore.Opener
+	sg     *spyglass.Spyglass
+	cfg    config.Getter
+}
+
+// EarlierRuns returns up to n runs of the job that preceded the run at src,
+// newest first, with the artifacts whose names match re.
+func (h lensHistory) EarlierRuns(src string, n int, re *regexp.Regexp) ([]lenses.Run, error) {
+	parts := strings.SplitN(src, "/", 3)
+	if len(parts) != 3 || parts[0] != "gcs" {
+		return nil, fmt.Errorf("invalid source for a job run: %s", src)
+	}
+	bucketName := parts[1]
+	root, current, err := jobHistoryRoot(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	b, err := h.opener.Open(context.Background(), bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %v", bucketName, err)
+	}
+	bucket := blobBucket{bucketName, b}
+
+	buildIDs, err := bucket.listBuildIDs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build ids: %v", err)
+	}
+	sort.Sort(sort.Reverse(int64slice(buildIDs)))
+	var earlierIDs []int64
+	for _, buildID := range buildIDs {
+		if len(earlierIDs) >= n {
+			break
+		}
+		if buildID < current {
+			earlierIDs = append(earlierIDs, buildID)
+		}
+	}
+
+	// concurrently fetch the artifacts of all the earlier runs
+	runs := make([]lenses.Run, len(earlierIDs))
+	errs := make([]error, len(earlierIDs))
+	var wg sync.WaitGroup
+	for i, buildID := range earlierIDs {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			runs[i], errs[i] = h.run(bucket, root, id, re)
+		}(i, strconv.FormatInt(buildID, 10))
+	}
+	wg.Wait()
+
+	var earlier []lenses.Run
+	for i, run := range runs {
+		if errs[i] != nil {
+			logrus.WithError(errs[i]).Warningf("failed to load run %d of %s", earlierIDs[i], root)
+			continue
+		}
+		earlier = append(earlier, run)
+	}
+	return earlier, nil
+}
+
+func (h lensHistory) run(bucket blobBucket, root, id string, re *regexp.Regexp) (lenses.Run, error) {
+	run := lenses.Run{ID: id}
+	dir, err := bucket.getPath(root, id, "")
+	if err != nil {
+		return run, fmt.Errorf("failed to get path: %v", err)
+	}
+	if run.Link, err = bucket.spyglassLink(root, id); err != nil {
+		return run, fmt.Errorf("failed to get spyglass link: %v", err)
+	}
+	src := path.Join("gcs", bucket.getName(), dir)
+	names, err := h.sg.ListArtifacts(src)
+	if err != nil {
+		return run, fmt.Errorf("failed to list artifacts: %v", err)
+	}
+	var matching []string
+	for _, name := range names {
+		if re.MatchString(name) {
+			matching = append(matching, name)
+		}
+	}
+	if len(matching) == 0 {
+		return run, nil
+	}
+	if run.Artifacts, err = h.sg.FetchArtifacts(src, "", h.cfg().Deck.Spyglass.SizeLimit, matching); err != nil {
+		return run, fmt.Errorf("failed to fetch artifacts: %v", err)
+	}
+	return run, nil
+}
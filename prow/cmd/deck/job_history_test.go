@@ -176,3 +176,58 @@ func TestLinkID(t *testing.T) {
 		}
 	}
 }
+
+func TestJobHistoryRoot(t *testing.T) {
+	cases := []struct {
+		dir    string
+		root   string
+		id     int64
+		expErr bool
+	}{
+		{
+			dir:  "logs/bar-e2e/123",
+			root: "logs/bar-e2e",
+			id:   123,
+		},
+		{
+			dir:  "pr-logs/pull/org_repo/42/bar-e2e/123/",
+			root: "pr-logs/directory/bar-e2e",
+			id:   123,
+		},
+		{
+			dir:  "pr-logs/pull/batch/bar-e2e/123",
+			root: "pr-logs/directory/bar-e2e",
+			id:   123,
+		},
+		{
+			dir:    "logs/bar-e2e/latest",
+			expErr: true,
+		},
+		{
+			dir:    "logs/bar-e2e",
+			expErr: true,
+		},
+		{
+			dir:    "other/bar-e2e/123",
+			expErr: true,
+		},
+	}
+	for _, tc := range cases {
+		root, id, err := jobHistoryRoot(tc.dir)
+		if tc.expErr {
+			if err == nil {
+				t.Errorf("finding the root of %q: expected an error, got none", tc.dir)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("finding the root of %q: unexpected error: %v", tc.dir, err)
+		}
+		if root != tc.root {
+			t.Errorf("finding the root of %q: expected root %s, got %s", tc.dir, tc.root, root)
+		}
+		if id != tc.id {
+			t.Errorf("finding the root of %q: expected id %d, got %d", tc.dir, tc.id, id)
+		}
+	}
+}
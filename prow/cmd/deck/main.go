@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
@@ -25,9 +26,11 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"strconv"
 	"strings"
@@ -44,21 +47,27 @@ import (
 	"golang.org/x/oauth2/github"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/config/secret"
 	"k8s.io/test-infra/prow/deck/jobs"
+	"k8s.io/test-infra/prow/flagutil"
 	"k8s.io/test-infra/prow/githuboauth"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pjutil"
 	"k8s.io/test-infra/prow/pluginhelp"
 	"k8s.io/test-infra/prow/prstatus"
 	"k8s.io/test-infra/prow/spyglass"
+	"k8s.io/test-infra/prow/tide/history"
 
 	// Import standard spyglass viewers
 
 	"k8s.io/test-infra/prow/spyglass/lenses"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/buildlog"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/coverage"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/junit"
 	_ "k8s.io/test-infra/prow/spyglass/lenses/metadata"
+	_ "k8s.io/test-infra/prow/spyglass/lenses/podinfo"
 )
 
 type options struct {
@@ -78,12 +87,18 @@ type options struct {
 	spyglass              bool
 	spyglassFilesLocation string
 	gcsCredentialsFile    string
+	s3CredentialsFile     string
+	fileStorageRoot       string
+	github                flagutil.GitHubOptions
 }
 
 func (o *options) Validate() error {
 	if o.configPath == "" {
 		return errors.New("required flag --config-path was unset")
 	}
+	if err := o.github.Validate(false); err != nil {
+		return err
+	}
 	if o.oauthURL != "" {
 		if o.githubOAuthConfigFile == "" {
 			return errors.New("an OAuth URL was provided but required flag --github-oauth-config-file was unset")
@@ -115,6 +130,10 @@ func gatherOptions() options {
 	flag.StringVar(&o.staticFilesLocation, "static-files-location", "/static", "Path to the static files")
 	flag.StringVar(&o.templateFilesLocation, "template-files-location", "/template", "Path to the template files")
 	flag.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Path to the GCS credentials file")
+	flag.StringVar(&o.s3CredentialsFile, "s3-credentials-file", "", "Path to the file with the credentials and endpoint of S3 buckets")
+	flag.StringVar(&o.fileStorageRoot, "file-storage-root", "", "Path to the directory that holds the directories of file buckets")
+	// the GitHub client fetches the sources that lenses show, anonymously unless a token is given
+	o.github.AddFlagsWithoutDefaultGithubTokenPath(flag.CommandLine)
 	flag.Parse()
 	return o
 }
@@ -226,8 +245,13 @@ func prodOnlyMain(cfg config.Getter, o options, mux *http.ServeMux) *http.ServeM
 	mux.Handle("/data.js", gziphandler.GzipHandler(handleData(ja)))
 	mux.Handle("/prowjobs.js", gziphandler.GzipHandler(handleProwJobs(ja)))
 	mux.Handle("/badge.svg", gziphandler.GzipHandler(handleBadge(ja)))
+	mux.Handle("/resources", gziphandler.GzipHandler(handleResources(o, cfg, ja)))
+	mux.Handle("/resources.js", gziphandler.GzipHandler(handleResourceData(ja)))
 	mux.Handle("/log", gziphandler.GzipHandler(handleLog(ja)))
+	// Compressing the events would hold them back until the buffer fills.
+	mux.Handle("/log-stream", handleLogStream(ja))
 	mux.Handle("/rerun", gziphandler.GzipHandler(handleRerun(kc)))
+	mux.Handle("/abort", handleAbort(kc, cfg))
 
 	if o.spyglass {
 		initSpyglass(cfg, o, mux, ja)
@@ -357,14 +381,34 @@ func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.Job
 	if err != nil {
 		logrus.WithError(err).Fatal("Error getting GCS client")
 	}
-	sg := spyglass.New(ja, cfg, c, context.Background())
+	opener := &objectstore.Opener{
+		GCSClient:         c,
+		S3CredentialsFile: o.s3CredentialsFile,
+		FileRoot:          o.fileStorageRoot,
+	}
+	sg := spyglass.New(ja, cfg, opener, c, context.Background())
 	sg.Start()
+	lenses.RegisterJobHistory(lensHistory{opener: opener, sg: sg, cfg: cfg})
+
+	var secretAgent *secret.Agent
+	if o.github.TokenPath != "" {
+		secretAgent = &secret.Agent{}
+		if err := secretAgent.Start([]string{o.github.TokenPath}); err != nil {
+			logrus.WithError(err).Fatal("Error starting secrets agent.")
+		}
+	}
+	githubClient, err := o.github.GitHubClient(secretAgent, false)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting GitHub client.")
+	}
+	lenses.RegisterSourceFetcher(lensSource{sg: sg, cfg: cfg, gh: githubClient})
 
 	mux.Handle("/spyglass/static/", http.StripPrefix("/spyglass/static", staticHandlerFromDir(o.spyglassFilesLocation)))
 	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg))))
 	mux.Handle("/view/", gziphandler.GzipHandler(handleRequestJobViews(sg, cfg, o)))
-	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, c)))
-	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, c)))
+	mux.Handle(spyglass.ArtifactPath, gziphandler.GzipHandler(http.StripPrefix(spyglass.ArtifactPath, handleArtifact(cfg, opener))))
+	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, opener)))
+	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, opener)))
 }
 
 func loadToken(file string) ([]byte, error) {
@@ -487,6 +531,64 @@ func handleBadge(ja *jobs.JobAgent) http.HandlerFunc {
 	}
 }
 
+// getResourceReport aggregates the resources recorded by the ProwJobs
+// known to the job agent according to the query of the request.
+func getResourceReport(ja *jobs.JobAgent, query url.Values) (*resourceReport, error) {
+	groupBy, window, err := parseResourceQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().Add(-window)
+	return &resourceReport{
+		GroupBy:   groupBy,
+		Since:     since,
+		Summaries: summarizeResources(ja.ProwJobs(), groupBy, since),
+	}, nil
+}
+
+// handleResources renders the resources used by jobs, repos or orgs.
+// The url may look like this:
+//
+// /resources?by=<job|repo|org>&window=<duration>
+//
+// Example:
+// - /resources?by=repo&window=720h
+func handleResources(o options, cfg config.Getter, ja *jobs.JobAgent) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		report, err := getResourceReport(ja, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handleSimpleTemplate(o, cfg, "resources.html", report)(w, r)
+	}
+}
+
+// handleResourceData serves the same report as handleResources as JSON.
+func handleResourceData(ja *jobs.JobAgent) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		report, err := getResourceReport(ja, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rd, err := json.Marshal(report)
+		if err != nil {
+			logrus.WithError(err).Error("Error marshaling resource report.")
+			rd = []byte("{}")
+		}
+		// If we have a "var" query, then write out "var value = {...};".
+		// Otherwise, just write out the JSON.
+		if v := r.URL.Query().Get("var"); v != "" {
+			fmt.Fprintf(w, "var %s = %s;", v, string(rd))
+		} else {
+			fmt.Fprint(w, string(rd))
+		}
+	}
+}
+
 // handleJobHistory handles requests to get the history of a given job
 // The url must look like this for presubmits:
 //
@@ -501,10 +603,12 @@ func handleBadge(ja *jobs.JobAgent) http.HandlerFunc {
 //
 // Example:
 // - /job-history/kubernetes-jenkins/logs/ci-kubernetes-e2e-prow-canary
-func handleJobHistory(o options, cfg config.Getter, gcsClient *storage.Client) http.HandlerFunc {
+//
+// Buckets in other storage than GCS are given like s3:<bucket-name>.
+func handleJobHistory(o options, cfg config.Getter, opener *objectstore.Opener) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		tmpl, err := getJobHistory(r.URL, cfg(), gcsClient)
+		tmpl, err := getJobHistory(r.URL, cfg(), opener)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get job history: %v", err)
 			logrus.WithField("url", r.URL).Error(msg)
@@ -519,10 +623,10 @@ func handleJobHistory(o options, cfg config.Getter, gcsClient *storage.Client) h
 // The url must look like this:
 //
 // /pr-history/<org>/<repo>/<pr number>
-func handlePRHistory(o options, cfg config.Getter, gcsClient *storage.Client) http.HandlerFunc {
+func handlePRHistory(o options, cfg config.Getter, opener *objectstore.Opener) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
-		tmpl, err := getPRHistory(r.URL, cfg(), gcsClient)
+		tmpl, err := getPRHistory(r.URL, cfg(), opener)
 		if err != nil {
 			msg := fmt.Sprintf("failed to get PR history: %v", err)
 			logrus.WithField("url", r.URL).Error(msg)
@@ -533,6 +637,88 @@ func handlePRHistory(o options, cfg config.Getter, gcsClient *storage.Client) ht
 	}
 }
 
+// handleArtifact serves the content of artifacts in buckets that have no web
+// address of their own. The url must look like this:
+//
+// /spyglass/artifact/<bucket>/<path>
+//
+// Example:
+// - /spyglass/artifact/s3:artifacts/logs/ci-kubernetes-e2e-prow-canary/1234/build-log.txt
+//
+// GCS artifacts are linked to directly and are not served. Neither are
+// artifacts in buckets that no job uploads to.
+func handleArtifact(cfg config.Getter, opener *objectstore.Opener) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(r.URL.Path, "/", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			http.Error(w, "expected /spyglass/artifact/<bucket>/<path>", http.StatusBadRequest)
+			return
+		}
+		scheme, _, err := objectstore.ParseBucket(parts[0])
+		if err != nil || scheme == objectstore.GCS {
+			http.Error(w, fmt.Sprintf("no artifacts are served from %q", parts[0]), http.StatusNotFound)
+			return
+		}
+		if !configuredBucket(cfg(), parts[0]) {
+			http.Error(w, fmt.Sprintf("bucket %q is not configured", parts[0]), http.StatusForbidden)
+			return
+		}
+		bucket, err := opener.Open(r.Context(), parts[0])
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed to open bucket %s.", parts[0])
+			http.Error(w, "failed to open bucket", http.StatusInternalServerError)
+			return
+		}
+		reader, err := bucket.NewReader(r.Context(), parts[1])
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			logrus.WithError(err).Errorf("Failed to read %s.", r.URL.Path)
+			http.Error(w, "failed to read artifact", http.StatusInternalServerError)
+			return
+		}
+		defer reader.Close()
+		// Artifacts are untrusted, so browsers should not render them.
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err := io.Copy(w, reader); err != nil {
+			logrus.WithError(err).Warnf("Failed to serve %s.", r.URL.Path)
+		}
+	}
+}
+
+// configuredBucket determines whether the default decoration config or the
+// decoration config of a job uploads to the bucket.
+func configuredBucket(c *config.Config, bucket string) bool {
+	var decorationConfigs []*prowapi.DecorationConfig
+	if c.Plank.DefaultDecorationConfig != nil {
+		decorationConfigs = append(decorationConfigs, c.Plank.DefaultDecorationConfig)
+	}
+	for _, job := range c.AllPresubmits(nil) {
+		decorationConfigs = append(decorationConfigs, job.DecorationConfig)
+	}
+	for _, job := range c.AllPostsubmits(nil) {
+		decorationConfigs = append(decorationConfigs, job.DecorationConfig)
+	}
+	for _, job := range c.AllPeriodics() {
+		decorationConfigs = append(decorationConfigs, job.DecorationConfig)
+	}
+	scheme, name, err := objectstore.ParseBucket(bucket)
+	if err != nil {
+		return false
+	}
+	for _, dc := range decorationConfigs {
+		if dc == nil || dc.GCSConfiguration == nil {
+			continue
+		}
+		if s, n, err := objectstore.ParseBucket(dc.GCSConfiguration.Bucket); err == nil && s == scheme && n == name {
+			return true
+		}
+	}
+	return false
+}
+
 // handleRequestJobViews handles requests to get all available artifact views for a given job.
 // The url must specify a storage key type, such as "prowjob" or "gcs":
 //
@@ -788,6 +974,7 @@ func handleTidePools(cfg config.Getter, ta *tideAgent) http.HandlerFunc {
 		payload := tidePools{
 			Queries:     queries,
 			TideQueries: queryConfigs,
+			Priorities:  cfg().Tide.Priority,
 			Pools:       pools,
 		}
 		pd, err := json.Marshal(payload)
@@ -808,13 +995,18 @@ func handleTidePools(cfg config.Getter, ta *tideAgent) http.HandlerFunc {
 func handleTideHistory(ta *tideAgent) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setHeadersNoCaching(w)
+		q, err := history.ParseQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		ta.Lock()
-		history := ta.history
+		hist := ta.history
 		ta.Unlock()
 
 		payload := tideHistory{
-			History: history,
+			History: queryHistory(hist, q),
 		}
 		pd, err := json.Marshal(payload)
 		if err != nil {
@@ -891,6 +1083,102 @@ func handleLog(lc logClient) http.HandlerFunc {
 	}
 }
 
+type logStreamer interface {
+	StreamJobLog(job, id string) (io.ReadCloser, error)
+}
+
+// handleLogStream follows the log of a running job as Server-Sent Events. Each line of the
+// log is an event holding the line as a JSON string, with the byte offset after the line as
+// its ID so that browsers that reconnect resume after the last line they got. An "end" event
+// is sent once the log is complete.
+//
+// Query params:
+// - job: required, the name of the job
+// - id: required, the build ID of the job
+// - offset: optional, the byte offset to start at, overridden by the Last-Event-ID header
+func handleLogStream(ls logStreamer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		job := r.URL.Query().Get("job")
+		id := r.URL.Query().Get("id")
+		logger := logrus.WithFields(logrus.Fields{"job": job, "id": id})
+		if err := validateLogRequest(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var offset int64
+		start := r.Header.Get("Last-Event-ID")
+		if start == "" {
+			start = r.URL.Query().Get("offset")
+		}
+		if start != "" {
+			var err error
+			if offset, err = strconv.ParseInt(start, 10, 64); err != nil || offset < 0 {
+				http.Error(w, fmt.Sprintf("invalid offset %q", start), http.StatusBadRequest)
+				return
+			}
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		stream, err := ls.StreamJobLog(job, id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Log not found: %v", err), http.StatusNotFound)
+			// Logs can't be followed before the test container starts,
+			// which is not something administrators can act on.
+			logger.WithError(err).Info("Log stream not found.")
+			return
+		}
+		defer stream.Close()
+		// Reading the stream only stops when the log does, so close it
+		// once the browser goes away.
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			<-ctx.Done()
+			stream.Close()
+		}()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Keep proxies like nginx from buffering the events.
+		w.Header().Set("X-Accel-Buffering", "no")
+		if _, err := io.CopyN(ioutil.Discard, stream, offset); err != nil && err != io.EOF {
+			logger.WithError(err).Info("Error skipping to the offset of the log stream.")
+			return
+		}
+		reader := bufio.NewReader(stream)
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				offset += int64(len(line))
+				// Encoding the line keeps carriage returns from
+				// ending the event early.
+				data, _ := json.Marshal(strings.TrimSuffix(line, "\n"))
+				if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", offset, data); err != nil {
+					return
+				}
+				if reader.Buffered() == 0 {
+					flusher.Flush()
+				}
+			}
+			if err == io.EOF {
+				fmt.Fprint(w, "event: end\ndata: \n\n")
+				flusher.Flush()
+				return
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.WithError(err).Info("Error following the log stream.")
+				}
+				return
+			}
+		}
+	}
+}
+
 func validateLogRequest(r *http.Request) error {
 	job := r.URL.Query().Get("job")
 	id := r.URL.Query().Get("id")
@@ -906,6 +1194,7 @@ func validateLogRequest(r *http.Request) error {
 
 type pjClient interface {
 	GetProwJob(string) (prowapi.ProwJob, error)
+	ReplaceProwJob(string, prowapi.ProwJob) (prowapi.ProwJob, error)
 }
 
 func handleRerun(kc pjClient) http.HandlerFunc {
@@ -934,6 +1223,46 @@ func handleRerun(kc pjClient) http.HandlerFunc {
 	}
 }
 
+// handleAbort requests the cancellation of a running ProwJob. The agent that
+// runs the job aborts it on its next sync.
+func handleAbort(kc pjClient, cfg config.Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg().Deck.AllowAbort {
+			http.Error(w, "Aborting jobs is not enabled.", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("Method %s is not allowed.", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Query().Get("prowjob")
+		if name == "" {
+			http.Error(w, "request did not provide the 'prowjob' query parameter", http.StatusBadRequest)
+			return
+		}
+		pj, err := kc.GetProwJob(name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ProwJob not found: %v", err), http.StatusNotFound)
+			logrus.WithError(err).Warning("ProwJob not found.")
+			return
+		}
+		if pj.Complete() {
+			http.Error(w, fmt.Sprintf("ProwJob %s has already completed.", name), http.StatusConflict)
+			return
+		}
+		if !pj.Spec.Cancel {
+			pj.Spec.Cancel = true
+			if _, err := kc.ReplaceProwJob(name, pj); err != nil {
+				http.Error(w, fmt.Sprintf("Error requesting cancellation: %v", err), http.StatusInternalServerError)
+				logrus.WithError(err).Error("Error requesting cancellation of ProwJob.")
+				return
+			}
+			logrus.WithFields(pjutil.ProwJobFields(&pj)).Info("Requested cancellation of ProwJob.")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
 func handleConfig(cfg config.Getter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// TODO(bentheelder): add the ability to query for portions of the config?
@@ -27,15 +27,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pluginhelp"
 	"k8s.io/test-infra/prow/tide"
 	"k8s.io/test-infra/prow/tide/history"
@@ -192,12 +197,104 @@ func TestHandleLog(t *testing.T) {
 	}
 }
 
+type fls int
+
+func (f fls) StreamJobLog(job, id string) (io.ReadCloser, error) {
+	if job == "job" && id == "123" {
+		return ioutil.NopCloser(strings.NewReader("hello\nwor\rld\nbye")), nil
+	}
+	return nil, errors.New("muahaha")
+}
+
+func TestHandleLogStream(t *testing.T) {
+	var testcases = []struct {
+		name        string
+		path        string
+		lastEventID string
+		code        int
+		body        string
+	}{
+		{
+			name: "job but no id",
+			path: "?job=job",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "id and job, not found",
+			path: "?job=ohno&id=123",
+			code: http.StatusNotFound,
+		},
+		{
+			name: "invalid offset",
+			path: "?job=job&id=123&offset=-1",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "whole log",
+			path: "?job=job&id=123",
+			code: http.StatusOK,
+			body: "id: 6\ndata: \"hello\"\n\nid: 13\ndata: \"wor\\rld\"\n\nid: 16\ndata: \"bye\"\n\nevent: end\ndata: \n\n",
+		},
+		{
+			name: "from offset",
+			path: "?job=job&id=123&offset=6",
+			code: http.StatusOK,
+			body: "id: 13\ndata: \"wor\\rld\"\n\nid: 16\ndata: \"bye\"\n\nevent: end\ndata: \n\n",
+		},
+		{
+			name:        "last event ID overrides the offset",
+			path:        "?job=job&id=123&offset=6",
+			lastEventID: "13",
+			code:        http.StatusOK,
+			body:        "id: 16\ndata: \"bye\"\n\nevent: end\ndata: \n\n",
+		},
+		{
+			name:        "offset past the end",
+			path:        "?job=job&id=123",
+			lastEventID: "100",
+			code:        http.StatusOK,
+			body:        "event: end\ndata: \n\n",
+		},
+	}
+	handler := handleLogStream(fls(0))
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/log-stream"+tc.path, nil)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.code {
+				t.Fatalf("Wrong error code. Got %v, want %v", rr.Code, tc.code)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("Wrong content type. Got %q", contentType)
+			}
+			if body := rr.Body.String(); body != tc.body {
+				t.Errorf("Unexpected body: got %q, want %q.", body, tc.body)
+			}
+		})
+	}
+}
+
 type fpjc prowapi.ProwJob
 
 func (fc *fpjc) GetProwJob(name string) (prowapi.ProwJob, error) {
 	return prowapi.ProwJob(*fc), nil
 }
 
+func (fc *fpjc) ReplaceProwJob(name string, pj prowapi.ProwJob) (prowapi.ProwJob, error) {
+	*fc = fpjc(pj)
+	return pj, nil
+}
+
 // TestRerun just checks that the result can be unmarshaled properly, has an
 // updated status, and has equal spec.
 func TestRerun(t *testing.T) {
@@ -245,6 +342,72 @@ func TestRerun(t *testing.T) {
 	}
 }
 
+func TestAbort(t *testing.T) {
+	completed := metav1.Now()
+	var testcases = []struct {
+		name       string
+		allowAbort bool
+		method     string
+		status     prowapi.ProwJobStatus
+
+		expectedCode   int
+		expectedCancel bool
+	}{
+		{
+			name:           "running job is cancelled",
+			allowAbort:     true,
+			method:         http.MethodPost,
+			status:         prowapi.ProwJobStatus{State: prowapi.PendingState},
+			expectedCode:   http.StatusAccepted,
+			expectedCancel: true,
+		},
+		{
+			name:         "aborting is disabled by default",
+			method:       http.MethodPost,
+			status:       prowapi.ProwJobStatus{State: prowapi.PendingState},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "only POST requests abort",
+			allowAbort:   true,
+			method:       http.MethodGet,
+			status:       prowapi.ProwJobStatus{State: prowapi.PendingState},
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "complete job cannot be cancelled",
+			allowAbort:   true,
+			method:       http.MethodPost,
+			status:       prowapi.ProwJobStatus{State: prowapi.SuccessState, CompletionTime: &completed},
+			expectedCode: http.StatusConflict,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := fpjc(prowapi.ProwJob{
+				Spec:   prowapi.ProwJobSpec{Job: "whoa"},
+				Status: tc.status,
+			})
+			cfg := func() *config.Config {
+				return &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{AllowAbort: tc.allowAbort}}}
+			}
+			handler := handleAbort(&fc, cfg)
+			req, err := http.NewRequest(tc.method, "/abort?prowjob=wowsuch", nil)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, rr.Code)
+			}
+			if fc.Spec.Cancel != tc.expectedCancel {
+				t.Errorf("expected cancel: %v, got %v", tc.expectedCancel, fc.Spec.Cancel)
+			}
+		})
+	}
+}
+
 func TestTide(t *testing.T) {
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		pools := []tide.Pool{
@@ -365,6 +528,94 @@ func TestTideHistory(t *testing.T) {
 	}
 }
 
+func TestHandleArtifact(t *testing.T) {
+	root, err := ioutil.TempDir("", "deck")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+	for _, bucket := range []string{"artifacts", "job-bucket", "other"} {
+		dir := filepath.Join(root, bucket, "logs", "job", "1")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Error creating job directory: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "build-log.txt"), []byte("<b>log</b>"), 0644); err != nil {
+			t.Fatalf("Error writing build log: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "artifact in a file bucket is served",
+			path:         "file:artifacts/logs/job/1/build-log.txt",
+			expectedCode: http.StatusOK,
+			expectedBody: "<b>log</b>",
+		},
+		{
+			name:         "missing artifact",
+			path:         "file:artifacts/logs/job/2/build-log.txt",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "GCS artifacts are not served",
+			path:         "artifacts/logs/job/1/build-log.txt",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "artifact in a job's bucket is served",
+			path:         "file:job-bucket/logs/job/1/build-log.txt",
+			expectedCode: http.StatusOK,
+			expectedBody: "<b>log</b>",
+		},
+		{
+			name:         "unconfigured bucket is forbidden",
+			path:         "file:other/logs/job/1/build-log.txt",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "missing path",
+			path:         "file:artifacts",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	cfg := &config.Config{}
+	cfg.Plank.DefaultDecorationConfig = &prowapi.DecorationConfig{
+		GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "file://artifacts"},
+	}
+	cfg.Periodics = []config.Periodic{{JobBase: config.JobBase{
+		Name: "job",
+		UtilityConfig: config.UtilityConfig{DecorationConfig: &prowapi.DecorationConfig{
+			GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "file://job-bucket"},
+		}},
+	}}}
+	handler := handleArtifact(func() *config.Config { return cfg }, &objectstore.Opener{FileRoot: root})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/"+tc.path, nil)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			req.URL.Path = tc.path
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Expected code %d, got %d", tc.expectedCode, rr.Code)
+			}
+			if tc.expectedBody != "" && rr.Body.String() != tc.expectedBody {
+				t.Errorf("Expected body %q, got %q", tc.expectedBody, rr.Body.String())
+			}
+			if tc.expectedCode == http.StatusOK && rr.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+				t.Errorf("Expected artifacts to be served as text, got %q", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
 func TestHelp(t *testing.T) {
 	hitCount := 0
 	help := pluginhelp.Help{
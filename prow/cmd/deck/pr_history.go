@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"path"
@@ -26,12 +27,12 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/sets"
 	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/gcsupload"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 )
 
@@ -230,15 +231,20 @@ func getGCSDirsForPR(config *config.Config, org, repo string, pr int) (map[strin
 			},
 		}, "")
 		gcsPath, _ = path.Split(path.Clean(gcsPath))
-		if _, ok := toSearch[gcsConfig.Bucket]; !ok {
-			toSearch[gcsConfig.Bucket] = sets.String{}
+		// Buckets are keyed by the form used in links.
+		bucket, err := objectstore.PathSegment(gcsConfig.Bucket)
+		if err != nil {
+			return toSearch, fmt.Errorf("invalid bucket for %s: %v", presubmit.Name, err)
 		}
-		toSearch[gcsConfig.Bucket].Insert(gcsPath)
+		if _, ok := toSearch[bucket]; !ok {
+			toSearch[bucket] = sets.String{}
+		}
+		toSearch[bucket].Insert(gcsPath)
 	}
 	return toSearch, nil
 }
 
-func getPRHistory(url *url.URL, config *config.Config, gcsClient *storage.Client) (prHistoryTemplate, error) {
+func getPRHistory(url *url.URL, config *config.Config, opener *objectstore.Opener) (prHistoryTemplate, error) {
 	start := time.Now()
 	template := prHistoryTemplate{}
 
@@ -259,7 +265,11 @@ func getPRHistory(url *url.URL, config *config.Config, gcsClient *storage.Client
 	jobCommitBuilds := make(map[string]map[string][]buildData)
 
 	for bucketName, gcsPaths := range toSearch {
-		bucket := gcsBucket{bucketName, gcsClient.Bucket(bucketName)}
+		b, err := opener.Open(context.Background(), bucketName)
+		if err != nil {
+			return template, fmt.Errorf("failed to open bucket %s: %v", bucketName, err)
+		}
+		bucket := blobBucket{bucketName, b}
 		for gcsPath := range gcsPaths {
 			jobPrefixes, err := bucket.listSubDirs(gcsPath)
 			if err != nil {
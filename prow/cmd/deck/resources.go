@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+const defaultResourceWindow = 7 * 24 * time.Hour
+
+// resourceSummary aggregates the resources used by the completed
+// jobs of a job, repo or org.
+type resourceSummary struct {
+	Name string `json:"name"`
+	Runs int    `json:"runs"`
+	// DurationSeconds is the wall-clock time the pods of the jobs ran for.
+	DurationSeconds int64 `json:"duration_seconds"`
+	// RequestedCPUCoreSeconds are the requested CPU cores times duration.
+	RequestedCPUCoreSeconds float64 `json:"requested_cpu_core_seconds"`
+	// RequestedMemoryGiBSeconds are the requested memory times duration.
+	RequestedMemoryGiBSeconds float64 `json:"requested_memory_gib_seconds"`
+	// UsedCPUSeconds is the CPU time the test processes consumed.
+	UsedCPUSeconds float64 `json:"used_cpu_seconds"`
+	// NodeTypeSeconds is the wall-clock time spent on each node type.
+	NodeTypeSeconds map[string]int64 `json:"node_type_seconds,omitempty"`
+}
+
+// resourceReport is served by /resources.js and rendered by /resources.
+type resourceReport struct {
+	GroupBy   string            `json:"group_by"`
+	Since     time.Time         `json:"since"`
+	Summaries []resourceSummary `json:"summaries"`
+}
+
+// parseResourceQuery reads the grouping and the time window of a resource
+// report from a query such as ?by=repo&window=720h.
+func parseResourceQuery(query url.Values) (string, time.Duration, error) {
+	groupBy := query.Get("by")
+	switch groupBy {
+	case "":
+		groupBy = "job"
+	case "job", "repo", "org":
+	default:
+		return "", 0, fmt.Errorf("cannot group by %q, expected one of job, repo or org", groupBy)
+	}
+	window := defaultResourceWindow
+	if w := query.Get("window"); w != "" {
+		var err error
+		if window, err = time.ParseDuration(w); err != nil || window <= 0 {
+			return "", 0, fmt.Errorf("invalid window %q", w)
+		}
+	}
+	return groupBy, window, nil
+}
+
+// resourceGroup determines the job, repo or org a ProwJob is accounted for.
+// Jobs without refs are accounted for under an empty repo and org.
+func resourceGroup(pj prowapi.ProwJob, groupBy string) string {
+	refs := pj.Spec.Refs
+	if refs == nil && len(pj.Spec.ExtraRefs) > 0 {
+		refs = &pj.Spec.ExtraRefs[0]
+	}
+	switch groupBy {
+	case "org":
+		if refs == nil {
+			return ""
+		}
+		return refs.Org
+	case "repo":
+		if refs == nil {
+			return ""
+		}
+		return refs.Org + "/" + refs.Repo
+	default:
+		return pj.Spec.Job
+	}
+}
+
+// summarizeResources aggregates the resources recorded by the jobs that
+// completed since the given time, ordering the most expensive first.
+func summarizeResources(pjs []prowapi.ProwJob, groupBy string, since time.Time) []resourceSummary {
+	byName := map[string]*resourceSummary{}
+	for _, pj := range pjs {
+		usage := pj.Status.Resources
+		if usage == nil || pj.Status.CompletionTime == nil || pj.Status.CompletionTime.Time.Before(since) {
+			continue
+		}
+		name := resourceGroup(pj, groupBy)
+		summary, ok := byName[name]
+		if !ok {
+			summary = &resourceSummary{Name: name, NodeTypeSeconds: map[string]int64{}}
+			byName[name] = summary
+		}
+		summary.Runs++
+		summary.DurationSeconds += usage.DurationSeconds
+		duration := float64(usage.DurationSeconds)
+		if cpu, ok := usage.Requests[coreapi.ResourceCPU]; ok {
+			summary.RequestedCPUCoreSeconds += float64(cpu.MilliValue()) / 1000 * duration
+		}
+		if memory, ok := usage.Requests[coreapi.ResourceMemory]; ok {
+			summary.RequestedMemoryGiBSeconds += float64(memory.Value()) / (1 << 30) * duration
+		}
+		summary.UsedCPUSeconds += float64(usage.CPUMillis) / 1000
+		if usage.NodeType != "" {
+			summary.NodeTypeSeconds[usage.NodeType] += usage.DurationSeconds
+		}
+	}
+
+	summaries := make([]resourceSummary, 0, len(byName))
+	for _, summary := range byName {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].RequestedCPUCoreSeconds != summaries[j].RequestedCPUCoreSeconds {
+			return summaries[i].RequestedCPUCoreSeconds > summaries[j].RequestedCPUCoreSeconds
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
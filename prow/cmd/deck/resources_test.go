@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestParseResourceQuery(t *testing.T) {
+	var testcases = []struct {
+		name  string
+		query string
+
+		expectedGroupBy string
+		expectedWindow  time.Duration
+		expectedErr     bool
+	}{
+		{
+			name:            "defaults",
+			expectedGroupBy: "job",
+			expectedWindow:  defaultResourceWindow,
+		},
+		{
+			name:            "group by repo over a month",
+			query:           "by=repo&window=720h",
+			expectedGroupBy: "repo",
+			expectedWindow:  720 * time.Hour,
+		},
+		{
+			name:        "unknown grouping",
+			query:       "by=author",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid window",
+			query:       "window=month",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("invalid query: %v", err)
+			}
+			groupBy, window, err := parseResourceQuery(query)
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if groupBy != tc.expectedGroupBy || window != tc.expectedWindow {
+				t.Errorf("expected %s over %s, got %s over %s", tc.expectedGroupBy, tc.expectedWindow, groupBy, window)
+			}
+		})
+	}
+}
+
+func TestSummarizeResources(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	prowJob := func(job, org, repo string, completed time.Time, usage *prowapi.ResourceUsage) prowapi.ProwJob {
+		completion := metav1.NewTime(completed)
+		pj := prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{Job: job},
+			Status: prowapi.ProwJobStatus{
+				CompletionTime: &completion,
+				Resources:      usage,
+			},
+		}
+		if org != "" {
+			pj.Spec.Refs = &prowapi.Refs{Org: org, Repo: repo}
+		}
+		return pj
+	}
+	usage := func(cpu, memory string, seconds int64) *prowapi.ResourceUsage {
+		return &prowapi.ResourceUsage{
+			Requests: coreapi.ResourceList{
+				coreapi.ResourceCPU:    resource.MustParse(cpu),
+				coreapi.ResourceMemory: resource.MustParse(memory),
+			},
+			CPUMillis:       seconds * 500,
+			NodeType:        "n1-standard-4",
+			DurationSeconds: seconds,
+		}
+	}
+	pjs := []prowapi.ProwJob{
+		prowJob("unit", "org", "a", now, usage("1", "1Gi", 100)),
+		prowJob("unit", "org", "a", now, usage("1", "1Gi", 100)),
+		prowJob("e2e", "org", "b", now, usage("4", "2Gi", 100)),
+		prowJob("periodic", "", "", now, usage("500m", "1Gi", 10)),
+		prowJob("old", "org", "a", now.Add(-2*time.Hour), usage("1", "1Gi", 100)),
+		prowJob("jenkins", "org", "a", now, nil),
+	}
+
+	var testcases = []struct {
+		groupBy  string
+		expected []resourceSummary
+	}{
+		{
+			groupBy: "job",
+			expected: []resourceSummary{
+				{Name: "e2e", Runs: 1, DurationSeconds: 100, RequestedCPUCoreSeconds: 400, RequestedMemoryGiBSeconds: 200, UsedCPUSeconds: 50, NodeTypeSeconds: map[string]int64{"n1-standard-4": 100}},
+				{Name: "unit", Runs: 2, DurationSeconds: 200, RequestedCPUCoreSeconds: 200, RequestedMemoryGiBSeconds: 200, UsedCPUSeconds: 100, NodeTypeSeconds: map[string]int64{"n1-standard-4": 200}},
+				{Name: "periodic", Runs: 1, DurationSeconds: 10, RequestedCPUCoreSeconds: 5, RequestedMemoryGiBSeconds: 10, UsedCPUSeconds: 5, NodeTypeSeconds: map[string]int64{"n1-standard-4": 10}},
+			},
+		},
+		{
+			groupBy: "org",
+			expected: []resourceSummary{
+				{Name: "org", Runs: 3, DurationSeconds: 300, RequestedCPUCoreSeconds: 600, RequestedMemoryGiBSeconds: 400, UsedCPUSeconds: 150, NodeTypeSeconds: map[string]int64{"n1-standard-4": 300}},
+				{Name: "", Runs: 1, DurationSeconds: 10, RequestedCPUCoreSeconds: 5, RequestedMemoryGiBSeconds: 10, UsedCPUSeconds: 5, NodeTypeSeconds: map[string]int64{"n1-standard-4": 10}},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.groupBy, func(t *testing.T) {
+			if actual := summarizeResources(pjs, tc.groupBy, since); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected summaries %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
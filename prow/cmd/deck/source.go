@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass"
+)
+
+type sourceClient interface {
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+}
+
+// lensSource gives lenses access to the source files that runs tested by
+// fetching them from GitHub at the refs recorded in the started.json of the run.
+type lensSource struct {
+	sg  *spyglass.Spyglass
+	cfg config.Getter
+	gh  sourceClient
+}
+
+// SourceFile returns the content of the file with the given import path at the
+// commit of its repo that the run at src tested.
+func (s lensSource) SourceFile(src, importPath string) ([]byte, error) {
+	artifacts, err := s.sg.FetchArtifacts(src, "", s.cfg().Deck.Spyglass.SizeLimit, []string{"started.json"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch started.json: %v", err)
+	}
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("no started.json found for %s", src)
+	}
+	content, err := artifacts[0].ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read started.json: %v", err)
+	}
+	var started gcs.Started
+	if err := json.Unmarshal(content, &started); err != nil {
+		return nil, fmt.Errorf("failed to parse started.json: %v", err)
+	}
+	orgRepo, file, ok := repoFile(started.Repos, importPath)
+	if !ok {
+		return nil, fmt.Errorf("%s is in none of the repos that the run checked out", importPath)
+	}
+	parts := strings.SplitN(orgRepo, "/", 2)
+	return s.gh.GetFile(parts[0], parts[1], file, testedCommit(started.Repos[orgRepo]))
+}
+
+// repoFile finds the repo that holds the file with the given import path among
+// the org/repo keys of started.json and the path of the file within it. Repos
+// are checked out under github.com/org/repo unless they have a path alias, which
+// started.json does not record, so a repo under an alias such as
+// k8s.io/test-infra is recognized by its name.
+func repoFile(repos map[string]string, importPath string) (string, string, bool) {
+	var orgRepos []string
+	for orgRepo := range repos {
+		if strings.Count(orgRepo, "/") == 1 {
+			orgRepos = append(orgRepos, orgRepo)
+		}
+	}
+	sort.Strings(orgRepos)
+
+	for _, orgRepo := range orgRepos {
+		if prefix := "github.com/" + orgRepo + "/"; strings.HasPrefix(importPath, prefix) {
+			return orgRepo, strings.TrimPrefix(importPath, prefix), true
+		}
+	}
+	elems := strings.Split(importPath, "/")
+	for _, orgRepo := range orgRepos {
+		repo := strings.SplitN(orgRepo, "/", 2)[1]
+		// The alias has at least a host and the repo, and the file follows it.
+		for i := 1; i < len(elems)-1; i++ {
+			if elems[i] == repo {
+				return orgRepo, strings.Join(elems[i+1:], "/"), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// testedCommit picks the commit to show the sources of a repo at from its refs
+// as recorded in started.json, e.g. master:abc123,42:def456. A run tests the
+// PRs merged into the base, which is no commit on GitHub, so the head of a
+// single PR stands in for it. Otherwise the base is shown.
+func testedCommit(refs string) string {
+	parts := strings.Split(refs, ",")
+	if len(parts) == 2 {
+		if pull := strings.Split(parts[1], ":"); len(pull) >= 2 && pull[1] != "" {
+			return pull[1]
+		}
+	}
+	base := strings.SplitN(parts[0], ":", 2)
+	if len(base) == 2 && base[1] != "" {
+		return base[1]
+	}
+	return base[0]
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import "testing"
+
+func TestRepoFile(t *testing.T) {
+	repos := map[string]string{
+		"kubernetes/test-infra": "master:abc",
+		"kubernetes/kubernetes": "master:def",
+		"org/repo":              "master",
+	}
+	cases := []struct {
+		importPath string
+		orgRepo    string
+		file       string
+		expectOK   bool
+	}{
+		{
+			importPath: "k8s.io/test-infra/prow/cmd/deck/main.go",
+			orgRepo:    "kubernetes/test-infra",
+			file:       "prow/cmd/deck/main.go",
+			expectOK:   true,
+		},
+		{
+			importPath: "github.com/org/repo/pkg/repo/a.go",
+			orgRepo:    "org/repo",
+			file:       "pkg/repo/a.go",
+			expectOK:   true,
+		},
+		{
+			importPath: "k8s.io/kubernetes/pkg/kubelet/kubelet.go",
+			orgRepo:    "kubernetes/kubernetes",
+			file:       "pkg/kubelet/kubelet.go",
+			expectOK:   true,
+		},
+		{
+			importPath: "k8s.io/apimachinery/pkg/util/sets/string.go",
+		},
+		{
+			importPath: "k8s.io/test-infra",
+		},
+	}
+	for _, tc := range cases {
+		orgRepo, file, ok := repoFile(repos, tc.importPath)
+		if ok != tc.expectOK {
+			t.Errorf("finding the repo of %s: expected found %t, got %t", tc.importPath, tc.expectOK, ok)
+			continue
+		}
+		if orgRepo != tc.orgRepo || file != tc.file {
+			t.Errorf("finding the repo of %s: expected %s in %s, got %s in %s", tc.importPath, tc.file, tc.orgRepo, file, orgRepo)
+		}
+	}
+}
+
+func TestTestedCommit(t *testing.T) {
+	cases := []struct {
+		refs     string
+		expected string
+	}{
+		{
+			refs:     "master:abc",
+			expected: "abc",
+		},
+		{
+			refs:     "master:abc,42:def",
+			expected: "def",
+		},
+		{
+			refs:     "master:abc,42:def:refs/pull/42/head",
+			expected: "def",
+		},
+		{
+			refs:     "master:abc,42:def,43:ghi",
+			expected: "abc",
+		},
+		{
+			refs:     "master",
+			expected: "master",
+		},
+	}
+	for _, tc := range cases {
+		if actual := testedCommit(tc.refs); actual != tc.expected {
+			t.Errorf("picking the commit of %s: expected %s, got %s", tc.refs, tc.expected, actual)
+		}
+	}
+}
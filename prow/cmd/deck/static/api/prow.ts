@@ -1,5 +1,5 @@
 export type JobType = "presubmit" | "postsubmit" | "batch" | "periodic";
-export type JobState = "triggered" | "pending" | "success" | "failure" | "aborted" | "error" | "unknown" | "";
+export type JobState = "waiting" | "triggered" | "pending" | "success" | "failure" | "aborted" | "error" | "unknown" | "";
 
 // Pull describes a pull request at a particular point in time.
 // Pull mirrors the Pull struct defined in types.go.
@@ -45,4 +45,8 @@ export interface Job {
   pod_name: string;
   agent: string;
   prow_job: string;
+  run_after_success?: string[];
+  queue?: string;
+  queue_position?: number;
+  failure_reason?: string;
 }
@@ -30,7 +30,7 @@ export interface PullRequest extends BasePullRequest {
   };
 }
 
-export type Action = "WAIT" | "TRIGGER" | "TRIGGER_BATCH" | "MERGE" | "MERGE_BATCH" | "BLOCKED";
+export type Action = "WAIT" | "TRIGGER" | "TRIGGER_BATCH" | "MERGE" | "MERGE_BATCH" | "BLOCKED" | "BISECT" | "REMOVE_CULPRIT";
 
 export interface Blocker {
   Number: number;
@@ -52,10 +52,17 @@ export interface TidePool {
   Action: Action;
   Target: PullRequest[];
   Blockers: Blocker[];
+  HoldReason?: string;
+}
+
+export interface TidePriority {
+  labels: string[];
+  weight: number;
 }
 
 export interface TideData {
   Queries: string[];
   TideQueries: TideQuery[];
+  Priorities?: TidePriority[];
   Pools: TidePool[];
 }
@@ -53,6 +53,9 @@ export namespace cell {
     displayState = displayState[0].toUpperCase() + displayState.slice(1);
     let displayIcon = "";
     switch (s) {
+      case "waiting":
+        displayIcon = "hourglass_empty";
+        break;
       case "triggered":
         displayIcon = "schedule";
         break;
@@ -19,6 +19,10 @@ interface UnifiedContext {
   state: UnifiedState;
   discrepancy: string | null;
   url?: string;
+  // parents are the contexts of the jobs this job runs after.
+  parents?: string[];
+  // depth is the nesting level of the context in the job dependency graph.
+  depth?: number;
 }
 
 interface ProcessedLabel {
@@ -357,6 +361,10 @@ function getFullPRContext(builds: Job[], contexts: Context[]): UnifiedContext[]
         }
     }
     if (builds) {
+        const jobContexts: Map<string, string> = new Map();
+        for (const build of builds) {
+            jobContexts.set(build.job, build.context);
+        }
         for (const build of builds) {
             let discrepancy = null;
             // If Github context exits, check if mismatch or not.
@@ -368,10 +376,17 @@ function getFullPRContext(builds: Job[], contexts: Context[]): UnifiedContext[]
                     discrepancy = "Github context and Prow Job states mismatch";
                 }
             }
+            const parents: string[] = [];
+            for (const job of build.run_after_success || []) {
+                if (jobContexts.has(job)) {
+                    parents.push(jobContexts.get(job)!);
+                }
+            }
             contextMap.set(build.context, {
                 context: build.context,
                 description: build.description,
                 discrepancy,
+                parents,
                 state: build.state,
                 url: build.url,
             });
@@ -564,6 +579,8 @@ function createContextList(contexts: UnifiedContext[], itemStyle: string[] = [])
                 return "error";
             case "pending":
                 return "watch_later";
+            case "waiting":
+                return "hourglass_empty";
             case "triggered":
                 return "schedule";
             case "aborted":
@@ -586,6 +603,9 @@ function createContextList(contexts: UnifiedContext[], itemStyle: string[] = [])
     contexts.forEach((context) => {
         const elCon = document.createElement("li");
         elCon.classList.add("mdl-list__item", "job-list-item", ...itemStyle);
+        if (context.depth) {
+            elCon.style.paddingLeft = `${4 + context.depth * 24}px`;
+        }
         const item = getItemContainer(context);
         item.classList.add("mdl-list__item-primary-content");
         item.appendChild(createIcon(
@@ -612,6 +632,38 @@ function createContextList(contexts: UnifiedContext[], itemStyle: string[] = [])
     return container;
 }
 
+/**
+ * Orders contexts as a depth-first walk of the job dependency graph, so that
+ * every job is listed below the first job it runs after. The depth of each
+ * context is set to its nesting level.
+ */
+function orderByDependencies(contexts: UnifiedContext[]): UnifiedContext[] {
+    const children: Map<string, UnifiedContext[]> = new Map();
+    const roots: UnifiedContext[] = [];
+    const known = new Set(contexts.map((context) => context.context));
+    for (const context of contexts) {
+        const parents = (context.parents || []).filter((parent) => known.has(parent));
+        if (parents.length === 0) {
+            roots.push(context);
+            continue;
+        }
+        if (!children.has(parents[0])) {
+            children.set(parents[0], []);
+        }
+        children.get(parents[0])!.push(context);
+    }
+
+    const ordered: UnifiedContext[] = [];
+    const visit = (context: UnifiedContext, depth: number) => {
+        ordered.push({...context, depth});
+        for (const child of children.get(context.context) || []) {
+            visit(child, depth + 1);
+        }
+    };
+    roots.forEach((root) => visit(root, 0));
+    return ordered;
+}
+
 /**
  * Creates Job status.
  */
@@ -664,7 +716,7 @@ function createJobStatus(builds: UnifiedContext[]): HTMLElement {
         failedJobsList = createContextList(failedJobs);
         statusContainer.appendChild(failedJobsList);
     }
-    const jobList = createContextList(builds);
+    const jobList = createContextList(orderByDependencies(builds));
     jobList.classList.add("hidden");
     status.addEventListener("click", () => {
         if (state === "unknown") {
@@ -1032,7 +1084,7 @@ function compareJobFn(a: UnifiedContext, b: UnifiedContext): number {
     const stateToPrio: {[key: string]: number} = {};
     stateToPrio.success = stateToPrio.expected = 3;
     stateToPrio.aborted = 2;
-    stateToPrio.pending = stateToPrio.triggered = 1;
+    stateToPrio.pending = stateToPrio.triggered = stateToPrio.waiting = 1;
     stateToPrio.error = stateToPrio.failure = 0;
 
     return stateToPrio[a.state] > stateToPrio[b.state] ? 1
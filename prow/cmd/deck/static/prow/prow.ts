@@ -472,7 +472,14 @@ function redraw(fz: FuzzySearch): void {
             continue;
         }
         const r = document.createElement("tr");
-        r.appendChild(cell.state(build.state));
+        const stateCell = cell.state(build.state);
+        if (build.queue_position) {
+            stateCell.title += ` (#${build.queue_position} in ${build.queue || "default"} queue)`;
+        }
+        if (build.failure_reason) {
+            stateCell.title += ` (${build.failure_reason})`;
+        }
+        r.appendChild(stateCell);
         if (build.pod_name) {
             const icon = createIcon("description", "Build log");
             icon.href = `log?job=${build.job}&id=${build.build_id}`;
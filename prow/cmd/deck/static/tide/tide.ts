@@ -25,6 +25,7 @@ function infoToggle(toToggle: HTMLElement): (event: Event) => void {
 
 function redraw(): void {
     redrawQueries();
+    redrawPriorities();
     redrawPools();
 }
 
@@ -204,6 +205,50 @@ function redrawQueries(): void {
     }
 }
 
+function redrawPriorities(): void {
+    const priorities = document.getElementById("priorities")!;
+    while (priorities.firstChild) {
+        priorities.removeChild(priorities.firstChild);
+    }
+    if (!tideData.Priorities || tideData.Priorities.length === 0) {
+        document.getElementById("priority-info")!.classList.add("hidden");
+        return;
+    }
+
+    const sorted = tideData.Priorities.slice().sort((a, b) => b.weight - a.weight);
+    for (const priority of sorted) {
+        const li = document.createElement("li");
+        li.appendChild(createStrong(`Weight ${priority.weight}: `));
+        for (let i = 0; i < priority.labels.length; i++) {
+            li.appendChild(createLabelEl(priority.labels[i]));
+            if (i + 1 < priority.labels.length) {
+                li.appendChild(document.createTextNode(" "));
+            }
+        }
+        priorities.appendChild(li);
+    }
+}
+
+/**
+ * priorityOf returns the weight Tide orders the PR by, which is the highest
+ * weight among the priorities the PR has a label of, or 0.
+ */
+function priorityOf(pr: PullRequest): number {
+    const labels = pr.Labels && pr.Labels.Nodes ? pr.Labels.Nodes.map((l) => l.Name) : [];
+    let weight = 0;
+    let matched = false;
+    for (const priority of tideData.Priorities || []) {
+        if (!priority.labels.some((l) => labels.indexOf(l) !== -1)) {
+            continue;
+        }
+        if (!matched || priority.weight > weight) {
+            weight = priority.weight;
+            matched = true;
+        }
+    }
+    return weight;
+}
+
 function redrawPools(): void {
     const pools = document.getElementById("pools")!.getElementsByTagName("tbody")[0];
     while (pools.firstChild) {
@@ -249,6 +294,15 @@ function createActionCell(pool: TidePool): HTMLTableDataCellElement {
     } else if (targeted) {
         addPRsToElem(c, pool, pool.Target);
     }
+    if (pool.HoldReason) {
+        if (pool.Action === "BLOCKED") {
+            c.classList.add("blocked");
+        }
+        const reason = document.createElement("div");
+        reason.classList.add("hold-reason");
+        reason.textContent = `Held: ${pool.HoldReason}`;
+        c.appendChild(reason);
+    }
     return c;
 }
 
@@ -295,8 +349,13 @@ function addPRsToElem(elem: HTMLElement, pool: TidePool, prs?: PullRequest[]): v
             a.href = `https://github.com/${pool.Org}/${pool.Repo}/pull/${prs[i].Number}`;
             a.appendChild(document.createTextNode("#" + prs[i].Number));
             a.id = `pr-${pool.Org}-${pool.Repo}-${prs[i].Number}-${nextID()}`;
+            const weight = priorityOf(prs[i]);
+            if (weight !== 0) {
+                a.classList.add(weight > 0 ? "prioritized" : "deprioritized");
+            }
             if (prs[i].Title) {
-                const tip = tooltip.forElem(a.id, document.createTextNode(prs[i].Title));
+                const title = weight !== 0 ? `${prs[i].Title} (priority ${weight})` : prs[i].Title;
+                const tip = tooltip.forElem(a.id, document.createTextNode(title));
                 a.appendChild(tip);
             }
             elem.appendChild(a);
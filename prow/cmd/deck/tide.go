@@ -35,6 +35,7 @@ import (
 type tidePools struct {
 	Queries     []string
 	TideQueries []config.TideQuery
+	Priorities  []config.TidePriority
 	Pools       []tide.Pool
 }
 
@@ -154,6 +155,29 @@ func (ta *tideAgent) updateHistory() error {
 	return nil
 }
 
+// queryHistory selects the records of the history that match the query.
+func queryHistory(hist map[string][]history.Record, q history.Query) map[string][]history.Record {
+	if q == (history.Query{}) {
+		return hist
+	}
+	res := make(map[string][]history.Record)
+	for pool, records := range hist {
+		if !q.MatchesPool(pool) {
+			continue
+		}
+		var matching []history.Record
+		for _, rec := range records {
+			if q.Matches(rec) {
+				matching = append(matching, rec)
+			}
+		}
+		if len(matching) > 0 {
+			res[pool] = matching
+		}
+	}
+	return res
+}
+
 func (ta *tideAgent) filterHiddenPools(pools []tide.Pool) []tide.Pool {
 	if len(ta.hiddenRepos) == 0 {
 		return pools
@@ -23,6 +23,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/equality"
 
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/tide"
 	"k8s.io/test-infra/prow/tide/history"
@@ -229,3 +231,49 @@ func TestMatches(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryHistory(t *testing.T) {
+	hist := map[string][]history.Record{
+		"org/repo:master": {
+			{Action: "MERGE", Target: []prowapi.Pull{{Number: 2}}},
+			{Action: "TRIGGER", Target: []prowapi.Pull{{Number: 1}}},
+		},
+		"org/other:master": {
+			{Action: "MERGE", Target: []prowapi.Pull{{Number: 1}}},
+		},
+	}
+	tests := []struct {
+		name     string
+		query    history.Query
+		expected map[string][]history.Record
+	}{
+		{
+			name:     "empty query",
+			expected: hist,
+		},
+		{
+			name:  "repo",
+			query: history.Query{Repo: "org/other"},
+			expected: map[string][]history.Record{
+				"org/other:master": hist["org/other:master"],
+			},
+		},
+		{
+			name:  "repo and PR",
+			query: history.Query{Repo: "org/repo", PR: 1},
+			expected: map[string][]history.Record{
+				"org/repo:master": {hist["org/repo:master"][1]},
+			},
+		},
+		{
+			name:     "no matching records",
+			query:    history.Query{PR: 3},
+			expected: map[string][]history.Record{},
+		},
+	}
+	for _, test := range tests {
+		if got := queryHistory(hist, test.query); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected history %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -50,6 +51,9 @@ type options struct {
 	github       prowflagutil.GitHubOptions
 	githubClient githubClient
 	pullRequest  *github.PullRequest
+
+	abort      string
+	kubernetes prowflagutil.KubernetesClientOptions
 }
 
 func (o *options) getPullRequest() (*github.PullRequest, error) {
@@ -127,17 +131,48 @@ type githubClient interface {
 }
 
 func (o *options) Validate() error {
-	if o.jobName == "" {
+	if o.jobName == "" && o.abort == "" {
 		return errors.New("required flag --job was unset")
 	}
 
+	if o.jobName != "" && o.abort != "" {
+		return errors.New("--job and --abort are mutually exclusive")
+	}
+
 	if o.configPath == "" {
 		return errors.New("required flag --config-path was unset")
 	}
 
+	if o.abort != "" {
+		return o.kubernetes.Validate(false)
+	}
+
 	return nil
 }
 
+type prowJobClient interface {
+	Get(name string, options metav1.GetOptions) (*prowapi.ProwJob, error)
+	Update(*prowapi.ProwJob) (*prowapi.ProwJob, error)
+}
+
+// abortProwJob requests the cancellation of the named ProwJob. The agent
+// that runs the job aborts it on its next sync.
+func abortProwJob(client prowJobClient, name string) error {
+	pj, err := client.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pj.Complete() {
+		return fmt.Errorf("prowjob %s has already completed in state %s", name, pj.Status.State)
+	}
+	if pj.Spec.Cancel {
+		return nil
+	}
+	pj.Spec.Cancel = true
+	_, err = client.Update(pj)
+	return err
+}
+
 func gatherOptions() options {
 	o := options{}
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -149,7 +184,9 @@ func gatherOptions() options {
 	fs.IntVar(&o.pullNumber, "pull-number", 0, "Git pull number under test")
 	fs.StringVar(&o.pullSha, "pull-sha", "", "Git pull SHA under test")
 	fs.StringVar(&o.pullAuthor, "pull-author", "", "Git pull author under test")
+	fs.StringVar(&o.abort, "abort", "", "Name of a running ProwJob to abort instead of creating a job.")
 	o.github.AddFlagsWithoutDefaultGithubTokenPath(fs)
+	o.kubernetes.AddFlags(fs)
 	fs.Parse(os.Args[1:])
 	return o
 }
@@ -165,6 +202,18 @@ func main() {
 		logrus.WithError(err).Fatal("Error loading config.")
 	}
 
+	if o.abort != "" {
+		client, err := o.kubernetes.ProwJobClient()
+		if err != nil {
+			logrus.WithError(err).Fatal("Error getting ProwJob client.")
+		}
+		if err := abortProwJob(client.ProwV1().ProwJobs(conf.ProwJobNamespace), o.abort); err != nil {
+			logrus.WithError(err).Fatalf("Failed to abort ProwJob %s.", o.abort)
+		}
+		logrus.Infof("Requested cancellation of ProwJob %s.", o.abort)
+		return
+	}
+
 	var secretAgent *secret.Agent
 	if o.github.TokenPath != "" {
 		secretAgent = &secret.Agent{}
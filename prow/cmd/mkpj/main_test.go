@@ -19,7 +19,10 @@ package main
 import (
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/client/clientset/versioned/fake"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 )
@@ -52,6 +55,23 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			expectedErr: true,
 		},
+		{
+			name: "abort instead of job",
+			input: options{
+				abort:      "some-prowjob",
+				configPath: "somewhere",
+			},
+			expectedErr: false,
+		},
+		{
+			name: "both job and abort",
+			input: options{
+				jobName:    "job",
+				abort:      "some-prowjob",
+				configPath: "somewhere",
+			},
+			expectedErr: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -65,6 +85,46 @@ func TestOptions_Validate(t *testing.T) {
 	}
 }
 
+func TestAbortProwJob(t *testing.T) {
+	completed := metav1.Now()
+	var testCases = []struct {
+		name        string
+		status      prowapi.ProwJobStatus
+		expectedErr bool
+	}{
+		{
+			name:   "running job is cancelled",
+			status: prowapi.ProwJobStatus{State: prowapi.PendingState},
+		},
+		{
+			name:        "complete job cannot be cancelled",
+			status:      prowapi.ProwJobStatus{State: prowapi.SuccessState, CompletionTime: &completed},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pj := &prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-prowjob", Namespace: "prowjobs"},
+				Status:     testCase.status,
+			}
+			client := fake.NewSimpleClientset(pj).ProwV1().ProwJobs("prowjobs")
+			err := abortProwJob(client, "some-prowjob")
+			if testCase.expectedErr != (err != nil) {
+				t.Fatalf("expected error: %v, got %v", testCase.expectedErr, err)
+			}
+			actual, err := client.Get("some-prowjob", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get prowjob: %v", err)
+			}
+			if actual.Spec.Cancel == testCase.expectedErr {
+				t.Errorf("expected cancel: %v, got %v", !testCase.expectedErr, actual.Spec.Cancel)
+			}
+		})
+	}
+}
+
 func TestDefaultPR(t *testing.T) {
 	author := "Bernardo Soares"
 	sha := "Esther Greenwood"
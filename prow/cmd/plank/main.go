@@ -28,17 +28,25 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"k8s.io/test-infra/pkg/flagutil"
+	prowjobinformer "k8s.io/test-infra/prow/client/informers/externalversions"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/config/secret"
 	prowflagutil "k8s.io/test-infra/prow/flagutil"
 	"k8s.io/test-infra/prow/kube"
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/metrics"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/plank"
 )
 
+// resync is how often plank syncs in the absence of events,
+// and how often it syncs in dry-run mode.
+const resync = 30 * time.Second
+
 type options struct {
 	totURL string
 
@@ -48,6 +56,11 @@ type options struct {
 	selector      string
 	skipReport    bool
 
+	uploadPodInfo      bool
+	gcsCredentialsFile string
+	s3CredentialsFile  string
+	fileStorageRoot    string
+
 	dryRun     bool
 	kubernetes prowflagutil.KubernetesOptions
 	github     prowflagutil.GitHubOptions
@@ -65,6 +78,11 @@ func gatherOptions() options {
 	fs.StringVar(&o.selector, "label-selector", kube.EmptySelector, "Label selector to be applied in prowjobs. See https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#label-selectors for constructing a label selector.")
 	fs.BoolVar(&o.skipReport, "skip-report", false, "Whether or not to ignore report with githubClient")
 
+	fs.BoolVar(&o.uploadPodInfo, "upload-pod-info", false, "Whether to upload the pod status and events of completed decorated jobs as podinfo.json next to their artifacts.")
+	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Path to the GCS credentials file, used if --upload-pod-info is set.")
+	fs.StringVar(&o.s3CredentialsFile, "s3-credentials-file", "", "Path to the file with the credentials and endpoint of S3 buckets, used if --upload-pod-info is set.")
+	fs.StringVar(&o.fileStorageRoot, "file-storage-root", "", "Path to the directory that holds the directories of file buckets, used if --upload-pod-info is set.")
+
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Whether or not to make mutating API calls to GitHub.")
 	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github} {
 		group.AddFlags(fs)
@@ -139,10 +157,22 @@ func main() {
 		}
 	}
 
-	c, err := plank.NewController(kubeClient, pkcs, githubClient, nil, cfg, o.totURL, o.selector, o.skipReport)
+	var c *plank.Controller
+	if o.dryRun {
+		c, err = plank.NewController(kubeClient, pkcs, githubClient, nil, cfg, o.totURL, o.selector, o.skipReport)
+	} else {
+		c, err = newInformerController(o, kubeClient, pkcs, githubClient, cfg)
+	}
 	if err != nil {
 		logrus.WithError(err).Fatal("Error creating plank controller.")
 	}
+	if o.uploadPodInfo && !o.dryRun {
+		c.UploadPodInfo(&objectstore.Opener{
+			GCSCredentialsFile: o.gcsCredentialsFile,
+			S3CredentialsFile:  o.s3CredentialsFile,
+			FileRoot:           o.fileStorageRoot,
+		})
+	}
 
 	// Push metrics to the configured prometheus pushgateway endpoint.
 	pushGateway := cfg().PushGateway
@@ -154,10 +184,20 @@ func main() {
 	// gather metrics for the jobs handled by plank.
 	go gather(c)
 
-	tick := time.Tick(30 * time.Second)
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 
+	if !o.dryRun {
+		stopCh := make(chan struct{})
+		go c.Run(stopCh, resync)
+		<-sig
+		logrus.Info("Plank is shutting down...")
+		close(stopCh)
+		return
+	}
+
+	// The dry-run clients cannot watch, so sync periodically.
+	tick := time.Tick(resync)
 	for {
 		select {
 		case <-tick:
@@ -173,6 +213,41 @@ func main() {
 	}
 }
 
+// newInformerController creates a plank controller that watches the
+// ProwJobs in the infrastructure cluster and the pods in every build
+// cluster.
+func newInformerController(o options, kubeClient *kube.Client, pkcs map[string]*kube.Client, githubClient plank.GitHubClient, cfg config.Getter) (*plank.Controller, error) {
+	prowJobClientset, err := kube.GetProwJobClient("", "")
+	if err != nil {
+		return nil, fmt.Errorf("error getting prowjob clientset: %v", err)
+	}
+	prowJobInformer := prowjobinformer.NewSharedInformerFactoryWithOptions(
+		prowJobClientset, resync, prowjobinformer.WithNamespace(cfg().ProwJobNamespace),
+	).Prow().V1().ProwJobs()
+
+	clusterConfigs, defaultContext, err := kube.LoadClusterConfigs("", o.buildCluster)
+	if err != nil {
+		return nil, fmt.Errorf("error loading build cluster configs: %v", err)
+	}
+	podInformers := map[string]cache.SharedIndexInformer{}
+	for alias := range pkcs {
+		clusterConfig, ok := clusterConfigs[alias]
+		if !ok && alias == kube.DefaultClusterAlias {
+			clusterConfig, ok = clusterConfigs[defaultContext]
+		}
+		if !ok {
+			return nil, fmt.Errorf("no config for build cluster %q", alias)
+		}
+		client, err := kubernetes.NewForConfig(&clusterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error getting kubernetes client for build cluster %q: %v", alias, err)
+		}
+		podInformers[alias] = plank.NewPodInformer(client, cfg().PodNamespace, o.selector, resync)
+	}
+
+	return plank.NewInformerController(kubeClient, pkcs, prowJobInformer, podInformers, githubClient, nil, cfg, o.totURL, o.selector, o.skipReport)
+}
+
 // serve starts a http server and serves prometheus metrics.
 // Meant to be called inside a goroutine.
 func serve() {
@@ -19,10 +19,13 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -35,6 +38,7 @@ import (
 	"k8s.io/test-infra/prow/logrusutil"
 	"k8s.io/test-infra/prow/metrics"
 	"k8s.io/test-infra/prow/tide"
+	"k8s.io/test-infra/prow/tide/history"
 )
 
 type options struct {
@@ -46,6 +50,12 @@ type options struct {
 	syncThrottle   int
 	statusThrottle int
 
+	historyURI         string
+	gcsCredentialsFile string
+
+	simulate         string
+	simulateProwJobs string
+
 	dryRun     bool
 	runOnce    bool
 	kubernetes prowflagutil.ExperimentalKubernetesOptions
@@ -53,6 +63,13 @@ type options struct {
 }
 
 func (o *options) Validate() error {
+	if o.simulate != "" {
+		// Simulations use neither GitHub nor the cluster.
+		return nil
+	}
+	if o.simulateProwJobs != "" {
+		return fmt.Errorf("--simulate-prowjobs requires --simulate")
+	}
 	for _, group := range []flagutil.OptionGroup{&o.kubernetes, &o.github} {
 		if err := group.Validate(o.dryRun); err != nil {
 			return err
@@ -75,6 +92,10 @@ func gatherOptions() options {
 	}
 	fs.IntVar(&o.syncThrottle, "sync-hourly-tokens", 800, "The maximum number of tokens per hour to be used by the sync controller.")
 	fs.IntVar(&o.statusThrottle, "status-hourly-tokens", 400, "The maximum number of tokens per hour to be used by the status controller.")
+	fs.StringVar(&o.historyURI, "history-uri", "", "Local directory or GCS URI (gs://bucket/path) to persist the history and pools in. They are kept in memory only if unset.")
+	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "Path to the GCS credentials file, used if --history-uri is a GCS URI.")
+	fs.StringVar(&o.simulate, "simulate", "", "Path to recorded PRs and ProwJobs to simulate a sync against. Prints the actions Tide would take and exits without changing anything.")
+	fs.StringVar(&o.simulateProwJobs, "simulate-prowjobs", "", "Path to a ProwJob list, like the output of kubectl get prowjobs -o yaml, to simulate a sync against instead of the ProwJobs in --simulate.")
 
 	fs.Parse(os.Args[1:])
 	return o
@@ -96,6 +117,13 @@ func main() {
 	}
 	cfg := configAgent.Config
 
+	if o.simulate != "" {
+		if err := simulate(os.Stdout, cfg, o.simulate, o.simulateProwJobs); err != nil {
+			logrus.WithError(err).Fatal("Error simulating sync.")
+		}
+		return
+	}
+
 	secretAgent := &secret.Agent{}
 	if err := secretAgent.Start([]string{o.github.TokenPath}); err != nil {
 		logrus.WithError(err).Fatal("Error starting secrets agent.")
@@ -131,7 +159,19 @@ func main() {
 		logrus.WithError(err).Fatal("Error getting Kubernetes client.")
 	}
 
-	c := tide.NewController(githubSync, githubStatus, kubeClient, cfg, gitClient, nil)
+	var storage history.Storage
+	if o.historyURI != "" {
+		if storage, err = history.NewStorage(o.historyURI, o.gcsCredentialsFile); err != nil {
+			logrus.WithError(err).Fatal("Error creating history storage.")
+		}
+	} else if cfg().Tide.BatchBisection != nil {
+		logrus.Warn("Batch bisection is enabled without --history-uri, so culprit PRs return to the pool when Tide restarts.")
+	}
+
+	c, err := tide.NewController(githubSync, githubStatus, kubeClient, cfg, gitClient, storage, nil)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating Tide controller.")
+	}
 	defer c.Shutdown()
 	http.Handle("/", c)
 	http.Handle("/history", c.History)
@@ -176,6 +216,50 @@ func sync(c *tide.Controller) {
 	}
 }
 
+// simulate runs a sync against the recorded state and prints the action
+// Tide would take on every pool and why the other PRs are in no pool.
+func simulate(w io.Writer, cfg config.Getter, statePath, prowJobsPath string) error {
+	state, err := tide.LoadSimulationState(statePath, prowJobsPath)
+	if err != nil {
+		return err
+	}
+	sim, err := tide.Simulate(cfg, state, nil)
+	if err != nil {
+		return err
+	}
+	for _, pool := range sim.Pools {
+		fmt.Fprintf(w, "%s/%s:%s\n", pool.Org, pool.Repo, pool.Branch)
+		fmt.Fprintf(w, "  action:  %s %s\n", pool.Action, prNumbers(pool.Target))
+		fmt.Fprintf(w, "  passing: %s\n", prNumbers(pool.SuccessPRs))
+		fmt.Fprintf(w, "  pending: %s\n", prNumbers(pool.PendingPRs))
+		fmt.Fprintf(w, "  missing: %s\n", prNumbers(pool.MissingPRs))
+		if len(pool.BatchPending) > 0 {
+			fmt.Fprintf(w, "  pending batch: %s\n", prNumbers(pool.BatchPending))
+		}
+		if pool.HoldReason != "" {
+			fmt.Fprintf(w, "  held: %s\n", pool.HoldReason)
+		}
+		if pool.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", pool.Error)
+		}
+	}
+	if keys := sim.ExcludedPRs(); len(keys) > 0 {
+		fmt.Fprintln(w, "not in any pool:")
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s: %s\n", key, sim.Excluded[key])
+		}
+	}
+	return nil
+}
+
+func prNumbers(prs []tide.PullRequest) string {
+	numbers := make([]string, 0, len(prs))
+	for _, pr := range prs {
+		numbers = append(numbers, fmt.Sprintf("#%d", int(pr.Number)))
+	}
+	return strings.Join(numbers, " ")
+}
+
 func tokensPerIteration(hourlyTokens int, iterPeriod time.Duration) int {
 	tokenRate := float64(hourlyTokens) / float64(time.Hour)
 	return int(tokenRate * float64(iterPeriod))
@@ -74,6 +74,8 @@ type ProwConfig struct {
 	Orgs             map[string]org.Config `json:"orgs,omitempty"`
 	Gerrit           Gerrit                `json:"gerrit,omitempty"`
 	GithubReporter   GithubReporter        `json:"github_reporter,omitempty"`
+	SlackReporter    SlackReporter         `json:"slack_reporter,omitempty"`
+	WebhookReporter  WebhookReporter       `json:"webhook_reporter,omitempty"`
 
 	// TODO: Move this out of the main config.
 	JenkinsOperators []JenkinsOperator `json:"jenkins_operators,omitempty"`
@@ -190,6 +192,61 @@ type Plank struct {
 	// JobURLPrefix is the host and path prefix under
 	// which job details will be viewable
 	JobURLPrefix string `json:"job_url_prefix,omitempty"`
+	// Queues group jobs that share a concurrency budget. Triggered
+	// jobs are admitted from the queue with the highest priority first
+	// and from queues of equal priority in proportion to their weight.
+	// Jobs that match no queue are admitted from an unbounded default
+	// queue with priority and weight 1.
+	Queues []SchedulingQueue `json:"queues,omitempty"`
+}
+
+// SchedulingQueue is a queue from which plank admits triggered jobs.
+// A job belongs to the first queue that matches it.
+type SchedulingQueue struct {
+	// Name identifies the queue.
+	Name string `json:"name"`
+	// Repos are the orgs or org/repos whose jobs are in this queue.
+	// If unset, jobs of any repo match.
+	Repos []string `json:"repos,omitempty"`
+	// LabelSelectorString compiles into LabelSelector at load time.
+	// If unset, jobs with any labels match.
+	LabelSelectorString string `json:"label_selector,omitempty"`
+	// LabelSelector selects the jobs in this queue by their labels.
+	LabelSelector labels.Selector `json:"-"`
+	// MaxConcurrency is the number of jobs from this queue that may
+	// run at once, 0 implies no limit.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// Priority orders the queues, jobs from queues with a higher
+	// priority are admitted first. Defaults to 1.
+	Priority int `json:"priority,omitempty"`
+	// Weight is the share of admissions this queue gets relative to
+	// the other queues with the same priority. Defaults to 1.
+	Weight int `json:"weight,omitempty"`
+}
+
+// Matches determines if the ProwJob belongs to the queue.
+func (q *SchedulingQueue) Matches(pj *prowapi.ProwJob) bool {
+	if len(q.Repos) > 0 {
+		if pj.Spec.Refs == nil {
+			return false
+		}
+		org, repo := pj.Spec.Refs.Org, pj.Spec.Refs.Org+"/"+pj.Spec.Refs.Repo
+		if !sets.NewString(q.Repos...).HasAny(org, repo) {
+			return false
+		}
+	}
+	return q.LabelSelector == nil || q.LabelSelector.Matches(labels.Set(pj.ObjectMeta.Labels))
+}
+
+// QueueFor returns the queue the ProwJob belongs to, or nil
+// if it belongs to the default queue.
+func (p *Plank) QueueFor(pj *prowapi.ProwJob) *SchedulingQueue {
+	for i := range p.Queues {
+		if p.Queues[i].Matches(pj) {
+			return &p.Queues[i]
+		}
+	}
+	return nil
 }
 
 // Gerrit is config for the gerrit controller.
@@ -228,6 +285,80 @@ type GithubReporter struct {
 	JobTypesToReport []prowapi.ProwJobType `json:"job_types_to_report,omitempty"`
 }
 
+// SlackReporter holds the config for report behavior in slack.
+// Jobs are only reported if they configure a channel in their
+// reporter_config.
+type SlackReporter struct {
+	// JobTypesToReport is used to determine which type of prowjob
+	// should be reported to slack, empty means all types.
+	JobTypesToReport []prowapi.ProwJobType `json:"job_types_to_report,omitempty"`
+	// JobStatesToReport is used to determine which state transitions
+	// are reported to slack unless a job overrides them.
+	//
+	// defaults to failure and error.
+	JobStatesToReport []prowapi.ProwJobState `json:"job_states_to_report,omitempty"`
+	// ReportTemplateString compiles into ReportTemplate at load time.
+	ReportTemplateString string `json:"report_template,omitempty"`
+	// ReportTemplate is compiled at load time from ReportTemplateString. It
+	// will be passed a prowapi.ProwJob and the result is posted to slack
+	// unless a job overrides it.
+	ReportTemplate *template.Template `json:"-"`
+}
+
+// WebhookReporter holds the config for reporting prowjobs
+// as CloudEvents to HTTP endpoints.
+type WebhookReporter struct {
+	// Endpoints are the HTTP endpoints that prowjobs are reported to.
+	Endpoints []WebhookEndpoint `json:"endpoints,omitempty"`
+}
+
+// WebhookEndpoint is an HTTP endpoint that receives the state
+// transitions of the prowjobs it selects.
+type WebhookEndpoint struct {
+	// Name identifies the endpoint.
+	Name string `json:"name"`
+	// URL is where the events are POSTed to.
+	URL string `json:"url"`
+	// HMACSecretFile is the path to a file holding the key used to
+	// sign the events. If unset, events are not signed.
+	HMACSecretFile string `json:"hmac_secret_file,omitempty"`
+	// JobTypesToReport selects the types of the prowjobs reported
+	// to the endpoint, empty means all types.
+	JobTypesToReport []prowapi.ProwJobType `json:"job_types_to_report,omitempty"`
+	// JobStatesToReport selects the states that are reported to
+	// the endpoint, empty means all states.
+	JobStatesToReport []prowapi.ProwJobState `json:"job_states_to_report,omitempty"`
+	// LabelSelectorString compiles into LabelSelector at load time.
+	LabelSelectorString string `json:"label_selector,omitempty"`
+	// LabelSelector selects the prowjobs reported to the endpoint
+	// by their labels.
+	LabelSelector labels.Selector `json:"-"`
+}
+
+// Selects determines if the state of the prowjob should be
+// reported to the endpoint.
+func (e *WebhookEndpoint) Selects(pj *prowapi.ProwJob) bool {
+	if len(e.JobTypesToReport) > 0 {
+		var found bool
+		for _, t := range e.JobTypesToReport {
+			found = found || t == pj.Spec.Type
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(e.JobStatesToReport) > 0 {
+		var found bool
+		for _, s := range e.JobStatesToReport {
+			found = found || s == pj.Status.State
+		}
+		if !found {
+			return false
+		}
+	}
+	return e.LabelSelector == nil || e.LabelSelector.Matches(labels.Set(pj.ObjectMeta.Labels))
+}
+
 // Sinker is config for the sinker controller.
 type Sinker struct {
 	// ResyncPeriodString compiles into ResyncPeriod at load time.
@@ -293,6 +424,10 @@ type Deck struct {
 	ExternalAgentLogs []ExternalAgentLog `json:"external_agent_logs,omitempty"`
 	// Branding of the frontend
 	Branding *Branding `json:"branding,omitempty"`
+	// AllowAbort enables the /abort endpoint, which lets anyone who can
+	// reach Deck abort running jobs. Only enable it when access to Deck
+	// is restricted, e.g. by an authenticating proxy.
+	AllowAbort bool `json:"allow_abort,omitempty"`
 }
 
 // ExternalAgentLog ensures an external agent like Jenkins can expose
@@ -643,6 +778,14 @@ func validateJobBase(v JobBase, jobType prowapi.ProwJobType, podNamespace string
 	if err := validateAgent(v, podNamespace); err != nil {
 		return err
 	}
+	if v.RetryPolicy != nil {
+		if err := v.RetryPolicy.Validate(); err != nil {
+			return fmt.Errorf("retry_policy: %v", err)
+		}
+	}
+	if err := validateReporterConfig(v.ReporterConfig); err != nil {
+		return fmt.Errorf("reporter_config: %v", err)
+	}
 	if err := validatePodSpec(jobType, v.Spec); err != nil {
 		return err
 	}
@@ -655,6 +798,19 @@ func validateJobBase(v JobBase, jobType prowapi.ProwJobType, podNamespace string
 	return validateDecoration(v.Spec.Containers[0], v.DecorationConfig)
 }
 
+func validateReporterConfig(rc *prowapi.ReporterConfig) error {
+	if rc == nil || rc.Slack == nil {
+		return nil
+	}
+	if rc.Slack.Channel == "" {
+		return errors.New("slack.channel must be set")
+	}
+	if _, err := template.New("SlackReport").Parse(rc.Slack.ReportTemplate); err != nil {
+		return fmt.Errorf("invalid slack.report_template: %v", err)
+	}
+	return nil
+}
+
 // validateJobConfig validates if all the jobspecs/presets are valid
 // if you are mutating the jobs, please add it to finalizeJobConfig above
 func (c *Config) validateJobConfig() error {
@@ -677,6 +833,17 @@ func (c *Config) validateJobConfig() error {
 		}
 	}
 
+	for repo, jobs := range c.Presubmits {
+		runAfterSuccess, agents := map[string][]string{}, map[string]string{}
+		for _, job := range jobs {
+			runAfterSuccess[job.Name] = append(runAfterSuccess[job.Name], job.RunAfterSuccess...)
+			agents[job.Name] = job.Agent
+		}
+		if err := validateRunAfterSuccess(runAfterSuccess, agents); err != nil {
+			return fmt.Errorf("invalid presubmits for %s: %v", repo, err)
+		}
+	}
+
 	for _, v := range c.AllPresubmits(nil) {
 		if err := validateJobBase(v.JobBase, prowapi.PresubmitJob, c.PodNamespace); err != nil {
 			return fmt.Errorf("invalid presubmit job %s: %v", v.Name, err)
@@ -701,6 +868,17 @@ func (c *Config) validateJobConfig() error {
 		}
 	}
 
+	for repo, jobs := range c.Postsubmits {
+		runAfterSuccess, agents := map[string][]string{}, map[string]string{}
+		for _, job := range jobs {
+			runAfterSuccess[job.Name] = append(runAfterSuccess[job.Name], job.RunAfterSuccess...)
+			agents[job.Name] = job.Agent
+		}
+		if err := validateRunAfterSuccess(runAfterSuccess, agents); err != nil {
+			return fmt.Errorf("invalid postsubmits for %s: %v", repo, err)
+		}
+	}
+
 	for _, j := range c.AllPostsubmits(nil) {
 		if err := validateJobBase(j.JobBase, prowapi.PostsubmitJob, c.PodNamespace); err != nil {
 			return fmt.Errorf("invalid postsubmit job %s: %v", j.Name, err)
@@ -757,6 +935,34 @@ func parseProwConfig(c *Config) error {
 		c.Plank.PodPendingTimeout = podPendingTimeout
 	}
 
+	queues := sets.NewString()
+	for i := range c.Plank.Queues {
+		q := &c.Plank.Queues[i]
+		if q.Name == "" {
+			return errors.New("plank.queues: every queue needs a name")
+		}
+		if queues.Has(q.Name) {
+			return fmt.Errorf("plank.queues: duplicated queue %q", q.Name)
+		}
+		queues.Insert(q.Name)
+		if q.MaxConcurrency < 0 || q.Priority < 0 || q.Weight < 0 {
+			return fmt.Errorf("plank.queues: max_concurrency, priority and weight of queue %q must be non-negative", q.Name)
+		}
+		if q.Priority == 0 {
+			q.Priority = 1
+		}
+		if q.Weight == 0 {
+			q.Weight = 1
+		}
+		if q.LabelSelectorString != "" {
+			sel, err := labels.Parse(q.LabelSelectorString)
+			if err != nil {
+				return fmt.Errorf("plank.queues: invalid label_selector of queue %q: %v", q.Name, err)
+			}
+			q.LabelSelector = sel
+		}
+	}
+
 	if c.Gerrit.TickIntervalString == "" {
 		c.Gerrit.TickInterval = time.Minute
 	} else {
@@ -784,6 +990,41 @@ func parseProwConfig(c *Config) error {
 		}
 	}
 
+	if len(c.SlackReporter.JobStatesToReport) == 0 {
+		c.SlackReporter.JobStatesToReport = []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState}
+	}
+
+	if c.SlackReporter.ReportTemplateString == "" {
+		c.SlackReporter.ReportTemplateString = `Job {{.Spec.Job}} of type {{.Spec.Type}} ended with state {{.Status.State}}{{if .Status.FailureReason}} ({{.Status.FailureReason}}){{end}}. <{{.Status.URL}}|View logs>`
+	}
+	slackReportTmpl, err := template.New("SlackReport").Parse(c.SlackReporter.ReportTemplateString)
+	if err != nil {
+		return fmt.Errorf("invalid slack_reporter.report_template: %v", err)
+	}
+	c.SlackReporter.ReportTemplate = slackReportTmpl
+
+	endpoints := sets.NewString()
+	for i := range c.WebhookReporter.Endpoints {
+		e := &c.WebhookReporter.Endpoints[i]
+		if e.Name == "" {
+			return errors.New("webhook_reporter.endpoints: every endpoint needs a name")
+		}
+		if endpoints.Has(e.Name) {
+			return fmt.Errorf("webhook_reporter.endpoints: duplicated endpoint %q", e.Name)
+		}
+		endpoints.Insert(e.Name)
+		if u, err := url.Parse(e.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("webhook_reporter.endpoints: invalid url %q of endpoint %q", e.URL, e.Name)
+		}
+		if e.LabelSelectorString != "" {
+			sel, err := labels.Parse(e.LabelSelectorString)
+			if err != nil {
+				return fmt.Errorf("webhook_reporter.endpoints: invalid label_selector of endpoint %q: %v", e.Name, err)
+			}
+			e.LabelSelector = sel
+		}
+	}
+
 	for i := range c.JenkinsOperators {
 		if err := ValidateController(&c.JenkinsOperators[i].Controller); err != nil {
 			return fmt.Errorf("validating jenkins_operators config: %v", err)
@@ -917,6 +1158,18 @@ func parseProwConfig(c *Config) error {
 		c.Tide.StatusUpdatePeriod = period
 	}
 
+	if c.Tide.HistoryRetentionString != "" {
+		retention, err := time.ParseDuration(c.Tide.HistoryRetentionString)
+		if err != nil {
+			return fmt.Errorf("cannot parse duration for tide.history_retention: %v", err)
+		}
+		c.Tide.HistoryRetention = retention
+	}
+
+	if c.Tide.MaxCommitsBehind < 0 {
+		return errors.New("tide.max_commits_behind must not be negative")
+	}
+
 	if c.Tide.MaxGoroutines == 0 {
 		c.Tide.MaxGoroutines = 20
 	}
@@ -932,12 +1185,62 @@ func parseProwConfig(c *Config) error {
 		}
 	}
 
+	for name, templ := range c.Tide.MergeTemplate {
+		if templ.TitleTemplate != "" {
+			title, err := template.New("CommitTitle").Parse(templ.TitleTemplate)
+			if err != nil {
+				return fmt.Errorf("parsing template for the commit title of %s: %v", name, err)
+			}
+			templ.Title = title
+		}
+		if templ.BodyTemplate != "" {
+			body, err := template.New("CommitBody").Parse(templ.BodyTemplate)
+			if err != nil {
+				return fmt.Errorf("parsing template for the commit body of %s: %v", name, err)
+			}
+			templ.Body = body
+		}
+		for i, section := range templ.StripSections {
+			if section.Start == "" || section.End == "" {
+				return fmt.Errorf("merge commit template for %s: strip_sections[%d] needs a start and an end", name, i)
+			}
+		}
+		c.Tide.MergeTemplate[name] = templ
+	}
+
+	for name, n := range c.Tide.SpeculativeBatches {
+		if n < 1 {
+			return fmt.Errorf("tide has invalid speculative_batches (%d) for %s, it needs to be a positive number", n, name)
+		}
+	}
+
 	for i, tq := range c.Tide.Queries {
 		if err := tq.Validate(); err != nil {
 			return fmt.Errorf("tide query (index %d) is invalid: %v", i, err)
 		}
 	}
 
+	for i, p := range c.Tide.Priority {
+		if len(p.Labels) == 0 {
+			return fmt.Errorf("tide priority (index %d) has no labels", i)
+		}
+	}
+
+	for i := range c.Tide.MergeWindows {
+		if err := c.Tide.MergeWindows[i].parse(); err != nil {
+			return fmt.Errorf("tide merge window (index %d) is invalid: %v", i, err)
+		}
+	}
+
+	for i, freeze := range c.Tide.CodeFreezes {
+		if freeze.Name == "" {
+			return fmt.Errorf("tide code freeze (index %d) has no name", i)
+		}
+		if !freeze.End.After(freeze.Start) {
+			return fmt.Errorf("tide code freeze %q does not end after it starts", freeze.Name)
+		}
+	}
+
 	if c.ProwJobNamespace == "" {
 		c.ProwJobNamespace = "default"
 	}
@@ -1000,6 +1303,57 @@ func validateLabels(labels map[string]string) error {
 	return nil
 }
 
+// validateRunAfterSuccess ensures that the jobs each job runs after exist,
+// that only plank runs jobs with dependencies and that there are no cycles
+// in the resulting dependency graph. The inputs map the name of every job
+// of a repo to the jobs it runs after and to its agent.
+func validateRunAfterSuccess(runAfterSuccess map[string][]string, agents map[string]string) error {
+	k := string(prowapi.KubernetesAgent)
+	for job, parents := range runAfterSuccess {
+		if len(parents) > 0 && agents[job] != k {
+			return fmt.Errorf("run_after_success of job %s only applies to agent: %s (found %q)", job, k, agents[job])
+		}
+		for _, parent := range parents {
+			if _, exists := runAfterSuccess[parent]; !exists {
+				return fmt.Errorf("job %s runs after unknown job %s", job, parent)
+			}
+			if agents[parent] != k {
+				return fmt.Errorf("job %s runs after job %s which does not use agent: %s (found %q)", job, parent, k, agents[parent])
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var visit func(job string) error
+	visit = func(job string) error {
+		switch state[job] {
+		case visiting:
+			return fmt.Errorf("job %s transitively runs after itself", job)
+		case visited:
+			return nil
+		}
+		state[job] = visiting
+		for _, parent := range runAfterSuccess[job] {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		state[job] = visited
+		return nil
+	}
+	for _, job := range sets.StringKeySet(runAfterSuccess).List() {
+		if err := visit(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func validateAgent(v JobBase, podNamespace string) error {
 	k := string(prowapi.KubernetesAgent)
 	b := string(prowapi.KnativeBuildAgent)
@@ -1022,6 +1376,8 @@ func validateAgent(v JobBase, podNamespace string) error {
 		return fmt.Errorf("decoration requires agent: %s or %s (found %q)", k, b, agent)
 	case v.ErrorOnEviction && agent != k:
 		return fmt.Errorf("error_on_eviction only applies to agent: %s (found %q)", k, agent)
+	case v.RetryPolicy != nil && agent != k:
+		return fmt.Errorf("retry_policy only applies to agent: %s (found %q)", k, agent)
 	case v.Namespace == nil || *v.Namespace == "":
 		return fmt.Errorf("failed to default namespace")
 	case *v.Namespace != podNamespace && agent != b:
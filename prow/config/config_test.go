@@ -562,6 +562,20 @@ func TestValidateAgent(t *testing.T) {
 			},
 			pass: true,
 		},
+		{
+			name: "retry_policy requires kubernetes agent",
+			base: func(j *JobBase) {
+				j.Agent = b
+				j.RetryPolicy = &prowapi.RetryPolicy{MaxAttempts: 2, States: []prowapi.ProwJobState{prowapi.ErrorState}}
+			},
+		},
+		{
+			name: "retry_policy allowed for kubernetes agent",
+			base: func(j *JobBase) {
+				j.RetryPolicy = &prowapi.RetryPolicy{MaxAttempts: 2, States: []prowapi.ProwJobState{prowapi.ErrorState}}
+			},
+			pass: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -957,6 +971,43 @@ func TestValidateJobBase(t *testing.T) {
 			},
 			pass: true,
 		},
+		{
+			name: "valid slack reporter config",
+			base: JobBase{
+				Name:      "name",
+				Agent:     ka,
+				Spec:      &goodSpec,
+				Namespace: &ns,
+				ReporterConfig: &prowjobv1.ReporterConfig{
+					Slack: &prowjobv1.SlackReporterConfig{Channel: "release", ReportTemplate: "{{.Spec.Job}}"},
+				},
+			},
+			pass: true,
+		},
+		{
+			name: "slack reporter config without a channel",
+			base: JobBase{
+				Name:      "name",
+				Agent:     ka,
+				Spec:      &goodSpec,
+				Namespace: &ns,
+				ReporterConfig: &prowjobv1.ReporterConfig{
+					Slack: &prowjobv1.SlackReporterConfig{},
+				},
+			},
+		},
+		{
+			name: "slack reporter config with an invalid template",
+			base: JobBase{
+				Name:      "name",
+				Agent:     ka,
+				Spec:      &goodSpec,
+				Namespace: &ns,
+				ReporterConfig: &prowjobv1.ReporterConfig{
+					Slack: &prowjobv1.SlackReporterConfig{Channel: "release", ReportTemplate: "{{.Spec.Job"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -985,6 +1036,37 @@ func TestValidConfigLoading(t *testing.T) {
 			name:       "one config",
 			prowConfig: ``,
 		},
+		{
+			name: "tide merge commit template",
+			prowConfig: `
+tide:
+  merge_commit_template:
+    kubernetes/test-infra:
+      title: "{{ .Title }} (#{{ .Number }})"
+      body: "{{ .Body }}"
+      strip_sections:
+      - start: "<!--"
+        end: "-->"`,
+		},
+		{
+			name: "reject invalid tide merge commit template",
+			prowConfig: `
+tide:
+  merge_commit_template:
+    kubernetes:
+      title: "{{ .Title"`,
+			expectError: true,
+		},
+		{
+			name: "reject tide merge commit template stripping sections without an end",
+			prowConfig: `
+tide:
+  merge_commit_template:
+    kubernetes:
+      strip_sections:
+      - start: "<!--"`,
+			expectError: true,
+		},
 		{
 			name:       "reject invalid kubernetes periodic",
 			prowConfig: ``,
@@ -1525,6 +1607,86 @@ periodics:
 			},
 			expectError: true,
 		},
+		{
+			name: "plank queues",
+			prowConfig: `
+plank:
+  queues:
+  - name: kubernetes
+    repos:
+    - kubernetes
+    max_concurrency: 10
+  - name: release
+    label_selector: release=true
+    priority: 2`,
+		},
+		{
+			name: "plank queue without a name",
+			prowConfig: `
+plank:
+  queues:
+  - repos:
+    - kubernetes`,
+			expectError: true,
+		},
+		{
+			name: "duplicated plank queues",
+			prowConfig: `
+plank:
+  queues:
+  - name: kubernetes
+  - name: kubernetes`,
+			expectError: true,
+		},
+		{
+			name: "plank queue with negative weight",
+			prowConfig: `
+plank:
+  queues:
+  - name: kubernetes
+    weight: -1`,
+			expectError: true,
+		},
+		{
+			name: "webhook reporter endpoints",
+			prowConfig: `
+webhook_reporter:
+  endpoints:
+  - name: dashboard
+    url: https://dashboard.example.com/prow
+    hmac_secret_file: /etc/webhook/hmac
+    job_types_to_report:
+    - periodic
+    label_selector: release=true`,
+		},
+		{
+			name: "webhook reporter endpoint without url",
+			prowConfig: `
+webhook_reporter:
+  endpoints:
+  - name: dashboard`,
+			expectError: true,
+		},
+		{
+			name: "duplicated webhook reporter endpoints",
+			prowConfig: `
+webhook_reporter:
+  endpoints:
+  - name: dashboard
+    url: https://dashboard.example.com/prow
+  - name: dashboard
+    url: https://dashboard.example.com/other`,
+			expectError: true,
+		},
+		{
+			name: "plank queue with invalid label selector",
+			prowConfig: `
+plank:
+  queues:
+  - name: kubernetes
+    label_selector: "!!"`,
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1843,3 +2005,89 @@ github_reporter:
 		}
 	}
 }
+
+func TestValidateRunAfterSuccess(t *testing.T) {
+	cases := []struct {
+		name            string
+		runAfterSuccess map[string][]string
+		agents          map[string]string
+		pass            bool
+	}{
+		{
+			name: "no dependencies",
+			runAfterSuccess: map[string][]string{
+				"unit": nil,
+				"e2e":  nil,
+			},
+			pass: true,
+		},
+		{
+			name: "valid dependency graph",
+			runAfterSuccess: map[string][]string{
+				"unit":   nil,
+				"verify": nil,
+				"e2e":    {"unit", "verify"},
+				"soak":   {"e2e"},
+			},
+			pass: true,
+		},
+		{
+			name: "dependent job requires kubernetes agent",
+			runAfterSuccess: map[string][]string{
+				"unit": nil,
+				"e2e":  {"unit"},
+			},
+			agents: map[string]string{
+				"e2e": string(prowapi.JenkinsAgent),
+			},
+		},
+		{
+			name: "parent job requires kubernetes agent",
+			runAfterSuccess: map[string][]string{
+				"unit": nil,
+				"e2e":  {"unit"},
+			},
+			agents: map[string]string{
+				"unit": string(prowapi.KnativeBuildAgent),
+			},
+		},
+		{
+			name: "unknown dependency",
+			runAfterSuccess: map[string][]string{
+				"e2e": {"unit"},
+			},
+		},
+		{
+			name: "job depends on itself",
+			runAfterSuccess: map[string][]string{
+				"e2e": {"e2e"},
+			},
+		},
+		{
+			name: "transitive cycle",
+			runAfterSuccess: map[string][]string{
+				"unit": {"soak"},
+				"e2e":  {"unit"},
+				"soak": {"e2e"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			agents := map[string]string{}
+			for job := range tc.runAfterSuccess {
+				agents[job] = string(prowapi.KubernetesAgent)
+			}
+			for job, agent := range tc.agents {
+				agents[job] = agent
+			}
+			switch err := validateRunAfterSuccess(tc.runAfterSuccess, agents); {
+			case err == nil && !tc.pass:
+				t.Error("validation failed to raise an error")
+			case err != nil && tc.pass:
+				t.Errorf("validation should have passed, got: %v", err)
+			}
+		})
+	}
+}
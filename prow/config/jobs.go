@@ -98,6 +98,11 @@ type JobBase struct {
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// RetryPolicy configures re-execution of the job when its pod
+	// fails for a reason unrelated to the code under test.
+	RetryPolicy *prowapi.RetryPolicy `json:"retry_policy,omitempty"`
+	// ReporterConfig configures how crier reporters report the job.
+	ReporterConfig *prowapi.ReporterConfig `json:"reporter_config,omitempty"`
 	// SourcePath contains the path where this job is defined
 	SourcePath string `json:"-"`
 	// Spec is the Kubernetes pod spec used if Agent is kubernetes.
@@ -129,6 +134,11 @@ type Presubmit struct {
 	// (Default: `/test <job name>`)
 	RerunCommand string `json:"rerun_command"`
 
+	// RunAfterSuccess are the names of presubmits for the same repo
+	// that must succeed before this job is started, if they are
+	// triggered alongside it.
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
+
 	Brancher
 
 	RegexpChangeMatcher
@@ -143,6 +153,11 @@ type Presubmit struct {
 type Postsubmit struct {
 	JobBase
 
+	// RunAfterSuccess are the names of postsubmits for the same repo
+	// that must succeed before this job is started, if they are
+	// triggered alongside it.
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
+
 	RegexpChangeMatcher
 
 	Brancher
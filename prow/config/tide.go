@@ -21,11 +21,13 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/gitattributes"
 	"k8s.io/test-infra/prow/github"
 )
 
@@ -73,6 +75,17 @@ type Tide struct {
 	// StatusUpdatePeriod specifies how often Tide will update Github status contexts.
 	// Defaults to the value of SyncPeriod.
 	StatusUpdatePeriod time.Duration `json:"-"`
+	// HistoryRetentionString compiles into HistoryRetention at load time.
+	HistoryRetentionString string `json:"history_retention,omitempty"`
+	// HistoryRetention specifies how long Tide keeps the records of the actions
+	// it took. Only the latest 1000 records per pool are kept if unset.
+	HistoryRetention time.Duration `json:"-"`
+	// MaxCommitsBehind limits how many commits the heads of PRs may be
+	// behind their base branches for PRs to merge on required status
+	// contexts that no presubmit reports, as those may have been tested
+	// against an older base. Not limited if unset. ProwJob results always
+	// have to be against the current base.
+	MaxCommitsBehind int `json:"max_commits_behind,omitempty"`
 	// Queries represents a list of GitHub search queries that collectively
 	// specify the set of PRs that meet merge requirements.
 	Queries TideQueries `json:"queries,omitempty"`
@@ -81,6 +94,21 @@ type Tide struct {
 	// the default method of merge. Valid options are squash, rebase, and merge.
 	MergeType map[string]github.PullRequestMergeType `json:"merge_method,omitempty"`
 
+	// MergeTemplate maps an org or org/repo to the templates of the title
+	// and the message of its merge commits, with the repo overriding the org.
+	MergeTemplate map[string]TideMergeCommitTemplate `json:"merge_commit_template,omitempty"`
+
+	// SpeculativeBatches enables the merge queue mode for an org or org/repo,
+	// with the repo overriding the org. In this mode Tide keeps up to the given
+	// number of batches in flight per pool, each of which tests the PRs of the
+	// previous one and some more. Defaults to 1, a single batch at a time.
+	SpeculativeBatches map[string]int `json:"speculative_batches,omitempty"`
+
+	// BatchBisection enables the isolation of the PRs that make batches fail.
+	// Tide splits failed batches in halves and tests them until it finds the
+	// culprit PRs, which it removes from the pool until they change.
+	BatchBisection *TideBatchBisection `json:"batch_bisection,omitempty"`
+
 	// URL for tide status contexts.
 	// We can consider allowing this to be set separately for separate repos, or
 	// allowing it to be a template.
@@ -111,6 +139,226 @@ type Tide struct {
 	// combined status; otherwise it may apply the branch protection setting or let user
 	// define their own options in case branch protection is not used.
 	ContextOptions TideContextPolicyOptions `json:"context_options,omitempty"`
+
+	// Priority orders the PRs of a pool by their labels when picking the
+	// PRs to merge or to test in a batch. PRs with higher weights are
+	// picked first, PRs of the same weight in the order they were opened.
+	Priority []TidePriority `json:"priority,omitempty"`
+
+	// MergeWindows are the weekly periods during which Tide merges to the
+	// branches they apply to. Merges to a branch that any window applies to
+	// are held outside of those windows.
+	MergeWindows []TideMergeWindow `json:"merge_windows,omitempty"`
+	// CodeFreezes hold merges to the branches they apply to for a while,
+	// except for PRs with an exception label.
+	CodeFreezes []TideCodeFreeze `json:"code_freezes,omitempty"`
+}
+
+// TideBatchBisection configures how Tide handles the culprit PRs of failed batches.
+// Culprits are only known from the Tide history, which has to be persisted with
+// --history-uri for them to stay out of the pool across restarts.
+type TideBatchBisection struct {
+	// Label is added to culprit PRs if set, e.g. do-not-merge/batch-failure.
+	// Queries that exclude the label keep culprits out of the pool even after
+	// they change.
+	Label string `json:"label,omitempty"`
+}
+
+// TideMergeCommitTemplate configures the merge commits of PRs. The templates
+// are executed with a tide.MergeCommitData, which holds the title, the body,
+// the author, the labels and the reviewers of the PR.
+type TideMergeCommitTemplate struct {
+	// TitleTemplate compiles into Title at load time.
+	TitleTemplate string `json:"title,omitempty"`
+	// BodyTemplate compiles into Body at load time.
+	BodyTemplate string `json:"body,omitempty"`
+	// StripSections are removed from the body of PRs before the templates
+	// are executed, e.g. release-note blocks.
+	StripSections []TideStripSection `json:"strip_sections,omitempty"`
+
+	Title *template.Template `json:"-"`
+	Body  *template.Template `json:"-"`
+}
+
+// TideStripSection is a section of the body of PRs that spans from a
+// start marker to the next end marker, both included.
+type TideStripSection struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// TidePriority assigns a weight to the PRs that have any of its labels.
+type TidePriority struct {
+	Labels []string `json:"labels"`
+	Weight int      `json:"weight"`
+}
+
+// PriorityOf returns the weight of a PR with the given labels, which is the
+// highest weight among the priorities it has a label of. PRs without any of
+// the labels weigh 0.
+func (t *Tide) PriorityOf(labels []string) int {
+	has := sets.NewString(labels...)
+	weight, matched := 0, false
+	for _, p := range t.Priority {
+		if !has.HasAny(p.Labels...) {
+			continue
+		}
+		if !matched || p.Weight > weight {
+			weight, matched = p.Weight, true
+		}
+	}
+	return weight
+}
+
+// TideMergeWindow is a weekly period during which merges are allowed.
+type TideMergeWindow struct {
+	// Repos are the orgs and org/repos the window applies to, all if empty.
+	Repos []string `json:"repos,omitempty"`
+	// Branches are the branches the window applies to, all if empty.
+	Branches []string `json:"branches,omitempty"`
+	// Start and End are a weekday and a time of day, like "Monday 08:00".
+	// Windows may wrap around the end of the week.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// TimeZone is the IANA name of the time zone of Start and End, like
+	// America/Los_Angeles. Defaults to UTC.
+	TimeZone string `json:"time_zone,omitempty"`
+
+	// start and end are offsets from Sunday 00:00 in location.
+	start, end time.Duration
+	location   *time.Location
+}
+
+// TideCodeFreeze holds merges between two points in time.
+type TideCodeFreeze struct {
+	// Name describes the freeze to the authors of held PRs.
+	Name string `json:"name"`
+	// Repos are the orgs and org/repos the freeze applies to, all if empty.
+	Repos []string `json:"repos,omitempty"`
+	// Branches are the branches the freeze applies to, all if empty.
+	Branches []string `json:"branches,omitempty"`
+	// Start and End are RFC 3339 times, like 2019-03-07T17:00:00-08:00.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// ExceptionLabels let PRs with any of them merge during the freeze.
+	ExceptionLabels []string `json:"exception_labels,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseWeekTime parses a weekday and a time of day, like "Monday 08:00",
+// into an offset from Sunday 00:00.
+func parseWeekTime(s string) (time.Duration, error) {
+	parts := strings.Fields(s)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("%q is not a weekday and a time of day like \"Monday 08:00\"", s)
+	}
+	day, ok := weekdays[strings.ToLower(parts[0])]
+	if !ok {
+		return 0, fmt.Errorf("%q is not a weekday", parts[0])
+	}
+	clock, err := time.Parse("15:04", parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day like 08:00", parts[1])
+	}
+	return time.Duration(day)*24*time.Hour + time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// parse compiles the start, end and time zone of the window.
+func (w *TideMergeWindow) parse() error {
+	var err error
+	if w.start, err = parseWeekTime(w.Start); err != nil {
+		return fmt.Errorf("invalid start: %v", err)
+	}
+	if w.end, err = parseWeekTime(w.End); err != nil {
+		return fmt.Errorf("invalid end: %v", err)
+	}
+	if w.start == w.end {
+		return errors.New("start and end are the same")
+	}
+	if w.location, err = time.LoadLocation(w.TimeZone); err != nil {
+		return fmt.Errorf("invalid time_zone: %v", err)
+	}
+	return nil
+}
+
+// Open determines if the window is open at the given time.
+func (w *TideMergeWindow) Open(now time.Time) bool {
+	location := w.location
+	if location == nil {
+		location = time.UTC
+	}
+	now = now.In(location)
+	offset := time.Duration(now.Weekday())*24*time.Hour +
+		time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	if w.start < w.end {
+		return w.start <= offset && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
+
+func (w *TideMergeWindow) String() string {
+	zone := w.TimeZone
+	if zone == "" {
+		zone = "UTC"
+	}
+	return fmt.Sprintf("%s to %s %s", w.Start, w.End, zone)
+}
+
+// Active determines if the freeze is in effect at the given time.
+func (f *TideCodeFreeze) Active(now time.Time) bool {
+	return !now.Before(f.Start) && now.Before(f.End)
+}
+
+// appliesToBranch determines if a window or freeze with the given repos and
+// branches covers a branch.
+func appliesToBranch(repos, branches []string, org, repo, branch string) bool {
+	if len(repos) > 0 && !sets.NewString(repos...).HasAny(org, org+"/"+repo) {
+		return false
+	}
+	return len(branches) == 0 || sets.NewString(branches...).Has(branch)
+}
+
+// MergeHold returns why the merge of a PR with the given labels to a branch
+// is held at the given time by a code freeze or by being outside of merge
+// windows, or the empty string if the PR may merge.
+func (t *Tide) MergeHold(org, repo, branch string, labels []string, now time.Time) string {
+	for _, freeze := range t.CodeFreezes {
+		if !appliesToBranch(freeze.Repos, freeze.Branches, org, repo, branch) || !freeze.Active(now) {
+			continue
+		}
+		if sets.NewString(labels...).HasAny(freeze.ExceptionLabels...) {
+			continue
+		}
+		return fmt.Sprintf("%s until %s.", freeze.Name, freeze.End.Format("Jan 2 15:04 MST"))
+	}
+
+	var windows []TideMergeWindow
+	for _, window := range t.MergeWindows {
+		if !appliesToBranch(window.Repos, window.Branches, org, repo, branch) {
+			continue
+		}
+		if window.Open(now) {
+			return ""
+		}
+		windows = append(windows, window)
+	}
+	switch len(windows) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("Outside of the merge window %s.", windows[0].String())
+	default:
+		return "Outside of the merge windows."
+	}
 }
 
 // MergeMethod returns the merge method to use for a repo. The default of merge is
@@ -130,6 +378,28 @@ func (t *Tide) MergeMethod(org, repo string) github.PullRequestMergeType {
 	return v
 }
 
+// MergeCommitTemplate returns the templates of the merge commits of a repo.
+// The templates are nil when not configured, in which case GitHub uses its
+// default title and message.
+func (t *Tide) MergeCommitTemplate(org, repo string) TideMergeCommitTemplate {
+	if v, ok := t.MergeTemplate[org+"/"+repo]; ok {
+		return v
+	}
+	return t.MergeTemplate[org]
+}
+
+// SpeculativeBatchCount returns the number of batches Tide keeps in flight
+// per pool for a repo, which is 1 when not overridden.
+func (t *Tide) SpeculativeBatchCount(org, repo string) int {
+	if n, ok := t.SpeculativeBatches[org+"/"+repo]; ok {
+		return n
+	}
+	if n, ok := t.SpeculativeBatches[org]; ok {
+		return n
+	}
+	return 1
+}
+
 // TideQuery is turned into a GitHub search query. See the docs for details:
 // https://help.github.com/articles/searching-issues-and-pull-requests/
 type TideQuery struct {
@@ -146,6 +416,30 @@ type TideQuery struct {
 	Milestone string `json:"milestone,omitempty"`
 
 	ReviewApprovedRequired bool `json:"reviewApprovedRequired,omitempty"`
+
+	// RequiredApprovals require changes to some paths to be approved by the
+	// members of a team. They are checked by Tide rather than GitHub search.
+	RequiredApprovals []TideRequiredApproval `json:"requiredApprovals,omitempty"`
+}
+
+// TideRequiredApproval requires an approving review or an /approve comment
+// by a member of a GitHub team or of an OWNERS_ALIASES alias of the repo
+// on PRs that change any of its paths.
+type TideRequiredApproval struct {
+	// Paths are patterns like those of .gitattributes files, e.g. docs/**.
+	Paths []string `json:"paths"`
+	// Team is the slug of a team of the org of the repo.
+	Team string `json:"team,omitempty"`
+	// Alias is an alias of the OWNERS_ALIASES file of the repo.
+	Alias string `json:"alias,omitempty"`
+}
+
+// Approvers names the team or the alias that may approve.
+func (ra *TideRequiredApproval) Approvers() string {
+	if ra.Team != "" {
+		return ra.Team
+	}
+	return ra.Alias
 }
 
 // Query returns the corresponding github search string for the tide query.
@@ -375,6 +669,20 @@ func (tq *TideQuery) Validate() error {
 		return err
 	}
 
+	for i, approval := range tq.RequiredApprovals {
+		if len(approval.Paths) == 0 {
+			return fmt.Errorf("requiredApprovals[%d]: has no paths", i)
+		}
+		for _, path := range approval.Paths {
+			if _, err := gitattributes.ParsePattern(path); err != nil {
+				return fmt.Errorf("requiredApprovals[%d]: invalid path: %v", i, err)
+			}
+		}
+		if (approval.Team == "") == (approval.Alias == "") {
+			return fmt.Errorf("requiredApprovals[%d]: exactly one of 'team' and 'alias' must be set", i)
+		}
+	}
+
 	return nil
 }
 
@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -149,6 +150,278 @@ func TestMergeMethod(t *testing.T) {
 	}
 }
 
+func TestSpeculativeBatchCount(t *testing.T) {
+	ti := &Tide{
+		SpeculativeBatches: map[string]int{
+			"kubernetes":            3,
+			"kubernetes/test-infra": 1,
+			"helm/charts":           4,
+		},
+	}
+
+	var testcases = []struct {
+		org      string
+		repo     string
+		expected int
+	}{
+		{"kubernetes", "kubernetes", 3},
+		{"kubernetes", "test-infra", 1},
+		{"helm", "charts", 4},
+		{"helm", "helm", 1},
+	}
+
+	for _, test := range testcases {
+		if actual := ti.SpeculativeBatchCount(test.org, test.repo); actual != test.expected {
+			t.Errorf("Expected %d speculative batches but got %d for %s/%s", test.expected, actual, test.org, test.repo)
+		}
+	}
+}
+
+func TestMergeCommitTemplate(t *testing.T) {
+	ti := &Tide{
+		MergeTemplate: map[string]TideMergeCommitTemplate{
+			"kubernetes":            {TitleTemplate: "org"},
+			"kubernetes/test-infra": {TitleTemplate: "repo"},
+		},
+	}
+
+	var testcases = []struct {
+		org      string
+		repo     string
+		expected string
+	}{
+		{"kubernetes", "kubernetes", "org"},
+		{"kubernetes", "test-infra", "repo"},
+		{"helm", "charts", ""},
+	}
+
+	for _, test := range testcases {
+		if actual := ti.MergeCommitTemplate(test.org, test.repo).TitleTemplate; actual != test.expected {
+			t.Errorf("Expected merge commit template %q but got %q for %s/%s", test.expected, actual, test.org, test.repo)
+		}
+	}
+}
+
+func TestParseMergeWindow(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		window TideMergeWindow
+
+		expectedStart time.Duration
+		expectedEnd   time.Duration
+		expectedErr   bool
+	}{
+		{
+			name:          "weekdays",
+			window:        TideMergeWindow{Start: "Monday 08:00", End: "friday 16:30"},
+			expectedStart: 32 * time.Hour,
+			expectedEnd:   5*24*time.Hour + 16*time.Hour + 30*time.Minute,
+		},
+		{
+			name:          "time zone",
+			window:        TideMergeWindow{Start: "Sunday 00:00", End: "Saturday 23:59", TimeZone: "America/Los_Angeles"},
+			expectedStart: 0,
+			expectedEnd:   6*24*time.Hour + 23*time.Hour + 59*time.Minute,
+		},
+		{
+			name:        "missing time of day",
+			window:      TideMergeWindow{Start: "Monday", End: "Friday 16:00"},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid weekday",
+			window:      TideMergeWindow{Start: "Mon 08:00", End: "Friday 16:00"},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid time of day",
+			window:      TideMergeWindow{Start: "Monday 8am", End: "Friday 16:00"},
+			expectedErr: true,
+		},
+		{
+			name:        "empty window",
+			window:      TideMergeWindow{Start: "Monday 08:00", End: "Monday 08:00"},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid time zone",
+			window:      TideMergeWindow{Start: "Monday 08:00", End: "Friday 16:00", TimeZone: "Nowhere/Special"},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.window.parse()
+			if err != nil {
+				if !tc.expectedErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if tc.expectedErr {
+				t.Fatal("expected an error, got none")
+			}
+			if tc.window.start != tc.expectedStart || tc.window.end != tc.expectedEnd {
+				t.Errorf("expected window from %v to %v, got %v to %v", tc.expectedStart, tc.expectedEnd, tc.window.start, tc.window.end)
+			}
+		})
+	}
+}
+
+func TestMergeHold(t *testing.T) {
+	// Fri Mar 1 2019.
+	friday := func(hour int) time.Time {
+		return time.Date(2019, time.March, 1, hour, 0, 0, 0, time.UTC)
+	}
+	weekdays := TideMergeWindow{Repos: []string{"kubernetes"}, Start: "Monday 08:00", End: "Friday 16:00"}
+	weekends := TideMergeWindow{Repos: []string{"kubernetes/test-infra"}, Branches: []string{"master"}, Start: "Friday 18:00", End: "Monday 06:00"}
+	for _, window := range []*TideMergeWindow{&weekdays, &weekends} {
+		if err := window.parse(); err != nil {
+			t.Fatalf("failed to parse window: %v", err)
+		}
+	}
+	ti := &Tide{
+		MergeWindows: []TideMergeWindow{weekdays, weekends},
+		CodeFreezes: []TideCodeFreeze{{
+			Name:            "v1.14 code freeze",
+			Repos:           []string{"kubernetes/kubernetes"},
+			Branches:        []string{"master"},
+			Start:           friday(10),
+			End:             friday(12),
+			ExceptionLabels: []string{"approved-for-milestone"},
+		}},
+	}
+
+	var testcases = []struct {
+		name   string
+		repo   string
+		branch string
+		labels []string
+		now    time.Time
+
+		expected string
+	}{
+		{
+			name:   "inside of a window",
+			repo:   "kubernetes/kubernetes",
+			branch: "release-1.13",
+			now:    friday(9),
+		},
+		{
+			name:     "outside of a window",
+			repo:     "kubernetes/kubernetes",
+			branch:   "release-1.13",
+			now:      friday(17),
+			expected: "Outside of the merge window Monday 08:00 to Friday 16:00 UTC.",
+		},
+		{
+			name:     "outside of all windows",
+			repo:     "kubernetes/test-infra",
+			branch:   "master",
+			now:      friday(17),
+			expected: "Outside of the merge windows.",
+		},
+		{
+			name:   "inside of a window that wraps around the week",
+			repo:   "kubernetes/test-infra",
+			branch: "master",
+			now:    friday(19),
+		},
+		{
+			name:   "no window applies",
+			repo:   "helm/charts",
+			branch: "master",
+			now:    friday(17),
+		},
+		{
+			name:     "during a freeze",
+			repo:     "kubernetes/kubernetes",
+			branch:   "master",
+			now:      friday(11),
+			expected: "v1.14 code freeze until Mar 1 12:00 UTC.",
+		},
+		{
+			name:   "during a freeze with an exception label",
+			repo:   "kubernetes/kubernetes",
+			branch: "master",
+			labels: []string{"lgtm", "approved-for-milestone"},
+			now:    friday(11),
+		},
+		{
+			name:   "freeze on another branch",
+			repo:   "kubernetes/kubernetes",
+			branch: "release-1.13",
+			now:    friday(11),
+		},
+		{
+			name:   "after a freeze",
+			repo:   "kubernetes/kubernetes",
+			branch: "master",
+			now:    friday(12),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			parts := strings.SplitN(tc.repo, "/", 2)
+			if actual := ti.MergeHold(parts[0], parts[1], tc.branch, tc.labels, tc.now); actual != tc.expected {
+				t.Errorf("expected hold %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPriorityOf(t *testing.T) {
+	ti := &Tide{
+		Priority: []TidePriority{
+			{Labels: []string{"priority/critical-urgent", "kind/revert"}, Weight: 10},
+			{Labels: []string{"priority/important-soon"}, Weight: 5},
+			{Labels: []string{"priority/awaiting-more-evidence"}, Weight: -5},
+		},
+	}
+
+	var testcases = []struct {
+		name     string
+		labels   []string
+		expected int
+	}{
+		{
+			name:     "no labels",
+			expected: 0,
+		},
+		{
+			name:     "unrelated labels",
+			labels:   []string{"lgtm", "approved"},
+			expected: 0,
+		},
+		{
+			name:     "any label of a priority matches",
+			labels:   []string{"lgtm", "kind/revert"},
+			expected: 10,
+		},
+		{
+			name:     "highest matching weight wins",
+			labels:   []string{"priority/important-soon", "priority/critical-urgent"},
+			expected: 10,
+		},
+		{
+			name:     "negative weight",
+			labels:   []string{"priority/awaiting-more-evidence"},
+			expected: -5,
+		},
+		{
+			name:     "negative weight is overridden by a higher one",
+			labels:   []string{"priority/awaiting-more-evidence", "priority/important-soon"},
+			expected: 5,
+		},
+	}
+
+	for _, tc := range testcases {
+		if actual := ti.PriorityOf(tc.labels); actual != tc.expected {
+			t.Errorf("%s: expected priority %d but got %d", tc.name, tc.expected, actual)
+		}
+	}
+}
+
 func TestParseTideContextPolicyOptions(t *testing.T) {
 	yes := true
 	no := false
@@ -694,6 +967,49 @@ func TestTideQuery_Validate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "required approvals by a team and an alias are valid",
+			query: TideQuery{
+				Orgs: []string{"kuber"},
+				RequiredApprovals: []TideRequiredApproval{
+					{Paths: []string{"docs/**"}, Team: "docs-approvers"},
+					{Paths: []string{"*.proto", "api/**"}, Alias: "api-reviewers"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "required approval without paths is invalid",
+			query: TideQuery{
+				Orgs:              []string{"kuber"},
+				RequiredApprovals: []TideRequiredApproval{{Team: "docs-approvers"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "required approval with a negative path is invalid",
+			query: TideQuery{
+				Orgs:              []string{"kuber"},
+				RequiredApprovals: []TideRequiredApproval{{Paths: []string{"!docs/**"}, Team: "docs-approvers"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "required approval by a team and an alias is invalid",
+			query: TideQuery{
+				Orgs:              []string{"kuber"},
+				RequiredApprovals: []TideRequiredApproval{{Paths: []string{"docs/**"}, Team: "docs-approvers", Alias: "docs"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "required approval by nobody is invalid",
+			query: TideQuery{
+				Orgs:              []string{"kuber"},
+				RequiredApprovals: []TideRequiredApproval{{Paths: []string{"docs/**"}}},
+			},
+			expectError: true,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"regexp"
@@ -34,6 +35,7 @@ import (
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/pjutil"
 )
 
 const (
@@ -63,6 +65,14 @@ type Job struct {
 	Agent       prowapi.ProwJobAgent `json:"agent"`
 	ProwJob     string               `json:"prow_job"`
 
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
+	// Queue and QueuePosition describe where a triggered
+	// job waits to be admitted by plank.
+	Queue         string `json:"queue,omitempty"`
+	QueuePosition int    `json:"queue_position,omitempty"`
+	// FailureReason classifies why a failed job failed.
+	FailureReason string `json:"failure_reason,omitempty"`
+
 	st time.Time
 	ft time.Time
 }
@@ -79,6 +89,8 @@ type PodLogClient interface {
 	GetContainerLog(pod, container string) ([]byte, error)
 	// GetLogTail returns the last n bytes of the pod log of the specified container
 	GetLogTail(pod, container string, n int64) ([]byte, error)
+	// StreamContainerLog follows the pod log of the specified container until it stops
+	StreamContainerLog(pod, container string) (io.ReadCloser, error)
 }
 
 // NewJobAgent is a JobAgent constructor.
@@ -236,6 +248,23 @@ func (ja *JobAgent) GetJobLog(job, id string) ([]byte, error) {
 	return nil, fmt.Errorf("cannot get logs for prowjob %q with agent %q: the agent is missing from the prow config file", j.ObjectMeta.Name, j.Spec.Agent)
 }
 
+// StreamJobLog follows the job logs until the job's test container stops. Only jobs with
+// the kubernetes agent can be followed.
+func (ja *JobAgent) StreamJobLog(job, id string) (io.ReadCloser, error) {
+	j, err := ja.GetProwJob(job, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting prowjob: %v", err)
+	}
+	if j.Spec.Agent != prowapi.KubernetesAgent {
+		return nil, fmt.Errorf("cannot stream logs for prowjob %q with agent %q", j.ObjectMeta.Name, j.Spec.Agent)
+	}
+	client, ok := ja.pkcs[j.ClusterAlias()]
+	if !ok {
+		return nil, fmt.Errorf("cannot stream logs for prowjob %q with agent %q: unknown cluster alias %q", j.ObjectMeta.Name, j.Spec.Agent, j.ClusterAlias())
+	}
+	return client.StreamContainerLog(j.Status.PodName, kube.TestContainerName)
+}
+
 func (ja *JobAgent) tryUpdate() {
 	if err := ja.update(); err != nil {
 		logrus.WithError(err).Warning("Error updating job list.")
@@ -256,6 +285,10 @@ func (ja *JobAgent) update() error {
 	var njs []Job
 	njsMap := make(map[string]Job)
 	njsIDMap := make(map[string]map[string]prowapi.ProwJob)
+	scheduled := make(map[string]pjutil.ScheduledJob)
+	for _, job := range pjutil.Schedule(ja.config().Plank, pjs) {
+		scheduled[job.ProwJob.ObjectMeta.Name] = job
+	}
 	for _, j := range pjs {
 		ft := time.Time{}
 		if j.Status.CompletionTime != nil {
@@ -276,6 +309,9 @@ func (ja *JobAgent) update() error {
 			PodName:     j.Status.PodName,
 			URL:         j.Status.URL,
 
+			RunAfterSuccess: j.Spec.RunAfterSuccess,
+			FailureReason:   string(j.Status.FailureReason),
+
 			st: j.Status.StartTime.Time,
 			ft: ft,
 		}
@@ -289,6 +325,10 @@ func (ja *JobAgent) update() error {
 			nj.Refs = *j.Spec.Refs
 			nj.RefsKey = j.Spec.Refs.String()
 		}
+		if job, ok := scheduled[j.ObjectMeta.Name]; ok {
+			nj.Queue = job.Queue
+			nj.QueuePosition = job.Position
+		}
 		njs = append(njs, nj)
 		if nj.PodName != "" {
 			njsMap[nj.PodName] = nj
@@ -17,10 +17,14 @@ limitations under the License.
 package jobs
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"testing"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
 )
 
@@ -62,6 +66,14 @@ func (f fpkc) GetLogTail(pod, container string, n int64) ([]byte, error) {
 	return log[logLen-n:], nil
 }
 
+func (f fpkc) StreamContainerLog(pod, container string) (io.ReadCloser, error) {
+	log, err := f.GetContainerLog(pod, container)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(log)), nil
+}
+
 func TestGetLogTail(t *testing.T) {
 	kc := fkc{
 		prowapi.ProwJob{
@@ -87,8 +99,9 @@ func TestGetLogTail(t *testing.T) {
 		},
 	}
 	ja := &JobAgent{
-		kc:   kc,
-		pkcs: map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")},
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")},
+		config: func() *config.Config { return &config.Config{} },
 	}
 	if err := ja.update(); err != nil {
 		t.Fatalf("Updating: %v", err)
@@ -131,8 +144,9 @@ func TestGetLog(t *testing.T) {
 		},
 	}
 	ja := &JobAgent{
-		kc:   kc,
-		pkcs: map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")},
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")},
+		config: func() *config.Config { return &config.Config{} },
 	}
 	if err := ja.update(); err != nil {
 		t.Fatalf("Updating: %v", err)
@@ -150,6 +164,53 @@ func TestGetLog(t *testing.T) {
 	}
 }
 
+func TestStreamJobLog(t *testing.T) {
+	kc := fkc{
+		prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{
+				Agent:   prowapi.KubernetesAgent,
+				Job:     "jib",
+				Cluster: "trusted",
+			},
+			Status: prowapi.ProwJobStatus{
+				PodName: "powowow",
+				BuildID: "123",
+			},
+		},
+		prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{
+				Agent: prowapi.JenkinsAgent,
+				Job:   "jenkins",
+			},
+			Status: prowapi.ProwJobStatus{
+				BuildID: "123",
+			},
+		},
+	}
+	ja := &JobAgent{
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")},
+		config: func() *config.Config { return &config.Config{} },
+	}
+	if err := ja.update(); err != nil {
+		t.Fatalf("Updating: %v", err)
+	}
+	stream, err := ja.StreamJobLog("jib", "123")
+	if err != nil {
+		t.Fatalf("Failed to stream log: %v", err)
+	}
+	defer stream.Close()
+	if res, err := ioutil.ReadAll(stream); err != nil {
+		t.Fatalf("Failed to read log stream: %v", err)
+	} else if got, expect := string(res), "clusterB"; got != expect {
+		t.Errorf("Unexpected result streaming logs for job 'jib'. Expected %q, but got %q.", expect, got)
+	}
+
+	if _, err := ja.StreamJobLog("jenkins", "123"); err == nil {
+		t.Error("Expected an error streaming logs for a job with the jenkins agent.")
+	}
+}
+
 func TestProwJobs(t *testing.T) {
 	kc := fkc{
 		prowapi.ProwJob{
@@ -168,8 +229,9 @@ func TestProwJobs(t *testing.T) {
 		},
 	}
 	ja := &JobAgent{
-		kc:   kc,
-		pkcs: map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		kc:     kc,
+		pkcs:   map[string]PodLogClient{kube.DefaultClusterAlias: fpkc("")},
+		config: func() *config.Config { return &config.Config{} },
 	}
 	if err := ja.update(); err != nil {
 		t.Fatalf("Updating: %v", err)
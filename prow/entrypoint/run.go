@@ -52,6 +52,10 @@ const (
 	// PreviousErrorCode indicates a previous step failed so we
 	// did not run this step.
 	PreviousErrorCode = internalCode + AbortedErrorCode
+	// TimeoutErrorCode is what we write to the marker file to
+	// indicate that the command did not finish before the timeout.
+	// We still exit with InternalErrorCode in that case.
+	TimeoutErrorCode = internalCode + InternalErrorCode
 
 	// DefaultTimeout is the default timeout for the test
 	// process before SIGINT is sent
@@ -69,6 +73,10 @@ var (
 	// errAborted is used as the command's error when the command
 	// is shut down by an external signal
 	errAborted = errors.New("process aborted")
+
+	// terminationMessagePath is where the resources the
+	// process used are recorded for the ProwJob controller
+	terminationMessagePath = wrapper.TerminationMessagePath
 )
 
 // Run executes the test process then writes the exit code to the marker file.
@@ -85,6 +93,9 @@ func (o Options) Run() int {
 	if o.AlwaysZero {
 		return 0
 	}
+	if code == TimeoutErrorCode {
+		return InternalErrorCode
+	}
 	return code
 }
 
@@ -147,7 +158,7 @@ func (o Options) ExecuteProcess() (int, error) {
 	timeout := optionOrDefault(o.Timeout, DefaultTimeout)
 	gracePeriod := optionOrDefault(o.GracePeriod, DefaultGracePeriod)
 	var commandErr error
-	cancelled, aborted := false, false
+	cancelled, aborted, exited := false, false, false
 	done := make(chan error)
 	go func() {
 		done <- command.Wait()
@@ -155,15 +166,19 @@ func (o Options) ExecuteProcess() (int, error) {
 	select {
 	case err := <-done:
 		commandErr = err
+		exited = true
 	case <-time.After(timeout):
 		logrus.Errorf("Process did not finish before %s timeout", timeout)
 		cancelled = true
-		gracefullyTerminate(command, done, gracePeriod)
+		exited = gracefullyTerminate(command, done, gracePeriod)
 	case s := <-interrupt:
 		logrus.Errorf("Entrypoint received interrupt: %v", s)
 		cancelled = true
 		aborted = true
-		gracefullyTerminate(command, done, gracePeriod)
+		exited = gracefullyTerminate(command, done, gracePeriod)
+	}
+	if exited {
+		recordUsage(command.ProcessState)
 	}
 
 	var returnCode int
@@ -173,7 +188,7 @@ func (o Options) ExecuteProcess() (int, error) {
 			returnCode = AbortedErrorCode
 		} else {
 			commandErr = errTimedOut
-			returnCode = InternalErrorCode
+			returnCode = TimeoutErrorCode
 		}
 	} else {
 		if status, ok := command.ProcessState.Sys().(syscall.WaitStatus); ok {
@@ -228,7 +243,10 @@ func optionOrDefault(option, defaultValue time.Duration) time.Duration {
 	return option
 }
 
-func gracefullyTerminate(command *exec.Cmd, done <-chan error, gracePeriod time.Duration) {
+// gracefullyTerminate interrupts the process then kills it if it does
+// not exit within the grace period. It returns true if the process
+// exited before it had to be killed.
+func gracefullyTerminate(command *exec.Cmd, done <-chan error, gracePeriod time.Duration) bool {
 	if err := command.Process.Signal(os.Interrupt); err != nil {
 		logrus.WithError(err).Error("Could not interrupt process after timeout")
 	}
@@ -236,10 +254,27 @@ func gracefullyTerminate(command *exec.Cmd, done <-chan error, gracePeriod time.
 	case <-done:
 		logrus.Errorf("Process gracefully exited before %s grace period", gracePeriod)
 		// but we ignore the output error as we will want errTimedOut
+		return true
 	case <-time.After(gracePeriod):
 		logrus.Errorf("Process did not exit before %s grace period", gracePeriod)
 		if err := command.Process.Kill(); err != nil {
 			logrus.WithError(err).Error("Could not kill process after grace period")
 		}
+		return false
+	}
+}
+
+// recordUsage records the resources the exited process used as the
+// termination message of the test container.
+func recordUsage(state *os.ProcessState) {
+	usage := wrapper.Usage{
+		CPUMillis: int64((state.UserTime() + state.SystemTime()) / time.Millisecond),
+	}
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// Linux reports the maximum resident set size in kilobytes.
+		usage.MaxMemoryBytes = rusage.Maxrss * 1024
+	}
+	if err := wrapper.RecordUsage(terminationMessagePath, usage); err != nil {
+		logrus.WithError(err).Warn("Could not record the resources used by the process")
 	}
 }
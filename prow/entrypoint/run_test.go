@@ -40,6 +40,7 @@ func TestOptions_Run(t *testing.T) {
 		expectedLog    string
 		expectedMarker string
 		expectedCode   int
+		expectedUsage  bool
 	}{
 		{
 			name:           "successful command",
@@ -47,6 +48,7 @@ func TestOptions_Run(t *testing.T) {
 			expectedLog:    "",
 			expectedMarker: "0",
 			expectedCode:   0,
+			expectedUsage:  true,
 		},
 		{
 			name:           "successful command with output",
@@ -54,6 +56,7 @@ func TestOptions_Run(t *testing.T) {
 			expectedLog:    "test\n",
 			expectedMarker: "0",
 			expectedCode:   0,
+			expectedUsage:  true,
 		},
 		{
 			name:           "unsuccessful command",
@@ -75,7 +78,7 @@ func TestOptions_Run(t *testing.T) {
 			timeout:        1 * time.Second,
 			gracePeriod:    1 * time.Second,
 			expectedLog:    "level=error msg=\"Process did not finish before 1s timeout\"\nlevel=error msg=\"Process gracefully exited before 1s grace period\"\n",
-			expectedMarker: strconv.Itoa(InternalErrorCode),
+			expectedMarker: strconv.Itoa(TimeoutErrorCode),
 			expectedCode:   InternalErrorCode,
 		},
 		{
@@ -84,7 +87,7 @@ func TestOptions_Run(t *testing.T) {
 			timeout:        1 * time.Second,
 			gracePeriod:    1 * time.Second,
 			expectedLog:    "level=error msg=\"Process did not finish before 1s timeout\"\nlevel=error msg=\"Process did not exit before 1s grace period\"\n",
-			expectedMarker: strconv.Itoa(InternalErrorCode),
+			expectedMarker: strconv.Itoa(TimeoutErrorCode),
 			expectedCode:   InternalErrorCode,
 		},
 		{
@@ -176,6 +179,9 @@ func TestOptions_Run(t *testing.T) {
 				options.MarkerFile = "/this/had/better/not/be/a/real/file!@!#$%#$^#%&*&&*()*"
 			}
 
+			terminationMessagePath = path.Join(tmpDir, "termination-log")
+			defer func() { terminationMessagePath = wrapper.TerminationMessagePath }()
+
 			if code := options.Run(); code != testCase.expectedCode {
 				t.Errorf("%s: expected exit code %d != actual %d", testCase.name, testCase.expectedCode, code)
 			}
@@ -184,6 +190,15 @@ func TestOptions_Run(t *testing.T) {
 			if !testCase.invalidMarker {
 				compareFileContents(testCase.name, options.MarkerFile, testCase.expectedMarker, t)
 			}
+			if testCase.expectedUsage {
+				message, err := ioutil.ReadFile(terminationMessagePath)
+				if err != nil {
+					t.Fatalf("%s: could not read termination message: %v", testCase.name, err)
+				}
+				if usage, ok := wrapper.ParseUsage(string(message)); !ok || usage.MaxMemoryBytes == 0 {
+					t.Errorf("%s: expected the resource usage to be recorded, got %q", testCase.name, message)
+				}
+			}
 		})
 	}
 }
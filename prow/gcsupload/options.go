@@ -22,6 +22,7 @@ import (
 	"flag"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/testgrid/util/gcs"
 )
 
@@ -33,7 +34,7 @@ func NewOptions() *Options {
 }
 
 // Options exposes the configuration necessary
-// for defining where in GCS an upload will land.
+// for defining where in storage an upload will land.
 type Options struct {
 	// Items are files or directories to upload.
 	Items []string `json:"items,omitempty"`
@@ -46,7 +47,13 @@ type Options struct {
 	// GcsCredentialsFile is the path to the JSON
 	// credentials for pushing to GCS.
 	GcsCredentialsFile string `json:"gcs_credentials_file,omitempty"`
-	DryRun             bool   `json:"dry_run"`
+	// S3CredentialsFile is the path to the JSON
+	// credentials for pushing to S3 buckets.
+	S3CredentialsFile string `json:"s3_credentials_file,omitempty"`
+	// FileStorageRoot is the directory that holds
+	// the directories of file buckets.
+	FileStorageRoot string `json:"file_storage_root,omitempty"`
+	DryRun          bool   `json:"dry_run"`
 
 	// gcsPath is used to store human-provided GCS
 	// paths that are parsed to get more granular
@@ -67,9 +74,16 @@ func (o *Options) Validate() error {
 			return errors.New("GCS upload was requested no GCS bucket was provided")
 		}
 
-		if o.GcsCredentialsFile == "" {
+		scheme, _, err := objectstore.ParseBucket(o.Bucket)
+		if err != nil {
+			return err
+		}
+		if scheme == objectstore.GCS && o.GcsCredentialsFile == "" {
 			return errors.New("GCS upload was requested but no GCS credentials file was provided")
 		}
+		if scheme == objectstore.File && o.FileStorageRoot == "" {
+			return errors.New("upload to a file bucket was requested but no file storage root was provided")
+		}
 	}
 
 	return o.GCSConfiguration.Validate()
@@ -102,6 +116,8 @@ func (o *Options) AddFlags(fs *flag.FlagSet) {
 
 	fs.Var(&o.gcsPath, "gcs-path", "GCS path to upload into")
 	fs.StringVar(&o.GcsCredentialsFile, "gcs-credentials-file", "", "file where Google Cloud authentication credentials are stored")
+	fs.StringVar(&o.S3CredentialsFile, "s3-credentials-file", "", "file where the credentials and endpoint of S3 buckets are stored")
+	fs.StringVar(&o.FileStorageRoot, "file-storage-root", "", "directory that holds the directories of file buckets")
 	fs.BoolVar(&o.DryRun, "dry-run", true, "do not interact with GCS")
 }
 
@@ -72,6 +72,52 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			expectedErr: true,
 		},
+		{
+			name: "push to S3 without credentials, ok",
+			input: Options{
+				DryRun: false,
+				GCSConfiguration: &prowapi.GCSConfiguration{
+					Bucket:       "s3://seal",
+					PathStrategy: prowapi.PathStrategyExplicit,
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "push to file bucket, ok",
+			input: Options{
+				DryRun:          false,
+				FileStorageRoot: "/buckets",
+				GCSConfiguration: &prowapi.GCSConfiguration{
+					Bucket:       "file://seal",
+					PathStrategy: prowapi.PathStrategyExplicit,
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "push to file bucket, missing root",
+			input: Options{
+				DryRun: false,
+				GCSConfiguration: &prowapi.GCSConfiguration{
+					Bucket:       "file://seal",
+					PathStrategy: prowapi.PathStrategyExplicit,
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "push to unknown storage",
+			input: Options{
+				DryRun:             false,
+				GcsCredentialsFile: "secrets",
+				GCSConfiguration: &prowapi.GCSConfiguration{
+					Bucket:       "azure://seal",
+					PathStrategy: prowapi.PathStrategyExplicit,
+				},
+			},
+			expectedErr: true,
+		},
 	}
 
 	for _, testCase := range testCases {
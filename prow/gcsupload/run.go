@@ -24,32 +24,35 @@ import (
 	"path/filepath"
 	"strings"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/api/option"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
 )
 
-// Run will upload files to GCS as prescribed by
-// the options. Any extra files can be passed as
+// Run will upload files to the bucket as prescribed
+// by the options. Any extra files can be passed as
 // a parameter and will have the prefix prepended
-// to their destination in GCS, so the caller can
-// operate relative to the base of the GCS dir.
+// to their destination in the bucket, so the caller
+// can operate relative to the base of the job dir.
 func (o Options) Run(spec *downwardapi.JobSpec, extra map[string]gcs.UploadFunc) error {
 	uploadTargets := o.assembleTargets(spec, extra)
 
 	if !o.DryRun {
-		ctx := context.Background()
-		gcsClient, err := storage.NewClient(ctx, option.WithCredentialsFile(o.GcsCredentialsFile))
+		opener := &objectstore.Opener{
+			GCSCredentialsFile: o.GcsCredentialsFile,
+			S3CredentialsFile:  o.S3CredentialsFile,
+			FileRoot:           o.FileStorageRoot,
+		}
+		bucket, err := opener.Open(context.Background(), o.Bucket)
 		if err != nil {
-			return fmt.Errorf("could not connect to GCS: %v", err)
+			return fmt.Errorf("could not open bucket %s: %v", o.Bucket, err)
 		}
 
-		if err := gcs.Upload(gcsClient.Bucket(o.Bucket), uploadTargets); err != nil {
-			return fmt.Errorf("failed to upload to GCS: %v", err)
+		if err := gcs.Upload(bucket, uploadTargets); err != nil {
+			return fmt.Errorf("failed to upload to %s: %v", o.Bucket, err)
 		}
 	} else {
 		for destination := range uploadTargets {
@@ -57,7 +60,7 @@ func (o Options) Run(spec *downwardapi.JobSpec, extra map[string]gcs.UploadFunc)
 		}
 	}
 
-	logrus.Info("Finished upload to GCS")
+	logrus.Infof("Finished upload to %s", o.Bucket)
 	return nil
 }
 
@@ -69,7 +72,9 @@ func (o Options) assembleTargets(spec *downwardapi.JobSpec, extra map[string]gcs
 	// ensure that an alias exists for any
 	// job we're uploading artifacts for
 	if alias := gcs.AliasForSpec(spec); alias != "" {
-		fullBasePath := "gs://" + path.Join(o.Bucket, jobBasePath)
+		// The bucket is validated with the options.
+		bucketURL, _ := objectstore.URL(o.Bucket)
+		fullBasePath := bucketURL + "/" + jobBasePath
 		uploadTargets[alias] = gcs.DataUploadWithMetadata(strings.NewReader(fullBasePath), map[string]string{
 			"x-goog-meta-link": fullBasePath,
 		})
@@ -60,7 +60,7 @@ func (c *Client) GetName() string {
 // ShouldReport returns if this prowjob should be reported by the gerrit reporter
 func (c *Client) ShouldReport(pj *v1.ProwJob) bool {
 
-	if pj.Status.State == v1.TriggeredState || pj.Status.State == v1.PendingState {
+	if pj.Status.State == v1.WaitingState || pj.Status.State == v1.TriggeredState || pj.Status.State == v1.PendingState {
 		// not done yet
 		logrus.WithField("prowjob", pj.ObjectMeta.Name).Info("PJ not finished")
 		return false
@@ -92,7 +92,7 @@ func (c *Client) ShouldReport(pj *v1.ProwJob) bool {
 	}
 
 	for _, pj := range pjs {
-		if pj.Status.State == v1.TriggeredState || pj.Status.State == v1.PendingState {
+		if pj.Status.State == v1.WaitingState || pj.Status.State == v1.TriggeredState || pj.Status.State == v1.PendingState {
 			// other jobs are still running on this revision, skip report
 			logrus.WithField("prowjob", pj.ObjectMeta.Name).Info("Other jobs are still running on this revision")
 			return false
@@ -59,7 +59,7 @@ func TestParsePattern(t *testing.T) {
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			if _, err := parsePattern(c.pattern); err != nil && !c.expectError {
+			if _, err := ParsePattern(c.pattern); err != nil && !c.expectError {
 				t.Fatalf("load error: %v", err)
 			}
 		})
@@ -136,7 +136,7 @@ func TestMatch(t *testing.T) {
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			p, _ := parsePattern(c.pattern)
+			p, _ := ParsePattern(c.pattern)
 			if p.Match(c.path) != c.shouldMatch {
 				t.Fatalf("mismatch")
 			}
@@ -1311,6 +1311,20 @@ func (c *Client) GetSingleCommit(org, repo, SHA string) (SingleCommit, error) {
 	return commit, err
 }
 
+// CompareCommits compares the head commit with the base commit.
+//
+// See https://developer.github.com/v3/repos/commits/#compare-two-commits
+func (c *Client) CompareCommits(org, repo, base, head string) (CommitComparison, error) {
+	c.log("CompareCommits", org, repo, base, head)
+	var comparison CommitComparison
+	_, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/repos/%s/%s/compare/%s...%s", org, repo, base, head),
+		exitCodes: []int{200},
+	}, &comparison)
+	return comparison, err
+}
+
 // GetBranches returns all branches in the repo.
 //
 // If onlyProtected is true it will only return repos with protection enabled,
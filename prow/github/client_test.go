@@ -384,6 +384,26 @@ func TestGetSingleCommit(t *testing.T) {
 	}
 }
 
+func TestCompareCommits(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/octocat/Hello-World/compare/master-sha...topic-sha" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"status": "diverged", "ahead_by": 1, "behind_by": 3}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	comparison, err := c.CompareCommits("octocat", "Hello-World", "master-sha", "topic-sha")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if expected := (CommitComparison{Status: "diverged", AheadBy: 1, BehindBy: 3}); comparison != expected {
+		t.Errorf("Expected comparison %+v, got %+v", expected, comparison)
+	}
+}
+
 func TestCreateStatus(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -51,6 +51,8 @@ type GithubClient interface {
 // https://developer.github.com/v3/repos/statuses/#create-a-status
 func prowjobStateToGithubStatus(pjState prowapi.ProwJobState) (string, error) {
 	switch pjState {
+	case prowapi.WaitingState:
+		return github.StatusPending, nil
 	case prowapi.TriggeredState:
 		return github.StatusPending, nil
 	case prowapi.PendingState:
@@ -258,13 +260,29 @@ func parseIssueComments(pj prowapi.ProwJob, botName string, ics []github.IssueCo
 }
 
 func createEntry(pj prowapi.ProwJob) string {
+	// Only suggest a rerun when the code under test is not to blame.
+	var rerun string
+	if reason := pj.Status.FailureReason; reason == "" || reason.IsInfra() {
+		rerun = fmt.Sprintf("`%s`", pj.Spec.RerunCommand)
+	}
 	return strings.Join([]string{
 		pj.Spec.Context,
 		fmt.Sprintf("[link](%s)", pj.Status.URL),
-		fmt.Sprintf("`%s`", pj.Spec.RerunCommand),
+		rerun,
 	}, " | ")
 }
 
+// suggestsRerun determines if any of the entries has a rerun command.
+func suggestsRerun(entries []string) bool {
+	for _, entry := range entries {
+		fields := strings.Split(entry, "|")
+		if len(fields) < 3 || strings.TrimSpace(fields[2]) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // createComment take a ProwJob and a list of entries generated with
 // createEntry and returns a nicely formatted comment. It may fail if template
 // execution fails.
@@ -279,11 +297,16 @@ func createComment(reportTemplate *template.Template, pj prowapi.ProwJob, entrie
 			return "", err
 		}
 	}
+	retest := ""
+	if suggestsRerun(entries) {
+		retest = ", say `/retest` to rerun them"
+	}
 	lines := []string{
-		fmt.Sprintf("@%s: The following test%s **failed** for commit %s, say `/retest` to rerun them:",
+		fmt.Sprintf("@%s: The following test%s **failed** for commit %s%s:",
 			pj.Spec.Refs.Pulls[0].Author,
 			plural,
-			pj.Spec.Refs.Pulls[0].SHA),
+			pj.Spec.Refs.Pulls[0].SHA,
+			retest),
 		"",
 		"Test name | Details | Rerun command",
 		tableLine,
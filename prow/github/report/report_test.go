@@ -779,6 +779,33 @@ func TestCreateComment(t *testing.T) {
 				commentTag,
 			},
 		},
+		{
+			name:    "should not suggest a retest when no test can be rerun",
+			commits: []github.RepositoryCommit{{SHA: "1234"}, {SHA: "5678"}},
+			entries: []string{"context | foo |"},
+			pj: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Refs: &prowapi.Refs{
+						Pulls: []prowapi.Pull{
+							{Author: "someuser", SHA: "135734"},
+						},
+					},
+				},
+			},
+			expectedLines: []string{
+				"@someuser: The following test **failed** for commit 135734:",
+				"",
+				"Test name | Details | Rerun command",
+				"--- | --- | ---",
+				"context | foo |",
+				"",
+				"<details>",
+				"",
+				plugins.AboutThisBot,
+				"</details>",
+				commentTag,
+			},
+		},
 		{
 			name:    "should return valid comment without commit list",
 			entries: []string{"context | foo | bar"},
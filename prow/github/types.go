@@ -567,6 +567,15 @@ type SingleCommit struct {
 	} `json:"commit"`
 }
 
+// CommitComparison is how a head commit compares to a base commit. The head
+// is behind the base by the commits of the base that it does not include.
+// https://developer.github.com/v3/repos/commits/#compare-two-commits
+type CommitComparison struct {
+	Status   string `json:"status"`
+	AheadBy  int    `json:"ahead_by"`
+	BehindBy int    `json:"behind_by"`
+}
+
 // ReviewEventAction enumerates the triggers for this
 // webhook payload type. See also:
 // https://developer.github.com/v3/activity/events/types/#pullrequestreviewevent
@@ -700,6 +709,7 @@ const (
 type Team struct {
 	ID           int    `json:"id,omitempty"`
 	Name         string `json:"name"`
+	Slug         string `json:"slug,omitempty"` // Only present in responses
 	Description  string `json:"description,omitempty"`
 	Privacy      string `json:"privacy,omitempty"`
 	Parent       *Team  `json:"parent,omitempty"`         // Only present in responses
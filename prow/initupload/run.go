@@ -25,9 +25,13 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/pod-utils/clone"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
 )
 
 // specToStarted translate a jobspec into a started struct
@@ -87,16 +91,26 @@ func (o Options) Run() error {
 	uploadTargets["started.json"] = gcs.DataUpload(bytes.NewReader(startedData))
 
 	if err := o.Options.Run(spec, uploadTargets); err != nil {
+		recordFailureReason(prowapi.UploadFailureReason)
 		return fmt.Errorf("failed to upload to GCS: %v", err)
 	}
 
 	if failed {
+		recordFailureReason(prowapi.CloneFailureReason)
 		return errors.New("cloning the appropriate refs failed")
 	}
 
 	return nil
 }
 
+// recordFailureReason records why initupload failed as its termination
+// message on a best-effort basis.
+func recordFailureReason(reason prowapi.FailureReason) {
+	if err := wrapper.RecordFailureReason(wrapper.TerminationMessagePath, reason); err != nil {
+		logrus.WithError(err).Warn("Could not record failure reason")
+	}
+}
+
 // processCloneLog checks if clone operation successed or failed for a ref
 // and upload clone logs as build log upon failures.
 // returns: bool - clone status
@@ -191,14 +191,18 @@ func (c *Controller) Sync() error {
 		return fmt.Errorf("error listing jenkins builds: %v", err)
 	}
 
+	reportCh := make(chan prowapi.ProwJob, len(jenkinsJobs))
+
 	var syncErrs []error
 	if err := c.terminateDupes(jenkinsJobs, jbs); err != nil {
 		syncErrs = append(syncErrs, err)
 	}
+	if err := c.abortCancelledJobs(jenkinsJobs, jbs, reportCh); err != nil {
+		syncErrs = append(syncErrs, err)
+	}
 
 	pendingCh, triggeredCh := pjutil.PartitionActive(jenkinsJobs)
 	errCh := make(chan error, len(jenkinsJobs))
-	reportCh := make(chan prowapi.ProwJob, len(jenkinsJobs))
 
 	// Reinstantiate on every resync of the controller instead of trying
 	// to keep this in sync with the state of the world.
@@ -309,6 +313,40 @@ func (c *Controller) terminateDupes(pjs []prowapi.ProwJob, jbs map[string]Build)
 	return nil
 }
 
+// abortCancelledJobs aborts the Jenkins builds of incomplete ProwJobs whose
+// cancellation has been requested. Enqueued builds cannot be stopped, so
+// their jobs are aborted once the build starts. It modifies pjs in-place
+// when it aborts.
+func (c *Controller) abortCancelledJobs(pjs []prowapi.ProwJob, jbs map[string]Build, reports chan<- prowapi.ProwJob) error {
+	for i, pj := range pjs {
+		if pj.Complete() || !pj.Spec.Cancel {
+			continue
+		}
+		if build, buildExists := jbs[pj.ObjectMeta.Name]; buildExists {
+			if build.IsEnqueued() {
+				continue
+			}
+			if err := c.jc.Abort(pj.Spec.Job, &build); err != nil {
+				return fmt.Errorf("error aborting Jenkins build of cancelled job %q: %v", pj.ObjectMeta.Name, err)
+			}
+		}
+		pj.SetComplete()
+		prevState := pj.Status.State
+		pj.Status.State = prowapi.AbortedState
+		pj.Status.Description = "Jenkins job aborted."
+		c.log.WithFields(pjutil.ProwJobFields(&pj)).
+			WithField("from", prevState).
+			WithField("to", pj.Status.State).Info("Transitioning states.")
+		npj, err := c.prowJobClient.Update(&pj)
+		if err != nil {
+			return err
+		}
+		reports <- *npj
+		pjs[i] = *npj
+	}
+	return nil
+}
+
 func syncProwJobs(
 	l *logrus.Entry,
 	syncFn syncFn,
@@ -105,6 +105,8 @@ type fjc struct {
 	pjs    []prowapi.ProwJob
 	err    error
 	builds map[string]Build
+	// aborted holds the names of the jobs whose builds were aborted
+	aborted []string
 }
 
 func (f *fjc) Build(pj *prowapi.ProwJob, buildID string) error {
@@ -130,6 +132,7 @@ func (f *fjc) ListBuilds(jobs []string) (map[string]Build, error) {
 func (f *fjc) Abort(job string, build *Build) error {
 	f.Lock()
 	defer f.Unlock()
+	f.aborted = append(f.aborted, job)
 	return nil
 }
 
@@ -681,6 +684,87 @@ func TestBatch(t *testing.T) {
 	}
 }
 
+func TestAbortCancelledJobs(t *testing.T) {
+	var testcases = []struct {
+		name   string
+		cancel bool
+		build  *Build
+
+		expectedState   prowapi.ProwJobState
+		expectedAborted []string
+		expectedReport  bool
+	}{
+		{
+			name:          "running job without cancel request is left alone",
+			build:         &Build{Number: 42},
+			expectedState: prowapi.PendingState,
+		},
+		{
+			name:            "cancelled running job has its build aborted",
+			cancel:          true,
+			build:           &Build{Number: 42},
+			expectedState:   prowapi.AbortedState,
+			expectedAborted: []string{"job"},
+			expectedReport:  true,
+		},
+		{
+			name:          "cancelled job with an enqueued build waits for the build to start",
+			cancel:        true,
+			build:         &Build{enqueued: true},
+			expectedState: prowapi.PendingState,
+		},
+		{
+			name:           "cancelled job without a build is aborted",
+			cancel:         true,
+			expectedState:  prowapi.AbortedState,
+			expectedReport: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "prowjobs"},
+				Spec:       prowapi.ProwJobSpec{Job: "job", Cancel: tc.cancel},
+				Status:     prowapi.ProwJobStatus{State: prowapi.PendingState},
+			}
+			jbs := map[string]Build{}
+			if tc.build != nil {
+				jbs["foo"] = *tc.build
+			}
+			fakeProwJobClient := fake.NewSimpleClientset(&pj)
+			jc := &fjc{}
+			c := Controller{
+				prowJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				jc:            jc,
+				log:           logrus.NewEntry(logrus.StandardLogger()),
+			}
+			pjs := []prowapi.ProwJob{pj}
+			reports := make(chan prowapi.ProwJob, len(pjs))
+			if err := c.abortCancelledJobs(pjs, jbs, reports); err != nil {
+				t.Fatalf("Error aborting cancelled jobs: %v", err)
+			}
+			close(reports)
+
+			actual, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").Get("foo", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get prowjob from client: %v", err)
+			}
+			if actual.Status.State != tc.expectedState {
+				t.Errorf("expected state %q, got %q", tc.expectedState, actual.Status.State)
+			}
+			if pjs[0].Status.State != tc.expectedState {
+				t.Errorf("expected state %q to be updated in-place, got %q", tc.expectedState, pjs[0].Status.State)
+			}
+			if !reflect.DeepEqual(jc.aborted, tc.expectedAborted) {
+				t.Errorf("expected aborted builds %v, got %v", tc.expectedAborted, jc.aborted)
+			}
+			if tc.expectedReport != (len(reports) == 1) {
+				t.Errorf("expected report: %v, got %d reports", tc.expectedReport, len(reports))
+			}
+		})
+	}
+}
+
 func TestMaxConcurrencyWithNewlyTriggeredJobs(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -152,6 +152,9 @@ type request struct {
 	deckPath    string
 	query       map[string]string
 	requestBody interface{}
+	// stream is set for requests whose bodies are read for
+	// longer than the request timeout, like followed logs.
+	stream bool
 }
 
 func (c *Client) request(r *request, ret interface{}) error {
@@ -172,7 +175,7 @@ func (c *Client) retry(r *request) (*http.Response, error) {
 	var err error
 	backoff := retryDelay
 	for retries := 0; retries < maxRetries; retries++ {
-		resp, err = c.doRequest(r.method, r.deckPath, r.path, r.query, r.requestBody)
+		resp, err = c.doRequest(r.method, r.deckPath, r.path, r.query, r.requestBody, r.stream)
 		if err == nil {
 			if resp.StatusCode < 500 {
 				break
@@ -189,7 +192,7 @@ func (c *Client) retry(r *request) (*http.Response, error) {
 // Retry on transport failures. Does not retry on 500s.
 func (c *Client) requestRetryStream(r *request) (io.ReadCloser, error) {
 	if c.fake && r.deckPath == "" {
-		return nil, nil
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
 	}
 	resp, err := c.retry(r)
 	if err != nil {
@@ -232,7 +235,7 @@ func (c *Client) requestRetry(r *request) ([]byte, error) {
 	return rb, nil
 }
 
-func (c *Client) doRequest(method, deckPath, urlPath string, query map[string]string, body interface{}) (*http.Response, error) {
+func (c *Client) doRequest(method, deckPath, urlPath string, query map[string]string, body interface{}, stream bool) (*http.Response, error) {
 	url := c.baseURL + urlPath
 	if c.deckURL != "" && deckPath != "" {
 		url = c.deckURL + deckPath
@@ -264,7 +267,13 @@ func (c *Client) doRequest(method, deckPath, urlPath string, query map[string]st
 	}
 	req.URL.RawQuery = q.Encode()
 
-	return c.client.Do(req)
+	client := c.client
+	if stream && client.Timeout != 0 {
+		noTimeout := *client
+		noTimeout.Timeout = 0
+		client = &noTimeout
+	}
+	return client.Do(req)
 }
 
 // NewFakeClient creates a client that doesn't do anything. If you provide a
@@ -446,6 +455,29 @@ func (c *Client) ListPods(selector string) ([]Pod, error) {
 	return pl.Items, err
 }
 
+// GetNode is analogous to kubectl get nodes/NAME
+func (c *Client) GetNode(name string) (Node, error) {
+	c.log("GetNode", name)
+	var retNode Node
+	err := c.request(&request{
+		path: fmt.Sprintf("/api/v1/nodes/%s", name),
+	}, &retNode)
+	return retNode, err
+}
+
+// ListEvents is analogous to kubectl get events --field-selector=SELECTOR --namespace=client.namespace
+func (c *Client) ListEvents(selector string) ([]Event, error) {
+	c.log("ListEvents", selector)
+	var el struct {
+		Items []Event `json:"items"`
+	}
+	err := c.request(&request{
+		path:  fmt.Sprintf("/api/v1/namespaces/%s/events", c.namespace),
+		query: map[string]string{"fieldSelector": selector},
+	}, &el)
+	return el.Items, err
+}
+
 // DeletePod deletes the pod at name in the client's specified namespace.
 //
 // Analogous to kubectl delete pod --namespace=client.namespace
@@ -618,6 +650,19 @@ func (c *Client) GetContainerLog(pod, container string) ([]byte, error) {
 	})
 }
 
+// StreamContainerLog follows the log of a container in the specified pod, in the client's
+// specified namespace, until the container stops or the returned reader is closed.
+//
+// Analogous to kubectl logs pod -c container --follow --namespace=client.namespace
+func (c *Client) StreamContainerLog(pod, container string) (io.ReadCloser, error) {
+	c.log("StreamContainerLog", pod)
+	return c.requestRetryStream(&request{
+		path:   fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log", c.namespace, pod),
+		query:  map[string]string{"container": container, "follow": "true"},
+		stream: true,
+	})
+}
+
 // CreateConfigMap creates a configmap, in the client's specified namespace.
 //
 // Analogous to kubectl create configmap --namespace=client.namespace
@@ -193,6 +193,30 @@ func TestListPods(t *testing.T) {
 	}
 }
 
+func TestListEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/api/v1/namespaces/ns/events" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		if selector := r.URL.Query().Get("fieldSelector"); selector != "involvedObject.name=po" {
+			t.Errorf("Bad field selector: %s", selector)
+		}
+		fmt.Fprint(w, `{"items": [{"reason": "Scheduled"}, {"reason": "Pulled"}]}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	es, err := c.ListEvents("involvedObject.name=po")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if len(es) != 2 || es[0].Reason != "Scheduled" {
+		t.Errorf("Expected two events, got %+v", es)
+	}
+}
+
 func TestDeletePod(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete {
@@ -231,6 +255,27 @@ func TestGetPod(t *testing.T) {
 	}
 }
 
+func TestGetNode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/api/v1/nodes/node-1" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"metadata": {"name": "node-1", "labels": {"beta.kubernetes.io/instance-type": "n1-standard-8"}}}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	node, err := c.GetNode("node-1")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if instanceType := node.ObjectMeta.Labels[InstanceTypeLabel]; instanceType != "n1-standard-8" {
+		t.Errorf("Wrong instance type: %s", instanceType)
+	}
+}
+
 func TestGetLogTail(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -288,6 +333,41 @@ func TestGetLogTail(t *testing.T) {
 	}
 }
 
+func TestStreamContainerLog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/ns/pods/testpod/log" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		if follow := r.URL.Query().Get("follow"); follow != "true" {
+			t.Errorf("Expected to follow the log, got follow=%q", follow)
+		}
+		if container := r.URL.Query().Get("container"); container != "test" {
+			t.Errorf("Bad container: %s", container)
+		}
+		fmt.Fprint(w, "started\n")
+		w.(http.Flusher).Flush()
+		// Outlive the request timeout to check that it does not
+		// cut the stream.
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "finished\n")
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	c.client.Timeout = 50 * time.Millisecond
+	stream, err := c.StreamContainerLog("testpod", "test")
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	defer stream.Close()
+	log, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("Didn't expect error reading the stream: %v", err)
+	}
+	if expected := "started\nfinished\n"; string(log) != expected {
+		t.Errorf("Expected log %q, got %q", expected, string(log))
+	}
+}
+
 func TestCreatePod(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
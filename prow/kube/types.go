@@ -79,6 +79,16 @@ type SecretSource = v1.SecretVolumeSource
 // ConfigMapSource is a kubernetes v1 ConfigMapVolumeSource
 type ConfigMapSource = v1.ConfigMapVolumeSource
 
+// Node is a kubernetes v1 node
+type Node = v1.Node
+
+// InstanceTypeLabel is the label the cloud provider sets
+// on a node to the machine type of its instance.
+const InstanceTypeLabel = "beta.kubernetes.io/instance-type"
+
+// Event is a kubernetes v1 event
+type Event = v1.Event
+
 // ConfigMap is a kubernetes v1 ConfigMap
 type ConfigMap = v1.ConfigMap
 
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+var resourceLabels = []string{
+	// name of the job
+	"job_name",
+	// type of the prowjob: presubmit, postsubmit, periodic, batch
+	"type",
+	// org and repo the job ran for, empty for periodics without refs
+	"org",
+	"repo",
+	// instance type of the node the pod ran on
+	"node_type",
+}
+
+var (
+	jobDuration = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prowjob_duration_seconds_total",
+		Help: "Wall-clock time the pods of completed prowjobs ran for.",
+	}, resourceLabels)
+	requestedCPU = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prowjob_requested_cpu_core_seconds_total",
+		Help: "CPU cores requested by the pods of completed prowjobs times the time they ran for.",
+	}, resourceLabels)
+	requestedMemory = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prowjob_requested_memory_byte_seconds_total",
+		Help: "Memory requested by the pods of completed prowjobs times the time they ran for.",
+	}, resourceLabels)
+	usedCPU = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prowjob_used_cpu_seconds_total",
+		Help: "CPU time consumed by the test processes of completed prowjobs.",
+	}, resourceLabels)
+)
+
+func init() {
+	prometheus.MustRegister(jobDuration)
+	prometheus.MustRegister(requestedCPU)
+	prometheus.MustRegister(requestedMemory)
+	prometheus.MustRegister(usedCPU)
+}
+
+// recordedPodsTTL is how long the pods whose usage was recorded are
+// remembered, which is far longer than a cache takes to catch up.
+const recordedPodsTTL = 24 * time.Hour
+
+// recordedPods remembers the pods whose usage was recorded, so that a
+// prowjob that is synced again from a stale cache is not counted twice.
+var recordedPods = struct {
+	sync.Mutex
+	seen map[types.UID]time.Time
+}{seen: map[types.UID]time.Time{}}
+
+// firstRecord returns whether the usage of the pod was not recorded yet
+// and remembers that it is recorded now.
+func firstRecord(uid types.UID, now time.Time) bool {
+	recordedPods.Lock()
+	defer recordedPods.Unlock()
+	for seen, at := range recordedPods.seen {
+		if now.Sub(at) > recordedPodsTTL {
+			delete(recordedPods.seen, seen)
+		}
+	}
+	if _, ok := recordedPods.seen[uid]; ok {
+		return false
+	}
+	recordedPods.seen[uid] = now
+	return true
+}
+
+// RecordResourceUsage accounts for the resources recorded
+// in the status of a completed prowjob, once per pod.
+func RecordResourceUsage(pj prowapi.ProwJob) {
+	usage := pj.Status.Resources
+	if usage == nil {
+		return
+	}
+	if usage.PodUID != "" && !firstRecord(usage.PodUID, time.Now()) {
+		return
+	}
+	var org, repo string
+	if refs := pj.Spec.Refs; refs != nil {
+		org, repo = refs.Org, refs.Repo
+	} else if len(pj.Spec.ExtraRefs) > 0 {
+		org, repo = pj.Spec.ExtraRefs[0].Org, pj.Spec.ExtraRefs[0].Repo
+	}
+	labels := prometheus.Labels{
+		"job_name":  pj.Spec.Job,
+		"type":      string(pj.Spec.Type),
+		"org":       org,
+		"repo":      repo,
+		"node_type": usage.NodeType,
+	}
+
+	duration := float64(usage.DurationSeconds)
+	jobDuration.With(labels).Add(duration)
+	if cpu, ok := usage.Requests[coreapi.ResourceCPU]; ok {
+		requestedCPU.With(labels).Add(float64(cpu.MilliValue()) / 1000 * duration)
+	}
+	if memory, ok := usage.Requests[coreapi.ResourceMemory]; ok {
+		requestedMemory.With(labels).Add(float64(memory.Value()) * duration)
+	}
+	usedCPU.With(labels).Add(float64(usage.CPUMillis) / 1000)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFirstRecord(t *testing.T) {
+	now := time.Date(2019, 3, 15, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		uid      types.UID
+		at       time.Time
+		expected bool
+	}{
+		{
+			name:     "new pod",
+			uid:      "first",
+			at:       now,
+			expected: true,
+		},
+		{
+			name: "same pod synced again",
+			uid:  "first",
+			at:   now.Add(time.Minute),
+		},
+		{
+			name:     "other pod",
+			uid:      "second",
+			at:       now.Add(time.Minute),
+			expected: true,
+		},
+		{
+			name:     "pod forgotten after a day",
+			uid:      "first",
+			at:       now.Add(recordedPodsTTL + time.Minute),
+			expected: true,
+		},
+	}
+	for _, tc := range testCases {
+		if actual := firstRecord(tc.uid, tc.at); actual != tc.expected {
+			t.Errorf("%s: expected first record %t, got %t", tc.name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// tempPrefix starts the names of the files that objects are written to
+// before they are renamed into place.
+const tempPrefix = ".objectstore-"
+
+// fileBucket stores objects as files in a directory. Object names are
+// paths relative to the directory and may not leave it.
+type fileBucket struct {
+	dir string
+}
+
+func (b *fileBucket) path(name string) string {
+	return filepath.Join(b.dir, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+func (b *fileBucket) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(b.path(name))
+}
+
+func (b *fileBucket) NewRangeReader(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	f, err := os.Open(b.path(name))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+func (b *fileBucket) NewWriter(ctx context.Context, name string, metadata map[string]string) (io.WriteCloser, error) {
+	dest := b.path(name)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(dest), tempPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &fileWriter{File: tmp, dest: dest}, nil
+}
+
+// fileWriter renames the file it writes over the object once it is
+// closed so that readers never see partial objects.
+type fileWriter struct {
+	*os.File
+	dest string
+}
+
+func (w *fileWriter) Close() error {
+	if err := w.File.Close(); err != nil {
+		os.Remove(w.File.Name())
+		return err
+	}
+	if err := os.Chmod(w.File.Name(), 0644); err != nil {
+		os.Remove(w.File.Name())
+		return err
+	}
+	return os.Rename(w.File.Name(), w.dest)
+}
+
+func (b *fileBucket) Attributes(ctx context.Context, name string) (*Attributes, error) {
+	info, err := os.Stat(b.path(name))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return &Attributes{Size: info.Size()}, nil
+}
+
+func (b *fileBucket) List(ctx context.Context, prefix, delimiter string) ([]string, []string, error) {
+	// Only the directory that holds the prefix needs to be walked.
+	start := b.path(prefix[:strings.LastIndex(prefix, "/")+1])
+	var names []string
+	err := filepath.Walk(start, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), tempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	objects, prefixes := listNames(names, prefix, delimiter)
+	return objects, prefixes, nil
+}
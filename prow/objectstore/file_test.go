@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileBucket(t *testing.T) {
+	root, err := ioutil.TempDir("", "objectstore")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	opener := &Opener{FileRoot: root}
+	bucket, err := opener.Open(context.Background(), "file://artifacts")
+	if err != nil {
+		t.Fatalf("failed to open bucket: %v", err)
+	}
+	ctx := context.Background()
+	write := func(name, content string) {
+		w, err := bucket.NewWriter(ctx, name, map[string]string{"ignored": "yes"})
+		if err != nil {
+			t.Fatalf("failed to create writer for %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close %s: %v", name, err)
+		}
+	}
+	write("logs/job/1/build-log.txt", "hello world")
+	write("logs/job/1/build-log.txt", "hello there world")
+	write("logs/job/latest-build.txt", "1")
+	write("../../escaped.txt", "contained")
+
+	if _, err := os.Stat(filepath.Join(root, "artifacts", "logs", "job", "1", "build-log.txt")); err != nil {
+		t.Errorf("expected the object in the bucket directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "artifacts", "escaped.txt")); err != nil {
+		t.Errorf("expected names to be confined to the bucket directory: %v", err)
+	}
+
+	attrs, err := bucket.Attributes(ctx, "logs/job/1/build-log.txt")
+	if err != nil {
+		t.Fatalf("failed to get attributes: %v", err)
+	}
+	if attrs.Size != int64(len("hello there world")) {
+		t.Errorf("expected the size of the replaced object, got %d", attrs.Size)
+	}
+
+	r, err := bucket.NewRangeReader(ctx, "logs/job/1/build-log.txt", 6, 5)
+	if err != nil {
+		t.Fatalf("failed to create range reader: %v", err)
+	}
+	content, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(content) != "there" {
+		t.Errorf("expected to read %q, got %q (error %v)", "there", content, err)
+	}
+
+	if _, err := bucket.NewReader(ctx, "logs/job/2/build-log.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error for a missing object, got %v", err)
+	}
+	if _, err := bucket.Attributes(ctx, "logs/job/1"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error for a directory, got %v", err)
+	}
+
+	objects, prefixes, err := bucket.List(ctx, "logs/job/", "/")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if expected := []string{"logs/job/latest-build.txt"}; !reflect.DeepEqual(objects, expected) {
+		t.Errorf("expected objects %v, got %v", expected, objects)
+	}
+	if expected := []string{"logs/job/1/"}; !reflect.DeepEqual(prefixes, expected) {
+		t.Errorf("expected prefixes %v, got %v", expected, prefixes)
+	}
+	objects, _, err = bucket.List(ctx, "missing/", "")
+	if err != nil || len(objects) != 0 {
+		t.Errorf("expected nothing under a missing prefix, got %v (error %v)", objects, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, "artifacts", tempPrefix+"partial"), []byte("partial"), 0644); err != nil {
+		t.Fatalf("failed to write temporary file: %v", err)
+	}
+	objects, _, err = bucket.List(ctx, "", "")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if expected := []string{"escaped.txt", "logs/job/1/build-log.txt", "logs/job/latest-build.txt"}; !reflect.DeepEqual(objects, expected) {
+		t.Errorf("expected objects without temporary files %v, got %v", expected, objects)
+	}
+}
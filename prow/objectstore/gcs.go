@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// NewGCSBucket wraps a GCS bucket handle.
+func NewGCSBucket(handle *storage.BucketHandle) Bucket {
+	return &gcsBucket{handle}
+}
+
+type gcsBucket struct {
+	*storage.BucketHandle
+}
+
+func gcsError(name string, err error) error {
+	if err == storage.ErrObjectNotExist {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return err
+}
+
+func (b *gcsBucket) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := b.Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, gcsError(name, err)
+	}
+	return r, nil
+}
+
+func (b *gcsBucket) NewRangeReader(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	r, err := b.Object(name).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		return nil, gcsError(name, err)
+	}
+	return r, nil
+}
+
+func (b *gcsBucket) NewWriter(ctx context.Context, name string, metadata map[string]string) (io.WriteCloser, error) {
+	w := b.Object(name).NewWriter(ctx)
+	w.Metadata = metadata
+	return w, nil
+}
+
+func (b *gcsBucket) Attributes(ctx context.Context, name string) (*Attributes, error) {
+	attrs, err := b.Object(name).Attrs(ctx)
+	if err != nil {
+		return nil, gcsError(name, err)
+	}
+	return &Attributes{
+		Size:            attrs.Size,
+		ContentEncoding: attrs.ContentEncoding,
+		Metadata:        attrs.Metadata,
+	}, nil
+}
+
+func (b *gcsBucket) List(ctx context.Context, prefix, delimiter string) ([]string, []string, error) {
+	var objects, prefixes []string
+	it := b.Objects(ctx, &storage.Query{
+		Prefix:    prefix,
+		Delimiter: delimiter,
+	})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if attrs.Prefix != "" {
+			prefixes = append(prefixes, attrs.Prefix)
+		} else {
+			objects = append(objects, attrs.Name)
+		}
+	}
+	return objects, prefixes, nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objectstore reads and writes the objects that jobs upload, in
+// GCS, S3-compatible storage like MinIO or a local directory.
+//
+// Buckets are named by URLs whose scheme selects the storage, like
+// s3://bucket or file://bucket. Names without a scheme are GCS buckets.
+// Links, like those Deck serves, hold buckets as a single path segment
+// instead, like s3:bucket, which is what PathSegment returns.
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/api/option"
+)
+
+// The schemes of the storage that buckets are in.
+const (
+	GCS  = "gs"
+	S3   = "s3"
+	File = "file"
+)
+
+// Attributes describe an object.
+type Attributes struct {
+	// Size is the length of the object in bytes.
+	Size int64
+	// ContentEncoding is the encoding of the stored object, like gzip.
+	ContentEncoding string
+	// Metadata are the custom key/value pairs set on the object.
+	Metadata map[string]string
+}
+
+// Bucket reads and writes the objects in a bucket. Errors about objects
+// that do not exist satisfy os.IsNotExist.
+type Bucket interface {
+	// NewReader reads the whole object.
+	NewReader(ctx context.Context, name string) (io.ReadCloser, error)
+	// NewRangeReader reads length bytes starting at offset, or the rest
+	// of the object if length is negative.
+	NewRangeReader(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error)
+	// NewWriter replaces the object with what is written once the writer
+	// is closed. Storage that cannot hold metadata ignores it.
+	NewWriter(ctx context.Context, name string, metadata map[string]string) (io.WriteCloser, error)
+	// Attributes describes the object.
+	Attributes(ctx context.Context, name string) (*Attributes, error)
+	// List returns the names of the objects that start with the prefix.
+	// With a delimiter, names that contain it after the prefix are
+	// collapsed into prefixes that end in the delimiter and that are
+	// returned separately.
+	List(ctx context.Context, prefix, delimiter string) (objects, prefixes []string, err error)
+}
+
+// ParseBucket splits a bucket into the scheme of its storage and its
+// name. It accepts both URLs like s3://bucket and path segments like
+// s3:bucket.
+func ParseBucket(bucket string) (string, string, error) {
+	scheme, name := GCS, bucket
+	if i := strings.Index(bucket, ":"); i >= 0 {
+		scheme, name = bucket[:i], strings.TrimPrefix(bucket[i+1:], "//")
+	}
+	switch scheme {
+	case GCS, S3, File:
+	default:
+		return "", "", fmt.Errorf("bucket %q is in unknown storage %q, expected %s, %s or %s", bucket, scheme, GCS, S3, File)
+	}
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || strings.Contains(name, "/") || name == "." || name == ".." {
+		return "", "", fmt.Errorf("bucket %q has an invalid name", bucket)
+	}
+	return scheme, name, nil
+}
+
+// PathSegment returns the form of the bucket that is used in paths. GCS
+// buckets are written by their name alone so that existing links work.
+func PathSegment(bucket string) (string, error) {
+	scheme, name, err := ParseBucket(bucket)
+	if err != nil {
+		return "", err
+	}
+	if scheme == GCS {
+		return name, nil
+	}
+	return scheme + ":" + name, nil
+}
+
+// URL returns the form of the bucket that is used in configuration and in
+// the links that jobs upload, like gs://bucket.
+func URL(bucket string) (string, error) {
+	scheme, name, err := ParseBucket(bucket)
+	if err != nil {
+		return "", err
+	}
+	return scheme + "://" + name, nil
+}
+
+// Opener opens buckets in all kinds of storage. Clients are created the
+// first time a bucket in their storage is opened.
+type Opener struct {
+	// GCSClient is used for GCS buckets. When it is nil a client is
+	// created with the credentials in GCSCredentialsFile.
+	GCSClient          *storage.Client
+	GCSCredentialsFile string
+	// S3CredentialsFile holds the S3Credentials for S3 buckets. Without
+	// it the default credentials of the AWS SDK are used.
+	S3CredentialsFile string
+	// FileRoot is the directory that holds a directory for every file
+	// bucket.
+	FileRoot string
+
+	lock     sync.Mutex
+	s3Client *s3.S3
+}
+
+// Open returns the bucket. No requests are made until objects are read
+// or written.
+func (o *Opener) Open(ctx context.Context, bucket string) (Bucket, error) {
+	scheme, name, err := ParseBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	switch scheme {
+	case S3:
+		client, err := o.s3()
+		if err != nil {
+			return nil, err
+		}
+		return &s3Bucket{client: client, name: name}, nil
+	case File:
+		if o.FileRoot == "" {
+			return nil, fmt.Errorf("cannot open %s: no root directory for file buckets is configured", bucket)
+		}
+		return &fileBucket{dir: filepath.Join(o.FileRoot, name)}, nil
+	default:
+		client, err := o.gcs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &gcsBucket{client.Bucket(name)}, nil
+	}
+}
+
+func (o *Opener) gcs(ctx context.Context) (*storage.Client, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.GCSClient != nil {
+		return o.GCSClient, nil
+	}
+	var opts []option.ClientOption
+	if o.GCSCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(o.GCSCredentialsFile))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to GCS: %v", err)
+	}
+	o.GCSClient = client
+	return client, nil
+}
+
+func (o *Opener) s3() (*s3.S3, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.s3Client != nil {
+		return o.s3Client, nil
+	}
+	client, err := newS3Client(o.S3CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to S3: %v", err)
+	}
+	o.s3Client = client
+	return client, nil
+}
+
+// listNames implements the prefix and delimiter semantics of List for
+// storage that can only list all names.
+func listNames(names []string, prefix, delimiter string) ([]string, []string) {
+	var objects, prefixes []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				p := name[:len(prefix)+i+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					prefixes = append(prefixes, p)
+				}
+				continue
+			}
+		}
+		objects = append(objects, name)
+	}
+	return objects, prefixes
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBucket(t *testing.T) {
+	testCases := []struct {
+		name            string
+		bucket          string
+		expectedScheme  string
+		expectedName    string
+		expectedSegment string
+		expectedURL     string
+		expectedErr     bool
+	}{
+		{
+			name:            "bare name is a GCS bucket",
+			bucket:          "kubernetes-jenkins",
+			expectedScheme:  GCS,
+			expectedName:    "kubernetes-jenkins",
+			expectedSegment: "kubernetes-jenkins",
+			expectedURL:     "gs://kubernetes-jenkins",
+		},
+		{
+			name:            "GCS URL",
+			bucket:          "gs://kubernetes-jenkins/",
+			expectedScheme:  GCS,
+			expectedName:    "kubernetes-jenkins",
+			expectedSegment: "kubernetes-jenkins",
+			expectedURL:     "gs://kubernetes-jenkins",
+		},
+		{
+			name:            "S3 URL",
+			bucket:          "s3://artifacts",
+			expectedScheme:  S3,
+			expectedName:    "artifacts",
+			expectedSegment: "s3:artifacts",
+			expectedURL:     "s3://artifacts",
+		},
+		{
+			name:            "file path segment",
+			bucket:          "file:artifacts",
+			expectedScheme:  File,
+			expectedName:    "artifacts",
+			expectedSegment: "file:artifacts",
+			expectedURL:     "file://artifacts",
+		},
+		{
+			name:        "unknown scheme",
+			bucket:      "azure://artifacts",
+			expectedErr: true,
+		},
+		{
+			name:        "missing name",
+			bucket:      "s3://",
+			expectedErr: true,
+		},
+		{
+			name:        "name with a path",
+			bucket:      "file:///var/artifacts",
+			expectedErr: true,
+		},
+		{
+			name:        "name leaving the file root",
+			bucket:      "file://..",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme, name, err := ParseBucket(tc.bucket)
+			if err != nil {
+				if !tc.expectedErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if tc.expectedErr {
+				t.Fatal("expected an error, got none")
+			}
+			if scheme != tc.expectedScheme || name != tc.expectedName {
+				t.Errorf("expected %s and %s, got %s and %s", tc.expectedScheme, tc.expectedName, scheme, name)
+			}
+			if segment, err := PathSegment(tc.bucket); err != nil || segment != tc.expectedSegment {
+				t.Errorf("expected path segment %s, got %s (error %v)", tc.expectedSegment, segment, err)
+			}
+			if url, err := URL(tc.bucket); err != nil || url != tc.expectedURL {
+				t.Errorf("expected URL %s, got %s (error %v)", tc.expectedURL, url, err)
+			}
+		})
+	}
+}
+
+func TestListNames(t *testing.T) {
+	names := []string{
+		"logs/job/1/build-log.txt",
+		"logs/job/1/artifacts/junit.xml",
+		"logs/job/2/build-log.txt",
+		"logs/job/latest-build.txt",
+		"logs/other/1/build-log.txt",
+	}
+	testCases := []struct {
+		name             string
+		prefix           string
+		delimiter        string
+		expectedObjects  []string
+		expectedPrefixes []string
+	}{
+		{
+			name:            "all names under the prefix",
+			prefix:          "logs/job/1/",
+			expectedObjects: []string{"logs/job/1/build-log.txt", "logs/job/1/artifacts/junit.xml"},
+		},
+		{
+			name:             "delimiter collapses directories",
+			prefix:           "logs/job/",
+			delimiter:        "/",
+			expectedObjects:  []string{"logs/job/latest-build.txt"},
+			expectedPrefixes: []string{"logs/job/1/", "logs/job/2/"},
+		},
+		{
+			name:             "prefix need not end in the delimiter",
+			prefix:           "logs/o",
+			delimiter:        "/",
+			expectedPrefixes: []string{"logs/other/"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, prefixes := listNames(names, tc.prefix, tc.delimiter)
+			if !reflect.DeepEqual(objects, tc.expectedObjects) {
+				t.Errorf("expected objects %v, got %v", tc.expectedObjects, objects)
+			}
+			if !reflect.DeepEqual(prefixes, tc.expectedPrefixes) {
+				t.Errorf("expected prefixes %v, got %v", tc.expectedPrefixes, prefixes)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3Credentials configure the client for S3 buckets. The endpoint and
+// path style addressing make it work with S3-compatible storage like
+// MinIO.
+type S3Credentials struct {
+	Region           string `json:"region"`
+	Endpoint         string `json:"endpoint"`
+	Insecure         bool   `json:"insecure"`
+	S3ForcePathStyle bool   `json:"s3_force_path_style"`
+	AccessKey        string `json:"access_key"`
+	SecretKey        string `json:"secret_key"`
+}
+
+func newS3Client(credentialsFile string) (*s3.S3, error) {
+	cfg := &aws.Config{}
+	if credentialsFile != "" {
+		raw, err := ioutil.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", credentialsFile, err)
+		}
+		var creds S3Credentials
+		if err := json.Unmarshal(raw, &creds); err != nil {
+			return nil, fmt.Errorf("error unmarshaling %s: %v", credentialsFile, err)
+		}
+		if creds.Region == "" {
+			// S3-compatible storage ignores the region, but the SDK
+			// requires one.
+			creds.Region = "us-east-1"
+		}
+		cfg.Region = aws.String(creds.Region)
+		if creds.Endpoint != "" {
+			cfg.Endpoint = aws.String(creds.Endpoint)
+		}
+		cfg.DisableSSL = aws.Bool(creds.Insecure)
+		cfg.S3ForcePathStyle = aws.Bool(creds.S3ForcePathStyle)
+		if creds.AccessKey != "" {
+			cfg.Credentials = credentials.NewStaticCredentials(creds.AccessKey, creds.SecretKey, "")
+		}
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+type s3Bucket struct {
+	client *s3.S3
+	name   string
+}
+
+func s3Error(name string, err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		// HeadObject has no body to carry the NoSuchKey code in.
+		if aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound" {
+			return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+	}
+	return err
+}
+
+func (b *s3Bucket) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.NewRangeReader(ctx, name, 0, -1)
+}
+
+func (b *s3Bucket) NewRangeReader(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(name),
+	}
+	if length > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+	out, err := b.client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, s3Error(name, err)
+	}
+	return out.Body, nil
+}
+
+// NewWriter buffers the object in a temporary file as uploads need to
+// know the length of the object up front.
+func (b *s3Bucket) NewWriter(ctx context.Context, name string, metadata map[string]string) (io.WriteCloser, error) {
+	tmp, err := ioutil.TempFile("", "s3-upload")
+	if err != nil {
+		return nil, err
+	}
+	return &s3Writer{ctx: ctx, bucket: b, name: name, metadata: metadata, File: tmp}, nil
+}
+
+type s3Writer struct {
+	ctx      context.Context
+	bucket   *s3Bucket
+	name     string
+	metadata map[string]string
+	*os.File
+}
+
+func (w *s3Writer) Close() error {
+	defer os.Remove(w.File.Name())
+	defer w.File.Close()
+	if _, err := w.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(w.bucket.name),
+		Key:    aws.String(w.name),
+		Body:   w.File,
+	}
+	if len(w.metadata) > 0 {
+		input.Metadata = aws.StringMap(w.metadata)
+	}
+	_, err := w.bucket.client.PutObjectWithContext(w.ctx, input)
+	return err
+}
+
+func (b *s3Bucket) Attributes(ctx context.Context, name string) (*Attributes, error) {
+	out, err := b.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, s3Error(name, err)
+	}
+	return &Attributes{
+		Size:            aws.Int64Value(out.ContentLength),
+		ContentEncoding: aws.StringValue(out.ContentEncoding),
+		Metadata:        aws.StringValueMap(out.Metadata),
+	}, nil
+}
+
+func (b *s3Bucket) List(ctx context.Context, prefix, delimiter string) ([]string, []string, error) {
+	var objects, prefixes []string
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.name),
+		Prefix: aws.String(prefix),
+	}
+	if delimiter != "" {
+		input.Delimiter = aws.String(delimiter)
+	}
+	err := b.client.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, o := range page.Contents {
+			objects = append(objects, aws.StringValue(o.Key))
+		}
+		for _, p := range page.CommonPrefixes {
+			prefixes = append(prefixes, aws.StringValue(p.Prefix))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return objects, prefixes, nil
+}
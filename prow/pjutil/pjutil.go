@@ -31,6 +31,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pod-utils/decorate"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 )
@@ -66,6 +67,16 @@ func newProwJob(spec prowapi.ProwJobSpec, extraLabels, extraAnnotations map[stri
 	}
 }
 
+// SetRunAfterSuccess makes the ProwJob wait for the named jobs to
+// succeed for the same refs before it is started.
+func SetRunAfterSuccess(pj *prowapi.ProwJob, jobs []string) {
+	if len(jobs) == 0 {
+		return
+	}
+	pj.Spec.RunAfterSuccess = jobs
+	pj.Status.State = prowapi.WaitingState
+}
+
 func createRefs(pr github.PullRequest, baseSHA string) prowapi.Refs {
 	org := pr.Base.Repo.Owner.Login
 	repo := pr.Base.Repo.Name
@@ -157,6 +168,8 @@ func specFromJobBase(jb config.JobBase) prowapi.ProwJobSpec {
 		Namespace:       namespace,
 		MaxConcurrency:  jb.MaxConcurrency,
 		ErrorOnEviction: jb.ErrorOnEviction,
+		RetryPolicy:     jb.RetryPolicy,
+		ReporterConfig:  jb.ReporterConfig,
 
 		ExtraRefs:        jb.ExtraRefs,
 		DecorationConfig: jb.DecorationConfig,
@@ -252,8 +265,14 @@ func JobURL(plank config.Plank, pj prowapi.ProwJob, log *logrus.Entry) string {
 		gcsConfig := pj.Spec.DecorationConfig.GCSConfiguration
 		_, gcsPath, _ := gcsupload.PathsForJob(gcsConfig, &spec, "")
 
+		// Buckets outside of GCS are a single segment like s3:bucket.
+		bucket, err := objectstore.PathSegment(gcsConfig.Bucket)
+		if err != nil {
+			log.WithFields(ProwJobFields(&pj)).Errorf("error getting job URL: %v", err)
+			bucket = gcsConfig.Bucket
+		}
 		prefix, _ := url.Parse(plank.JobURLPrefix)
-		prefix.Path = path.Join(prefix.Path, gcsConfig.Bucket, gcsPath)
+		prefix.Path = path.Join(prefix.Path, bucket, gcsPath)
 		return prefix.String()
 	}
 	var b bytes.Buffer
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"sort"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+// ScheduledJob is a triggered ProwJob waiting to be admitted by plank.
+type ScheduledJob struct {
+	ProwJob prowapi.ProwJob
+	// Queue is the name of the queue the job is admitted from,
+	// empty for the default queue.
+	Queue string
+	// Position is the position of the job in its queue, starting at 1.
+	Position int
+}
+
+type queueState struct {
+	name     string
+	priority int
+	weight   int
+	running  int
+	jobs     []prowapi.ProwJob
+}
+
+// Schedule orders the triggered ProwJobs run by plank in the order plank
+// admits them. Queues are drained by priority, queues of equal priority
+// share admissions in proportion to their weight relative to the number
+// of their jobs that are already pending, and jobs in the same queue are
+// admitted in the order they were created.
+func Schedule(plank config.Plank, pjs []prowapi.ProwJob) []ScheduledJob {
+	queues := map[string]*queueState{}
+	queueFor := func(pj *prowapi.ProwJob) *queueState {
+		name, priority, weight := "", 1, 1
+		if q := plank.QueueFor(pj); q != nil {
+			name, priority, weight = q.Name, q.Priority, q.Weight
+		}
+		if _, ok := queues[name]; !ok {
+			queues[name] = &queueState{name: name, priority: priority, weight: weight}
+		}
+		return queues[name]
+	}
+
+	for i := range pjs {
+		pj := &pjs[i]
+		if pj.Spec.Agent != prowapi.KubernetesAgent {
+			continue
+		}
+		switch pj.Status.State {
+		case prowapi.PendingState:
+			queueFor(pj).running++
+		case prowapi.TriggeredState:
+			q := queueFor(pj)
+			q.jobs = append(q.jobs, *pj)
+		}
+	}
+
+	var candidates []*queueState
+	for _, q := range queues {
+		if len(q.jobs) == 0 {
+			continue
+		}
+		sort.SliceStable(q.jobs, func(i, j int) bool {
+			return q.jobs[i].Status.StartTime.Before(&q.jobs[j].Status.StartTime)
+		})
+		candidates = append(candidates, q)
+	}
+	// Break ties between otherwise equal queues by name so that
+	// the order is stable across syncs.
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].name < candidates[j].name
+	})
+
+	var scheduled []ScheduledJob
+	positions := map[string]int{}
+	for len(candidates) > 0 {
+		next := 0
+		for i, q := range candidates[1:] {
+			if before(q, candidates[next]) {
+				next = i + 1
+			}
+		}
+		q := candidates[next]
+		positions[q.name]++
+		scheduled = append(scheduled, ScheduledJob{ProwJob: q.jobs[0], Queue: q.name, Position: positions[q.name]})
+		q.jobs = q.jobs[1:]
+		q.running++
+		if len(q.jobs) == 0 {
+			candidates = append(candidates[:next], candidates[next+1:]...)
+		}
+	}
+	return scheduled
+}
+
+// before determines if the next job of queue a is admitted before the
+// next job of queue b.
+func before(a, b *queueState) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	// Compare running/weight of both queues without dividing.
+	return a.running*b.weight < b.running*a.weight
+}
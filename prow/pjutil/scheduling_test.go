@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Now()
+	job := func(name, org string, state prowapi.ProwJobState, age time.Duration, jobLabels map[string]string) prowapi.ProwJob {
+		return prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: jobLabels},
+			Spec: prowapi.ProwJobSpec{
+				Agent: prowapi.KubernetesAgent,
+				Refs:  &prowapi.Refs{Org: org, Repo: "repo"},
+			},
+			Status: prowapi.ProwJobStatus{
+				State:     state,
+				StartTime: metav1.NewTime(now.Add(-age)),
+			},
+		}
+	}
+	priority, err := labels.Parse("priority=high")
+	if err != nil {
+		t.Fatalf("failed to parse label selector: %v", err)
+	}
+
+	var testCases = []struct {
+		name   string
+		queues []config.SchedulingQueue
+		pjs    []prowapi.ProwJob
+
+		expected []string
+	}{
+		{
+			name: "default queue admits oldest jobs first",
+			pjs: []prowapi.ProwJob{
+				job("new", "org", prowapi.TriggeredState, time.Minute, nil),
+				job("old", "org", prowapi.TriggeredState, time.Hour, nil),
+				job("running", "org", prowapi.PendingState, 2*time.Hour, nil),
+				job("done", "org", prowapi.SuccessState, 2*time.Hour, nil),
+			},
+			expected: []string{"old", "new"},
+		},
+		{
+			name: "higher priority queue is drained first",
+			queues: []config.SchedulingQueue{
+				{Name: "urgent", LabelSelector: priority, Priority: 2, Weight: 1},
+			},
+			pjs: []prowapi.ProwJob{
+				job("routine", "org", prowapi.TriggeredState, time.Hour, nil),
+				job("urgent-1", "org", prowapi.TriggeredState, time.Minute, map[string]string{"priority": "high"}),
+				job("urgent-2", "org", prowapi.TriggeredState, time.Second, map[string]string{"priority": "high"}),
+			},
+			expected: []string{"urgent-1", "urgent-2", "routine"},
+		},
+		{
+			name: "queues of equal priority share by weight",
+			queues: []config.SchedulingQueue{
+				{Name: "busy", Repos: []string{"busy"}, Priority: 1, Weight: 1},
+				{Name: "quiet", Repos: []string{"quiet/repo"}, Priority: 1, Weight: 2},
+			},
+			pjs: []prowapi.ProwJob{
+				job("busy-1", "busy", prowapi.TriggeredState, 6*time.Hour, nil),
+				job("busy-2", "busy", prowapi.TriggeredState, 5*time.Hour, nil),
+				job("busy-3", "busy", prowapi.TriggeredState, 4*time.Hour, nil),
+				job("quiet-1", "quiet", prowapi.TriggeredState, time.Minute, nil),
+				job("quiet-2", "quiet", prowapi.TriggeredState, time.Second, nil),
+			},
+			expected: []string{"busy-1", "quiet-1", "quiet-2", "busy-2", "busy-3"},
+		},
+		{
+			name: "pending jobs count against the share of their queue",
+			queues: []config.SchedulingQueue{
+				{Name: "busy", Repos: []string{"busy"}, Priority: 1, Weight: 1},
+				{Name: "quiet", Repos: []string{"quiet"}, Priority: 1, Weight: 1},
+			},
+			pjs: []prowapi.ProwJob{
+				job("busy-running-1", "busy", prowapi.PendingState, 6*time.Hour, nil),
+				job("busy-running-2", "busy", prowapi.PendingState, 6*time.Hour, nil),
+				job("busy-1", "busy", prowapi.TriggeredState, 5*time.Hour, nil),
+				job("quiet-1", "quiet", prowapi.TriggeredState, time.Minute, nil),
+				job("quiet-2", "quiet", prowapi.TriggeredState, time.Second, nil),
+			},
+			expected: []string{"quiet-1", "quiet-2", "busy-1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, job := range Schedule(config.Plank{Queues: tc.queues}, tc.pjs) {
+				actual = append(actual, job.ProwJob.ObjectMeta.Name)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected jobs to be scheduled as %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
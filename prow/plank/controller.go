@@ -17,7 +17,11 @@ limitations under the License.
 package plank
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -25,15 +29,25 @@ import (
 	"github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
 	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	pjinformers "k8s.io/test-infra/prow/client/informers/externalversions/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/github"
 	reportlib "k8s.io/test-infra/prow/github/report"
 	"k8s.io/test-infra/prow/github/reporter"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/metrics"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pjutil"
 	"k8s.io/test-infra/prow/pod-utils/decorate"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/pod-utils/wrapper"
 )
 
 const (
@@ -49,6 +63,9 @@ type kubeClient interface {
 	CreatePod(v1.Pod) (coreapi.Pod, error)
 	ListPods(string) ([]coreapi.Pod, error)
 	DeletePod(string) error
+
+	GetNode(string) (coreapi.Node, error)
+	ListEvents(string) ([]coreapi.Event, error)
 }
 
 // GitHubClient contains the methods used by plank on k8s.io/test-infra/prow/github.Client
@@ -82,6 +99,9 @@ type Controller struct {
 	// pendingJobs is a short-lived cache that helps in limiting
 	// the maximum concurrency of jobs.
 	pendingJobs map[string]int
+	// admissible is a short-lived cache of the triggered jobs that
+	// may start during this sync when scheduling queues are used.
+	admissible sets.String
 
 	pjLock sync.RWMutex
 	// shared across the controller and a goroutine that gathers metrics.
@@ -89,6 +109,16 @@ type Controller struct {
 
 	// if skip report job results to github
 	skipReport bool
+
+	// podInfoOpener is set when the pods of completed jobs
+	// are recorded, see UploadPodInfo.
+	podInfoOpener *objectstore.Opener
+
+	// informers and queue are set when the controller
+	// is driven by events, see NewInformerController.
+	prowJobInformer pjinformers.ProwJobInformer
+	podInformers    map[string]cache.SharedIndexInformer
+	queue           workqueue.RateLimitingInterface
 }
 
 // NewController creates a new Controller from the provided clients.
@@ -113,12 +143,25 @@ func NewController(kc *kube.Client, pkcs map[string]*kube.Client, ghc GitHubClie
 	}, nil
 }
 
+// UploadPodInfo makes the controller record the pod of every completed
+// decorated job and the events about it as podinfo.json, next to the
+// other artifacts of the job. The opener must be able to write to the
+// storage the jobs upload their artifacts to.
+func (c *Controller) UploadPodInfo(opener *objectstore.Opener) {
+	c.podInfoOpener = opener
+}
+
 // canExecuteConcurrently checks whether the provided ProwJob can
 // be executed concurrently.
 func (c *Controller) canExecuteConcurrently(pj *prowapi.ProwJob) bool {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	if c.admissible != nil && !c.admissible.Has(pj.ObjectMeta.Name) {
+		c.log.WithFields(pjutil.ProwJobFields(pj)).Debug("Not starting job, jobs queued before it use the available concurrency.")
+		return false
+	}
+
 	if max := c.config().Plank.MaxConcurrency; max > 0 {
 		var running int
 		for _, num := range c.pendingJobs {
@@ -144,6 +187,58 @@ func (c *Controller) canExecuteConcurrently(pj *prowapi.ProwJob) bool {
 	return true
 }
 
+// scheduleTriggeredJobs orders the triggered ProwJobs by their scheduling
+// queues and determines which of them fit into the concurrency budgets of
+// the controller, their jobs and their queues when admitted in that order.
+// It must be called after pending jobs have been synced.
+func (c *Controller) scheduleTriggeredJobs(pjs []prowapi.ProwJob) chan prowapi.ProwJob {
+	plank := c.config().Plank
+	scheduled := pjutil.Schedule(plank, pjs)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var running int
+	perJob := map[string]int{}
+	for job, num := range c.pendingJobs {
+		perJob[job] = num
+		running += num
+	}
+	perQueue := map[string]int{}
+	for i := range pjs {
+		if pjs[i].Status.State != prowapi.PendingState {
+			continue
+		}
+		if q := plank.QueueFor(&pjs[i]); q != nil {
+			perQueue[q.Name]++
+		}
+	}
+
+	c.admissible = sets.NewString()
+	triggered := make(chan prowapi.ProwJob, len(scheduled))
+	for _, job := range scheduled {
+		pj := job.ProwJob
+		triggered <- pj
+		if plank.MaxConcurrency > 0 && running >= plank.MaxConcurrency {
+			continue
+		}
+		if pj.Spec.MaxConcurrency > 0 && perJob[pj.Spec.Job] >= pj.Spec.MaxConcurrency {
+			continue
+		}
+		q := plank.QueueFor(&pj)
+		if q != nil && q.MaxConcurrency > 0 && perQueue[q.Name] >= q.MaxConcurrency {
+			continue
+		}
+		c.admissible.Insert(pj.ObjectMeta.Name)
+		running++
+		perJob[pj.Spec.Job]++
+		if q != nil {
+			perQueue[q.Name]++
+		}
+	}
+	close(triggered)
+	return triggered
+}
+
 // incrementNumPendingJobs increments the amount of
 // pending ProwJobs for the given job identifier
 func (c *Controller) incrementNumPendingJobs(job string) {
@@ -176,10 +271,7 @@ func (c *Controller) Sync() error {
 	if err != nil {
 		return fmt.Errorf("error listing prow jobs: %v", err)
 	}
-	selector := fmt.Sprintf("%s=true", kube.CreatedByProw)
-	if len(c.selector) > 0 {
-		selector = strings.Join([]string{c.selector, selector}, ",")
-	}
+	selector := podSelector(c.selector)
 
 	pm := map[string]kube.Pod{}
 	for alias, client := range c.pkcs {
@@ -201,10 +293,18 @@ func (c *Controller) Sync() error {
 	}
 	pjs = k8sJobs
 
+	reportCh := make(chan prowapi.ProwJob, len(pjs))
+
 	var syncErrs []error
 	if err := c.terminateDupes(pjs, pm); err != nil {
 		syncErrs = append(syncErrs, err)
 	}
+	if err := c.abortCancelledJobs(pjs, pm, reportCh); err != nil {
+		syncErrs = append(syncErrs, err)
+	}
+	if err := c.syncWaitingJobs(pjs, reportCh); err != nil {
+		syncErrs = append(syncErrs, err)
+	}
 
 	// Share what we have for gathering metrics.
 	c.pjLock.Lock()
@@ -213,7 +313,6 @@ func (c *Controller) Sync() error {
 
 	pendingCh, triggeredCh := pjutil.PartitionActive(pjs)
 	errCh := make(chan error, len(pjs))
-	reportCh := make(chan prowapi.ProwJob, len(pjs))
 
 	// Reinstantiate on every resync of the controller instead of trying
 	// to keep this in sync with the state of the world.
@@ -223,6 +322,12 @@ func (c *Controller) Sync() error {
 	maxSyncRoutines := c.config().Plank.MaxGoroutines
 	c.log.Debugf("Handling %d pending prowjobs", len(pendingCh))
 	syncProwJobs(c.log, c.syncPendingJob, maxSyncRoutines, pendingCh, reportCh, errCh, pm)
+	if len(c.config().Plank.Queues) > 0 {
+		// Hand out the remaining concurrency in scheduling order.
+		triggeredCh = c.scheduleTriggeredJobs(pjs)
+	} else {
+		c.admissible = nil
+	}
 	c.log.Debugf("Handling %d triggered prowjobs", len(triggeredCh))
 	syncProwJobs(c.log, c.syncTriggeredJob, maxSyncRoutines, triggeredCh, reportCh, errCh, pm)
 
@@ -256,6 +361,16 @@ func (c *Controller) Sync() error {
 	return fmt.Errorf("errors syncing: %v, errors reporting: %v", syncErrs, reportErrs)
 }
 
+// podSelector selects the pods created by prow for the
+// ProwJobs that match the given selector.
+func podSelector(selector string) string {
+	podSelector := fmt.Sprintf("%s=true", kube.CreatedByProw)
+	if len(selector) > 0 {
+		podSelector = strings.Join([]string{selector, podSelector}, ",")
+	}
+	return podSelector
+}
+
 // SyncMetrics records metrics for the cached prowjobs.
 func (c *Controller) SyncMetrics() {
 	c.pjLock.RLock()
@@ -311,6 +426,102 @@ func (c *Controller) terminateDupes(pjs []prowapi.ProwJob, pm map[string]coreapi
 	return nil
 }
 
+// abortCancelledJobs aborts incomplete ProwJobs whose cancellation has been
+// requested, deleting their pods so they shut down gracefully. It modifies
+// pjs in-place when it aborts.
+func (c *Controller) abortCancelledJobs(pjs []prowapi.ProwJob, pm map[string]coreapi.Pod, reports chan<- prowapi.ProwJob) error {
+	for i, pj := range pjs {
+		if pj.Complete() || !pj.Spec.Cancel {
+			continue
+		}
+		if pod, exists := pm[pj.ObjectMeta.Name]; exists {
+			client, ok := c.pkcs[pj.ClusterAlias()]
+			if !ok {
+				return fmt.Errorf("unknown cluster alias %q", pj.ClusterAlias())
+			}
+			if err := client.DeletePod(pod.ObjectMeta.Name); err != nil {
+				return fmt.Errorf("error deleting pod %q of cancelled job: %v", pod.ObjectMeta.Name, err)
+			}
+		}
+		pj.SetComplete()
+		prevState := pj.Status.State
+		pj.Status.State = prowapi.AbortedState
+		pj.Status.Description = "Job was aborted."
+		c.log.WithFields(pjutil.ProwJobFields(&pj)).
+			WithField("from", prevState).
+			WithField("to", pj.Status.State).Info("Transitioning states.")
+		npj, err := c.kc.ReplaceProwJob(pj.ObjectMeta.Name, pj)
+		if err != nil {
+			return err
+		}
+		reports <- npj
+		pjs[i] = npj
+	}
+	return nil
+}
+
+// syncWaitingJobs triggers waiting ProwJobs once the latest runs of all
+// jobs they run after have succeeded for the same refs, and aborts them
+// once any of those runs ends in another state. It modifies pjs in-place.
+func (c *Controller) syncWaitingJobs(pjs []prowapi.ProwJob, reports chan<- prowapi.ProwJob) error {
+	// "job org/repo refs" -> latest job
+	latest := make(map[string]prowapi.ProwJob)
+	for _, pj := range pjs {
+		if pj.Spec.Refs == nil {
+			continue
+		}
+		key := runKey(pj.Spec.Job, pj.Spec.Refs)
+		if prev, ok := latest[key]; !ok || (&prev.Status.StartTime).Before(&pj.Status.StartTime) {
+			latest[key] = pj
+		}
+	}
+
+	for i, pj := range pjs {
+		if pj.Status.State != prowapi.WaitingState {
+			continue
+		}
+		prevState := pj.Status.State
+		pj.Status.State = prowapi.TriggeredState
+		for _, parent := range pj.Spec.RunAfterSuccess {
+			if pj.Spec.Refs == nil {
+				break
+			}
+			parentJob, exists := latest[runKey(parent, pj.Spec.Refs)]
+			if !exists || !parentJob.Complete() {
+				pj.Status.State = prowapi.WaitingState
+				continue
+			}
+			if parentJob.Status.State != prowapi.SuccessState {
+				pj.SetComplete()
+				pj.Status.State = prowapi.AbortedState
+				pj.Status.Description = fmt.Sprintf("Job %s did not succeed.", parent)
+				break
+			}
+		}
+		if pj.Status.State == prevState {
+			continue
+		}
+
+		c.log.WithFields(pjutil.ProwJobFields(&pj)).
+			WithField("from", prevState).
+			WithField("to", pj.Status.State).Info("Transitioning states.")
+		npj, err := c.kc.ReplaceProwJob(pj.ObjectMeta.Name, pj)
+		if err != nil {
+			return err
+		}
+		if npj.Complete() {
+			reports <- npj
+		}
+		pjs[i] = npj
+	}
+	return nil
+}
+
+// runKey identifies the runs of a job for the same refs.
+func runKey(job string, refs *prowapi.Refs) string {
+	return fmt.Sprintf("%s %s/%s %s", job, refs.Org, refs.Repo, refs.String())
+}
+
 // TODO: Dry this out
 func syncProwJobs(
 	l *logrus.Entry,
@@ -365,6 +576,12 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]coreapi.Po
 			pj.Status.PodName = pn
 			c.log.WithFields(pjutil.ProwJobFields(&pj)).Info("Pod is missing, starting a new pod")
 		}
+	} else if attemptRecorded(&pj, &pod) {
+		// The pod of an attempt that was already retried can linger in
+		// the cache while it terminates. Wait for it to be gone instead
+		// of counting the attempt again.
+		c.incrementNumPendingJobs(pj.Spec.Job)
+		return nil
 	} else {
 		switch pod.Status.Phase {
 		case coreapi.PodUnknown:
@@ -392,6 +609,7 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]coreapi.Po
 					pj.SetComplete()
 					pj.Status.State = prowapi.ErrorState
 					pj.Status.Description = "Job pod was evicted by the cluster."
+					pj.Status.FailureReason = prowapi.EvictedReason
 					break
 				}
 				// ErrorOnEviction is disabled. Delete the pod now and recreate it in
@@ -407,6 +625,7 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]coreapi.Po
 			pj.SetComplete()
 			pj.Status.State = prowapi.FailureState
 			pj.Status.Description = "Job failed."
+			pj.Status.FailureReason = jobFailureReason(&pod)
 
 		case coreapi.PodPending:
 			maxPodPending := c.config().Plank.PodPendingTimeout
@@ -421,12 +640,24 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]coreapi.Po
 			pj.SetComplete()
 			pj.Status.State = prowapi.ErrorState
 			pj.Status.Description = "Pod pending timeout."
+			pj.Status.FailureReason = prowapi.PodPendingTimeoutReason
+			if imagePullFailed(&pod) {
+				pj.Status.FailureReason = prowapi.ImagePullReason
+			}
 
 		default:
 			// Pod is running. Do nothing.
 			c.incrementNumPendingJobs(pj.Spec.Job)
 			return nil
 		}
+
+		// Pod is done. Discard the attempt instead of completing
+		// the job if the retry policy of the job allows for that.
+		if retried, err := c.retryJob(&pj, &pod); retried || err != nil {
+			return err
+		}
+		pj.Status.Resources = c.resourceUsage(pj, &pod)
+		c.recordPodInfo(pj, &pod)
 	}
 
 	pj.Status.URL = pjutil.JobURL(c.config().Plank, pj, c.log)
@@ -438,8 +669,11 @@ func (c *Controller) syncPendingJob(pj prowapi.ProwJob, pm map[string]coreapi.Po
 			WithField("from", prevState).
 			WithField("to", pj.Status.State).Info("Transitioning states.")
 	}
-	_, err := c.kc.ReplaceProwJob(pj.ObjectMeta.Name, pj)
-	return err
+	if _, err := c.kc.ReplaceProwJob(pj.ObjectMeta.Name, pj); err != nil {
+		return err
+	}
+	metrics.RecordResourceUsage(pj)
+	return nil
 }
 
 func (c *Controller) syncTriggeredJob(pj prowapi.ProwJob, pm map[string]coreapi.Pod, reports chan<- prowapi.ProwJob) error {
@@ -493,6 +727,229 @@ func (c *Controller) syncTriggeredJob(pj prowapi.ProwJob, pm map[string]coreapi.
 	return err
 }
 
+// retryJob records the attempt of a completed ProwJob and deletes its
+// pod if the retry policy of the job allows another attempt, so that a
+// new pod with a fresh build ID is started in the next resync. It
+// returns whether the job is retried.
+func (c *Controller) retryJob(pj *prowapi.ProwJob, pod *coreapi.Pod) (bool, error) {
+	if pj.Status.State != prowapi.FailureState && pj.Status.State != prowapi.ErrorState {
+		return false, nil
+	}
+	reason := podFailureReason(pod)
+	attempts := len(pj.Status.Attempts) + 1
+	if !pj.Spec.RetryPolicy.ShouldRetry(attempts, pj.Status.State, reason) {
+		return false, nil
+	}
+
+	client, ok := c.pkcs[pj.ClusterAlias()]
+	if !ok {
+		return false, fmt.Errorf("unknown cluster alias %q", pj.ClusterAlias())
+	}
+	c.incrementNumPendingJobs(pj.Spec.Job)
+	if err := client.DeletePod(pod.ObjectMeta.Name); err != nil {
+		return false, err
+	}
+
+	pj.Status.Attempts = append(pj.Status.Attempts, prowapi.ProwJobAttempt{
+		BuildID:        pj.Status.BuildID,
+		PodName:        pod.ObjectMeta.Name,
+		PodUID:         pod.ObjectMeta.UID,
+		CompletionTime: *pj.Status.CompletionTime,
+		State:          pj.Status.State,
+		Reason:         reason,
+		Description:    pj.Status.Description,
+	})
+	c.log.WithFields(pjutil.ProwJobFields(pj)).
+		WithField("state", pj.Status.State).
+		WithField("reason", reason).
+		Infof("Retrying job, attempt %d of %d failed.", attempts, pj.Spec.RetryPolicy.MaxAttempts)
+	pj.Status.CompletionTime = nil
+	pj.Status.State = prowapi.PendingState
+	pj.Status.FailureReason = ""
+	pj.Status.Description = fmt.Sprintf("Retrying job (attempt %d of %d).", attempts+1, pj.Spec.RetryPolicy.MaxAttempts)
+	_, err := c.kc.ReplaceProwJob(pj.ObjectMeta.Name, *pj)
+	return true, err
+}
+
+// attemptRecorded returns whether the pod ran an attempt of the job
+// that was already retried.
+func attemptRecorded(pj *prowapi.ProwJob, pod *coreapi.Pod) bool {
+	if pod.ObjectMeta.UID == "" {
+		return false
+	}
+	for _, attempt := range pj.Status.Attempts {
+		if attempt.PodUID == pod.ObjectMeta.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// podFailureReason returns why the pod failed: the reason set on the
+// pod itself (e.g. Evicted) or else the first reason a container was
+// terminated unsuccessfully (e.g. OOMKilled) or is waiting for (e.g.
+// ErrImagePull).
+func podFailureReason(pod *coreapi.Pod) string {
+	if pod.Status.Reason != "" {
+		return pod.Status.Reason
+	}
+	var statuses []coreapi.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 && terminated.Reason != "" {
+			return terminated.Reason
+		}
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" {
+			return waiting.Reason
+		}
+	}
+	return ""
+}
+
+// jobFailureReason classifies why the pod of a job failed. The pod
+// utilities record the reason as the termination message of their
+// containers; pods that do not run them are assumed to have failed
+// their tests.
+func jobFailureReason(pod *coreapi.Pod) prowapi.FailureReason {
+	var statuses []coreapi.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			continue
+		}
+		switch reason := prowapi.FailureReason(strings.TrimSpace(terminated.Message)); reason {
+		case prowapi.TestFailureReason, prowapi.TimeoutReason, prowapi.InfraErrorReason,
+			prowapi.CloneFailureReason, prowapi.UploadFailureReason:
+			return reason
+		}
+	}
+	if imagePullFailed(pod) {
+		return prowapi.ImagePullReason
+	}
+	return prowapi.TestFailureReason
+}
+
+// imagePullFailed determines if a container of the pod is waiting
+// for an image that cannot be pulled.
+func imagePullFailed(pod *coreapi.Pod) bool {
+	var statuses []coreapi.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resourceUsage records what the pod of a completed job requested and
+// used. The entrypoint records the usage of the test process as the
+// termination message of the test container.
+func (c *Controller) resourceUsage(pj prowapi.ProwJob, pod *coreapi.Pod) *prowapi.ResourceUsage {
+	usage := &prowapi.ResourceUsage{Requests: podRequests(&pod.Spec), PodUID: pod.ObjectMeta.UID}
+	finished := time.Now()
+	var lastFinished time.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			continue
+		}
+		if terminated.FinishedAt.Time.After(lastFinished) {
+			lastFinished = terminated.FinishedAt.Time
+		}
+		if used, ok := wrapper.ParseUsage(terminated.Message); ok {
+			usage.CPUMillis += used.CPUMillis
+			usage.MaxMemoryBytes += used.MaxMemoryBytes
+		}
+	}
+	if !lastFinished.IsZero() {
+		finished = lastFinished
+	}
+	if pod.Status.StartTime != nil {
+		usage.DurationSeconds = int64(finished.Sub(pod.Status.StartTime.Time) / time.Second)
+	}
+
+	if pod.Spec.NodeName != "" {
+		if client, ok := c.pkcs[pj.ClusterAlias()]; ok {
+			node, err := client.GetNode(pod.Spec.NodeName)
+			if err != nil {
+				c.log.WithFields(pjutil.ProwJobFields(&pj)).WithError(err).Warn("Could not get the node the job ran on.")
+			} else {
+				usage.NodeType = node.ObjectMeta.Labels[kube.InstanceTypeLabel]
+			}
+		}
+	}
+	return usage
+}
+
+// recordPodInfo uploads the status of the pod of a completed job with the
+// events about it, so that the reasons it was slow to schedule or failed
+// can be seen without access to the build cluster.
+func (c *Controller) recordPodInfo(pj prowapi.ProwJob, pod *coreapi.Pod) {
+	if c.podInfoOpener == nil || pj.Spec.DecorationConfig == nil || pj.Spec.DecorationConfig.GCSConfiguration == nil {
+		return
+	}
+	log := c.log.WithFields(pjutil.ProwJobFields(&pj))
+	info := gcs.PodInfo{Pod: gcs.NewPodSummary(pod), Resources: pj.Status.Resources}
+	if client, ok := c.pkcs[pj.ClusterAlias()]; ok {
+		events, err := client.ListEvents(fmt.Sprintf("involvedObject.kind=Pod,involvedObject.name=%s", pod.Name))
+		if err != nil {
+			log.WithError(err).Warn("Could not list the events of the pod.")
+		}
+		info.Events = events
+	}
+	raw, err := json.Marshal(info)
+	if err != nil {
+		log.WithError(err).Warn("Could not marshal the pod info.")
+		return
+	}
+
+	gcsConfig := pj.Spec.DecorationConfig.GCSConfiguration
+	spec := downwardapi.NewJobSpec(pj.Spec, pj.Status.BuildID, pj.Name)
+	_, dir, _ := gcsupload.PathsForJob(gcsConfig, &spec, "")
+	bucket, err := c.podInfoOpener.Open(context.Background(), gcsConfig.Bucket)
+	if err != nil {
+		log.WithError(err).Warnf("Could not open bucket %s.", gcsConfig.Bucket)
+		return
+	}
+	target := path.Join(dir, gcs.PodInfoFile)
+	if err := gcs.Upload(bucket, map[string]gcs.UploadFunc{target: gcs.DataUpload(bytes.NewReader(raw))}); err != nil {
+		log.WithError(err).Warnf("Could not upload %s.", target)
+	}
+}
+
+// podRequests computes the effective resource requests of a pod: the
+// larger of the sum of the requests of its containers and the largest
+// request of any of its init containers, which run one at a time.
+func podRequests(spec *coreapi.PodSpec) coreapi.ResourceList {
+	requests := coreapi.ResourceList{}
+	for _, container := range spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			sum := requests[name]
+			sum.Add(quantity)
+			requests[name] = sum
+		}
+	}
+	for _, container := range spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, ok := requests[name]; !ok || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	return requests
+}
+
 // TODO: No need to return the pod name since we already have the
 // prowjob in the call site.
 func (c *Controller) startPod(pj prowapi.ProwJob) (string, string, error) {
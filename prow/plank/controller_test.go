@@ -17,11 +17,16 @@ limitations under the License.
 package plank
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"text/template"
@@ -29,14 +34,19 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/reporter"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pjutil"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
 )
 
 type fca struct {
@@ -104,6 +114,8 @@ type fkc struct {
 	prowjobs    []prowapi.ProwJob
 	pods        []kube.Pod
 	deletedPods []kube.Pod
+	nodes       []kube.Node
+	events      []kube.Event
 	err         error
 }
 
@@ -173,6 +185,23 @@ func (f *fkc) DeletePod(name string) error {
 	return fmt.Errorf("did not find pod %s", name)
 }
 
+func (f *fkc) GetNode(name string) (kube.Node, error) {
+	f.Lock()
+	defer f.Unlock()
+	for _, node := range f.nodes {
+		if node.ObjectMeta.Name == name {
+			return node, nil
+		}
+	}
+	return kube.Node{}, fmt.Errorf("did not find node %s", name)
+}
+
+func (f *fkc) ListEvents(selector string) ([]kube.Event, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.events, nil
+}
+
 type fghc struct {
 	sync.Mutex
 	changes []github.PullRequestChange
@@ -412,6 +441,298 @@ func TestTerminateDupes(t *testing.T) {
 	}
 }
 
+func TestSyncWaitingJobs(t *testing.T) {
+	now := time.Now()
+	completed := metav1.NewTime(now)
+	refs := &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "master", Pulls: []prowapi.Pull{{Number: 1, SHA: "abc"}}}
+	otherRefs := &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "master", Pulls: []prowapi.Pull{{Number: 2, SHA: "def"}}}
+	parent := func(name, job string, refs *prowapi.Refs, state prowapi.ProwJobState, age time.Duration) prowapi.ProwJob {
+		pj := prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       prowapi.ProwJobSpec{Job: job, Refs: refs},
+			Status: prowapi.ProwJobStatus{
+				StartTime: metav1.NewTime(now.Add(-age)),
+				State:     state,
+			},
+		}
+		if state != prowapi.PendingState {
+			pj.Status.CompletionTime = &completed
+		}
+		return pj
+	}
+	waiting := prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "child"},
+		Spec: prowapi.ProwJobSpec{
+			Job:             "e2e",
+			Refs:            refs,
+			RunAfterSuccess: []string{"unit", "verify"},
+		},
+		Status: prowapi.ProwJobStatus{
+			StartTime: metav1.NewTime(now),
+			State:     prowapi.WaitingState,
+		},
+	}
+
+	var testcases = []struct {
+		name    string
+		parents []prowapi.ProwJob
+
+		expectedState  prowapi.ProwJobState
+		expectedReport bool
+	}{
+		{
+			name: "keep waiting while a parent is running",
+			parents: []prowapi.ProwJob{
+				parent("unit", "unit", refs, prowapi.SuccessState, time.Minute),
+				parent("verify", "verify", refs, prowapi.PendingState, time.Minute),
+			},
+			expectedState: prowapi.WaitingState,
+		},
+		{
+			name: "keep waiting while a parent only ran for other refs",
+			parents: []prowapi.ProwJob{
+				parent("unit", "unit", refs, prowapi.SuccessState, time.Minute),
+				parent("verify", "verify", otherRefs, prowapi.SuccessState, time.Minute),
+			},
+			expectedState: prowapi.WaitingState,
+		},
+		{
+			name: "trigger once all parents succeeded",
+			parents: []prowapi.ProwJob{
+				parent("unit", "unit", refs, prowapi.SuccessState, time.Minute),
+				parent("verify", "verify", refs, prowapi.SuccessState, time.Minute),
+			},
+			expectedState: prowapi.TriggeredState,
+		},
+		{
+			name: "trigger once the latest run of a parent succeeded",
+			parents: []prowapi.ProwJob{
+				parent("unit", "unit", refs, prowapi.SuccessState, time.Minute),
+				parent("verify-old", "verify", refs, prowapi.FailureState, time.Hour),
+				parent("verify", "verify", refs, prowapi.SuccessState, time.Minute),
+			},
+			expectedState: prowapi.TriggeredState,
+		},
+		{
+			name: "abort once a parent failed",
+			parents: []prowapi.ProwJob{
+				parent("unit", "unit", refs, prowapi.FailureState, time.Minute),
+				parent("verify", "verify", refs, prowapi.PendingState, time.Minute),
+			},
+			expectedState:  prowapi.AbortedState,
+			expectedReport: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fkc := &fkc{prowjobs: append([]prowapi.ProwJob{waiting}, tc.parents...)}
+			c := &Controller{
+				kc:  fkc,
+				log: logrus.NewEntry(logrus.StandardLogger()),
+			}
+			pjs := append([]prowapi.ProwJob{}, fkc.prowjobs...)
+			reports := make(chan prowapi.ProwJob, len(pjs))
+			if err := c.syncWaitingJobs(pjs, reports); err != nil {
+				t.Fatalf("Error syncing waiting jobs: %v", err)
+			}
+			close(reports)
+
+			if actual := fkc.prowjobs[0].Status.State; actual != tc.expectedState {
+				t.Errorf("expected state %q, got %q", tc.expectedState, actual)
+			}
+			if actual := pjs[0].Status.State; actual != tc.expectedState {
+				t.Errorf("expected state %q to be updated in-place, got %q", tc.expectedState, actual)
+			}
+			if tc.expectedReport != (len(reports) == 1) {
+				t.Errorf("expected report: %v, got %d reports", tc.expectedReport, len(reports))
+			}
+		})
+	}
+}
+
+func TestAbortCancelledJobs(t *testing.T) {
+	completed := metav1.Now()
+	var testcases = []struct {
+		name string
+		pj   prowapi.ProwJob
+		pods []kube.Pod
+
+		expectedState       prowapi.ProwJobState
+		expectedDeletedPods int
+		expectedReport      bool
+	}{
+		{
+			name: "running job without cancel request is left alone",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Status:     prowapi.ProwJobStatus{State: prowapi.PendingState},
+			},
+			pods:          []kube.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}},
+			expectedState: prowapi.PendingState,
+		},
+		{
+			name: "cancelled pending job is aborted and its pod deleted",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec:       prowapi.ProwJobSpec{Cancel: true},
+				Status:     prowapi.ProwJobStatus{State: prowapi.PendingState},
+			},
+			pods:                []kube.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}},
+			expectedState:       prowapi.AbortedState,
+			expectedDeletedPods: 1,
+			expectedReport:      true,
+		},
+		{
+			name: "cancelled triggered job is aborted before it gets a pod",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec:       prowapi.ProwJobSpec{Cancel: true},
+				Status:     prowapi.ProwJobStatus{State: prowapi.TriggeredState},
+			},
+			expectedState:  prowapi.AbortedState,
+			expectedReport: true,
+		},
+		{
+			name: "cancelled complete job is left alone",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec:       prowapi.ProwJobSpec{Cancel: true},
+				Status: prowapi.ProwJobStatus{
+					State:          prowapi.SuccessState,
+					CompletionTime: &completed,
+				},
+			},
+			pods:          []kube.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}},
+			expectedState: prowapi.SuccessState,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pjc := &fkc{prowjobs: []prowapi.ProwJob{tc.pj}}
+			fpc := &fkc{pods: tc.pods}
+			pm := map[string]kube.Pod{}
+			for _, pod := range tc.pods {
+				pm[pod.ObjectMeta.Name] = pod
+			}
+			c := &Controller{
+				kc:   pjc,
+				pkcs: map[string]kubeClient{kube.DefaultClusterAlias: fpc},
+				log:  logrus.NewEntry(logrus.StandardLogger()),
+			}
+			pjs := append([]prowapi.ProwJob{}, pjc.prowjobs...)
+			reports := make(chan prowapi.ProwJob, len(pjs))
+			if err := c.abortCancelledJobs(pjs, pm, reports); err != nil {
+				t.Fatalf("Error aborting cancelled jobs: %v", err)
+			}
+			close(reports)
+
+			if actual := pjc.prowjobs[0].Status.State; actual != tc.expectedState {
+				t.Errorf("expected state %q, got %q", tc.expectedState, actual)
+			}
+			if actual := pjs[0].Status.State; actual != tc.expectedState {
+				t.Errorf("expected state %q to be updated in-place, got %q", tc.expectedState, actual)
+			}
+			if actual := len(fpc.deletedPods); actual != tc.expectedDeletedPods {
+				t.Errorf("expected %d deleted pods, got %d", tc.expectedDeletedPods, actual)
+			}
+			if tc.expectedReport != (len(reports) == 1) {
+				t.Errorf("expected report: %v, got %d reports", tc.expectedReport, len(reports))
+			}
+		})
+	}
+}
+
+func TestScheduleTriggeredJobs(t *testing.T) {
+	now := time.Now()
+	job := func(name, job, org string, state prowapi.ProwJobState, age time.Duration) prowapi.ProwJob {
+		return prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: prowapi.ProwJobSpec{
+				Job:   job,
+				Agent: prowapi.KubernetesAgent,
+				Refs:  &prowapi.Refs{Org: org, Repo: "repo"},
+			},
+			Status: prowapi.ProwJobStatus{
+				State:     state,
+				StartTime: metav1.NewTime(now.Add(-age)),
+			},
+		}
+	}
+	var testcases = []struct {
+		name           string
+		maxConcurrency int
+		queues         []config.SchedulingQueue
+		pjs            []prowapi.ProwJob
+		pendingJobs    map[string]int
+
+		expectedOrder      []string
+		expectedAdmissible []string
+	}{
+		{
+			name:           "global concurrency is handed out by priority",
+			maxConcurrency: 2,
+			queues: []config.SchedulingQueue{
+				{Name: "release", Repos: []string{"release"}, Priority: 2, Weight: 1},
+			},
+			pjs: []prowapi.ProwJob{
+				job("batch-1", "batch", "org", prowapi.TriggeredState, time.Hour),
+				job("batch-2", "batch", "org", prowapi.TriggeredState, time.Hour),
+				job("release-1", "release", "release", prowapi.TriggeredState, time.Minute),
+			},
+			expectedOrder:      []string{"release-1", "batch-1", "batch-2"},
+			expectedAdmissible: []string{"release-1", "batch-1"},
+		},
+		{
+			name: "queue concurrency does not block other queues",
+			queues: []config.SchedulingQueue{
+				{Name: "flood", Repos: []string{"flood"}, MaxConcurrency: 1, Priority: 2, Weight: 1},
+			},
+			pjs: []prowapi.ProwJob{
+				job("flood-running", "flood", "flood", prowapi.PendingState, 2*time.Hour),
+				job("flood-1", "flood", "flood", prowapi.TriggeredState, time.Hour),
+				job("other-1", "other", "org", prowapi.TriggeredState, time.Minute),
+			},
+			pendingJobs:        map[string]int{"flood": 1},
+			expectedOrder:      []string{"flood-1", "other-1"},
+			expectedAdmissible: []string{"other-1"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fca := newFakeConfigAgent(t, tc.maxConcurrency)
+			fca.c.Plank.Queues = tc.queues
+			pendingJobs := map[string]int{}
+			for job, num := range tc.pendingJobs {
+				pendingJobs[job] = num
+			}
+			c := &Controller{
+				log:         logrus.NewEntry(logrus.StandardLogger()),
+				config:      fca.Config,
+				pendingJobs: pendingJobs,
+			}
+
+			var order []string
+			for pj := range c.scheduleTriggeredJobs(tc.pjs) {
+				order = append(order, pj.ObjectMeta.Name)
+			}
+			if !reflect.DeepEqual(order, tc.expectedOrder) {
+				t.Errorf("expected jobs to be synced as %v, got %v", tc.expectedOrder, order)
+			}
+			if actual := c.admissible.List(); !reflect.DeepEqual(actual, sets.NewString(tc.expectedAdmissible...).List()) {
+				t.Errorf("expected jobs %v to be admissible, got %v", tc.expectedAdmissible, actual)
+			}
+			for _, pj := range tc.pjs {
+				if pj.Status.State != prowapi.TriggeredState {
+					continue
+				}
+				if admitted := c.canExecuteConcurrently(&pj); admitted != c.admissible.Has(pj.ObjectMeta.Name) {
+					t.Errorf("expected job %s to be admitted: %v, got %v", pj.ObjectMeta.Name, !admitted, admitted)
+				}
+			}
+		})
+	}
+}
+
 func handleTot(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "42")
 }
@@ -819,6 +1140,9 @@ func TestSyncPendingJob(t *testing.T) {
 		expectedCreatedPJs int
 		expectedReport     bool
 		expectedURL        string
+		expectedAttempts   int
+
+		expectedFailureReason prowapi.FailureReason
 	}{
 		{
 			name: "reset when pod goes missing",
@@ -931,11 +1255,56 @@ func TestSyncPendingJob(t *testing.T) {
 					},
 				},
 			},
-			expectedComplete: true,
-			expectedState:    prowapi.FailureState,
-			expectedNumPods:  1,
-			expectedReport:   true,
-			expectedURL:      "boop-42/failure",
+			expectedComplete:      true,
+			expectedState:         prowapi.FailureState,
+			expectedNumPods:       1,
+			expectedReport:        true,
+			expectedURL:           "boop-42/failure",
+			expectedFailureReason: prowapi.TestFailureReason,
+		},
+		{
+			name: "failed pod with failure reason recorded by the sidecar",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "boop-42",
+				},
+				Spec: prowapi.ProwJobSpec{
+					PodSpec: &kube.PodSpec{Containers: []kube.Container{{Name: "test-name", Env: []kube.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+				},
+			},
+			pods: []kube.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "boop-42",
+					},
+					Status: kube.PodStatus{
+						Phase: kube.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{
+							{
+								Name:  "test",
+								State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 127}},
+							},
+							{
+								Name: "sidecar",
+								State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+									ExitCode: 1,
+									Message:  string(prowapi.TimeoutReason),
+								}},
+							},
+						},
+					},
+				},
+			},
+			expectedComplete:      true,
+			expectedState:         prowapi.FailureState,
+			expectedNumPods:       1,
+			expectedReport:        true,
+			expectedURL:           "boop-42/failure",
+			expectedFailureReason: prowapi.TimeoutReason,
 		},
 		{
 			name: "delete evicted pod",
@@ -992,11 +1361,12 @@ func TestSyncPendingJob(t *testing.T) {
 					},
 				},
 			},
-			expectedComplete: true,
-			expectedState:    prowapi.ErrorState,
-			expectedNumPods:  1,
-			expectedReport:   true,
-			expectedURL:      "boop-42/error",
+			expectedComplete:      true,
+			expectedState:         prowapi.ErrorState,
+			expectedNumPods:       1,
+			expectedReport:        true,
+			expectedURL:           "boop-42/error",
+			expectedFailureReason: prowapi.EvictedReason,
 		},
 		{
 			name: "running pod",
@@ -1098,11 +1468,188 @@ func TestSyncPendingJob(t *testing.T) {
 					},
 				},
 			},
-			expectedState:    prowapi.ErrorState,
+			expectedState:         prowapi.ErrorState,
+			expectedNumPods:       1,
+			expectedComplete:      true,
+			expectedReport:        true,
+			expectedURL:           "nightmare/error",
+			expectedFailureReason: prowapi.PodPendingTimeoutReason,
+		},
+		{
+			name: "stale pending prow job that cannot pull its image",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "nightmare",
+				},
+				Spec: prowapi.ProwJobSpec{},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "nightmare",
+				},
+			},
+			pods: []kube.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "nightmare",
+					},
+					Status: kube.PodStatus{
+						Phase:     kube.PodPending,
+						StartTime: startTime(time.Now().Add(-podPendingTimeout)),
+						ContainerStatuses: []v1.ContainerStatus{
+							{
+								Name:  "test",
+								State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+							},
+						},
+					},
+				},
+			},
+			expectedState:         prowapi.ErrorState,
+			expectedNumPods:       1,
+			expectedComplete:      true,
+			expectedReport:        true,
+			expectedURL:           "nightmare/error",
+			expectedFailureReason: prowapi.ImagePullReason,
+		},
+		{
+			name: "retry failed pod w/ retry_policy, delete pod",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "boop-42",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 2, Reasons: []string{"OOMKilled"}},
+					PodSpec:     &kube.PodSpec{Containers: []kube.Container{{Name: "test-name", Env: []kube.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+					BuildID: "0987654321",
+				},
+			},
+			pods: []kube.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "boop-42",
+					},
+					Status: kube.PodStatus{
+						Phase: kube.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{{
+							Name:  "sidecar",
+							State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+						}},
+					},
+				},
+			},
+			expectedState:    prowapi.PendingState,
+			expectedNumPods:  0,
+			expectedAttempts: 1,
+		},
+		{
+			name: "don't count the retried attempt again while its pod is still cached",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "boop-42",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 3, Reasons: []string{"OOMKilled"}},
+					PodSpec:     &kube.PodSpec{Containers: []kube.Container{{Name: "test-name", Env: []kube.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "boop-42",
+					BuildID:  "0987654321",
+					Attempts: []prowapi.ProwJobAttempt{{BuildID: "0987654321", PodUID: "boop-42-uid", State: prowapi.FailureState}},
+				},
+			},
+			pods: []kube.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "boop-42",
+						UID:  "boop-42-uid",
+					},
+					Status: kube.PodStatus{
+						Phase: kube.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{{
+							Name:  "sidecar",
+							State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+						}},
+					},
+				},
+			},
+			expectedState:    prowapi.PendingState,
 			expectedNumPods:  1,
-			expectedComplete: true,
-			expectedReport:   true,
-			expectedURL:      "nightmare/error",
+			expectedAttempts: 1,
+		},
+		{
+			name: "don't retry failed pod w/ retry_policy for unlisted reason",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "boop-42",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 2, Reasons: []string{"OOMKilled"}},
+					PodSpec:     &kube.PodSpec{Containers: []kube.Container{{Name: "test-name", Env: []kube.EnvVar{}}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop-42",
+				},
+			},
+			pods: []kube.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "boop-42",
+					},
+					Status: kube.PodStatus{
+						Phase: kube.PodFailed,
+						ContainerStatuses: []v1.ContainerStatus{{
+							Name:  "test",
+							State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+						}},
+					},
+				},
+			},
+			expectedComplete:      true,
+			expectedState:         prowapi.FailureState,
+			expectedNumPods:       1,
+			expectedReport:        true,
+			expectedURL:           "boop-42/failure",
+			expectedFailureReason: prowapi.TestFailureReason,
+		},
+		{
+			name: "don't retry stale pending pod w/ retry_policy after max attempts",
+			pj: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "nightmare",
+				},
+				Spec: prowapi.ProwJobSpec{
+					RetryPolicy: &prowapi.RetryPolicy{MaxAttempts: 2, States: []prowapi.ProwJobState{prowapi.ErrorState}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:    prowapi.PendingState,
+					PodName:  "nightmare",
+					Attempts: []prowapi.ProwJobAttempt{{State: prowapi.ErrorState}},
+				},
+			},
+			pods: []kube.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "nightmare",
+					},
+					Status: kube.PodStatus{
+						Phase:     kube.PodPending,
+						StartTime: startTime(time.Now().Add(-podPendingTimeout)),
+					},
+				},
+			},
+			expectedState:         prowapi.ErrorState,
+			expectedNumPods:       1,
+			expectedComplete:      true,
+			expectedReport:        true,
+			expectedURL:           "nightmare/error",
+			expectedAttempts:      1,
+			expectedFailureReason: prowapi.PodPendingTimeoutReason,
 		},
 	}
 	for _, tc := range testcases {
@@ -1149,6 +1696,12 @@ func TestSyncPendingJob(t *testing.T) {
 		if len(fc.prowjobs) != tc.expectedCreatedPJs+1 {
 			t.Errorf("for case %q got %d created prowjobs", tc.name, len(fc.prowjobs)-1)
 		}
+		if actual.Status.FailureReason != tc.expectedFailureReason {
+			t.Errorf("for case %q got failure reason %q, expected %q", tc.name, actual.Status.FailureReason, tc.expectedFailureReason)
+		}
+		if len(actual.Status.Attempts) != tc.expectedAttempts {
+			t.Errorf("for case %q got %d attempts, expected %d", tc.name, len(actual.Status.Attempts), tc.expectedAttempts)
+		}
 		if tc.expectedReport && len(reports) != 1 {
 			t.Errorf("for case %q wanted one report but got %d", tc.name, len(reports))
 		}
@@ -1166,6 +1719,214 @@ func TestSyncPendingJob(t *testing.T) {
 }
 
 // TestPeriodic walks through the happy path of a periodic job.
+func TestPodRequests(t *testing.T) {
+	container := func(cpu, memory string) v1.Container {
+		return v1.Container{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse(memory),
+		}}}
+	}
+	var testcases = []struct {
+		name     string
+		spec     v1.PodSpec
+		expected v1.ResourceList
+	}{
+		{
+			name: "no requests",
+			spec: v1.PodSpec{Containers: []v1.Container{{}}},
+		},
+		{
+			name: "requests of containers add up",
+			spec: v1.PodSpec{Containers: []v1.Container{container("1", "1Gi"), container("500m", "2Gi")}},
+			expected: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1500m"),
+				v1.ResourceMemory: resource.MustParse("3Gi"),
+			},
+		},
+		{
+			name: "larger init container request wins",
+			spec: v1.PodSpec{
+				InitContainers: []v1.Container{container("4", "100Mi")},
+				Containers:     []v1.Container{container("1", "1Gi")},
+			},
+			expected: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := podRequests(&tc.spec); !equality.Semantic.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected requests %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResourceUsage(t *testing.T) {
+	start := metav1.NewTime(time.Date(2019, 2, 22, 10, 0, 0, 0, time.UTC))
+	finished := metav1.NewTime(start.Add(90 * time.Second))
+	pj := prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "job"},
+		Spec:       prowapi.ProwJobSpec{Job: "job", Agent: prowapi.KubernetesAgent},
+	}
+	pod := kube.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "job"},
+		Spec: v1.PodSpec{
+			NodeName: "node-1",
+			Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("2"),
+			}}}},
+		},
+		Status: v1.PodStatus{
+			StartTime: &start,
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name: "test",
+					State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+						FinishedAt: finished,
+						Message:    `{"cpu_millis":45000,"max_memory_bytes":1048576}`,
+					}},
+				},
+				{
+					Name: "sidecar",
+					State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+						FinishedAt: start,
+						Message:    string(prowapi.TestFailureReason),
+					}},
+				},
+			},
+		},
+	}
+	pkc := &fkc{nodes: []kube.Node{{ObjectMeta: metav1.ObjectMeta{
+		Name:   "node-1",
+		Labels: map[string]string{kube.InstanceTypeLabel: "n1-standard-8"},
+	}}}}
+	c := Controller{
+		pkcs: map[string]kubeClient{kube.DefaultClusterAlias: pkc},
+		log:  logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	expected := &prowapi.ResourceUsage{
+		Requests:        v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+		CPUMillis:       45000,
+		MaxMemoryBytes:  1048576,
+		NodeType:        "n1-standard-8",
+		DurationSeconds: 90,
+	}
+	if actual := c.resourceUsage(pj, &pod); !equality.Semantic.DeepEqual(actual, expected) {
+		t.Errorf("expected usage %+v, got %+v", expected, actual)
+	}
+}
+
+func TestRecordPodInfo(t *testing.T) {
+	decoration := &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{
+		Bucket:       "file://bucket",
+		PathStrategy: prowapi.PathStrategyExplicit,
+	}}
+	testCases := []struct {
+		name       string
+		decoration *prowapi.DecorationConfig
+		noStorage  bool
+		expected   bool
+	}{
+		{
+			name:       "decorated job is recorded",
+			decoration: decoration,
+			expected:   true,
+		},
+		{
+			name: "undecorated job is not recorded",
+		},
+		{
+			name:       "nothing is recorded without storage",
+			decoration: decoration,
+			noStorage:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root, err := ioutil.TempDir("", "plank")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(root)
+
+			pj := prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "job"},
+				Spec: prowapi.ProwJobSpec{
+					Type:             prowapi.PeriodicJob,
+					Job:              "job",
+					Agent:            prowapi.KubernetesAgent,
+					DecorationConfig: tc.decoration,
+				},
+				Status: prowapi.ProwJobStatus{
+					BuildID:   "5",
+					Resources: &prowapi.ResourceUsage{NodeType: "n1-standard-8"},
+				},
+			}
+			pod := kube.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "job"},
+				Spec: v1.PodSpec{
+					NodeName:           "node-1",
+					ServiceAccountName: "secret-account",
+					Containers: []v1.Container{{
+						Name: "test",
+						Args: []string{"--token=secret-arg"},
+						Env:  []v1.EnvVar{{Name: "TOKEN", Value: "secret-env"}},
+					}},
+				},
+				Status: v1.PodStatus{
+					Phase:             v1.PodFailed,
+					ContainerStatuses: []v1.ContainerStatus{{Name: "test"}},
+				},
+			}
+			pkc := &fkc{events: []kube.Event{{Reason: "Scheduled", Message: "Successfully assigned job to node-1"}}}
+			c := Controller{
+				pkcs: map[string]kubeClient{kube.DefaultClusterAlias: pkc},
+				log:  logrus.NewEntry(logrus.StandardLogger()),
+			}
+			if !tc.noStorage {
+				c.UploadPodInfo(&objectstore.Opener{FileRoot: root})
+			}
+
+			c.recordPodInfo(pj, &pod)
+			raw, err := ioutil.ReadFile(filepath.Join(root, "bucket", "logs", "job", "5", gcs.PodInfoFile))
+			if !tc.expected {
+				if !os.IsNotExist(err) {
+					t.Errorf("expected no pod info, got %s (error %v)", raw, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to read the pod info: %v", err)
+			}
+			for _, secret := range []string{"secret-account", "secret-arg", "secret-env"} {
+				if strings.Contains(string(raw), secret) {
+					t.Errorf("expected the pod spec to be left out, got %q in %s", secret, raw)
+				}
+			}
+			var info gcs.PodInfo
+			if err := json.Unmarshal(raw, &info); err != nil {
+				t.Fatalf("failed to unmarshal the pod info: %v", err)
+			}
+			expected := gcs.PodInfo{
+				Pod: &gcs.PodSummary{
+					Name:     "job",
+					NodeName: "node-1",
+					Status:   pod.Status,
+				},
+				Events:    pkc.events,
+				Resources: pj.Status.Resources,
+			}
+			if !equality.Semantic.DeepEqual(info, expected) {
+				t.Errorf("expected pod info %+v, got %+v", expected, info)
+			}
+		})
+	}
+}
+
 func TestPeriodic(t *testing.T) {
 	per := config.Periodic{
 		JobBase: config.JobBase{
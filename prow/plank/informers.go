@@ -0,0 +1,198 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	pjinformers "k8s.io/test-infra/prow/client/informers/externalversions/prowjobs/v1"
+	pjlisters "k8s.io/test-infra/prow/client/listers/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/kube"
+)
+
+// syncKey is the only key in the queue of the controller. Plank decides
+// what to do with a ProwJob based on all the other ProwJobs (e.g. to
+// honor max_concurrency), so events are coalesced into syncs of all
+// cached ProwJobs and pods rather than handled per ProwJob.
+const syncKey = "sync"
+
+// NewPodInformer returns an informer for the pods that prow created
+// in the namespace of a build cluster for ProwJobs matching selector.
+func NewPodInformer(client kubernetes.Interface, namespace, selector string, resync time.Duration) cache.SharedIndexInformer {
+	selector = podSelector(selector)
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return client.CoreV1().Pods(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return client.CoreV1().Pods(namespace).Watch(options)
+			},
+		},
+		&coreapi.Pod{},
+		resync,
+		cache.Indexers{},
+	)
+}
+
+// NewInformerController creates a new Controller that syncs whenever a
+// ProwJob or a pod in one of the build clusters changes. ProwJobs and
+// pods are read from the caches of the informers, while all changes are
+// made through the provided clients.
+func NewInformerController(kc *kube.Client, pkcs map[string]*kube.Client, prowJobInformer pjinformers.ProwJobInformer, podInformers map[string]cache.SharedIndexInformer, ghc GitHubClient, logger *logrus.Entry, cfg config.Getter, totURL, selector string, skipReport bool) (*Controller, error) {
+	c, err := NewController(kc, pkcs, ghc, logger, cfg, totURL, selector, skipReport)
+	if err != nil {
+		return nil, err
+	}
+	for alias, client := range c.pkcs {
+		informer, ok := podInformers[alias]
+		if !ok {
+			return nil, fmt.Errorf("no pod informer for cluster %q", alias)
+		}
+		c.pkcs[alias] = &cachedPodClient{kubeClient: client, indexer: informer.GetIndexer()}
+	}
+	c.kc = &cachedProwJobClient{kubeClient: c.kc, lister: prowJobInformer.Lister()}
+	c.prowJobInformer = prowJobInformer
+	c.podInformers = podInformers
+	c.queue = kube.RateLimiter("plank")
+	return c, nil
+}
+
+// Run starts the informers of the controller and syncs whenever a
+// ProwJob or pod changes, and at least once every resync period, until
+// stopCh is closed. It may only be called on controllers created by
+// NewInformerController.
+func (c *Controller) Run(stopCh <-chan struct{}, resync time.Duration) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { c.queue.Add(syncKey) },
+		UpdateFunc: func(interface{}, interface{}) { c.queue.Add(syncKey) },
+		DeleteFunc: func(interface{}) { c.queue.Add(syncKey) },
+	}
+	informers := []cache.SharedIndexInformer{c.prowJobInformer.Informer()}
+	for _, informer := range c.podInformers {
+		informers = append(informers, informer)
+	}
+	var synced []cache.InformerSynced
+	for _, informer := range informers {
+		informer.AddEventHandler(handler)
+		go informer.Run(stopCh)
+		synced = append(synced, informer.HasSynced)
+	}
+
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		utilruntime.HandleError(errors.New("error syncing caches"))
+		return
+	}
+	c.log.Info("Caches synced.")
+
+	// Events may be missed, e.g. while a watch is re-established,
+	// and timeouts are not driven by events, so sync periodically.
+	go wait.Until(func() { c.queue.Add(syncKey) }, resync, stopCh)
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	<-stopCh
+	c.log.Info("Shutting down the controller.")
+}
+
+// runWorker processes items until the queue is shut down. As all
+// items are the same key, a single worker serializes the syncs.
+func (c *Controller) runWorker() {
+	for c.processNextItem() {
+	}
+}
+
+// processNextItem syncs for the next item of the queue and retries
+// with backoff if the sync fails.
+func (c *Controller) processNextItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	start := time.Now()
+	if err := c.Sync(); err != nil {
+		c.log.WithError(err).Error("Error syncing.")
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	c.log.WithField("duration", fmt.Sprintf("%v", time.Since(start))).Info("Synced")
+	return true
+}
+
+// cachedProwJobClient lists ProwJobs from an informer cache
+// and makes all other calls through the wrapped client.
+type cachedProwJobClient struct {
+	kubeClient
+	lister pjlisters.ProwJobLister
+}
+
+func (c *cachedProwJobClient) ListProwJobs(selector string) ([]prowapi.ProwJob, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := c.lister.List(sel)
+	if err != nil {
+		return nil, err
+	}
+	pjs := make([]prowapi.ProwJob, 0, len(cached))
+	for _, pj := range cached {
+		pjs = append(pjs, *pj.DeepCopy())
+	}
+	return pjs, nil
+}
+
+// cachedPodClient lists pods from an informer cache and
+// makes all other calls through the wrapped client.
+type cachedPodClient struct {
+	kubeClient
+	indexer cache.Indexer
+}
+
+func (c *cachedPodClient) ListPods(selector string) ([]coreapi.Pod, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	var pods []coreapi.Pod
+	err = cache.ListAll(c.indexer, sel, func(obj interface{}) {
+		pods = append(pods, *obj.(*coreapi.Pod).DeepCopy())
+	})
+	return pods, err
+}
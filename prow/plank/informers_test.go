@@ -0,0 +1,238 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	fakeprow "k8s.io/test-infra/prow/client/clientset/versioned/fake"
+	prowv1 "k8s.io/test-infra/prow/client/clientset/versioned/typed/prowjobs/v1"
+	prowjobinformer "k8s.io/test-infra/prow/client/informers/externalversions"
+	pjlisters "k8s.io/test-infra/prow/client/listers/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestCachedClients(t *testing.T) {
+	pjIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range []string{"foo", "bar"} {
+		pjIndexer.Add(&prowapi.ProwJob{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prowjobs", Labels: map[string]string{"job": name}}})
+		podIndexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "pods", Labels: map[string]string{"job": name, kube.CreatedByProw: "true"}}})
+	}
+	podIndexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "pods"}})
+
+	var testCases = []struct {
+		name         string
+		selector     string
+		expectedPJs  []string
+		expectedPods []string
+	}{
+		{
+			name:         "empty selector",
+			selector:     kube.EmptySelector,
+			expectedPJs:  []string{"bar", "foo"},
+			expectedPods: []string{"bar", "foo"},
+		},
+		{
+			name:         "label selector",
+			selector:     "job=foo",
+			expectedPJs:  []string{"foo"},
+			expectedPods: []string{"foo"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pjClient := &cachedProwJobClient{kubeClient: &fkc{}, lister: pjlisters.NewProwJobLister(pjIndexer)}
+			pjs, err := pjClient.ListProwJobs(tc.selector)
+			if err != nil {
+				t.Fatalf("unexpected error listing prowjobs: %v", err)
+			}
+			var pjNames []string
+			for _, pj := range pjs {
+				pjNames = append(pjNames, pj.ObjectMeta.Name)
+			}
+			if !reflect.DeepEqual(sets.NewString(pjNames...).List(), tc.expectedPJs) {
+				t.Errorf("expected prowjobs %v, got %v", tc.expectedPJs, pjNames)
+			}
+
+			podClient := &cachedPodClient{kubeClient: &fkc{}, indexer: podIndexer}
+			pods, err := podClient.ListPods(podSelector(tc.selector))
+			if err != nil {
+				t.Fatalf("unexpected error listing pods: %v", err)
+			}
+			var podNames []string
+			for _, pod := range pods {
+				podNames = append(podNames, pod.ObjectMeta.Name)
+			}
+			if !reflect.DeepEqual(sets.NewString(podNames...).List(), tc.expectedPods) {
+				t.Errorf("expected pods %v, got %v", tc.expectedPods, podNames)
+			}
+		})
+	}
+}
+
+// clientsetClient makes all calls through fake clientsets,
+// so that changes are observed by the informers.
+type clientsetClient struct {
+	pjs    prowv1.ProwJobInterface
+	pods   corev1.PodInterface
+	nodes  corev1.NodeInterface
+	events corev1.EventInterface
+}
+
+func (c *clientsetClient) CreateProwJob(pj prowapi.ProwJob) (prowapi.ProwJob, error) {
+	created, err := c.pjs.Create(&pj)
+	if err != nil {
+		return prowapi.ProwJob{}, err
+	}
+	return *created, nil
+}
+
+func (c *clientsetClient) GetProwJob(name string) (prowapi.ProwJob, error) {
+	pj, err := c.pjs.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return prowapi.ProwJob{}, err
+	}
+	return *pj, nil
+}
+
+func (c *clientsetClient) ListProwJobs(selector string) ([]prowapi.ProwJob, error) {
+	list, err := c.pjs.List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (c *clientsetClient) ReplaceProwJob(name string, pj prowapi.ProwJob) (prowapi.ProwJob, error) {
+	replaced, err := c.pjs.Update(&pj)
+	if err != nil {
+		return prowapi.ProwJob{}, err
+	}
+	return *replaced, nil
+}
+
+func (c *clientsetClient) CreatePod(pod v1.Pod) (v1.Pod, error) {
+	created, err := c.pods.Create(&pod)
+	if err != nil {
+		return v1.Pod{}, err
+	}
+	return *created, nil
+}
+
+func (c *clientsetClient) ListPods(selector string) ([]v1.Pod, error) {
+	list, err := c.pods.List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (c *clientsetClient) DeletePod(name string) error {
+	return c.pods.Delete(name, &metav1.DeleteOptions{})
+}
+
+func (c *clientsetClient) GetNode(name string) (v1.Node, error) {
+	node, err := c.nodes.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return v1.Node{}, err
+	}
+	return *node, nil
+}
+
+func (c *clientsetClient) ListEvents(selector string) ([]v1.Event, error) {
+	list, err := c.events.List(metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func TestRunStartsTriggeredJobs(t *testing.T) {
+	totServ := httptest.NewServer(http.HandlerFunc(handleTot))
+	defer totServ.Close()
+
+	pjClientset := fakeprow.NewSimpleClientset()
+	podClientset := fakekube.NewSimpleClientset()
+	client := &clientsetClient{
+		pjs:    pjClientset.ProwV1().ProwJobs("prowjobs"),
+		pods:   podClientset.CoreV1().Pods("pods"),
+		nodes:  podClientset.CoreV1().Nodes(),
+		events: podClientset.CoreV1().Events("pods"),
+	}
+	pjInformer := prowjobinformer.NewSharedInformerFactory(pjClientset, 0).Prow().V1().ProwJobs()
+	podInformer := NewPodInformer(podClientset, "pods", kube.EmptySelector, 0)
+	c := &Controller{
+		kc:              &cachedProwJobClient{kubeClient: client, lister: pjInformer.Lister()},
+		pkcs:            map[string]kubeClient{kube.DefaultClusterAlias: &cachedPodClient{kubeClient: client, indexer: podInformer.GetIndexer()}},
+		log:             logrus.NewEntry(logrus.StandardLogger()),
+		config:          newFakeConfigAgent(t, 0).Config,
+		totURL:          totServ.URL,
+		pendingJobs:     make(map[string]int),
+		skipReport:      true,
+		prowJobInformer: pjInformer,
+		podInformers:    map[string]cache.SharedIndexInformer{kube.DefaultClusterAlias: podInformer},
+		queue:           kube.RateLimiter("plank"),
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go c.Run(stopCh, time.Hour)
+
+	pj := prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "prowjobs"},
+		Spec: prowapi.ProwJobSpec{
+			Job:     "foo",
+			Type:    prowapi.PeriodicJob,
+			Agent:   prowapi.KubernetesAgent,
+			PodSpec: &v1.PodSpec{Containers: []v1.Container{{Name: "test-name", Env: []v1.EnvVar{}}}},
+		},
+		Status: prowapi.ProwJobStatus{State: prowapi.TriggeredState},
+	}
+	if _, err := client.CreateProwJob(pj); err != nil {
+		t.Fatalf("failed to create prowjob: %v", err)
+	}
+
+	if err := wait.Poll(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		actual, err := client.GetProwJob("foo")
+		return err == nil && actual.Status.State == prowapi.PendingState, nil
+	}); err != nil {
+		t.Fatalf("expected the triggered job to become pending: %v", err)
+	}
+	pods, err := client.ListPods(kube.EmptySelector)
+	if err != nil {
+		t.Fatalf("failed to list pods: %v", err)
+	}
+	if len(pods) != 1 {
+		t.Errorf("expected one pod to be started, got %d", len(pods))
+	}
+}
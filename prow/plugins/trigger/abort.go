@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/errorutil"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+var abortRe = regexp.MustCompile(`(?m)^/abort((?: +[^\s,]+,?)*)\s*$`)
+
+// abortedJobs determines which jobs the /abort commands in a comment ask
+// to abort, or whether they ask to abort all jobs.
+func abortedJobs(body string) (all bool, jobs sets.String) {
+	jobs = sets.NewString()
+	for _, match := range abortRe.FindAllStringSubmatch(body, -1) {
+		names := strings.Fields(strings.Replace(match[1], ",", " ", -1))
+		if len(names) == 0 {
+			all = true
+		}
+		for _, name := range names {
+			if name == "all" {
+				all = true
+			}
+			jobs.Insert(name)
+		}
+	}
+	return all, jobs
+}
+
+// handleAbort requests the cancellation of the running presubmits for
+// the PR that a trusted user asked to abort with an /abort command.
+func handleAbort(c Client, trigger *plugins.Trigger, gc github.GenericCommentEvent) error {
+	org := gc.Repo.Owner.Login
+	repo := gc.Repo.Name
+	number := gc.Number
+	commentAuthor := gc.User.Login
+
+	trusted, err := TrustedUser(c.GitHubClient, trigger, commentAuthor, org, repo)
+	if err != nil {
+		return fmt.Errorf("error checking trust of %s: %v", commentAuthor, err)
+	}
+	if !trusted {
+		resp := "Only members of the trusted organization for the repo can abort jobs."
+		c.Logger.Infof("Commenting \"%s\".", resp)
+		return c.GitHubClient.CreateComment(org, repo, number, plugins.FormatResponseRaw(gc.Body, gc.HTMLURL, commentAuthor, resp))
+	}
+
+	selector := fmt.Sprintf("%s=%s,%s=%s,%s=%s,%s=%d",
+		kube.ProwJobTypeLabel, prowapi.PresubmitJob,
+		kube.OrgLabel, org,
+		kube.RepoLabel, repo,
+		kube.PullLabel, number,
+	)
+	pjs, err := c.ProwJobClient.List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("error listing prow jobs: %v", err)
+	}
+
+	all, jobs := abortedJobs(gc.Body)
+	var aborted int
+	var errs []error
+	for _, pj := range pjs.Items {
+		if pj.Complete() || pj.Spec.Cancel {
+			continue
+		}
+		if !all && !jobs.Has(pj.Spec.Job) {
+			continue
+		}
+		pj.Spec.Cancel = true
+		if _, err := c.ProwJobClient.Update(&pj); err != nil {
+			errs = append(errs, fmt.Errorf("error requesting cancellation of %s: %v", pj.ObjectMeta.Name, err))
+			continue
+		}
+		c.Logger.WithField("job", pj.Spec.Job).Infof("Requested cancellation of %s.", pj.ObjectMeta.Name)
+		aborted++
+	}
+	if len(errs) > 0 {
+		return errorutil.NewAggregate(errs...)
+	}
+	if aborted == 0 {
+		resp := "There are no running jobs to abort."
+		if !all {
+			resp = fmt.Sprintf("There are no running jobs named %s to abort.", strings.Join(jobs.List(), ", "))
+		}
+		c.Logger.Infof("Commenting \"%s\".", resp)
+		return c.GitHubClient.CreateComment(org, repo, number, plugins.FormatResponseRaw(gc.Body, gc.HTMLURL, commentAuthor, resp))
+	}
+	return nil
+}
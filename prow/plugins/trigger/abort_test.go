@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/client/clientset/versioned/fake"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+func TestAbortedJobs(t *testing.T) {
+	var testcases = []struct {
+		name string
+		body string
+
+		expectedAll  bool
+		expectedJobs []string
+	}{
+		{
+			name:        "bare command aborts all jobs",
+			body:        "/abort",
+			expectedAll: true,
+		},
+		{
+			name:         "explicit all aborts all jobs",
+			body:         "/abort all",
+			expectedAll:  true,
+			expectedJobs: []string{"all"},
+		},
+		{
+			name:         "named jobs",
+			body:         "/abort pull-unit, pull-e2e",
+			expectedJobs: []string{"pull-e2e", "pull-unit"},
+		},
+		{
+			name:         "commands on several lines",
+			body:         "/abort pull-unit\nthanks\n/abort pull-e2e",
+			expectedJobs: []string{"pull-e2e", "pull-unit"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			all, jobs := abortedJobs(tc.body)
+			if all != tc.expectedAll {
+				t.Errorf("expected all: %v, got %v", tc.expectedAll, all)
+			}
+			if !jobs.Equal(sets.NewString(tc.expectedJobs...)) {
+				t.Errorf("expected jobs %v, got %v", tc.expectedJobs, jobs.List())
+			}
+		})
+	}
+}
+
+func TestHandleAbort(t *testing.T) {
+	completed := metav1.Now()
+	prowJob := func(name, job string, number int, complete bool) *prowapi.ProwJob {
+		pj := &prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "prowjobs",
+				Labels: map[string]string{
+					kube.ProwJobTypeLabel: string(prowapi.PresubmitJob),
+					kube.OrgLabel:         "org",
+					kube.RepoLabel:        "repo",
+					kube.PullLabel:        strconv.Itoa(number),
+				},
+			},
+			Spec:   prowapi.ProwJobSpec{Job: job, Type: prowapi.PresubmitJob},
+			Status: prowapi.ProwJobStatus{State: prowapi.PendingState},
+		}
+		if complete {
+			pj.Status.State = prowapi.FailureState
+			pj.Status.CompletionTime = &completed
+		}
+		return pj
+	}
+
+	var testcases = []struct {
+		name   string
+		author string
+		body   string
+
+		expectedCancelled []string
+		expectedComment   bool
+	}{
+		{
+			name:              "trusted user aborts all running jobs of the PR",
+			author:            "trusted-member",
+			body:              "/abort",
+			expectedCancelled: []string{"unit-1", "e2e-1"},
+		},
+		{
+			name:              "trusted user aborts a single job",
+			author:            "trusted-member",
+			body:              "/abort pull-e2e",
+			expectedCancelled: []string{"e2e-1"},
+		},
+		{
+			name:            "untrusted user cannot abort jobs",
+			author:          "untrusted",
+			body:            "/abort",
+			expectedComment: true,
+		},
+		{
+			name:            "unknown job is reported",
+			author:          "trusted-member",
+			body:            "/abort pull-lint",
+			expectedComment: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &fakegithub.FakeClient{
+				OrgMembers:    map[string][]string{"org": {"trusted-member"}},
+				IssueComments: map[int][]github.IssueComment{},
+			}
+			fakeProwJobClient := fake.NewSimpleClientset([]runtime.Object{
+				prowJob("unit-1", "pull-unit", 1, false),
+				prowJob("e2e-1", "pull-e2e", 1, false),
+				prowJob("verify-1", "pull-verify", 1, true),
+				prowJob("unit-2", "pull-unit", 2, false),
+			}...)
+			c := Client{
+				GitHubClient:  g,
+				ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				Config:        &config.Config{},
+				Logger:        logrus.WithField("plugin", pluginName),
+			}
+			event := github.GenericCommentEvent{
+				Action: github.GenericCommentActionCreated,
+				Repo: github.Repo{
+					Owner:    github.User{Login: "org"},
+					Name:     "repo",
+					FullName: "org/repo",
+				},
+				Number: 1,
+				Body:   tc.body,
+				User:   github.User{Login: tc.author},
+			}
+			if err := handleAbort(c, &plugins.Trigger{}, event); err != nil {
+				t.Fatalf("didn't expect error: %v", err)
+			}
+
+			pjs, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").List(metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list prowjobs: %v", err)
+			}
+			cancelled := sets.NewString()
+			for _, pj := range pjs.Items {
+				if pj.Spec.Cancel {
+					cancelled.Insert(pj.ObjectMeta.Name)
+				}
+			}
+			if !cancelled.Equal(sets.NewString(tc.expectedCancelled...)) {
+				t.Errorf("expected cancelled jobs %v, got %v", tc.expectedCancelled, cancelled.List())
+			}
+			if commented := len(g.IssueComments[1]) > 0; commented != tc.expectedComment {
+				t.Errorf("expected comment: %v, got %v", tc.expectedComment, g.IssueComments[1])
+			}
+		})
+	}
+}
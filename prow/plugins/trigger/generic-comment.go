@@ -43,6 +43,11 @@ func handleGenericComment(c Client, trigger *plugins.Trigger, gc github.GenericC
 	if gc.Action != github.GenericCommentActionCreated || !gc.IsPR || gc.IssueState != "open" {
 		return nil
 	}
+	if abortRe.MatchString(gc.Body) {
+		if err := handleAbort(c, trigger, gc); err != nil {
+			return err
+		}
+	}
 	// Skip comments not germane to this plugin
 	if !retestRe.MatchString(gc.Body) && !okToTestRe.MatchString(gc.Body) && !testAllRe.MatchString(gc.Body) {
 		matched := false
@@ -17,6 +17,7 @@ limitations under the License.
 package trigger
 
 import (
+	"k8s.io/apimachinery/pkg/util/sets"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
@@ -60,12 +61,18 @@ func handlePE(c Client, pe github.PushEvent) error {
 		// we should not trigger jobs for a branch deletion
 		return nil
 	}
+	var toRun []config.Postsubmit
+	triggered := sets.NewString()
 	for _, j := range c.Config.Postsubmits[pe.Repo.FullName] {
 		if shouldRun, err := j.ShouldRun(pe.Branch(), listPushEventChanges(pe)); err != nil {
 			return err
 		} else if !shouldRun {
 			continue
 		}
+		toRun = append(toRun, j)
+		triggered.Insert(j.Name)
+	}
+	for _, j := range toRun {
 		refs := createRefs(pe)
 		labels := make(map[string]string)
 		for k, v := range j.Labels {
@@ -73,6 +80,7 @@ func handlePE(c Client, pe github.PushEvent) error {
 		}
 		labels[github.EventGUID] = pe.GUID
 		pj := pjutil.NewProwJob(pjutil.PostsubmitSpec(j, refs), labels)
+		pjutil.SetRunAfterSuccess(&pj, runAfterSuccessWithin(j.RunAfterSuccess, triggered))
 		c.Logger.WithFields(pjutil.ProwJobFields(&pj)).Info("Creating a new prowjob.")
 		if _, err := c.ProwJobClient.Create(&pj); err != nil {
 			return err
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
@@ -63,7 +64,8 @@ func helpProvider(config *plugins.Configuration, enabledRepos []string) (*plugin
 	pluginHelp := &pluginhelp.PluginHelp{
 		Description: `The trigger plugin starts tests in reaction to commands and pull request events. It is responsible for ensuring that test jobs are only run on trusted PRs. A PR is considered trusted if the author is a member of the 'trusted organization' for the repository or if such a member has left an '/ok-to-test' command on the PR.
 <br>Trigger starts jobs automatically when a new trusted PR is created or when an untrusted PR becomes trusted, but it can also be used to start jobs manually via the '/test' command.
-<br>The '/retest' command can be used to rerun jobs that have reported failure.`,
+<br>The '/retest' command can be used to rerun jobs that have reported failure.
+<br>The '/abort' command can be used to stop running jobs.`,
 		Config: configInfo,
 	}
 	pluginHelp.AddCommand(pluginhelp.Command{
@@ -87,6 +89,13 @@ func helpProvider(config *plugins.Configuration, enabledRepos []string) (*plugin
 		WhoCanUse:   "Anyone can trigger this command on a trusted PR.",
 		Examples:    []string{"/retest"},
 	})
+	pluginHelp.AddCommand(pluginhelp.Command{
+		Usage:       "/abort [<job name>|all]",
+		Description: "Aborts a/all running test job(s).",
+		Featured:    false,
+		WhoCanUse:   "Members of the trusted organization for the repo.",
+		Examples:    []string{"/abort", "/abort pull-bazel-test"},
+	})
 	return pluginHelp, nil
 }
 
@@ -109,6 +118,8 @@ type githubClient interface {
 
 type prowJobClient interface {
 	Create(*prowapi.ProwJob) (*prowapi.ProwJob, error)
+	List(opts metav1.ListOptions) (*prowapi.ProwJobList, error)
+	Update(*prowapi.ProwJob) (*prowapi.ProwJob, error)
 }
 
 // Client holds the necessary structures to work with prow via logging, github, kubernetes and its configuration.
@@ -233,10 +244,16 @@ func RunRequested(c Client, pr *github.PullRequest, requestedJobs []config.Presu
 		return err
 	}
 
+	requested := sets.NewString()
+	for _, job := range requestedJobs {
+		requested.Insert(job.Name)
+	}
+
 	var errors []error
 	for _, job := range requestedJobs {
 		c.Logger.Infof("Starting %s build.", job.Name)
 		pj := pjutil.NewPresubmit(*pr, baseSHA, job, eventGUID)
+		pjutil.SetRunAfterSuccess(&pj, runAfterSuccessWithin(job.RunAfterSuccess, requested))
 		c.Logger.WithFields(pjutil.ProwJobFields(&pj)).Info("Creating a new prowjob.")
 		if _, err := c.ProwJobClient.Create(&pj); err != nil {
 			c.Logger.WithError(err).Error("Failed to create prowjob.")
@@ -246,6 +263,19 @@ func RunRequested(c Client, pr *github.PullRequest, requestedJobs []config.Presu
 	return errorutil.NewAggregate(errors...)
 }
 
+// runAfterSuccessWithin filters the jobs a job runs after down to the
+// ones that are triggered alongside it, as only those will ever complete
+// for the same refs.
+func runAfterSuccessWithin(runAfterSuccess []string, triggered sets.String) []string {
+	var jobs []string
+	for _, job := range runAfterSuccess {
+		if triggered.Has(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
 // skipRequested posts skipped statuses for the config.Presubmits that are requested
 func skipRequested(c Client, pr *github.PullRequest, skippedJobs []config.Presubmit) error {
 	var errors []error
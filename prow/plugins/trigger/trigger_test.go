@@ -360,12 +360,36 @@ func TestRunRequested(t *testing.T) {
 		requestedJobs   []config.Presubmit
 		jobCreationErrs sets.String // job names which fail creation
 
-		expectedJobs sets.String // by name
-		expectedErr  bool
+		expectedJobs    sets.String // by name
+		expectedWaiting sets.String // by name
+		expectedErr     bool
 	}{
 		{
 			name: "nothing requested means nothing done",
 		},
+		{
+			name: "jobs wait only for the jobs they run after that are requested",
+			requestedJobs: []config.Presubmit{{
+				JobBase: config.JobBase{
+					Name: "unit",
+				},
+				Reporter: config.Reporter{Context: "unit-context"},
+			}, {
+				JobBase: config.JobBase{
+					Name: "e2e",
+				},
+				Reporter:        config.Reporter{Context: "e2e-context"},
+				RunAfterSuccess: []string{"unit", "verify"},
+			}, {
+				JobBase: config.JobBase{
+					Name: "soak",
+				},
+				Reporter:        config.Reporter{Context: "soak-context"},
+				RunAfterSuccess: []string{"verify"},
+			}},
+			expectedJobs:    sets.NewString("unit", "e2e", "soak"),
+			expectedWaiting: sets.NewString("e2e"),
+		},
 		{
 			name: "all requested jobs get run",
 			requestedJobs: []config.Presubmit{{
@@ -451,8 +475,12 @@ func TestRunRequested(t *testing.T) {
 			t.Errorf("%s: could not list current state of prow jobs: %v", testCase.name, err)
 			continue
 		}
+		observedWaitingProwJobs := sets.NewString()
 		for _, job := range existingProwJobs.Items {
 			observedCreatedProwJobs.Insert(job.Spec.Job)
+			if job.Status.State == prowapi.WaitingState {
+				observedWaitingProwJobs.Insert(job.Spec.Job)
+			}
 		}
 
 		if missing := testCase.expectedJobs.Difference(observedCreatedProwJobs); missing.Len() > 0 {
@@ -461,6 +489,9 @@ func TestRunRequested(t *testing.T) {
 		if extra := observedCreatedProwJobs.Difference(testCase.expectedJobs); extra.Len() > 0 {
 			t.Errorf("%s: created unexpected ProwJobs: %s", testCase.name, extra.List())
 		}
+		if !observedWaitingProwJobs.Equal(testCase.expectedWaiting) {
+			t.Errorf("%s: expected ProwJobs %v to be waiting, got %v", testCase.name, testCase.expectedWaiting.List(), observedWaitingProwJobs.List())
+		}
 	}
 }
 
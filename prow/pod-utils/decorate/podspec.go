@@ -36,6 +36,7 @@ import (
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/initupload"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pod-utils/clone"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
@@ -54,6 +55,8 @@ const (
 	toolsMountPath          = "/tools"
 	gcsCredentialsMountName = "gcs-credentials"
 	gcsCredentialsMountPath = "/secrets/gcs"
+	fileStorageMountName    = "file-storage"
+	fileStorageMountPath    = "/buckets"
 )
 
 // Labels returns a string slice with label consts from kube.
@@ -63,12 +66,12 @@ func Labels() []string {
 
 // VolumeMounts returns a string slice with *MountName consts in it.
 func VolumeMounts() []string {
-	return []string{logMountName, codeMountName, toolsMountName, gcsCredentialsMountName}
+	return []string{logMountName, codeMountName, toolsMountName, gcsCredentialsMountName, fileStorageMountName}
 }
 
 // VolumeMountPaths returns a string slice with *MountPath consts in it.
 func VolumeMountPaths() []string {
-	return []string{logMountPath, codeMountPath, toolsMountPath, gcsCredentialsMountPath}
+	return []string{logMountPath, codeMountPath, toolsMountPath, gcsCredentialsMountPath, fileStorageMountPath}
 }
 
 // LabelsAndAnnotationsForSpec returns a minimal set of labels to add to prowjobs or its owned resources.
@@ -416,7 +419,39 @@ func PlaceEntrypoint(image string, toolsMount coreapi.VolumeMount) coreapi.Conta
 	}
 }
 
+// GCSOptions returns the volume that the utilities upload with, its mount
+// and their options. Buckets in GCS and S3 are accessed with the credentials
+// in the secret, while file buckets are the persistent volume claim of the
+// same name.
 func GCSOptions(dc prowapi.DecorationConfig) (coreapi.Volume, coreapi.VolumeMount, gcsupload.Options) {
+	opt := gcsupload.Options{
+		// TODO: pass the artifact dir here too once we figure that out
+		GCSConfiguration: dc.GCSConfiguration,
+		DryRun:           false,
+	}
+	// The bucket is validated with the configuration. Without a
+	// configuration the utilities fall back to GCS.
+	var scheme, name string
+	if dc.GCSConfiguration != nil {
+		scheme, name, _ = objectstore.ParseBucket(dc.GCSConfiguration.Bucket)
+	}
+	if scheme == objectstore.File {
+		vol := coreapi.Volume{
+			Name: fileStorageMountName,
+			VolumeSource: coreapi.VolumeSource{
+				PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{
+					ClaimName: name,
+				},
+			},
+		}
+		mount := coreapi.VolumeMount{
+			Name:      vol.Name,
+			MountPath: path.Join(fileStorageMountPath, name),
+		}
+		opt.FileStorageRoot = fileStorageMountPath
+		return vol, mount, opt
+	}
+
 	vol := coreapi.Volume{
 		Name: gcsCredentialsMountName,
 		VolumeSource: coreapi.VolumeSource{
@@ -429,11 +464,10 @@ func GCSOptions(dc prowapi.DecorationConfig) (coreapi.Volume, coreapi.VolumeMoun
 		Name:      vol.Name,
 		MountPath: gcsCredentialsMountPath,
 	}
-	opt := gcsupload.Options{
-		// TODO: pass the artifact dir here too once we figure that out
-		GCSConfiguration:   dc.GCSConfiguration,
-		GcsCredentialsFile: fmt.Sprintf("%s/service-account.json", mount.MountPath),
-		DryRun:             false,
+	if scheme == objectstore.S3 {
+		opt.S3CredentialsFile = fmt.Sprintf("%s/s3-credentials.json", mount.MountPath)
+	} else {
+		opt.GcsCredentialsFile = fmt.Sprintf("%s/service-account.json", mount.MountPath)
 	}
 
 	return vol, mount, opt
@@ -1812,3 +1812,90 @@ func TestProwJobToPod(t *testing.T) {
 		})
 	}
 }
+
+func TestGCSOptions(t *testing.T) {
+	testCases := []struct {
+		name            string
+		bucket          string
+		noConfiguration bool
+		expectedVolume  coreapi.Volume
+		expectedMount   coreapi.VolumeMount
+		expectedGCS     string
+		expectedS3      string
+		expectedFileDir string
+	}{
+		{
+			name:   "GCS bucket uses the service account",
+			bucket: "my-bucket",
+			expectedVolume: coreapi.Volume{
+				Name: "gcs-credentials",
+				VolumeSource: coreapi.VolumeSource{
+					Secret: &coreapi.SecretVolumeSource{SecretName: "secret-name"},
+				},
+			},
+			expectedMount: coreapi.VolumeMount{Name: "gcs-credentials", MountPath: "/secrets/gcs"},
+			expectedGCS:   "/secrets/gcs/service-account.json",
+		},
+		{
+			name:   "S3 bucket uses the S3 credentials",
+			bucket: "s3://my-bucket",
+			expectedVolume: coreapi.Volume{
+				Name: "gcs-credentials",
+				VolumeSource: coreapi.VolumeSource{
+					Secret: &coreapi.SecretVolumeSource{SecretName: "secret-name"},
+				},
+			},
+			expectedMount: coreapi.VolumeMount{Name: "gcs-credentials", MountPath: "/secrets/gcs"},
+			expectedS3:    "/secrets/gcs/s3-credentials.json",
+		},
+		{
+			name:   "file bucket mounts the claim of the same name",
+			bucket: "file://my-bucket",
+			expectedVolume: coreapi.Volume{
+				Name: "file-storage",
+				VolumeSource: coreapi.VolumeSource{
+					PersistentVolumeClaim: &coreapi.PersistentVolumeClaimVolumeSource{ClaimName: "my-bucket"},
+				},
+			},
+			expectedMount:   coreapi.VolumeMount{Name: "file-storage", MountPath: "/buckets/my-bucket"},
+			expectedFileDir: "/buckets",
+		},
+		{
+			name:            "no configuration uses the service account",
+			noConfiguration: true,
+			expectedVolume: coreapi.Volume{
+				Name: "gcs-credentials",
+				VolumeSource: coreapi.VolumeSource{
+					Secret: &coreapi.SecretVolumeSource{SecretName: "secret-name"},
+				},
+			},
+			expectedMount: coreapi.VolumeMount{Name: "gcs-credentials", MountPath: "/secrets/gcs"},
+			expectedGCS:   "/secrets/gcs/service-account.json",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dc := prowapi.DecorationConfig{GCSCredentialsSecret: "secret-name"}
+			if !tc.noConfiguration {
+				dc.GCSConfiguration = &prowapi.GCSConfiguration{Bucket: tc.bucket}
+			}
+			vol, mount, opt := GCSOptions(dc)
+			if !equality.Semantic.DeepEqual(vol, tc.expectedVolume) {
+				t.Errorf("unexpected volume diff:\n%s", diff.ObjectReflectDiff(tc.expectedVolume, vol))
+			}
+			if !equality.Semantic.DeepEqual(mount, tc.expectedMount) {
+				t.Errorf("unexpected mount diff:\n%s", diff.ObjectReflectDiff(tc.expectedMount, mount))
+			}
+			if opt.GcsCredentialsFile != tc.expectedGCS || opt.S3CredentialsFile != tc.expectedS3 || opt.FileStorageRoot != tc.expectedFileDir {
+				t.Errorf("expected credentials %q, %q and root %q, got %q, %q and %q", tc.expectedGCS, tc.expectedS3, tc.expectedFileDir, opt.GcsCredentialsFile, opt.S3CredentialsFile, opt.FileStorageRoot)
+			}
+			if tc.noConfiguration {
+				if opt.GCSConfiguration != nil {
+					t.Errorf("expected no configuration, got %v", opt.GCSConfiguration)
+				}
+			} else if opt.Bucket != tc.bucket {
+				t.Errorf("expected bucket %s, got %s", tc.bucket, opt.Bucket)
+			}
+		})
+	}
+}
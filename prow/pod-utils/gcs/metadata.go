@@ -17,6 +17,10 @@ limitations under the License.
 package gcs
 
 import (
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/testgrid/metadata"
 )
 
@@ -27,3 +31,37 @@ type Started = metadata.Started
 
 // Finished holds finished.json data
 type Finished = metadata.Finished
+
+// PodInfoFile is the name of the file that plank records
+// the pod of a completed job in
+const PodInfoFile = "podinfo.json"
+
+// PodInfo holds podinfo.json data: the pod of a job as it was
+// when the job completed, and the events the cluster recorded
+// about it
+type PodInfo struct {
+	Pod       *PodSummary            `json:"pod,omitempty"`
+	Events    []coreapi.Event        `json:"events,omitempty"`
+	Resources *prowapi.ResourceUsage `json:"resources,omitempty"`
+}
+
+// PodSummary is the part of a pod that is recorded with the
+// artifacts of its job. The spec is left out, as its environment,
+// arguments and volumes may hold secrets and artifacts are often
+// public.
+type PodSummary struct {
+	Name              string            `json:"name"`
+	NodeName          string            `json:"node_name,omitempty"`
+	CreationTimestamp metav1.Time       `json:"creation_timestamp"`
+	Status            coreapi.PodStatus `json:"status"`
+}
+
+// NewPodSummary summarizes the pod.
+func NewPodSummary(pod *coreapi.Pod) *PodSummary {
+	return &PodSummary{
+		Name:              pod.Name,
+		NodeName:          pod.Spec.NodeName,
+		CreationTimestamp: pod.CreationTimestamp,
+		Status:            pod.Status,
+	}
+}
@@ -23,32 +23,32 @@ import (
 	"os"
 	"sync"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/errorutil"
+	"k8s.io/test-infra/prow/objectstore"
 )
 
-// UploadFunc knows how to upload into an object
-type UploadFunc func(obj *storage.ObjectHandle) error
+// UploadFunc knows how to upload into the named
+// object in a bucket
+type UploadFunc func(bucket objectstore.Bucket, name string) error
 
 // Upload uploads all of the data in the
-// uploadTargets map to GCS in parallel. The map is
-// keyed on GCS path under the bucket
-func Upload(bucket *storage.BucketHandle, uploadTargets map[string]UploadFunc) error {
+// uploadTargets map to the bucket in parallel.
+// The map is keyed on path under the bucket
+func Upload(bucket objectstore.Bucket, uploadTargets map[string]UploadFunc) error {
 	errCh := make(chan error, len(uploadTargets))
 	group := &sync.WaitGroup{}
 	group.Add(len(uploadTargets))
 	for dest, upload := range uploadTargets {
-		obj := bucket.Object(dest)
 		logrus.WithField("dest", dest).Info("Queued for upload")
-		go func(f UploadFunc, obj *storage.ObjectHandle, name string) {
+		go func(f UploadFunc, name string) {
 			defer group.Done()
-			if err := f(obj); err != nil {
+			if err := f(bucket, name); err != nil {
 				errCh <- err
 			}
 			logrus.WithField("dest", name).Info("Finished upload")
-		}(upload, obj, dest)
+		}(upload, dest)
 	}
 	group.Wait()
 	close(errCh)
@@ -64,15 +64,15 @@ func Upload(bucket *storage.BucketHandle, uploadTargets map[string]UploadFunc) e
 }
 
 // FileUpload returns an UploadFunc which copies all
-// data from the file on disk to the GCS object
+// data from the file on disk to the object
 func FileUpload(file string) UploadFunc {
-	return func(obj *storage.ObjectHandle) error {
+	return func(bucket objectstore.Bucket, name string) error {
 		reader, err := os.Open(file)
 		if err != nil {
 			return err
 		}
 
-		uploadErr := DataUpload(reader)(obj)
+		uploadErr := DataUpload(reader)(bucket, name)
 		closeErr := reader.Close()
 
 		return errorutil.NewAggregate(uploadErr, closeErr)
@@ -80,24 +80,20 @@ func FileUpload(file string) UploadFunc {
 }
 
 // DataUpload returns an UploadFunc which copies all
-// data from src reader into GCS
+// data from src reader into the object
 func DataUpload(src io.Reader) UploadFunc {
-	return func(obj *storage.ObjectHandle) error {
-		writer := obj.NewWriter(context.Background())
-		_, copyErr := io.Copy(writer, src)
-		closeErr := writer.Close()
-
-		return errorutil.NewAggregate(copyErr, closeErr)
-	}
+	return DataUploadWithMetadata(src, nil)
 }
 
 // DataUploadWithMetadata returns an UploadFunc which copies all
-// data from src reader into GCS and also sets the provided metadata
-// fields onto the object.
+// data from src reader into the object and also sets the provided
+// metadata fields onto the object.
 func DataUploadWithMetadata(src io.Reader, metadata map[string]string) UploadFunc {
-	return func(obj *storage.ObjectHandle) error {
-		writer := obj.NewWriter(context.Background())
-		writer.Metadata = metadata
+	return func(bucket objectstore.Bucket, name string) error {
+		writer, err := bucket.NewWriter(context.Background(), name, metadata)
+		if err != nil {
+			return err
+		}
 		_, copyErr := io.Copy(writer, src)
 		closeErr := writer.Close()
 
@@ -22,7 +22,7 @@ import (
 	"sync"
 	"testing"
 
-	"cloud.google.com/go/storage"
+	"k8s.io/test-infra/prow/objectstore"
 )
 
 func TestUploadToGcs(t *testing.T) {
@@ -68,12 +68,12 @@ func TestUploadToGcs(t *testing.T) {
 			count = count + 1
 		}
 
-		fail := func(obj *storage.ObjectHandle) error {
+		fail := func(bucket objectstore.Bucket, name string) error {
 			update()
 			return errors.New("fail")
 		}
 
-		success := func(obj *storage.ObjectHandle) error {
+		success := func(bucket objectstore.Bucket, name string) error {
 			update()
 			return nil
 		}
@@ -87,7 +87,7 @@ func TestUploadToGcs(t *testing.T) {
 			targets[fmt.Sprintf("fail-%d", i)] = fail
 		}
 
-		err := Upload(&storage.BucketHandle{}, targets)
+		err := Upload(nil, targets)
 		if err != nil && !testCase.expectedErr {
 			t.Errorf("%s: expected no error but got %v", testCase.name, err)
 		}
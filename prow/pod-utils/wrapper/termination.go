@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wrapper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// TerminationMessagePath is the file Kubernetes reads the termination
+// message of a container from unless the container overrides it.
+const TerminationMessagePath = "/dev/termination-log"
+
+// RecordFailureReason writes the reason the job failed as the termination
+// message of the calling container so that the ProwJob controller can
+// read it back from the pod status.
+func RecordFailureReason(path string, reason prowapi.FailureReason) error {
+	return ioutil.WriteFile(path, []byte(reason), 0644)
+}
+
+// Usage describes the resources a wrapped process consumed.
+type Usage struct {
+	// CPUMillis is the user and system CPU time of the process.
+	CPUMillis int64 `json:"cpu_millis"`
+	// MaxMemoryBytes is the peak resident memory of the process.
+	MaxMemoryBytes int64 `json:"max_memory_bytes"`
+}
+
+// RecordUsage writes the resources the wrapped process used as
+// the termination message of the calling container.
+func RecordUsage(path string, usage Usage) error {
+	content, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// ParseUsage reads back the usage recorded by RecordUsage from the
+// termination message of a container, if it holds one.
+func ParseUsage(message string) (Usage, bool) {
+	var usage Usage
+	if err := json.Unmarshal([]byte(message), &usage); err != nil {
+		return Usage{}, false
+	}
+	return usage, true
+}
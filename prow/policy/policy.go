@@ -0,0 +1,283 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates the pod specs of jobs against rules that an
+// organization declares, both for the job configuration and for the
+// ProwJobs created in the cluster.
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/errorutil"
+)
+
+// Policy is a set of rules the pod specs of jobs must follow.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule constrains the pod specs of the jobs it applies to. A rule
+// may combine several constraints, all of which must be satisfied.
+type Rule struct {
+	// Name identifies the rule in error messages.
+	Name string `json:"name"`
+	// Message explains the rule to the authors of violating jobs.
+	Message string `json:"message,omitempty"`
+	// Repos restricts the rule to jobs for these orgs or org/repos.
+	// The rule applies to all jobs when empty.
+	Repos []string `json:"repos,omitempty"`
+	// ExemptRepos are orgs or org/repos whose jobs the rule ignores.
+	ExemptRepos []string `json:"exempt_repos,omitempty"`
+
+	// ForbidPrivileged rejects privileged containers.
+	ForbidPrivileged bool `json:"forbid_privileged,omitempty"`
+	// RequiredRequests are resources every container must request.
+	RequiredRequests []corev1.ResourceName `json:"required_requests,omitempty"`
+	// AllowedRegistries are the registries, or repositories within
+	// them, that every image must be pulled from.
+	AllowedRegistries []string `json:"allowed_registries,omitempty"`
+	// ForbidHostPath rejects hostPath volumes.
+	ForbidHostPath bool `json:"forbid_host_path,omitempty"`
+	// MaxTimeoutString compiles into MaxTimeout at load time.
+	MaxTimeoutString string `json:"max_timeout,omitempty"`
+	// MaxTimeout is the longest timeout decorated jobs may set.
+	MaxTimeout time.Duration `json:"-"`
+}
+
+// Job describes what the rules are evaluated against, either a
+// job from the configuration or a ProwJob.
+type Job struct {
+	Name string
+	// Org and Repo the job runs for, both empty for jobs without refs.
+	Org  string
+	Repo string
+	Spec *corev1.PodSpec
+	// Timeout is the timeout of the test process of decorated jobs.
+	Timeout time.Duration
+}
+
+// Load reads and validates the policy at path.
+func Load(path string) (*Policy, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	var p Policy
+	if err := yaml.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("error unmarshaling %s: %v", path, err)
+	}
+	if err := p.finalize(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Policy) finalize() error {
+	names := sets.NewString()
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i)
+		}
+		if names.Has(rule.Name) {
+			return fmt.Errorf("rule %s is declared more than once", rule.Name)
+		}
+		names.Insert(rule.Name)
+		if rule.MaxTimeoutString != "" {
+			timeout, err := time.ParseDuration(rule.MaxTimeoutString)
+			if err != nil {
+				return fmt.Errorf("rule %s has an invalid max_timeout: %v", rule.Name, err)
+			}
+			rule.MaxTimeout = timeout
+		}
+		if !rule.ForbidPrivileged && !rule.ForbidHostPath && rule.MaxTimeout == 0 &&
+			len(rule.RequiredRequests) == 0 && len(rule.AllowedRegistries) == 0 {
+			return fmt.Errorf("rule %s does not constrain anything", rule.Name)
+		}
+	}
+	return nil
+}
+
+// Validate evaluates the job against every rule that applies to it.
+func (p *Policy) Validate(job Job) error {
+	var errs []error
+	for _, rule := range p.Rules {
+		if !rule.appliesTo(job) {
+			continue
+		}
+		for _, violation := range rule.violations(job) {
+			msg := violation
+			if rule.Message != "" {
+				msg = fmt.Sprintf("%s (%s)", rule.Message, violation)
+			}
+			errs = append(errs, fmt.Errorf("job %s violates rule %s: %s", job.Name, rule.Name, msg))
+		}
+	}
+	return errorutil.NewAggregate(errs...)
+}
+
+// ValidateJobConfig evaluates every job in the configuration.
+func (p *Policy) ValidateJobConfig(c config.JobConfig) error {
+	var errs []error
+	for orgRepo, presubmits := range c.Presubmits {
+		for _, presubmit := range presubmits {
+			errs = append(errs, p.Validate(configJob(presubmit.JobBase, orgRepo, nil)))
+		}
+	}
+	for orgRepo, postsubmits := range c.Postsubmits {
+		for _, postsubmit := range postsubmits {
+			errs = append(errs, p.Validate(configJob(postsubmit.JobBase, orgRepo, nil)))
+		}
+	}
+	for _, periodic := range c.Periodics {
+		errs = append(errs, p.Validate(configJob(periodic.JobBase, "", periodic.ExtraRefs)))
+	}
+	return errorutil.NewAggregate(errs...)
+}
+
+// ValidateProwJob evaluates a ProwJob, typically on its creation.
+func (p *Policy) ValidateProwJob(pj prowapi.ProwJob) error {
+	job := Job{
+		Name: pj.Spec.Job,
+		Spec: pj.Spec.PodSpec,
+	}
+	if refs := pj.Spec.Refs; refs != nil {
+		job.Org, job.Repo = refs.Org, refs.Repo
+	} else if len(pj.Spec.ExtraRefs) > 0 {
+		job.Org, job.Repo = pj.Spec.ExtraRefs[0].Org, pj.Spec.ExtraRefs[0].Repo
+	}
+	if pj.Spec.DecorationConfig != nil {
+		job.Timeout = pj.Spec.DecorationConfig.Timeout
+	}
+	return p.Validate(job)
+}
+
+func configJob(base config.JobBase, orgRepo string, extraRefs []prowapi.Refs) Job {
+	job := Job{
+		Name: base.Name,
+		Spec: base.Spec,
+	}
+	if parts := strings.SplitN(orgRepo, "/", 2); len(parts) == 2 {
+		job.Org, job.Repo = parts[0], parts[1]
+	} else if len(extraRefs) > 0 {
+		job.Org, job.Repo = extraRefs[0].Org, extraRefs[0].Repo
+	}
+	if base.DecorationConfig != nil {
+		job.Timeout = base.DecorationConfig.Timeout
+	}
+	return job
+}
+
+// appliesTo determines if the rule covers the org and repo of the job.
+func (r *Rule) appliesTo(job Job) bool {
+	matches := func(repos []string) bool {
+		for _, repo := range repos {
+			if job.Org != "" && (repo == job.Org || repo == job.Org+"/"+job.Repo) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(r.Repos) > 0 && !matches(r.Repos) {
+		return false
+	}
+	return !matches(r.ExemptRepos)
+}
+
+// violations lists how the job breaks the constraints of the rule.
+func (r *Rule) violations(job Job) []string {
+	var violations []string
+	if r.MaxTimeout != 0 && job.Timeout > r.MaxTimeout {
+		violations = append(violations, fmt.Sprintf("timeout %s exceeds %s", job.Timeout, r.MaxTimeout))
+	}
+	if job.Spec == nil {
+		return violations
+	}
+
+	if r.ForbidHostPath {
+		for _, volume := range job.Spec.Volumes {
+			if volume.HostPath != nil {
+				violations = append(violations, fmt.Sprintf("volume %q mounts host path %s", volume.Name, volume.HostPath.Path))
+			}
+		}
+	}
+	var containers []corev1.Container
+	containers = append(containers, job.Spec.InitContainers...)
+	containers = append(containers, job.Spec.Containers...)
+	for _, container := range containers {
+		name := container.Name
+		if name == "" {
+			name = container.Image
+		}
+		if r.ForbidPrivileged {
+			if sc := container.SecurityContext; sc != nil && sc.Privileged != nil && *sc.Privileged {
+				violations = append(violations, fmt.Sprintf("container %q is privileged", name))
+			}
+		}
+		if len(r.AllowedRegistries) > 0 && !allowedImage(container.Image, r.AllowedRegistries) {
+			violations = append(violations, fmt.Sprintf("container %q uses image %s from a registry that is not allowed", name, container.Image))
+		}
+		var missing []string
+		for _, resource := range r.RequiredRequests {
+			if _, ok := container.Resources.Requests[resource]; !ok {
+				missing = append(missing, string(resource))
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			violations = append(violations, fmt.Sprintf("container %q does not request %s", name, strings.Join(missing, ", ")))
+		}
+	}
+	return violations
+}
+
+// allowedImage determines if the image is pulled from one of the registries.
+// Images without a registry are pulled from Docker Hub, which needs to be
+// allowed as docker.io.
+func allowedImage(image string, registries []string) bool {
+	image = qualifiedImage(image)
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(registry, "/")
+		if strings.HasPrefix(image, registry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedImage prefixes the image with the Docker Hub registry unless
+// its first component names a registry.
+func qualifiedImage(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return image
+	}
+	if len(parts) == 1 {
+		return "docker.io/library/" + image
+	}
+	return "docker.io/" + image
+}
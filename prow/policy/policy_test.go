@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/errorutil"
+)
+
+func TestFinalize(t *testing.T) {
+	var testcases = []struct {
+		name        string
+		policy      string
+		expectedErr bool
+	}{
+		{
+			name: "valid policy",
+			policy: `rules:
+- name: no-privileged
+  forbid_privileged: true
+- name: max-timeout
+  max_timeout: 2h`,
+		},
+		{
+			name: "unnamed rule",
+			policy: `rules:
+- forbid_privileged: true`,
+			expectedErr: true,
+		},
+		{
+			name: "duplicate rule",
+			policy: `rules:
+- name: no-privileged
+  forbid_privileged: true
+- name: no-privileged
+  forbid_host_path: true`,
+			expectedErr: true,
+		},
+		{
+			name: "invalid timeout",
+			policy: `rules:
+- name: max-timeout
+  max_timeout: forever`,
+			expectedErr: true,
+		},
+		{
+			name: "rule without constraints",
+			policy: `rules:
+- name: nothing
+  repos: [org]`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p Policy
+			if err := yaml.Unmarshal([]byte(tc.policy), &p); err != nil {
+				t.Fatalf("failed to unmarshal policy: %v", err)
+			}
+			if err := p.finalize(); (err != nil) != tc.expectedErr {
+				t.Errorf("expected error: %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	privileged := true
+	p := Policy{Rules: []Rule{
+		{
+			Name:             "no-privileged",
+			Message:          "only test-infra may run privileged containers",
+			ExemptRepos:      []string{"kubernetes/test-infra"},
+			ForbidPrivileged: true,
+		},
+		{
+			Name:             "requests",
+			Repos:            []string{"kubernetes"},
+			RequiredRequests: []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory},
+		},
+		{
+			Name:              "registries",
+			AllowedRegistries: []string{"gcr.io/k8s-testimages", "docker.io/library"},
+		},
+		{
+			Name:           "no-host-path",
+			ForbidHostPath: true,
+		},
+		{
+			Name:       "timeout",
+			MaxTimeout: 2 * time.Hour,
+		},
+	}}
+
+	var testcases = []struct {
+		name     string
+		job      Job
+		expected []string
+	}{
+		{
+			name: "compliant job",
+			job: Job{
+				Name: "unit", Org: "kubernetes", Repo: "kubernetes",
+				Spec: &corev1.PodSpec{Containers: []corev1.Container{{
+					Name:  "test",
+					Image: "gcr.io/k8s-testimages/kubekins-e2e:latest",
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("1"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					}},
+				}}},
+				Timeout: time.Hour,
+			},
+		},
+		{
+			name: "privileged container in an exempt repo",
+			job: Job{
+				Name: "e2e", Org: "kubernetes", Repo: "test-infra",
+				Spec: &corev1.PodSpec{Containers: []corev1.Container{{
+					Name:            "test",
+					Image:           "golang:1.11",
+					SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("1"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					}},
+				}}},
+			},
+		},
+		{
+			name: "every rule is violated",
+			job: Job{
+				Name: "e2e", Org: "kubernetes", Repo: "kubernetes",
+				Spec: &corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:            "test",
+						Image:           "quay.io/someone/image",
+						SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+					}},
+					Volumes: []corev1.Volume{{
+						Name:         "docker",
+						VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}},
+					}},
+				},
+				Timeout: 4 * time.Hour,
+			},
+			expected: []string{
+				`job e2e violates rule no-host-path: volume "docker" mounts host path /var/run/docker.sock`,
+				`job e2e violates rule no-privileged: only test-infra may run privileged containers (container "test" is privileged)`,
+				`job e2e violates rule registries: container "test" uses image quay.io/someone/image from a registry that is not allowed`,
+				`job e2e violates rule requests: container "test" does not request cpu, memory`,
+				`job e2e violates rule timeout: timeout 4h0m0s exceeds 2h0m0s`,
+			},
+		},
+		{
+			name: "rules restricted to other orgs do not apply",
+			job: Job{
+				Name: "unit", Org: "other", Repo: "repo",
+				Spec: &corev1.PodSpec{Containers: []corev1.Container{{Name: "test", Image: "ubuntu"}}},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			if err := p.Validate(tc.job); err != nil {
+				actual = err.(errorutil.Aggregate).Strings()
+			}
+			sort.Strings(actual)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected violations %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateProwJobAndJobConfig(t *testing.T) {
+	p := Policy{Rules: []Rule{{Name: "timeout", Repos: []string{"org/repo"}, MaxTimeout: time.Hour}}}
+	decoration := &prowapi.DecorationConfig{Timeout: 2 * time.Hour}
+
+	pj := prowapi.ProwJob{Spec: prowapi.ProwJobSpec{
+		Job:              "hand-crafted",
+		Refs:             &prowapi.Refs{Org: "org", Repo: "repo"},
+		DecorationConfig: decoration,
+	}}
+	if err := p.ValidateProwJob(pj); err == nil {
+		t.Error("expected the ProwJob to violate the policy")
+	}
+
+	periodic := config.Periodic{JobBase: config.JobBase{Name: "periodic"}}
+	periodic.DecorationConfig = decoration
+	periodic.ExtraRefs = []prowapi.Refs{{Org: "org", Repo: "repo"}}
+	presubmit := config.Presubmit{JobBase: config.JobBase{Name: "presubmit"}}
+	presubmit.DecorationConfig = decoration
+	c := config.JobConfig{
+		Presubmits: map[string][]config.Presubmit{"org/other": {presubmit}},
+		Periodics:  []config.Periodic{periodic},
+	}
+	err := p.ValidateJobConfig(c)
+	if err == nil {
+		t.Fatal("expected the periodic to violate the policy")
+	}
+	if violations := err.(errorutil.Aggregate).Strings(); len(violations) != 1 {
+		t.Errorf("expected only the periodic to violate the policy, got %q", violations)
+	}
+}
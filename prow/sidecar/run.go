@@ -31,6 +31,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/entrypoint"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
@@ -41,10 +42,11 @@ func nameEntry(idx int, opt wrapper.Options) string {
 	return fmt.Sprintf("entry %d: %s", idx, strings.Join(opt.Args, " "))
 }
 
-func wait(ctx context.Context, entries []wrapper.Options) (bool, bool, int) {
+func wait(ctx context.Context, entries []wrapper.Options) (bool, bool, int, prowapi.FailureReason) {
 	passed := true
 	var aborted bool
 	var failures int
+	var reason prowapi.FailureReason
 
 	for _, opt := range entries {
 		returnCode, err := wrapper.WaitForMarker(ctx, opt.MarkerFile)
@@ -53,8 +55,27 @@ func wait(ctx context.Context, entries []wrapper.Options) (bool, bool, int) {
 		if returnCode != 0 && returnCode != entrypoint.PreviousErrorCode {
 			failures++
 		}
+		if reason == "" {
+			reason = failureReason(returnCode, err)
+		}
+	}
+	return passed, aborted, failures, reason
+}
+
+// failureReason classifies the outcome of an entry from its marker.
+func failureReason(returnCode int, err error) prowapi.FailureReason {
+	switch {
+	case err != nil:
+		return prowapi.InfraErrorReason
+	case returnCode == 0, returnCode == entrypoint.AbortedErrorCode, returnCode == entrypoint.PreviousErrorCode:
+		return ""
+	case returnCode == entrypoint.TimeoutErrorCode:
+		return prowapi.TimeoutReason
+	case returnCode == entrypoint.InternalErrorCode:
+		return prowapi.InfraErrorReason
+	default:
+		return prowapi.TestFailureReason
 	}
-	return passed, aborted, failures
 }
 
 // Run will watch for the process being wrapped to exit
@@ -90,7 +111,7 @@ func (o Options) Run(ctx context.Context) (int, error) {
 		logrus.Warnf("Using deprecated wrapper_options instead of entries. Please update prow/pod-utils/decorate before June 2019")
 	}
 	entries := o.entries()
-	passed, aborted, failures := wait(ctx, entries)
+	passed, aborted, failures, reason := wait(ctx, entries)
 
 	cancel()
 	// If we are being asked to terminate by the kubelet but we have
@@ -102,10 +123,25 @@ func (o Options) Run(ctx context.Context) (int, error) {
 
 	buildLog := logReader(entries)
 	metadata := combineMetadata(entries)
-	return failures, o.doUpload(spec, passed, aborted, metadata, buildLog)
+	if !passed && !aborted {
+		metadata[failureReasonKey] = reason
+	}
+	err = o.doUpload(spec, passed, aborted, metadata, buildLog)
+	if err != nil {
+		reason = prowapi.UploadFailureReason
+	}
+	if reason != "" && !aborted {
+		if err := wrapper.RecordFailureReason(wrapper.TerminationMessagePath, reason); err != nil {
+			logrus.WithError(err).Warn("Could not record failure reason")
+		}
+	}
+	return failures, err
 }
 
-const errorKey = "sidecar-errors"
+const (
+	errorKey         = "sidecar-errors"
+	failureReasonKey = "failure-reason"
+)
 
 func start(part string) string {
 	return fmt.Sprintf("\n==== start of %s log ====\n", part)
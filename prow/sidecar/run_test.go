@@ -28,6 +28,7 @@ import (
 	"strings"
 	"testing"
 
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/entrypoint"
 	"k8s.io/test-infra/prow/pod-utils/wrapper"
 
@@ -39,6 +40,8 @@ import (
 func TestWait(t *testing.T) {
 	aborted := strconv.Itoa(entrypoint.AbortedErrorCode)
 	skip := strconv.Itoa(entrypoint.PreviousErrorCode)
+	timeout := strconv.Itoa(entrypoint.TimeoutErrorCode)
+	internal := strconv.Itoa(entrypoint.InternalErrorCode)
 	const (
 		pass = "0"
 		fail = "1"
@@ -51,6 +54,7 @@ func TestWait(t *testing.T) {
 		accessDenied bool
 		missing      bool
 		failures     int
+		reason       prowapi.FailureReason
 	}{
 		{
 			name:    "pass, not abort when 1 item passes",
@@ -66,11 +70,13 @@ func TestWait(t *testing.T) {
 			name:     "fail, not abort when 1 item fails",
 			markers:  []string{fail},
 			failures: 1,
+			reason:   prowapi.TestFailureReason,
 		},
 		{
 			name:     "fail when any item fails",
 			markers:  []string{pass, fail, pass},
 			failures: 1,
+			reason:   prowapi.TestFailureReason,
 		},
 		{
 			name:     "abort and fail when 1 item aborts",
@@ -83,23 +89,39 @@ func TestWait(t *testing.T) {
 			markers:  []string{pass, aborted, fail},
 			abort:    true,
 			failures: 2,
+			reason:   prowapi.TestFailureReason,
 		},
 		{
 			name:     "fail when marker cannot be read",
 			markers:  []string{pass, "not-an-exit-code", pass},
 			failures: 1,
+			reason:   prowapi.InfraErrorReason,
 		},
 		{
 			name:     "fail when marker does not exist",
 			markers:  []string{pass},
 			missing:  true,
 			failures: 1,
+			reason:   prowapi.InfraErrorReason,
 		},
 		{
 			name:     "count all failures",
 			markers:  []string{pass, fail, aborted, skip, fail, pass},
 			abort:    true,
 			failures: 3,
+			reason:   prowapi.TestFailureReason,
+		},
+		{
+			name:     "time out when an item times out",
+			markers:  []string{pass, timeout, skip},
+			failures: 1,
+			reason:   prowapi.TimeoutReason,
+		},
+		{
+			name:     "report the reason of the first failure",
+			markers:  []string{internal, fail},
+			failures: 2,
+			reason:   prowapi.InfraErrorReason,
 		},
 	}
 
@@ -133,7 +155,7 @@ func TestWait(t *testing.T) {
 				go cancel()
 			}
 
-			pass, abort, failures := wait(ctx, entries)
+			pass, abort, failures, reason := wait(ctx, entries)
 			cancel()
 			if pass != tc.pass {
 				t.Errorf("expected pass %t != actual %t", tc.pass, pass)
@@ -144,6 +166,9 @@ func TestWait(t *testing.T) {
 			if failures != tc.failures {
 				t.Errorf("expected failures %d != actual %d", tc.failures, failures)
 			}
+			if reason != tc.reason {
+				t.Errorf("expected reason %q != actual %q", tc.reason, reason)
+			}
 		})
 	}
 }
@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reporter contains helpers for reporting prowjob
+// state transitions to Slack channels.
+package reporter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"text/template"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+const reporterName = "slackreporter"
+
+type slackClient interface {
+	WriteMessage(text, channel string) error
+}
+
+// Client is a reporter client fed to crier controller
+type Client struct {
+	client slackClient
+	config config.Getter
+}
+
+// NewReporter creates a new Slack reporter
+func NewReporter(client slackClient, cfg config.Getter) *Client {
+	return &Client{
+		client: client,
+		config: cfg,
+	}
+}
+
+// GetName returns the name of the reporter
+func (c *Client) GetName() string {
+	return reporterName
+}
+
+// ShouldReport tells if a prowjob should be reported by this reporter
+func (c *Client) ShouldReport(pj *prowapi.ProwJob) bool {
+	if pj.Spec.ReporterConfig == nil || pj.Spec.ReporterConfig.Slack == nil {
+		return false
+	}
+	slackConfig := pj.Spec.ReporterConfig.Slack
+	if slackConfig.Channel == "" {
+		return false
+	}
+
+	cfg := c.config().SlackReporter
+	if len(cfg.JobTypesToReport) > 0 && !hasJobType(cfg.JobTypesToReport, pj.Spec.Type) {
+		return false
+	}
+	states := cfg.JobStatesToReport
+	if len(slackConfig.JobStatesToReport) > 0 {
+		states = slackConfig.JobStatesToReport
+	}
+	return hasState(states, pj.Status.State)
+}
+
+// Report posts a message generated from the report template
+// to the Slack channel the prowjob is configured to report to
+func (c *Client) Report(pj *prowapi.ProwJob) error {
+	message, err := c.generateMessageFromPJ(pj)
+	if err != nil {
+		return err
+	}
+	if err := c.client.WriteMessage(message, pj.Spec.ReporterConfig.Slack.Channel); err != nil {
+		return fmt.Errorf("failed to write slack message: %v", err)
+	}
+	return nil
+}
+
+func (c *Client) generateMessageFromPJ(pj *prowapi.ProwJob) (string, error) {
+	tmpl := c.config().SlackReporter.ReportTemplate
+	if reportTemplate := pj.Spec.ReporterConfig.Slack.ReportTemplate; reportTemplate != "" {
+		var err error
+		if tmpl, err = template.New("SlackReport").Parse(reportTemplate); err != nil {
+			return "", fmt.Errorf("failed to parse report template: %v", err)
+		}
+	}
+	if tmpl == nil {
+		return "", errors.New("no report template is configured")
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, pj); err != nil {
+		return "", fmt.Errorf("failed to execute report template: %v", err)
+	}
+	return b.String(), nil
+}
+
+func hasJobType(types []prowapi.ProwJobType, jobType prowapi.ProwJobType) bool {
+	for _, t := range types {
+		if t == jobType {
+			return true
+		}
+	}
+	return false
+}
+
+func hasState(states []prowapi.ProwJobState, state prowapi.ProwJobState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
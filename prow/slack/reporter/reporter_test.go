@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reporter
+
+import (
+	"testing"
+	"text/template"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+type fakeSlackClient struct {
+	messages map[string][]string
+}
+
+func (f *fakeSlackClient) WriteMessage(text, channel string) error {
+	if f.messages == nil {
+		f.messages = map[string][]string{}
+	}
+	f.messages[channel] = append(f.messages[channel], text)
+	return nil
+}
+
+func testConfig(types []prowapi.ProwJobType) config.Getter {
+	return func() *config.Config {
+		return &config.Config{
+			ProwConfig: config.ProwConfig{
+				SlackReporter: config.SlackReporter{
+					JobTypesToReport:  types,
+					JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState},
+					ReportTemplate:    template.Must(template.New("SlackReport").Parse("{{.Spec.Job}}: {{.Status.State}}")),
+				},
+			},
+		}
+	}
+}
+
+func TestShouldReport(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		types    []prowapi.ProwJobType
+		slack    *prowapi.SlackReporterConfig
+		jobType  prowapi.ProwJobType
+		state    prowapi.ProwJobState
+		expected bool
+	}{
+		{
+			name:    "job without slack config is not reported",
+			jobType: prowapi.PeriodicJob,
+			state:   prowapi.FailureState,
+		},
+		{
+			name:     "failed job with a channel is reported",
+			slack:    &prowapi.SlackReporterConfig{Channel: "release"},
+			jobType:  prowapi.PeriodicJob,
+			state:    prowapi.FailureState,
+			expected: true,
+		},
+		{
+			name:    "successful job is not reported by default",
+			slack:   &prowapi.SlackReporterConfig{Channel: "release"},
+			jobType: prowapi.PeriodicJob,
+			state:   prowapi.SuccessState,
+		},
+		{
+			name:     "job may override the states to report",
+			slack:    &prowapi.SlackReporterConfig{Channel: "release", JobStatesToReport: []prowapi.ProwJobState{prowapi.SuccessState}},
+			jobType:  prowapi.PeriodicJob,
+			state:    prowapi.SuccessState,
+			expected: true,
+		},
+		{
+			name:    "job of a type that is not reported",
+			types:   []prowapi.ProwJobType{prowapi.PeriodicJob},
+			slack:   &prowapi.SlackReporterConfig{Channel: "release"},
+			jobType: prowapi.PresubmitJob,
+			state:   prowapi.FailureState,
+		},
+		{
+			name:     "job of a type that is reported",
+			types:    []prowapi.ProwJobType{prowapi.PeriodicJob},
+			slack:    &prowapi.SlackReporterConfig{Channel: "release"},
+			jobType:  prowapi.PeriodicJob,
+			state:    prowapi.ErrorState,
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := &prowapi.ProwJob{
+				Spec:   prowapi.ProwJobSpec{Type: tc.jobType},
+				Status: prowapi.ProwJobStatus{State: tc.state},
+			}
+			if tc.slack != nil {
+				pj.Spec.ReporterConfig = &prowapi.ReporterConfig{Slack: tc.slack}
+			}
+			if actual := NewReporter(&fakeSlackClient{}, testConfig(tc.types)).ShouldReport(pj); actual != tc.expected {
+				t.Errorf("expected ShouldReport to return %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "default template",
+			expected: "ci-release: failure",
+		},
+		{
+			name:     "job template",
+			template: "{{.Spec.Job}} failed, see {{.Status.URL}}",
+			expected: "ci-release failed, see https://prow/ci-release",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := &prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Job:  "ci-release",
+					Type: prowapi.PeriodicJob,
+					ReporterConfig: &prowapi.ReporterConfig{
+						Slack: &prowapi.SlackReporterConfig{Channel: "release", ReportTemplate: tc.template},
+					},
+				},
+				Status: prowapi.ProwJobStatus{
+					State: prowapi.FailureState,
+					URL:   "https://prow/ci-release",
+				},
+			}
+			client := &fakeSlackClient{}
+			if err := NewReporter(client, testConfig(nil)).Report(pj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if messages := client.messages["release"]; len(messages) != 1 || messages[0] != tc.expected {
+				t.Errorf("expected message %q in channel release, got %v", tc.expected, client.messages)
+			}
+		})
+	}
+}
@@ -22,9 +22,9 @@ import (
 	"io"
 	"io/ioutil"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/spyglass/lenses"
 )
 
@@ -39,6 +39,9 @@ type GCSArtifact struct {
 	// The path of the Artifact within the job
 	path string
 
+	// The Spyglass source of the job run the Artifact belongs to
+	runSource string
+
 	// sizeLimit is the max size to read before failing
 	sizeLimit int64
 
@@ -48,7 +51,7 @@ type GCSArtifact struct {
 }
 
 type artifactHandle interface {
-	Attrs(ctx context.Context) (*storage.ObjectAttrs, error)
+	Attributes(ctx context.Context) (*objectstore.Attributes, error)
 	NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error)
 	NewReader(ctx context.Context) (io.ReadCloser, error)
 }
@@ -70,9 +73,14 @@ func fieldsFor(a *GCSArtifact) logrus.Fields {
 	}
 }
 
+// RunSource returns the Spyglass source of the job run the artifact belongs to
+func (a *GCSArtifact) RunSource() string {
+	return a.runSource
+}
+
 // Size returns the size of the artifact in GCS
 func (a *GCSArtifact) Size() (int64, error) {
-	attrs, err := a.handle.Attrs(a.ctx)
+	attrs, err := a.handle.Attributes(a.ctx)
 	if err != nil {
 		return 0, fmt.Errorf("error getting gcs attributes for artifact: %v", err)
 	}
@@ -234,7 +242,7 @@ func (a *GCSArtifact) ReadTail(n int64) ([]byte, error) {
 
 // gzipped returns whether the file is gzip-encoded in GCS
 func (a *GCSArtifact) gzipped() (bool, error) {
-	attrs, err := a.handle.Attrs(a.ctx)
+	attrs, err := a.handle.Attributes(a.ctx)
 	if err != nil {
 		return false, fmt.Errorf("error getting gcs attributes for artifact: %v", err)
 	}
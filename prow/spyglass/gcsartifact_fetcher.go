@@ -27,17 +27,19 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/api/iterator"
 
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/spyglass/lenses"
-	"k8s.io/test-infra/testgrid/util/gcs"
 )
 
 const (
 	httpScheme  = "http"
 	httpsScheme = "https"
+
+	// ArtifactPath serves the artifacts of buckets that have no web
+	// address of their own, like those in S3 or file buckets.
+	ArtifactPath = "/spyglass/artifact/"
 )
 
 var (
@@ -46,26 +48,28 @@ var (
 )
 
 // GCSArtifactFetcher contains information used for fetching artifacts from GCS
+// and the other storage that the objectstore package supports
 type GCSArtifactFetcher struct {
-	client *storage.Client
+	opener *objectstore.Opener
 }
 
-// gcsJobSource is a location in GCS where Prow job-specific artifacts are stored. This implementation assumes
+// gcsJobSource is a location in a bucket where Prow job-specific artifacts are stored. This implementation assumes
 // Prow's native GCS upload format (treating GCS keys as a directory structure), and is not
 // intended to support arbitrary GCS bucket upload formats.
 type gcsJobSource struct {
-	source     string
-	linkPrefix string
-	bucket     string
-	jobPrefix  string
-	jobName    string
-	buildID    string
+	source string
+	// bucket is the path segment of the bucket, like bucket for GCS or s3:bucket for S3
+	bucket    string
+	scheme    string
+	jobPrefix string
+	jobName   string
+	buildID   string
 }
 
-// NewGCSArtifactFetcher creates a new ArtifactFetcher with a real GCS Client
-func NewGCSArtifactFetcher(c *storage.Client) *GCSArtifactFetcher {
+// NewGCSArtifactFetcher creates a new ArtifactFetcher that opens buckets with the opener
+func NewGCSArtifactFetcher(opener *objectstore.Opener) *GCSArtifactFetcher {
 	return &GCSArtifactFetcher{
-		client: c,
+		opener: opener,
 	}
 }
 
@@ -77,29 +81,23 @@ func fieldsForJob(src *gcsJobSource) logrus.Fields {
 
 // newGCSJobSource creates a new gcsJobSource from a given bucket and jobPrefix
 func newGCSJobSource(src string) (*gcsJobSource, error) {
-	gcsURL, err := url.Parse(fmt.Sprintf("gs://%s", src))
-	if err != nil {
+	tokens := strings.FieldsFunc(src, func(c rune) bool { return c == '/' })
+	if len(tokens) < 3 {
 		return &gcsJobSource{}, ErrCannotParseSource
 	}
-	gcsPath := &gcs.Path{}
-	err = gcsPath.SetURL(gcsURL)
+	scheme, _, err := objectstore.ParseBucket(tokens[0])
 	if err != nil {
 		return &gcsJobSource{}, ErrCannotParseSource
 	}
-
-	tokens := strings.FieldsFunc(gcsPath.Object(), func(c rune) bool { return c == '/' })
-	if len(tokens) < 2 {
-		return &gcsJobSource{}, ErrCannotParseSource
-	}
 	buildID := tokens[len(tokens)-1]
 	name := tokens[len(tokens)-2]
 	return &gcsJobSource{
-		source:     src,
-		linkPrefix: "gs://",
-		bucket:     gcsPath.Bucket(),
-		jobPrefix:  path.Clean(gcsPath.Object()) + "/",
-		jobName:    name,
-		buildID:    buildID,
+		source:    src,
+		bucket:    tokens[0],
+		scheme:    scheme,
+		jobPrefix: path.Clean(strings.Join(tokens[1:], "/")) + "/",
+		jobName:   name,
+		buildID:   buildID,
 	}, nil
 }
 
@@ -111,31 +109,26 @@ func (af *GCSArtifactFetcher) artifacts(key string) ([]string, error) {
 	}
 
 	listStart := time.Now()
-	bucketName, prefix := extractBucketPrefixPair(src.jobPath())
-	artifacts := []string{}
-	bkt := af.client.Bucket(bucketName)
-	q := storage.Query{
-		Prefix:   prefix,
-		Versions: false,
+	bkt, err := af.opener.Open(context.Background(), src.bucket)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open bucket %s: %v", src.bucket, err)
 	}
-	objIter := bkt.Objects(context.Background(), &q)
+	var names []string
 	wait := []time.Duration{16, 32, 64, 128, 256, 256, 512, 512}
-	for i := 0; ; {
-		oAttrs, err := objIter.Next()
-		if err == iterator.Done {
+	for i := 0; ; i++ {
+		names, _, err = bkt.List(context.Background(), src.jobPrefix, "")
+		if err == nil {
 			break
 		}
-		if err != nil {
-			logrus.WithFields(fieldsForJob(src)).WithError(err).Error("Error accessing GCS artifact.")
-			if i >= len(wait) {
-				return artifacts, fmt.Errorf("timed out: error accessing GCS artifact: %v", err)
-			}
-			time.Sleep((wait[i] + time.Duration(rand.Intn(10))) * time.Millisecond)
-			i++
-			continue
+		logrus.WithFields(fieldsForJob(src)).WithError(err).Error("Error accessing GCS artifact.")
+		if i >= len(wait) {
+			return []string{}, fmt.Errorf("timed out: error accessing GCS artifact: %v", err)
 		}
-		artifacts = append(artifacts, strings.TrimPrefix(oAttrs.Name, prefix))
-		i = 0
+		time.Sleep((wait[i] + time.Duration(rand.Intn(10))) * time.Millisecond)
+	}
+	artifacts := []string{}
+	for _, name := range names {
+		artifacts = append(artifacts, strings.TrimPrefix(name, src.jobPrefix))
 	}
 	listElapsed := time.Since(listStart)
 	logrus.WithField("duration", listElapsed).Infof("Listed %d artifacts.", len(artifacts))
@@ -143,48 +136,56 @@ func (af *GCSArtifactFetcher) artifacts(key string) ([]string, error) {
 }
 
 type gcsArtifactHandle struct {
-	*storage.ObjectHandle
+	bucket objectstore.Bucket
+	name   string
+}
+
+func (h *gcsArtifactHandle) Attributes(ctx context.Context) (*objectstore.Attributes, error) {
+	return h.bucket.Attributes(ctx, h.name)
 }
 
 func (h *gcsArtifactHandle) NewReader(ctx context.Context) (io.ReadCloser, error) {
-	return h.ObjectHandle.NewReader(ctx)
+	return h.bucket.NewReader(ctx, h.name)
 }
 
 func (h *gcsArtifactHandle) NewRangeReader(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
-	return h.ObjectHandle.NewRangeReader(ctx, offset, length)
+	return h.bucket.NewRangeReader(ctx, h.name, offset, length)
 }
 
-// Artifact constructs a GCS artifact from the given GCS bucket and key. Uses the golang GCS library
-// to get read handles. If the artifactName is not a valid key in the bucket a handle will still be
-// constructed and returned, but all read operations will fail (dictated by behavior of golang GCS lib).
+// Artifact constructs a GCS artifact from the given bucket and key. If the artifactName is not a
+// valid key in the bucket a handle will still be constructed and returned, but all read operations
+// will fail.
 func (af *GCSArtifactFetcher) artifact(key string, artifactName string, sizeLimit int64) (lenses.Artifact, error) {
 	src, err := newGCSJobSource(key)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get GCS job source from %s: %v", key, err)
 	}
 
-	bucketName, prefix := extractBucketPrefixPair(src.jobPath())
-	bkt := af.client.Bucket(bucketName)
-	obj := &gcsArtifactHandle{bkt.Object(path.Join(prefix, artifactName))}
+	bkt, err := af.opener.Open(context.Background(), src.bucket)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open bucket %s: %v", src.bucket, err)
+	}
+	obj := &gcsArtifactHandle{bucket: bkt, name: path.Join(src.jobPrefix, artifactName)}
 	artifactLink := &url.URL{
 		Scheme: httpsScheme,
 		Host:   "storage.googleapis.com",
 		Path:   path.Join(src.jobPath(), artifactName),
 	}
-	return NewGCSArtifact(context.Background(), obj, artifactLink.String(), artifactName, sizeLimit), nil
-}
-
-func extractBucketPrefixPair(gcsPath string) (string, string) {
-	split := strings.SplitN(gcsPath, "/", 2)
-	return split[0], split[1]
+	if src.scheme != objectstore.GCS {
+		artifactLink = &url.URL{Path: ArtifactPath + path.Join(src.jobPath(), artifactName)}
+	}
+	artifact := NewGCSArtifact(context.Background(), obj, artifactLink.String(), artifactName, sizeLimit)
+	artifact.runSource = path.Join(gcsKeyType, src.bucket, src.jobPrefix)
+	return artifact, nil
 }
 
-// CanonicalLink gets a link to the location of job-specific artifacts in GCS
+// CanonicalLink gets a link to the location of job-specific artifacts in the bucket
 func (src *gcsJobSource) canonicalLink() string {
-	return path.Join(src.linkPrefix, src.bucket, src.jobPrefix)
+	bucket, _ := objectstore.URL(src.bucket)
+	return bucket + "/" + src.jobPrefix
 }
 
-// JobPath gets the prefix to all artifacts in GCS in the job
+// JobPath gets the prefix to all artifacts in the bucket in the job
 func (src *gcsJobSource) jobPath() string {
 	return fmt.Sprintf("%s/%s", src.bucket, src.jobPrefix)
 }
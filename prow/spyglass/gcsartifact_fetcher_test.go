@@ -17,7 +17,14 @@ limitations under the License.
 package spyglass
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"k8s.io/test-infra/prow/objectstore"
+	"k8s.io/test-infra/prow/spyglass/lenses"
 )
 
 func TestNewGCSJobSource(t *testing.T) {
@@ -57,6 +64,20 @@ func TestNewGCSJobSource(t *testing.T) {
 			exBuildID:   "403",
 			expectedErr: nil,
 		},
+		{
+			name:        "Test S3 link",
+			src:         "s3:test-bucket/logs/example-ci-run/403",
+			exBucket:    "s3:test-bucket",
+			exJobPrefix: "logs/example-ci-run/403/",
+			exName:      "example-ci-run",
+			exBuildID:   "403",
+			expectedErr: nil,
+		},
+		{
+			name:        "Test link in unknown storage",
+			src:         "azure:test-bucket/logs/example-ci-run/403",
+			expectedErr: ErrCannotParseSource,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -80,7 +101,7 @@ func TestNewGCSJobSource(t *testing.T) {
 // Tests listing objects associated with the current job in GCS
 func TestArtifacts_ListGCS(t *testing.T) {
 	fakeGCSClient := fakeGCSServer.Client()
-	testAf := NewGCSArtifactFetcher(fakeGCSClient)
+	testAf := NewGCSArtifactFetcher(&objectstore.Opener{GCSClient: fakeGCSClient})
 	testCases := []struct {
 		name              string
 		handle            artifactHandle
@@ -132,7 +153,7 @@ func TestArtifacts_ListGCS(t *testing.T) {
 // Tests getting handles to objects associated with the current job in GCS
 func TestFetchArtifacts_GCS(t *testing.T) {
 	fakeGCSClient := fakeGCSServer.Client()
-	testAf := NewGCSArtifactFetcher(fakeGCSClient)
+	testAf := NewGCSArtifactFetcher(&objectstore.Opener{GCSClient: fakeGCSClient})
 	maxSize := int64(500e6)
 	testCases := []struct {
 		name         string
@@ -173,3 +194,53 @@ func TestFetchArtifacts_GCS(t *testing.T) {
 		}
 	}
 }
+
+// Tests listing and fetching artifacts of a job in a file bucket
+func TestArtifacts_File(t *testing.T) {
+	root, err := ioutil.TempDir("", "spyglass")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+	jobDir := filepath.Join(root, "test-bucket", "logs", "example-ci-run", "403")
+	if err := os.MkdirAll(filepath.Join(jobDir, "artifacts"), 0755); err != nil {
+		t.Fatalf("Failed to create job directory: %v", err)
+	}
+	for name, content := range map[string]string{
+		"build-log.txt":          "Oh wow\nlogs",
+		"artifacts/junit_01.xml": "<testsuite/>",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(jobDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	testAf := NewGCSArtifactFetcher(&objectstore.Opener{FileRoot: root})
+	src := "file:test-bucket/logs/example-ci-run/403"
+	artifacts, err := testAf.artifacts(src)
+	if err != nil {
+		t.Fatalf("Failed to list artifacts: %v", err)
+	}
+	if expected := []string{"artifacts/junit_01.xml", "build-log.txt"}; !reflect.DeepEqual(artifacts, expected) {
+		t.Errorf("Expected artifacts %v, got %v", expected, artifacts)
+	}
+
+	artifact, err := testAf.artifact(src, "build-log.txt", 500e6)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	content, err := artifact.ReadAll()
+	if err != nil || string(content) != "Oh wow\nlogs" {
+		t.Errorf("Expected to read the build log, got %q (error %v)", content, err)
+	}
+	if expected := "/spyglass/artifact/file:test-bucket/logs/example-ci-run/403/build-log.txt"; artifact.CanonicalLink() != expected {
+		t.Errorf("Expected link %s, got %s", expected, artifact.CanonicalLink())
+	}
+	runArtifact, ok := artifact.(lenses.RunArtifact)
+	if !ok {
+		t.Fatal("Expected the artifact to know its job run")
+	}
+	if expected := "gcs/file:test-bucket/logs/example-ci-run/403"; runArtifact.RunSource() != expected {
+		t.Errorf("Expected run source %s, got %s", expected, runArtifact.RunSource())
+	}
+}
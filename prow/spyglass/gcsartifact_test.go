@@ -24,7 +24,7 @@ import (
 	"io"
 	"testing"
 
-	"cloud.google.com/go/storage"
+	"k8s.io/test-infra/prow/objectstore"
 )
 
 type ByteReadCloser struct {
@@ -47,11 +47,11 @@ func (rc *ByteReadCloser) Read(p []byte) (int, error) {
 }
 
 type fakeArtifactHandle struct {
-	oAttrs   *storage.ObjectAttrs
+	oAttrs   *objectstore.Attributes
 	contents []byte
 }
 
-func (h *fakeArtifactHandle) Attrs(ctx context.Context) (*storage.ObjectAttrs, error) {
+func (h *fakeArtifactHandle) Attributes(ctx context.Context) (*objectstore.Attributes, error) {
 	if bytes.Equal(h.contents, []byte("no attrs")) {
 		return nil, fmt.Errorf("error getting attrs")
 	}
@@ -149,9 +149,7 @@ func TestReadTail(t *testing.T) {
 	for _, tc := range testCases {
 		artifact := NewGCSArtifact(context.Background(), &fakeArtifactHandle{
 			contents: tc.contents,
-			oAttrs: &storage.ObjectAttrs{
-				Bucket:          "foo-bucket",
-				Name:            "build-log.txt",
+			oAttrs: &objectstore.Attributes{
 				Size:            int64(len(tc.contents)),
 				ContentEncoding: tc.encoding,
 			},
@@ -228,9 +226,7 @@ func TestReadAtMost(t *testing.T) {
 	for _, tc := range testCases {
 		artifact := NewGCSArtifact(context.Background(), &fakeArtifactHandle{
 			contents: tc.contents,
-			oAttrs: &storage.ObjectAttrs{
-				Bucket:          "foo-bucket",
-				Name:            "build-log.txt",
+			oAttrs: &objectstore.Attributes{
 				Size:            int64(len(tc.contents)),
 				ContentEncoding: tc.encoding,
 			},
@@ -315,9 +311,7 @@ func TestReadAt(t *testing.T) {
 	for _, tc := range testCases {
 		artifact := NewGCSArtifact(context.Background(), &fakeArtifactHandle{
 			contents: tc.contents,
-			oAttrs: &storage.ObjectAttrs{
-				Bucket:          "foo-bucket",
-				Name:            "build-log.txt",
+			oAttrs: &objectstore.Attributes{
 				Size:            int64(len(tc.contents)),
 				ContentEncoding: tc.encoding,
 			},
@@ -378,10 +372,8 @@ func TestReadAll(t *testing.T) {
 	for _, tc := range testCases {
 		artifact := NewGCSArtifact(context.Background(), &fakeArtifactHandle{
 			contents: tc.contents,
-			oAttrs: &storage.ObjectAttrs{
-				Bucket: "foo-bucket",
-				Name:   "build-log.txt",
-				Size:   int64(len(tc.contents)),
+			oAttrs: &objectstore.Attributes{
+				Size: int64(len(tc.contents)),
 			},
 		}, "", "build-log.txt", tc.sizeLimit)
 
@@ -400,7 +392,7 @@ func TestReadAll(t *testing.T) {
 
 func TestSize_GCS(t *testing.T) {
 	fakeGCSClient := fakeGCSServer.Client()
-	fakeGCSBucket := fakeGCSClient.Bucket("test-bucket")
+	fakeGCSBucket := objectstore.NewGCSBucket(fakeGCSClient.Bucket("test-bucket"))
 	startedContent := []byte("hi jason, im started")
 	testCases := []struct {
 		name      string
@@ -412,10 +404,8 @@ func TestSize_GCS(t *testing.T) {
 			name: "Test size simple",
 			handle: &fakeArtifactHandle{
 				contents: startedContent,
-				oAttrs: &storage.ObjectAttrs{
-					Bucket: "foo-bucket",
-					Name:   "started.json",
-					Size:   int64(len(startedContent)),
+				oAttrs: &objectstore.Attributes{
+					Size: int64(len(startedContent)),
 				},
 			},
 			expected:  int64(len(startedContent)),
@@ -425,17 +415,15 @@ func TestSize_GCS(t *testing.T) {
 			name: "Test size from attrs error",
 			handle: &fakeArtifactHandle{
 				contents: []byte("no attrs"),
-				oAttrs: &storage.ObjectAttrs{
-					Bucket: "foo-bucket",
-					Name:   "started.json",
-					Size:   8,
+				oAttrs: &objectstore.Attributes{
+					Size: 8,
 				},
 			},
 			expectErr: true,
 		},
 		{
 			name:      "Size of nonexistentArtifact",
-			handle:    &gcsArtifactHandle{fakeGCSBucket.Object("logs/example-ci-run/404/started.json")},
+			handle:    &gcsArtifactHandle{bucket: fakeGCSBucket, name: "logs/example-ci-run/404/started.json"},
 			expectErr: true,
 		},
 	}
@@ -67,6 +67,9 @@ async function handleShowSkipped(this: HTMLDivElement, e: MouseEvent) {
   spyglass.contentUpdated();
 }
 
+// The byte offsets and line numbers that followed logs are shown up to, by artifact.
+const followed = new Map<string, {offset: number, line: number}>();
+
 async function handleShowAll(this: HTMLButtonElement) {
   // Remove ourselves immediately.
   if (this.parentElement) {
@@ -74,11 +77,106 @@ async function handleShowAll(this: HTMLButtonElement) {
   }
 
   const {artifact} = this.dataset;
-  const content = await spyglass.request(JSON.stringify({artifact, offset: 0, length: -1}));
-  document.getElementById(`${artifact}-content`)!.innerHTML = `<tbody class="shown">${ansiToHTML(content)}</tbody>`;
+  const log = document.getElementById(`${artifact}-content`)!;
+  // Lines of followed logs that arrive while we wait are kept.
+  const replaced = Array.from(log.children);
+  const position = followed.get(artifact!);
+  const request = position ?
+    {artifact, offset: 0, length: Math.max(position.offset - 1, 0), startLine: 0} :
+    {artifact, offset: 0, length: -1};
+  const content = await spyglass.request(JSON.stringify(request));
+  const all = document.createElement('tbody');
+  all.className = 'shown';
+  all.innerHTML = ansiToHTML(content);
+  log.insertBefore(all, log.firstChild);
+  for (const child of replaced) {
+    log.removeChild(child);
+  }
   spyglass.contentUpdated();
 }
 
+// errRE matches keywords and glog error messages, like errRE in lens.go.
+const errRE = /timed out|ERROR:|(\s|^)(FAIL|Failure \[)\b|(\s|^)panic\b|^E\d{4} \d\d:\d\d:\d\d\.\d\d\d]/;
+
+function escapeHTML(text: string): string {
+  return text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+}
+
+// renderLine renders a line of a followed log like the "line group" template does.
+function renderLine(num: number, text: string): HTMLTableRowElement {
+  let html = '';
+  let highlighted = false;
+  let match = errRE.exec(text);
+  while (match) {
+    highlighted = true;
+    html += `<span>${escapeHTML(text.slice(0, match.index))}</span>`;
+    html += `<span class="match-highlighted">${escapeHTML(match[0])}</span>`;
+    text = text.slice(match.index + match[0].length);
+    match = errRE.exec(text);
+  }
+  html += `<span>${escapeHTML(text)}</span>`;
+  const row = document.createElement('tr');
+  row.innerHTML = `<td class="linenum">${num}</td>` +
+    `<td class="linetext"><span${highlighted ? ' class="line-highlighted"' : ''}>${ansiToHTML(html)}</span></td>`;
+  return row;
+}
+
+// How long to wait before reopening a stream that could not be opened, e.g. because
+// the test container has not started yet.
+const reconnectDelay = 5 * 1000;
+// How long to wait after a log ends before loading the lens again. By then the
+// sidecar has usually uploaded build-log.txt, which is shown instead of the pod log.
+const uploadDelay = 15 * 1000;
+// Marks lenses that were loaded again after their log ended, so that they are only
+// loaded again once if the upload takes longer.
+const reloadedHash = '#log-ended';
+
+function followLog(log: HTMLTableElement): void {
+  const {artifact, streamLink, streamOffset, streamLines} = log.dataset;
+  const position = {offset: +streamOffset!, line: +streamLines!};
+  followed.set(artifact!, position);
+
+  let pending: HTMLTableSectionElement | null = null;
+  const connect = () => {
+    // Browsers send the ID of the last event they got when they reconnect, which
+    // takes precedence over the offset we started at.
+    const source = new EventSource(`${streamLink}&offset=${position.offset}`);
+    source.addEventListener('message', (e) => {
+      const event = e as MessageEvent;
+      position.offset = +event.lastEventId;
+      position.line++;
+      if (!pending) {
+        pending = document.createElement('tbody');
+        pending.className = 'shown';
+        // Add lines in batches so that the lens is not resized for every line.
+        window.requestAnimationFrame(() => {
+          log.appendChild(pending!);
+          pending = null;
+          spyglass.contentUpdated();
+        });
+      }
+      pending.appendChild(renderLine(position.line, JSON.parse(event.data)));
+    });
+    source.addEventListener('end', () => {
+      source.close();
+      if (location.hash === reloadedHash) {
+        return;
+      }
+      window.setTimeout(() => {
+        location.hash = reloadedHash;
+        location.reload();
+      }, uploadDelay);
+    });
+    source.addEventListener('error', () => {
+      // Browsers reconnect by themselves unless the stream was never opened.
+      if (source.readyState === EventSource.CLOSED) {
+        window.setTimeout(connect, reconnectDelay);
+      }
+    });
+  };
+  connect();
+}
+
 window.addEventListener('load', () => {
   const shown = document.getElementsByClassName("shown");
   for (const child of Array.from(shown)) {
@@ -92,4 +190,8 @@ window.addEventListener('load', () => {
   for (const button of Array.from(document.querySelectorAll<HTMLButtonElement>("button.show-all-button"))) {
     button.addEventListener('click', handleShowAll);
   }
+
+  for (const log of Array.from(document.querySelectorAll<HTMLTableElement>("table[data-stream-link]"))) {
+    followLog(log);
+  }
 });
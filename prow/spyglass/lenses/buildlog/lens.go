@@ -106,6 +106,11 @@ type LogArtifactView struct {
 	ArtifactLink string
 	LineGroups   []LineGroup
 	ViewAll      bool
+	// StreamLink is set for logs that are still being written, which are
+	// followed from StreamOffset, the end of the last line in LineGroups.
+	StreamLink   string
+	StreamOffset int
+	StreamLines  int
 }
 
 // BuildLogsView holds each log file view
@@ -129,7 +134,15 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 			ArtifactName: a.JobPath(),
 			ArtifactLink: a.CanonicalLink(),
 		}
-		lines, err := logLinesAll(a)
+		var lines []string
+		var err error
+		if s, ok := a.(lenses.StreamingArtifact); ok {
+			av.StreamLink = s.StreamLink()
+			lines, av.StreamOffset, err = logLinesComplete(a)
+			av.StreamLines = len(lines)
+		} else {
+			lines, err = logLinesAll(a)
+		}
 		if err != nil {
 			logrus.WithError(err).Info("Error reading log.")
 			continue
@@ -188,6 +201,20 @@ func logLinesAll(artifact lenses.Artifact) ([]string, error) {
 	return logLines, nil
 }
 
+// logLinesComplete reads the complete lines of an artifact that is still being written and
+// returns them with the offset of the end of the last one.
+func logLinesComplete(artifact lenses.Artifact) ([]string, int, error) {
+	read, err := artifact.ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read log %q: %v", artifact.JobPath(), err)
+	}
+	end := bytes.LastIndexByte(read, '\n') + 1
+	if end == 0 {
+		return nil, 0, nil
+	}
+	return strings.Split(string(read[:end-1]), "\n"), end, nil
+}
+
 func logLines(artifact lenses.Artifact, offset, length int64) ([]string, error) {
 	b := make([]byte, length)
 	_, err := artifact.ReadAt(b, offset)
@@ -17,7 +17,10 @@ limitations under the License.
 package buildlog
 
 import (
+	"reflect"
 	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
 )
 
 func TestGroupLines(t *testing.T) {
@@ -193,3 +196,55 @@ func TestGroupLines(t *testing.T) {
 		})
 	}
 }
+
+type fakeArtifact struct {
+	lenses.Artifact
+	content string
+}
+
+func (a fakeArtifact) ReadAll() ([]byte, error) {
+	return []byte(a.content), nil
+}
+
+func TestLogLinesComplete(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		lines   []string
+		offset  int
+	}{
+		{
+			name: "Test empty log",
+		},
+		{
+			name:    "Test partial line",
+			content: "Downloading",
+		},
+		{
+			name:    "Test complete lines",
+			content: "a\nb\n",
+			lines:   []string{"a", "b"},
+			offset:  4,
+		},
+		{
+			name:    "Test complete lines and a partial line",
+			content: "a\n\nb\nDownloading",
+			lines:   []string{"a", "", "b"},
+			offset:  5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, offset, err := logLinesComplete(fakeArtifact{content: test.content})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("Expected lines %q, got %q", test.lines, lines)
+			}
+			if offset != test.offset {
+				t.Errorf("Expected offset %d, got %d", test.offset, offset)
+			}
+		})
+	}
+}
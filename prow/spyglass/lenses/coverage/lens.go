@@ -0,0 +1,285 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package coverage provides a Go coverage profile viewer for Spyglass
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/tools/cover"
+
+	"k8s.io/test-infra/gopherage/pkg/cov"
+	"k8s.io/test-infra/gopherage/pkg/cov/junit/calculation"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "coverage"
+	title    = "Coverage"
+	priority = 7
+)
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a Go coverage-rendering Spyglass lens.
+type Lens struct{}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// LineRange describes consecutive lines of a covered file that share their
+// coverage. Lines outside of any block are not listed.
+type LineRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	State     string `json:"state"`
+}
+
+// SourceLine is a line of a covered file. Its state is empty if the line is
+// outside of any block.
+type SourceLine struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+	State  string `json:"state,omitempty"`
+}
+
+// FileCoverage is the coverage of a file as shown when it is expanded: its
+// source with the coverage of each line, or the line ranges alone with the
+// reason why the source could not be fetched.
+type FileCoverage struct {
+	Lines       []SourceLine `json:"lines,omitempty"`
+	Ranges      []LineRange  `json:"ranges"`
+	SourceError string       `json:"sourceError,omitempty"`
+}
+
+// The coverage states of lines. A line is partially covered if it belongs
+// to both covered and uncovered blocks.
+const (
+	covered          = "covered"
+	uncovered        = "uncovered"
+	partiallyCovered = "partial"
+)
+
+// Callback returns the coverage of the file named in data, so that the
+// front-end can show its source with the covered and uncovered lines
+// highlighted. The source is fetched at the commit that the run tested.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string) string {
+	profiles, err := loadProfiles(artifacts)
+	if err != nil {
+		logrus.WithError(err).Info("Error loading coverage profiles.")
+		return "{}"
+	}
+	var blocks []cover.ProfileBlock
+	for _, profile := range profiles {
+		if profile.FileName == data {
+			blocks = append(blocks, profile.Blocks...)
+		}
+	}
+	states := lineStates(blocks)
+	file := FileCoverage{Ranges: lineRanges(states)}
+	if len(blocks) > 0 {
+		if content, err := lenses.SourceFile(runSource(artifacts), data); err != nil {
+			logrus.WithError(err).WithField("file", data).Info("Error fetching the source of a covered file.")
+			file.SourceError = err.Error()
+		} else {
+			file.Lines = sourceLines(content, states)
+		}
+	}
+	out, err := json.Marshal(file)
+	if err != nil {
+		return "{}"
+	}
+	return string(out)
+}
+
+// runSource returns the source of the run that the artifacts belong to.
+func runSource(artifacts []lenses.Artifact) string {
+	for _, artifact := range artifacts {
+		if a, ok := artifact.(lenses.RunArtifact); ok {
+			return a.RunSource()
+		}
+	}
+	return ""
+}
+
+// lineStates determines the coverage state of each line that the blocks span.
+func lineStates(blocks []cover.ProfileBlock) map[int]string {
+	states := map[int]string{}
+	for _, b := range blocks {
+		state := uncovered
+		if b.Count > 0 {
+			state = covered
+		}
+		for line := b.StartLine; line <= b.EndLine; line++ {
+			if previous, ok := states[line]; ok && previous != state {
+				states[line] = partiallyCovered
+			} else {
+				states[line] = state
+			}
+		}
+	}
+	return states
+}
+
+// lineRanges groups consecutive lines of the same state.
+func lineRanges(states map[int]string) []LineRange {
+	var lines []int
+	for line := range states {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	ranges := []LineRange{}
+	for _, line := range lines {
+		if last := len(ranges) - 1; last >= 0 && ranges[last].EndLine == line-1 && ranges[last].State == states[line] {
+			ranges[last].EndLine = line
+			continue
+		}
+		ranges = append(ranges, LineRange{StartLine: line, EndLine: line, State: states[line]})
+	}
+	return ranges
+}
+
+// sourceLines splits the content of a file into its lines with their states.
+func sourceLines(content []byte, states map[int]string) []SourceLine {
+	text := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	lines := make([]SourceLine, 0, len(text))
+	for i, line := range text {
+		lines = append(lines, SourceLine{Number: i + 1, Text: line, State: states[i+1]})
+	}
+	return lines
+}
+
+type coverageView struct {
+	Error    string
+	Total    *calculation.CoverageList
+	Packages []*calculation.CoverageList
+}
+
+// Body renders the per-package and per-file coverage of the profiles.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string) string {
+	coverageTemplate, err := template.New("template.html").Funcs(template.FuncMap{
+		"percent": percent,
+	}).ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("Failed to load template: %v", err)
+	}
+
+	var view coverageView
+	if profiles, err := loadProfiles(artifacts); err != nil {
+		logrus.WithError(err).Info("Error loading coverage profiles.")
+		view.Error = err.Error()
+	} else {
+		view.Total, view.Packages = summarize(profiles)
+	}
+
+	var buf bytes.Buffer
+	if err := coverageTemplate.ExecuteTemplate(&buf, "body", view); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// summarize computes the coverage of every file, grouped by package.
+func summarize(profiles []*cover.Profile) (*calculation.CoverageList, []*calculation.CoverageList) {
+	total := calculation.ProduceCovList(profiles)
+	byPackage := map[string]*calculation.CoverageList{}
+	for _, file := range total.Group {
+		dir := path.Dir(file.Name)
+		if _, ok := byPackage[dir]; !ok {
+			byPackage[dir] = &calculation.CoverageList{Coverage: &calculation.Coverage{Name: dir}}
+		}
+		byPackage[dir].Group = append(byPackage[dir].Group, file)
+	}
+	var packages []*calculation.CoverageList
+	for _, dir := range total.ListDirectories() {
+		p := byPackage[dir]
+		// Ratio sums up the statements of the package's files.
+		p.Ratio()
+		packages = append(packages, p)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	total.Ratio()
+	return total, packages
+}
+
+func percent(ratio float32) string {
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
+
+// loadProfiles parses and merges the coverage profiles in the artifacts.
+func loadProfiles(artifacts []lenses.Artifact) ([]*cover.Profile, error) {
+	var all [][]*cover.Profile
+	for _, artifact := range artifacts {
+		profiles, err := parseProfile(artifact)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", artifact.JobPath(), err)
+		}
+		all = append(all, profiles)
+	}
+	return cov.MergeMultipleProfiles(all)
+}
+
+func parseProfile(artifact lenses.Artifact) ([]*cover.Profile, error) {
+	content, err := artifact.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// ParseProfiles only accepts a filename, so the profile has to be
+	// written to disk for it to read it back.
+	tf, err := ioutil.TempFile("", "coverage")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tf.Name())
+	defer tf.Close()
+	if _, err := tf.Write(content); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %v", err)
+	}
+	return cover.ParseProfiles(tf.Name())
+}
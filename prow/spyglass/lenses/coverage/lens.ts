@@ -0,0 +1,121 @@
+type LineState = 'covered' | 'uncovered' | 'partial';
+
+// LineRange mirrors LineRange in lens.go.
+interface LineRange {
+  startLine: number;
+  endLine: number;
+  state: LineState;
+}
+
+// SourceLine mirrors SourceLine in lens.go.
+interface SourceLine {
+  number: number;
+  text: string;
+  state?: LineState;
+}
+
+// FileCoverage mirrors FileCoverage in lens.go.
+interface FileCoverage {
+  lines?: SourceLine[];
+  ranges?: LineRange[];
+  sourceError?: string;
+}
+
+const stateDescriptions = {
+  covered: 'covered',
+  partial: 'partially covered',
+  uncovered: 'not covered',
+};
+
+function describeRange(range: LineRange): string {
+  const lines = range.startLine === range.endLine ?
+    `line ${range.startLine}` : `lines ${range.startLine}-${range.endLine}`;
+  return `${lines} ${stateDescriptions[range.state]}`;
+}
+
+function toggle(row: HTMLTableRowElement, target: Element): boolean {
+  const icon = row.querySelector('i')!;
+  const hidden = target.classList.toggle('hidden');
+  icon.innerText = hidden ? 'expand_more' : 'expand_less';
+  return hidden;
+}
+
+function addPackageExpanders(): void {
+  const rows = document.querySelectorAll<HTMLTableRowElement>('tr.section-expander');
+  for (const row of Array.from(rows)) {
+    row.onclick = () => {
+      toggle(row, row.parentElement!.nextElementSibling!);
+      spyglass.contentUpdated();
+    };
+  }
+}
+
+function renderSource(cell: HTMLTableDataCellElement, lines: SourceLine[]): void {
+  const table = document.createElement('table');
+  table.className = 'source';
+  for (const line of lines) {
+    const row = table.insertRow();
+    if (line.state) {
+      row.className = line.state;
+      row.title = stateDescriptions[line.state];
+    }
+    const number = row.insertCell();
+    number.className = 'line-number';
+    number.innerText = String(line.number);
+    const text = row.insertCell();
+    text.className = 'line-text';
+    text.innerText = line.text;
+  }
+  cell.appendChild(table);
+}
+
+function renderRanges(cell: HTMLTableDataCellElement, file: FileCoverage): void {
+  const ranges = file.ranges || [];
+  if (file.sourceError) {
+    const note = document.createElement('div');
+    note.className = 'source-error';
+    note.innerText = `Failed to fetch the source: ${file.sourceError}`;
+    cell.appendChild(note);
+  }
+  for (const range of ranges) {
+    const div = document.createElement('div');
+    div.className = `line-range ${range.state}`;
+    div.innerText = describeRange(range);
+    cell.appendChild(div);
+  }
+  if (ranges.length === 0) {
+    cell.innerText = 'No covered or uncovered lines found.';
+  }
+}
+
+async function loadLines(row: HTMLTableRowElement, cell: HTMLTableDataCellElement): Promise<void> {
+  const file: FileCoverage = JSON.parse(await spyglass.request(row.dataset.file!));
+  if (file.lines) {
+    renderSource(cell, file.lines);
+  } else {
+    renderRanges(cell, file);
+  }
+  spyglass.contentUpdated();
+}
+
+function addFileExpanders(): void {
+  const rows = document.querySelectorAll<HTMLTableRowElement>('tr.coverage-file');
+  for (const row of Array.from(rows)) {
+    let loaded = false;
+    row.onclick = () => {
+      const sibling = row.nextElementSibling!;
+      if (!toggle(row, sibling) && !loaded) {
+        loaded = true;
+        loadLines(row, sibling.querySelector('td')!).then();
+      }
+      spyglass.contentUpdated();
+    };
+  }
+}
+
+function loaded(): void {
+  addPackageExpanders();
+  addFileExpanders();
+}
+
+window.addEventListener('DOMContentLoaded', loaded);
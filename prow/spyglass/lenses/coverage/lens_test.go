@@ -0,0 +1,289 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coverage
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+type fakeArtifact struct {
+	lenses.Artifact
+	path    string
+	content string
+}
+
+func (a fakeArtifact) ReadAll() ([]byte, error) {
+	return []byte(a.content), nil
+}
+
+func (a fakeArtifact) JobPath() string {
+	return a.path
+}
+
+func (a fakeArtifact) RunSource() string {
+	return "gcs/bucket/logs/job/10"
+}
+
+type fakeSourceFetcher map[string]string
+
+func (f fakeSourceFetcher) SourceFile(src, importPath string) ([]byte, error) {
+	content, ok := f[importPath]
+	if src != "gcs/bucket/logs/job/10" || !ok {
+		return nil, errors.New("file not found")
+	}
+	return []byte(content), nil
+}
+
+const (
+	unitProfile = `mode: count
+k8s.io/test-infra/prow/foo/a.go:10.2,12.16 2 1
+k8s.io/test-infra/prow/foo/a.go:12.16,14.3 1 0
+k8s.io/test-infra/prow/foo/b.go:3.20,5.2 1 0
+k8s.io/test-infra/prow/bar/c.go:7.30,9.2 4 3
+`
+	e2eProfile = `mode: count
+k8s.io/test-infra/prow/foo/b.go:3.20,5.2 1 2
+`
+)
+
+func TestSummarize(t *testing.T) {
+	testCases := []struct {
+		name             string
+		artifacts        []lenses.Artifact
+		expectedTotal    [2]int
+		expectedPackages map[string][2]int
+		expectedFiles    map[string][2]int
+	}{
+		{
+			name:          "single profile",
+			artifacts:     []lenses.Artifact{fakeArtifact{path: "artifacts/unit.cov", content: unitProfile}},
+			expectedTotal: [2]int{6, 8},
+			expectedPackages: map[string][2]int{
+				"k8s.io/test-infra/prow/bar": {4, 4},
+				"k8s.io/test-infra/prow/foo": {2, 4},
+			},
+			expectedFiles: map[string][2]int{
+				"k8s.io/test-infra/prow/bar/c.go": {4, 4},
+				"k8s.io/test-infra/prow/foo/a.go": {2, 3},
+				"k8s.io/test-infra/prow/foo/b.go": {0, 1},
+			},
+		},
+		{
+			name: "merged profiles",
+			artifacts: []lenses.Artifact{
+				fakeArtifact{path: "artifacts/unit.cov", content: unitProfile},
+				fakeArtifact{path: "artifacts/e2e.cov", content: e2eProfile},
+			},
+			expectedTotal: [2]int{7, 8},
+			expectedPackages: map[string][2]int{
+				"k8s.io/test-infra/prow/bar": {4, 4},
+				"k8s.io/test-infra/prow/foo": {3, 4},
+			},
+			expectedFiles: map[string][2]int{
+				"k8s.io/test-infra/prow/bar/c.go": {4, 4},
+				"k8s.io/test-infra/prow/foo/a.go": {2, 3},
+				"k8s.io/test-infra/prow/foo/b.go": {1, 1},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			profiles, err := loadProfiles(tc.artifacts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			total, packages := summarize(profiles)
+			if actual := [2]int{total.NumCoveredStmts, total.NumAllStmts}; actual != tc.expectedTotal {
+				t.Errorf("expected total coverage %v, got %v", tc.expectedTotal, actual)
+			}
+			actualPackages := map[string][2]int{}
+			actualFiles := map[string][2]int{}
+			var names []string
+			for _, p := range packages {
+				names = append(names, p.Name)
+				actualPackages[p.Name] = [2]int{p.NumCoveredStmts, p.NumAllStmts}
+				for _, f := range p.Group {
+					actualFiles[f.Name] = [2]int{f.NumCoveredStmts, f.NumAllStmts}
+				}
+			}
+			if expected := []string{"k8s.io/test-infra/prow/bar", "k8s.io/test-infra/prow/foo"}; !reflect.DeepEqual(names, expected) {
+				t.Errorf("expected packages in order %v, got %v", expected, names)
+			}
+			if !reflect.DeepEqual(actualPackages, tc.expectedPackages) {
+				t.Errorf("expected package coverage %v, got %v", tc.expectedPackages, actualPackages)
+			}
+			if !reflect.DeepEqual(actualFiles, tc.expectedFiles) {
+				t.Errorf("expected file coverage %v, got %v", tc.expectedFiles, actualFiles)
+			}
+		})
+	}
+}
+
+func TestCallback(t *testing.T) {
+	artifacts := []lenses.Artifact{fakeArtifact{path: "artifacts/unit.cov", content: unitProfile}}
+	lenses.RegisterSourceFetcher(fakeSourceFetcher{
+		"k8s.io/test-infra/prow/foo/a.go": strings.Repeat("\n", 9) + "a := 1\nb := 2\nif a < b {\n\treturn\n}\nreturn\n",
+	})
+	defer lenses.RegisterSourceFetcher(nil)
+	aRanges := []LineRange{
+		{StartLine: 10, EndLine: 11, State: covered},
+		{StartLine: 12, EndLine: 12, State: partiallyCovered},
+		{StartLine: 13, EndLine: 14, State: uncovered},
+	}
+	testCases := []struct {
+		name     string
+		file     string
+		expected FileCoverage
+	}{
+		{
+			name: "source with covered and uncovered blocks sharing a line",
+			file: "k8s.io/test-infra/prow/foo/a.go",
+			expected: FileCoverage{
+				Lines: []SourceLine{
+					{Number: 1}, {Number: 2}, {Number: 3}, {Number: 4}, {Number: 5},
+					{Number: 6}, {Number: 7}, {Number: 8}, {Number: 9},
+					{Number: 10, Text: "a := 1", State: covered},
+					{Number: 11, Text: "b := 2", State: covered},
+					{Number: 12, Text: "if a < b {", State: partiallyCovered},
+					{Number: 13, Text: "\treturn", State: uncovered},
+					{Number: 14, Text: "}", State: uncovered},
+					{Number: 15, Text: "return"},
+				},
+				Ranges: aRanges,
+			},
+		},
+		{
+			name: "source cannot be fetched",
+			file: "k8s.io/test-infra/prow/foo/b.go",
+			expected: FileCoverage{
+				Ranges:      []LineRange{{StartLine: 3, EndLine: 5, State: uncovered}},
+				SourceError: "file not found",
+			},
+		},
+		{
+			name:     "unknown file",
+			file:     "k8s.io/test-infra/prow/foo/missing.go",
+			expected: FileCoverage{Ranges: []LineRange{}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var file FileCoverage
+			if err := json.Unmarshal([]byte(Lens{}.Callback(artifacts, "", tc.file)), &file); err != nil {
+				t.Fatalf("failed to unmarshal the file coverage: %v", err)
+			}
+			if !reflect.DeepEqual(file, tc.expected) {
+				t.Errorf("expected file coverage %+v, got %+v", tc.expected, file)
+			}
+		})
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		blocks   []cover.ProfileBlock
+		expected []LineRange
+	}{
+		{
+			name:     "no blocks",
+			expected: []LineRange{},
+		},
+		{
+			name: "gaps between blocks split ranges",
+			blocks: []cover.ProfileBlock{
+				{StartLine: 3, EndLine: 4, Count: 1},
+				{StartLine: 7, EndLine: 8, Count: 2},
+			},
+			expected: []LineRange{
+				{StartLine: 3, EndLine: 4, State: covered},
+				{StartLine: 7, EndLine: 8, State: covered},
+			},
+		},
+		{
+			name: "adjacent blocks of the same state are merged",
+			blocks: []cover.ProfileBlock{
+				{StartLine: 5, EndLine: 6, Count: 0},
+				{StartLine: 1, EndLine: 4, Count: 0},
+			},
+			expected: []LineRange{
+				{StartLine: 1, EndLine: 6, State: uncovered},
+			},
+		},
+		{
+			name: "nested uncovered block",
+			blocks: []cover.ProfileBlock{
+				{StartLine: 1, EndLine: 9, Count: 1},
+				{StartLine: 4, EndLine: 5, Count: 0},
+			},
+			expected: []LineRange{
+				{StartLine: 1, EndLine: 3, State: covered},
+				{StartLine: 4, EndLine: 5, State: partiallyCovered},
+				{StartLine: 6, EndLine: 9, State: covered},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := lineRanges(lineStates(tc.blocks)); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected line ranges %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBody(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:    "valid profile",
+			content: unitProfile,
+			expected: []string{
+				"75.0% of 8 statements covered.",
+				`data-file="k8s.io/test-infra/prow/foo/b.go"`,
+				"0.0%",
+				"2/4",
+			},
+		},
+		{
+			name:     "invalid profile",
+			content:  "not a profile",
+			expected: []string{"Failed to load the coverage profiles"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := Lens{}.Body([]lenses.Artifact{fakeArtifact{path: "artifacts/unit.cov", content: tc.content}}, ".", "")
+			for _, expected := range tc.expected {
+				if !strings.Contains(body, expected) {
+					t.Errorf("expected body to contain %q, got:\n%s", expected, body)
+				}
+			}
+		})
+	}
+}
@@ -19,9 +19,11 @@ package junit
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"time"
 
@@ -35,8 +37,20 @@ const (
 	name     = "junit"
 	title    = "JUnit"
 	priority = 5
+
+	// historyRequest is sent by the front-end to compare the failed tests with earlier runs
+	historyRequest = "history"
+	// historyRuns is how many earlier runs failed tests are compared with
+	historyRuns = 10
+
+	newFailure        = "new"
+	flakyFailure      = "flaky"
+	consistentFailure = "consistent"
 )
 
+// junitRE matches the JUnit files of earlier runs
+var junitRE = regexp.MustCompile(`(^|/)junit.*\.xml$`)
+
 func init() {
 	lenses.RegisterLens(Lens{})
 }
@@ -66,9 +80,52 @@ func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string
 	return buf.String()
 }
 
-// Callback does nothing.
+// Callback compares the failed tests with the earlier runs of the job when
+// the front-end asks for their history.
 func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string) string {
-	return ""
+	if data != historyRequest {
+		return ""
+	}
+	var src string
+	for _, artifact := range artifacts {
+		if a, ok := artifact.(lenses.RunArtifact); ok {
+			src = a.RunSource()
+			break
+		}
+	}
+	if src == "" {
+		return "[]"
+	}
+	runs, err := lenses.EarlierRuns(src, historyRuns, junitRE)
+	if err != nil {
+		logrus.WithError(err).WithField("src", src).Info("Error getting earlier runs.")
+		return "[]"
+	}
+	var earlier []pastRun
+	for _, run := range runs {
+		past := pastRun{id: run.ID, link: run.Link, failed: map[string]bool{}}
+		for _, result := range parseResults(run.Artifacts) {
+			for _, test := range result.junit {
+				if test.Skipped != nil {
+					continue
+				}
+				// Tests that are repeated in a run only pass if every attempt does.
+				past.failed[test.Name] = past.failed[test.Name] || test.Failure != nil
+			}
+		}
+		if len(past.failed) > 0 {
+			earlier = append(earlier, past)
+		}
+	}
+	histories := []TestHistory{}
+	for _, test := range viewData(artifacts).Failed {
+		histories = append(histories, testHistory(test.Junit.Name, earlier))
+	}
+	out, err := json.Marshal(histories)
+	if err != nil {
+		return "[]"
+	}
+	return string(out)
 }
 
 type JunitResult struct {
@@ -87,12 +144,34 @@ type TestResult struct {
 
 // Body renders the <body> for JUnit tests
 func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string) string {
-	type testResults struct {
-		junit []junit.Result
-		link  string
-		path  string
-		err   error
+	jvd := viewData(artifacts)
+	if jvd.NumTests == 0 {
+		return "Found no valid JUnit test results"
+	}
+
+	junitTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := junitTemplate.ExecuteTemplate(&buf, "body", jvd); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
 	}
+
+	return buf.String()
+}
+
+type testResults struct {
+	junit []junit.Result
+	link  string
+	path  string
+	err   error
+}
+
+// parseResults reads the tests in the artifacts, sorted by the paths of the artifacts.
+func parseResults(artifacts []lenses.Artifact) []testResults {
 	resultChan := make(chan testResults)
 	for _, artifact := range artifacts {
 		go func(artifact lenses.Artifact) {
@@ -127,14 +206,20 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 		results = append(results, <-resultChan)
 	}
 	sort.Slice(results, func(i, j int) bool { return results[i].path < results[j].path })
+	return results
+}
 
-	jvd := struct {
-		NumTests int
-		Passed   []TestResult
-		Failed   []TestResult
-		Skipped  []TestResult
-	}{}
-	for _, result := range results {
+// junitView holds the tests of a run as they are shown
+type junitView struct {
+	NumTests int
+	Passed   []TestResult
+	Failed   []TestResult
+	Skipped  []TestResult
+}
+
+func viewData(artifacts []lenses.Artifact) junitView {
+	jvd := junitView{}
+	for _, result := range parseResults(artifacts) {
 		if result.err != nil {
 			continue
 		}
@@ -157,21 +242,60 @@ func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data stri
 			}
 		}
 	}
+	jvd.NumTests = len(jvd.Passed) + len(jvd.Failed) + len(jvd.Skipped)
+	return jvd
+}
 
-	if jvd.NumTests = len(jvd.Passed) + len(jvd.Failed) + len(jvd.Skipped); jvd.NumTests == 0 {
-		return "Found no valid JUnit test results"
-	}
+// pastRun holds whether the tests of an earlier run failed, by name.
+// Tests that did not run are missing.
+type pastRun struct {
+	id     string
+	link   string
+	failed map[string]bool
+}
 
-	junitTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
-	if err != nil {
-		logrus.WithError(err).Error("Error executing template.")
-		return fmt.Sprintf("Failed to load template file: %v", err)
-	}
+// TestHistory describes how a failed test fared in the earlier runs of its job
+type TestHistory struct {
+	Name string `json:"name"`
+	// Kind is whether the failure is new, flaky or consistent
+	Kind string `json:"kind"`
+	// Failures counts the runs that failed the test among the
+	// Runs that ran it, including this one.
+	Failures int `json:"failures"`
+	Runs     int `json:"runs"`
+	// FirstFailureID and FirstFailureLink point to the first run of
+	// the failures that lead up to this one, if it is an earlier run.
+	FirstFailureID   string `json:"firstFailureId,omitempty"`
+	FirstFailureLink string `json:"firstFailureLink,omitempty"`
+}
 
-	var buf bytes.Buffer
-	if err := junitTemplate.ExecuteTemplate(&buf, "body", jvd); err != nil {
-		logrus.WithError(err).Error("Error executing template.")
+// testHistory compares a failed test with the earlier runs, which are newest first.
+func testHistory(name string, earlier []pastRun) TestHistory {
+	h := TestHistory{Name: name, Failures: 1, Runs: 1}
+	streak := true
+	for _, run := range earlier {
+		failed, ran := run.failed[name]
+		if !ran {
+			continue
+		}
+		h.Runs++
+		if !failed {
+			streak = false
+			continue
+		}
+		h.Failures++
+		if streak {
+			h.FirstFailureID = run.id
+			h.FirstFailureLink = run.link
+		}
 	}
-
-	return buf.String()
+	switch {
+	case h.Failures == 1:
+		h.Kind = newFailure
+	case h.Failures == h.Runs:
+		h.Kind = consistentFailure
+	default:
+		h.Kind = flakyFailure
+	}
+	return h
 }
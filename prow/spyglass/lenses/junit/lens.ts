@@ -50,10 +50,59 @@ function addStdoutOpeners(): void {
   }
 }
 
+// TestHistory mirrors TestHistory in lens.go.
+interface TestHistory {
+  name: string;
+  kind: 'new' | 'flaky' | 'consistent';
+  failures: number;
+  runs: number;
+  firstFailureId?: string;
+  firstFailureLink?: string;
+}
+
+function describeHistory(history: TestHistory): string {
+  switch (history.kind) {
+    case 'new':
+      return 'new failure';
+    case 'flaky':
+      return `flaky (failed ${history.failures} of last ${history.runs})`;
+    case 'consistent':
+      return 'consistently failing';
+  }
+}
+
+async function addTestHistory(): Promise<void> {
+  const spans = document.querySelectorAll<HTMLSpanElement>('span.test-history');
+  if (spans.length === 0) {
+    return;
+  }
+  const histories: TestHistory[] = JSON.parse(await spyglass.request('history'));
+  for (const span of Array.from(spans)) {
+    const history = histories[+span.dataset.index!];
+    if (!history) {
+      continue;
+    }
+    span.classList.add(history.kind);
+    span.innerText = describeHistory(history);
+    if (history.firstFailureId && history.firstFailureLink) {
+      span.appendChild(document.createTextNode(', first failed in '));
+      const link = document.createElement('a');
+      link.href = history.firstFailureLink;
+      link.target = '_top';
+      link.innerText = `#${history.firstFailureId}`;
+      // Don't expand the failure when following the link.
+      link.onclick = (e) => e.stopPropagation();
+      span.appendChild(link);
+    }
+  }
+  spyglass.contentUpdated();
+}
+
 function loaded(): void {
   addTestExpanders();
   addStdoutOpeners();
   addSectionExpanders();
+  addTestHistory().then();
 }
 
 window.addEventListener('DOMContentLoaded', loaded);
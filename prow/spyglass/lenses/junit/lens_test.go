@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package junit
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+func TestTestHistory(t *testing.T) {
+	earlier := []pastRun{
+		{id: "9", link: "/view/gcs/bucket/logs/job/9", failed: map[string]bool{"flaky": true, "broken": true, "fixed": false}},
+		{id: "8", link: "/view/gcs/bucket/logs/job/8", failed: map[string]bool{"flaky": false, "broken": true}},
+		{id: "7", link: "/view/gcs/bucket/logs/job/7", failed: map[string]bool{"flaky": true, "broken": true, "fixed": true}},
+	}
+	testCases := []struct {
+		name     string
+		test     string
+		earlier  []pastRun
+		expected TestHistory
+	}{
+		{
+			name:     "no earlier runs",
+			test:     "flaky",
+			expected: TestHistory{Name: "flaky", Kind: newFailure, Failures: 1, Runs: 1},
+		},
+		{
+			name:     "passed before",
+			test:     "fixed",
+			earlier:  earlier,
+			expected: TestHistory{Name: "fixed", Kind: flakyFailure, Failures: 2, Runs: 3},
+		},
+		{
+			name:     "never ran before",
+			test:     "added",
+			earlier:  earlier,
+			expected: TestHistory{Name: "added", Kind: newFailure, Failures: 1, Runs: 1},
+		},
+		{
+			name:    "failed on and off",
+			test:    "flaky",
+			earlier: earlier,
+			expected: TestHistory{
+				Name:             "flaky",
+				Kind:             flakyFailure,
+				Failures:         3,
+				Runs:             4,
+				FirstFailureID:   "9",
+				FirstFailureLink: "/view/gcs/bucket/logs/job/9",
+			},
+		},
+		{
+			name:    "failed every time",
+			test:    "broken",
+			earlier: earlier,
+			expected: TestHistory{
+				Name:             "broken",
+				Kind:             consistentFailure,
+				Failures:         4,
+				Runs:             4,
+				FirstFailureID:   "7",
+				FirstFailureLink: "/view/gcs/bucket/logs/job/7",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := testHistory(tc.test, tc.earlier); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected history %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+type fakeArtifact struct {
+	lenses.Artifact
+	path    string
+	content string
+}
+
+func (a fakeArtifact) ReadAll() ([]byte, error) {
+	return []byte(a.content), nil
+}
+
+func (a fakeArtifact) CanonicalLink() string {
+	return "https://storage.googleapis.com/bucket/" + a.path
+}
+
+func (a fakeArtifact) JobPath() string {
+	return a.path
+}
+
+func (a fakeArtifact) RunSource() string {
+	return "gcs/bucket/logs/job/10"
+}
+
+type fakeJobHistory []lenses.Run
+
+func (h fakeJobHistory) EarlierRuns(src string, n int, re *regexp.Regexp) ([]lenses.Run, error) {
+	if src != "gcs/bucket/logs/job/10" || n != historyRuns || !re.MatchString("artifacts/junit_01.xml") {
+		return nil, nil
+	}
+	return h, nil
+}
+
+func TestCallback(t *testing.T) {
+	lenses.RegisterJobHistory(fakeJobHistory{
+		{
+			ID:   "9",
+			Link: "/view/gcs/bucket/logs/job/9",
+			Artifacts: []lenses.Artifact{fakeArtifact{
+				path:    "artifacts/junit_01.xml",
+				content: `<testsuite><testcase name="flaky"><failure>oops</failure></testcase><testcase name="broken"><failure>oops</failure></testcase></testsuite>`,
+			}},
+		},
+		{
+			ID:   "8",
+			Link: "/view/gcs/bucket/logs/job/8",
+			Artifacts: []lenses.Artifact{fakeArtifact{
+				path:    "artifacts/junit_01.xml",
+				content: `<testsuite><testcase name="flaky"/><testcase name="broken"><failure>oops</failure></testcase></testsuite>`,
+			}},
+		},
+	})
+	defer lenses.RegisterJobHistory(nil)
+
+	artifacts := []lenses.Artifact{fakeArtifact{
+		path:    "artifacts/junit_01.xml",
+		content: `<testsuite><testcase name="broken"><failure>oops</failure></testcase><testcase name="flaky"><failure>oops</failure></testcase><testcase name="new"><failure>oops</failure></testcase><testcase name="passing"/></testsuite>`,
+	}}
+	var histories []TestHistory
+	if err := json.Unmarshal([]byte(Lens{}.Callback(artifacts, "", historyRequest)), &histories); err != nil {
+		t.Fatalf("failed to unmarshal the histories: %v", err)
+	}
+	expected := []TestHistory{
+		{Name: "broken", Kind: consistentFailure, Failures: 3, Runs: 3, FirstFailureID: "8", FirstFailureLink: "/view/gcs/bucket/logs/job/8"},
+		{Name: "flaky", Kind: flakyFailure, Failures: 2, Runs: 3, FirstFailureID: "9", FirstFailureLink: "/view/gcs/bucket/logs/job/9"},
+		{Name: "new", Kind: newFailure, Failures: 1, Runs: 1},
+	}
+	if !reflect.DeepEqual(histories, expected) {
+		t.Errorf("expected histories %+v, got %+v", expected, histories)
+	}
+
+	if response := (Lens{}).Callback(artifacts, "", "something else"); response != "" {
+		t.Errorf("expected no response to other requests, got %q", response)
+	}
+}
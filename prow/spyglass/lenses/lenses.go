@@ -25,10 +25,13 @@ import (
 	"github.com/sirupsen/logrus"
 	"io"
 	"path/filepath"
+	"regexp"
 )
 
 var (
-	lensReg = map[string]Lens{}
+	lensReg    = map[string]Lens{}
+	jobHistory JobHistory
+	sources    SourceFetcher
 
 	// ErrGzipOffsetRead will be thrown when an offset read is attempted on a gzip-compressed object
 	ErrGzipOffsetRead = errors.New("offset read on gzipped files unsupported")
@@ -42,6 +45,12 @@ var (
 	// ErrContextUnsupported is thrown when attempting to use a context with an artifact that
 	// does not support context operations (cancel, withtimeout, etc.)
 	ErrContextUnsupported = errors.New("artifact does not support context operations")
+	// ErrNoJobHistory is thrown when earlier runs of a job are asked for before a job history
+	// has been registered using RegisterJobHistory.
+	ErrNoJobHistory = errors.New("no job history registered")
+	// ErrNoSourceFetcher is thrown when a source file is asked for before a source fetcher
+	// has been registered using RegisterSourceFetcher.
+	ErrNoSourceFetcher = errors.New("no source fetcher registered")
 )
 
 type LensConfig struct {
@@ -87,6 +96,74 @@ type Artifact interface {
 	Size() (int64, error)
 }
 
+// StreamingArtifact is an artifact that is still being written, like the log of a running job
+type StreamingArtifact interface {
+	Artifact
+	// StreamLink gets a link to a Server-Sent Events stream that follows the artifact line by
+	// line. Each event holds a line as a JSON string and has the byte offset after the line as
+	// its ID; an "end" event is sent when the artifact is complete.
+	StreamLink() string
+}
+
+// RunArtifact is an artifact that knows the job run it belongs to
+type RunArtifact interface {
+	Artifact
+	// RunSource gets the Spyglass source of the job run, e.g. gcs/kubernetes-jenkins/logs/ci-job/1234
+	RunSource() string
+}
+
+// Run is an earlier run of a job found in the job history
+type Run struct {
+	// ID is the build ID of the run
+	ID string
+	// Link is the Spyglass page of the run
+	Link string
+	// Artifacts are the artifacts of the run that were asked for
+	Artifacts []Artifact
+}
+
+// JobHistory finds the earlier runs of jobs for lenses that compare a run with the runs before it
+type JobHistory interface {
+	// EarlierRuns gets up to n runs of the job that started before the run in src, newest first,
+	// with the artifacts whose paths within the job match re.
+	EarlierRuns(src string, n int, re *regexp.Regexp) ([]Run, error)
+}
+
+// RegisterJobHistory makes the job history available to lenses through EarlierRuns
+func RegisterJobHistory(history JobHistory) {
+	jobHistory = history
+}
+
+// EarlierRuns gets up to n runs of the job that started before the run in src from the
+// registered job history, newest first, with the artifacts whose paths match re.
+func EarlierRuns(src string, n int, re *regexp.Regexp) ([]Run, error) {
+	if jobHistory == nil {
+		return nil, ErrNoJobHistory
+	}
+	return jobHistory.EarlierRuns(src, n, re)
+}
+
+// SourceFetcher fetches the source files that runs of jobs tested for lenses that show them
+type SourceFetcher interface {
+	// SourceFile gets the content of the file with the given Go import path, e.g.
+	// k8s.io/test-infra/prow/cmd/deck/main.go, at the commit that the run in src tested.
+	SourceFile(src, importPath string) ([]byte, error)
+}
+
+// RegisterSourceFetcher makes the source fetcher available to lenses through SourceFile
+func RegisterSourceFetcher(fetcher SourceFetcher) {
+	sources = fetcher
+}
+
+// SourceFile gets the content of the file with the given import path at the commit that
+// the run in src tested from the registered source fetcher.
+func SourceFile(src, importPath string) ([]byte, error) {
+	if sources == nil {
+		return nil, ErrNoSourceFetcher
+	}
+	return sources.SourceFile(src, importPath)
+}
+
 // ResourceDirForLens returns the path to a lens's public resource directory.
 func ResourceDirForLens(baseDir, name string) string {
 	return filepath.Join(baseDir, name)
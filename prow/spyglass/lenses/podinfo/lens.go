@@ -0,0 +1,300 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podinfo provides a viewer for the pod of a job for Spyglass
+package podinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+const (
+	name     = "podinfo"
+	title    = "Pod Info"
+	priority = 1
+
+	// The names of the containers the pod utilities add to the pod of a
+	// decorated job, see pod-utils/decorate.
+	cloneRefsContainer  = "clonerefs"
+	initUploadContainer = "initupload"
+	sidecarContainer    = "sidecar"
+)
+
+// Lens is the implementation of a pod-info-rendering Spyglass lens.
+type Lens struct{}
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    title,
+		Priority: priority,
+	}
+}
+
+// Header renders the content of <head> from template.html.
+func (lens Lens) Header(artifacts []lenses.Artifact, resourceDir string) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// Callback does nothing.
+func (lens Lens) Callback(artifacts []lenses.Artifact, resourceDir string, data string) string {
+	return ""
+}
+
+// Phase is a step of the job the time of which is known
+type Phase struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// ContainerInfo describes the state of one container of the pod
+type ContainerInfo struct {
+	Name     string
+	Init     bool
+	State    string
+	Reason   string
+	Message  string
+	ExitCode int32
+	Restarts int32
+	Duration time.Duration
+	// Failed is set for containers that exited unsuccessfully or are
+	// stuck waiting, e.g. for an image that cannot be pulled.
+	Failed bool
+}
+
+// EventInfo describes one event the cluster recorded about the pod
+type EventInfo struct {
+	Time    time.Time
+	Type    string
+	Reason  string
+	Source  string
+	Message string
+	Count   int32
+}
+
+// Resources describes what the pod requested and what the test used
+type Resources struct {
+	Requests  map[string]string
+	CPUTime   time.Duration
+	MaxMemory string
+	NodeType  string
+}
+
+type podInfoView struct {
+	Error      string
+	PodName    string
+	NodeName   string
+	Phase      coreapi.PodPhase
+	Reason     string
+	Message    string
+	Phases     []Phase
+	Containers []ContainerInfo
+	Events     []EventInfo
+	Resources  *Resources
+}
+
+// Body renders the status, timing and events of the pod of the job.
+func (lens Lens) Body(artifacts []lenses.Artifact, resourceDir string, data string) string {
+	podInfoTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("Failed to load template: %v", err)
+	}
+
+	var view podInfoView
+	if info, err := readPodInfo(artifacts); err != nil {
+		logrus.WithError(err).Info("Error reading pod info.")
+		view.Error = err.Error()
+	} else {
+		view = newView(info)
+	}
+
+	var buf bytes.Buffer
+	if err := podInfoTemplate.ExecuteTemplate(&buf, "body", view); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+func readPodInfo(artifacts []lenses.Artifact) (gcs.PodInfo, error) {
+	var info gcs.PodInfo
+	for _, a := range artifacts {
+		if a.JobPath() != gcs.PodInfoFile {
+			continue
+		}
+		raw, err := a.ReadAll()
+		if err != nil {
+			return info, fmt.Errorf("failed to read %s: %v", gcs.PodInfoFile, err)
+		}
+		if err := json.Unmarshal(raw, &info); err != nil {
+			return info, fmt.Errorf("failed to parse %s: %v", gcs.PodInfoFile, err)
+		}
+		if info.Pod == nil {
+			return info, fmt.Errorf("%s does not describe a pod", gcs.PodInfoFile)
+		}
+		return info, nil
+	}
+	return info, fmt.Errorf("no %s found", gcs.PodInfoFile)
+}
+
+func newView(info gcs.PodInfo) podInfoView {
+	pod := info.Pod
+	view := podInfoView{
+		PodName:  pod.Name,
+		NodeName: pod.NodeName,
+		Phase:    pod.Status.Phase,
+		Reason:   pod.Status.Reason,
+		Message:  pod.Status.Message,
+		Phases:   phases(pod),
+		Events:   events(info.Events),
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		view.Containers = append(view.Containers, containerInfo(status, true))
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		view.Containers = append(view.Containers, containerInfo(status, false))
+	}
+	if usage := info.Resources; usage != nil {
+		view.Resources = &Resources{
+			Requests: map[string]string{},
+			CPUTime:  time.Duration(usage.CPUMillis) * time.Millisecond,
+			NodeType: usage.NodeType,
+		}
+		for name, quantity := range usage.Requests {
+			view.Resources.Requests[string(name)] = quantity.String()
+		}
+		if usage.MaxMemoryBytes > 0 {
+			view.Resources.MaxMemory = resource.NewQuantity(usage.MaxMemoryBytes, resource.BinarySI).String()
+		}
+	}
+	return view
+}
+
+// phases breaks the time the pod took down into waiting to be scheduled
+// and the steps of a decorated job, as far as they are known.
+func phases(pod *gcs.PodSummary) []Phase {
+	var phases []Phase
+	add := func(name string, start, end time.Time) {
+		if start.IsZero() || end.IsZero() || end.Before(start) {
+			return
+		}
+		phases = append(phases, Phase{Name: name, Start: start, Duration: end.Sub(start)})
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == coreapi.PodScheduled && condition.Status == coreapi.ConditionTrue {
+			add("scheduling", pod.CreationTimestamp.Time, condition.LastTransitionTime.Time)
+		}
+	}
+	var statuses []coreapi.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	terminated := map[string]*coreapi.ContainerStateTerminated{}
+	for _, status := range statuses {
+		if status.State.Terminated != nil {
+			terminated[status.Name] = status.State.Terminated
+		}
+	}
+	for _, step := range []struct{ phase, container string }{
+		{phase: "clone", container: cloneRefsContainer},
+		{phase: "initupload", container: initUploadContainer},
+		{phase: "test", container: kube.TestContainerName},
+	} {
+		if state, ok := terminated[step.container]; ok {
+			add(step.phase, state.StartedAt.Time, state.FinishedAt.Time)
+		}
+	}
+	// The sidecar runs alongside the test and only uploads once it ends.
+	if test, ok := terminated[kube.TestContainerName]; ok {
+		if sidecar, ok := terminated[sidecarContainer]; ok {
+			add("upload", test.FinishedAt.Time, sidecar.FinishedAt.Time)
+		}
+	}
+	return phases
+}
+
+func containerInfo(status coreapi.ContainerStatus, init bool) ContainerInfo {
+	info := ContainerInfo{Name: status.Name, Init: init, Restarts: status.RestartCount}
+	switch state := status.State; {
+	case state.Terminated != nil:
+		info.State = "terminated"
+		info.Reason = state.Terminated.Reason
+		info.Message = state.Terminated.Message
+		info.ExitCode = state.Terminated.ExitCode
+		info.Failed = state.Terminated.ExitCode != 0
+		if !state.Terminated.StartedAt.IsZero() {
+			info.Duration = state.Terminated.FinishedAt.Sub(state.Terminated.StartedAt.Time)
+		}
+	case state.Running != nil:
+		info.State = "running"
+	case state.Waiting != nil:
+		info.State = "waiting"
+		info.Reason = state.Waiting.Reason
+		info.Message = state.Waiting.Message
+		info.Failed = state.Waiting.Reason != "" && state.Waiting.Reason != "PodInitializing"
+	}
+	return info
+}
+
+func events(events []coreapi.Event) []EventInfo {
+	var infos []EventInfo
+	for _, event := range events {
+		info := EventInfo{
+			Time:    event.LastTimestamp.Time,
+			Type:    event.Type,
+			Reason:  event.Reason,
+			Source:  event.Source.Component,
+			Message: event.Message,
+			Count:   event.Count,
+		}
+		if info.Time.IsZero() {
+			info.Time = event.FirstTimestamp.Time
+		}
+		if event.Source.Host != "" {
+			info.Source = fmt.Sprintf("%s, %s", info.Source, event.Source.Host)
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].Time.Before(infos[j].Time) })
+	return infos
+}
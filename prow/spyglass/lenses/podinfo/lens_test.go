@@ -0,0 +1,218 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podinfo
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/spyglass/lenses"
+)
+
+var created = time.Date(2019, 3, 15, 10, 0, 0, 0, time.UTC)
+
+func at(seconds int) metav1.Time {
+	return metav1.NewTime(created.Add(time.Duration(seconds) * time.Second))
+}
+
+func terminated(name string, start, end int, exitCode int32, reason string) coreapi.ContainerStatus {
+	return coreapi.ContainerStatus{
+		Name: name,
+		State: coreapi.ContainerState{Terminated: &coreapi.ContainerStateTerminated{
+			StartedAt:  at(start),
+			FinishedAt: at(end),
+			ExitCode:   exitCode,
+			Reason:     reason,
+		}},
+	}
+}
+
+func TestPhases(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   coreapi.PodStatus
+		expected []Phase
+	}{
+		{
+			name: "never scheduled",
+			status: coreapi.PodStatus{Conditions: []coreapi.PodCondition{
+				{Type: coreapi.PodScheduled, Status: coreapi.ConditionFalse, LastTransitionTime: at(600)},
+			}},
+		},
+		{
+			name: "decorated job",
+			status: coreapi.PodStatus{
+				Conditions: []coreapi.PodCondition{
+					{Type: coreapi.PodScheduled, Status: coreapi.ConditionTrue, LastTransitionTime: at(30)},
+				},
+				InitContainerStatuses: []coreapi.ContainerStatus{
+					terminated(cloneRefsContainer, 40, 70, 0, "Completed"),
+					terminated(initUploadContainer, 70, 75, 0, "Completed"),
+				},
+				ContainerStatuses: []coreapi.ContainerStatus{
+					terminated("test", 80, 380, 1, "Error"),
+					terminated(sidecarContainer, 80, 400, 0, "Completed"),
+				},
+			},
+			expected: []Phase{
+				{Name: "scheduling", Start: created, Duration: 30 * time.Second},
+				{Name: "clone", Start: at(40).Time, Duration: 30 * time.Second},
+				{Name: "initupload", Start: at(70).Time, Duration: 5 * time.Second},
+				{Name: "test", Start: at(80).Time, Duration: 5 * time.Minute},
+				{Name: "upload", Start: at(380).Time, Duration: 20 * time.Second},
+			},
+		},
+		{
+			name: "test still running",
+			status: coreapi.PodStatus{
+				ContainerStatuses: []coreapi.ContainerStatus{
+					{Name: "test", State: coreapi.ContainerState{Running: &coreapi.ContainerStateRunning{StartedAt: at(80)}}},
+					terminated(sidecarContainer, 80, 400, 0, "Completed"),
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &gcs.PodSummary{CreationTimestamp: at(0), Status: tc.status}
+			if actual := phases(pod); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected phases %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestContainerInfo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   coreapi.ContainerStatus
+		expected ContainerInfo
+	}{
+		{
+			name:     "completed",
+			status:   terminated("test", 0, 60, 0, "Completed"),
+			expected: ContainerInfo{Name: "test", State: "terminated", Reason: "Completed", Duration: time.Minute},
+		},
+		{
+			name:     "killed for using too much memory",
+			status:   terminated("test", 0, 60, 137, "OOMKilled"),
+			expected: ContainerInfo{Name: "test", State: "terminated", Reason: "OOMKilled", ExitCode: 137, Duration: time.Minute, Failed: true},
+		},
+		{
+			name: "image cannot be pulled",
+			status: coreapi.ContainerStatus{Name: "test", State: coreapi.ContainerState{Waiting: &coreapi.ContainerStateWaiting{
+				Reason:  "ImagePullBackOff",
+				Message: "Back-off pulling image",
+			}}},
+			expected: ContainerInfo{Name: "test", State: "waiting", Reason: "ImagePullBackOff", Message: "Back-off pulling image", Failed: true},
+		},
+		{
+			name: "waiting for init containers",
+			status: coreapi.ContainerStatus{Name: "test", State: coreapi.ContainerState{Waiting: &coreapi.ContainerStateWaiting{
+				Reason: "PodInitializing",
+			}}},
+			expected: ContainerInfo{Name: "test", State: "waiting", Reason: "PodInitializing"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := containerInfo(tc.status, false); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected container info %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+type fakeArtifact struct {
+	lenses.Artifact
+	path    string
+	content string
+}
+
+func (a fakeArtifact) ReadAll() ([]byte, error) {
+	return []byte(a.content), nil
+}
+
+func (a fakeArtifact) JobPath() string {
+	return a.path
+}
+
+func TestBody(t *testing.T) {
+	info, err := json.Marshal(gcs.PodInfo{
+		Pod: &gcs.PodSummary{
+			Name:              "abc-123",
+			NodeName:          "node-1",
+			CreationTimestamp: at(0),
+			Status: coreapi.PodStatus{
+				Phase:             coreapi.PodFailed,
+				ContainerStatuses: []coreapi.ContainerStatus{terminated("test", 0, 60, 137, "OOMKilled")},
+			},
+		},
+		Events: []coreapi.Event{
+			{
+				Type:          "Warning",
+				Reason:        "FailedScheduling",
+				Message:       "0/3 nodes are available: 3 Insufficient cpu.",
+				Source:        coreapi.EventSource{Component: "default-scheduler"},
+				LastTimestamp: at(20),
+				Count:         4,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal the pod info: %v", err)
+	}
+	testCases := []struct {
+		name      string
+		artifacts []lenses.Artifact
+		expected  []string
+	}{
+		{
+			name:      "pod info",
+			artifacts: []lenses.Artifact{fakeArtifact{path: gcs.PodInfoFile, content: string(info)}},
+			expected: []string{
+				"abc-123",
+				"node-1",
+				"terminated: OOMKilled",
+				"0/3 nodes are available: 3 Insufficient cpu.",
+				"default-scheduler",
+			},
+		},
+		{
+			name:      "invalid pod info",
+			artifacts: []lenses.Artifact{fakeArtifact{path: gcs.PodInfoFile, content: "{"}},
+			expected:  []string{"Failed to load the pod info"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := Lens{}.Body(tc.artifacts, ".", "")
+			for _, expected := range tc.expected {
+				if !strings.Contains(body, expected) {
+					t.Errorf("expected body to contain %q, got:\n%s", expected, body)
+				}
+			}
+		})
+	}
+}
@@ -79,6 +79,19 @@ func (a *PodLogArtifact) CanonicalLink() string {
 	return u.String()
 }
 
+// StreamLink returns a link to where pod logs are followed while the job runs
+func (a *PodLogArtifact) StreamLink() string {
+	q := url.Values{
+		"job": []string{a.name},
+		"id":  []string{a.buildID},
+	}
+	u := url.URL{
+		Path:     "/log-stream",
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
 // JobPath gets the path within the job for the pod log. Always returns build-log.txt.
 // This is because the pod log becomes the build log after the job artifact uploads
 // are complete, which should be used instead of the pod log.
@@ -58,20 +58,22 @@ func (j *fakePodLogJAgent) GetJobLogTail(job, id string, n int64) ([]byte, error
 
 func TestNewPodLogArtifact(t *testing.T) {
 	testCases := []struct {
-		name         string
-		jobName      string
-		buildID      string
-		sizeLimit    int64
-		expectedErr  error
-		expectedLink string
+		name               string
+		jobName            string
+		buildID            string
+		sizeLimit          int64
+		expectedErr        error
+		expectedLink       string
+		expectedStreamLink string
 	}{
 		{
-			name:         "Create pod log with valid fields",
-			jobName:      "job",
-			buildID:      "123",
-			sizeLimit:    500e6,
-			expectedErr:  nil,
-			expectedLink: "/log?id=123&job=job",
+			name:               "Create pod log with valid fields",
+			jobName:            "job",
+			buildID:            "123",
+			sizeLimit:          500e6,
+			expectedErr:        nil,
+			expectedLink:       "/log?id=123&job=job",
+			expectedStreamLink: "/log-stream?id=123&job=job",
 		},
 		{
 			name:         "Create pod log with no jobName",
@@ -111,6 +113,9 @@ func TestNewPodLogArtifact(t *testing.T) {
 			if link != tc.expectedLink {
 				t.Errorf("Unexpected link, expected %s, got %q", tc.expectedLink, link)
 			}
+			if link := artifact.StreamLink(); link != tc.expectedStreamLink {
+				t.Errorf("Unexpected stream link, expected %s, got %q", tc.expectedStreamLink, link)
+			}
 		})
 	}
 }
@@ -31,6 +31,7 @@ import (
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/deck/jobs"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/pod-utils/gcs"
 	"k8s.io/test-infra/prow/spyglass/lenses"
 )
@@ -64,13 +65,14 @@ type LensRequest struct {
 	Artifacts []string `json:"artifacts"`
 }
 
-// New constructs a Spyglass object from a JobAgent, a config.Agent, and a storage Client.
-func New(ja *jobs.JobAgent, cfg config.Getter, c *storage.Client, ctx context.Context) *Spyglass {
+// New constructs a Spyglass object from a JobAgent, a config.Agent, an opener for the buckets
+// that hold artifacts, and a storage Client for TestGrid.
+func New(ja *jobs.JobAgent, cfg config.Getter, opener *objectstore.Opener, c *storage.Client, ctx context.Context) *Spyglass {
 	return &Spyglass{
 		JobAgent:              ja,
 		config:                cfg,
 		PodLogArtifactFetcher: NewPodLogArtifactFetcher(ja),
-		GCSArtifactFetcher:    NewGCSArtifactFetcher(c),
+		GCSArtifactFetcher:    NewGCSArtifactFetcher(opener),
 		testgrid: &TestGrid{
 			conf:   cfg,
 			client: c,
@@ -152,7 +154,10 @@ func (s *Spyglass) JobPath(src string) (string, error) {
 		if job.Spec.DecorationConfig.GCSConfiguration == nil {
 			return "", fmt.Errorf("failed to locate GCS upload bucket for %s: missing GCS configuration", jobName)
 		}
-		bktName := job.Spec.DecorationConfig.GCSConfiguration.Bucket
+		bktName, err := objectstore.PathSegment(job.Spec.DecorationConfig.GCSConfiguration.Bucket)
+		if err != nil {
+			return "", fmt.Errorf("failed to locate GCS upload bucket for %s: %v", jobName, err)
+		}
 		if job.Spec.Type == prowapi.PresubmitJob {
 			return path.Join(bktName, gcs.PRLogs, "directory", jobName), nil
 		}
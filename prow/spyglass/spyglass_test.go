@@ -19,6 +19,8 @@ package spyglass
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"k8s.io/test-infra/prow/gcsupload"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"os"
@@ -32,6 +34,7 @@ import (
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/deck/jobs"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/objectstore"
 	"k8s.io/test-infra/prow/spyglass/lenses"
 	tgconf "k8s.io/test-infra/testgrid/config"
 )
@@ -87,6 +90,13 @@ func (f fpkc) GetLogTail(pod, container string, n int64) ([]byte, error) {
 	return nil, fmt.Errorf("pod not found: %s", pod)
 }
 
+func (f fpkc) StreamContainerLog(pod, container string) (io.ReadCloser, error) {
+	if pod == "wowowow" || pod == "powowow" {
+		return ioutil.NopCloser(strings.NewReader(string(f))), nil
+	}
+	return nil, fmt.Errorf("pod not found: %s", pod)
+}
+
 type fca struct {
 	c config.Config
 }
@@ -186,6 +196,7 @@ test/e2e/e2e.go:137 BeforeSuite on Node 1 failed test/e2e/e2e.go:137
 		},
 	}
 	fca := config.Agent{}
+	fca.Set(&config.Config{})
 	fakeJa = jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")}, fca.Config)
 	fakeJa.Start()
 	os.Exit(m.Run())
@@ -252,7 +263,7 @@ func TestViews(t *testing.T) {
 				lenses.RegisterLens(l)
 			}
 			fca := config.Agent{}
-			sg := New(fakeJa, fca.Config, fakeGCSClient, context.Background())
+			sg := New(fakeJa, fca.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 			lenses := sg.Lenses(tc.matchCache)
 			for _, l := range lenses {
 				var found bool
@@ -381,6 +392,7 @@ func TestJobPath(t *testing.T) {
 		},
 	}
 	fca := config.Agent{}
+	fca.Set(&config.Config{})
 	fakeJa = jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")}, fca.Config)
 	fakeJa.Start()
 	testCases := []struct {
@@ -448,7 +460,7 @@ func TestJobPath(t *testing.T) {
 	for _, tc := range testCases {
 		fakeGCSClient := fakeGCSServer.Client()
 		fca := config.Agent{}
-		sg := New(fakeJa, fca.Config, fakeGCSClient, context.Background())
+		sg := New(fakeJa, fca.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 		jobPath, err := sg.JobPath(tc.src)
 		if tc.expError && err == nil {
 			t.Errorf("test %q: JobPath(%q) expected error", tc.name, tc.src)
@@ -509,6 +521,7 @@ func TestRunPath(t *testing.T) {
 		},
 	}
 	fca := config.Agent{}
+	fca.Set(&config.Config{})
 	fakeJa = jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")}, fca.Config)
 	fakeJa.Start()
 	testCases := []struct {
@@ -573,7 +586,7 @@ func TestRunPath(t *testing.T) {
 				},
 			},
 		})
-		sg := New(fakeJa, fca.Config, fakeGCSClient, context.Background())
+		sg := New(fakeJa, fca.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 		jobPath, err := sg.RunPath(tc.src)
 		if tc.expError && err == nil {
 			t.Errorf("test %q: RunPath(%q) expected error, got  %q", tc.name, tc.src, jobPath)
@@ -633,6 +646,7 @@ func TestRunToPR(t *testing.T) {
 		},
 	}
 	fca := config.Agent{}
+	fca.Set(&config.Config{})
 	fakeJa = jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")}, fca.Config)
 	fakeJa.Start()
 	testCases := []struct {
@@ -721,7 +735,7 @@ func TestRunToPR(t *testing.T) {
 				},
 			},
 		})
-		sg := New(fakeJa, fca.Config, fakeGCSClient, context.Background())
+		sg := New(fakeJa, fca.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 		org, repo, num, err := sg.RunToPR(tc.src)
 		if tc.expError && err == nil {
 			t.Errorf("test %q: RunToPR(%q) expected error", tc.name, tc.src)
@@ -808,7 +822,7 @@ func TestProwToGCS(t *testing.T) {
 		}
 		fakeJa = jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")}, fakeConfigAgent.Config)
 		fakeJa.Start()
-		sg := New(fakeJa, fakeConfigAgent.Config, fakeGCSClient, context.Background())
+		sg := New(fakeJa, fakeConfigAgent.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 
 		p, err := sg.prowToGCS(tc.key)
 		if err != nil && !tc.expectError {
@@ -941,7 +955,7 @@ func TestGCSPathRoundTrip(t *testing.T) {
 
 		fakeGCSClient := fakeGCSServer.Client()
 
-		sg := New(fakeJa, fakeConfigAgent.Config, fakeGCSClient, context.Background())
+		sg := New(fakeJa, fakeConfigAgent.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 		gcspath, _, _ := gcsupload.PathsForJob(
 			&prowapi.GCSConfiguration{Bucket: "test-bucket", PathStrategy: tc.pathStrategy},
 			&downwardapi.JobSpec{
@@ -1016,6 +1030,7 @@ func TestTestGridLink(t *testing.T) {
 
 	kc := fkc{}
 	fca := config.Agent{}
+	fca.Set(&config.Config{})
 	fakeJa = jobs.NewJobAgent(kc, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA"), "trusted": fpkc("clusterB")}, fca.Config)
 	fakeJa.Start()
 
@@ -1053,7 +1068,7 @@ func TestTestGridLink(t *testing.T) {
 				},
 			},
 		})
-		sg := New(fakeJa, fca.Config, fakeGCSClient, context.Background())
+		sg := New(fakeJa, fca.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 		sg.testgrid = &tg
 		link, err := sg.TestGridLink(tc.src)
 		if tc.expError {
@@ -1100,7 +1115,7 @@ func TestFetchArtifactsPodLog(t *testing.T) {
 
 	fakeGCSClient := fakeGCSServer.Client()
 
-	sg := New(fakeJa, fakeConfigAgent.Config, fakeGCSClient, context.Background())
+	sg := New(fakeJa, fakeConfigAgent.Config, &objectstore.Opener{GCSClient: fakeGCSClient}, fakeGCSClient, context.Background())
 	testKeys := []string{
 		"prowjob/job/123",
 		"gcs/kubernetes-jenkins/logs/job/123/",
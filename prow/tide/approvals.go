@@ -0,0 +1,259 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/gitattributes"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+// approveRe matches /approve commands the way the approve plugin reads them.
+var approveRe = regexp.MustCompile(`(?mi)^/approve[\t ]*(no-issue|cancel)?[\t ]*$`)
+
+// approvalChecker finds the approvals required by a query that a PR lacks.
+type approvalChecker interface {
+	// MissingApprovals describes the approvals that the query requires and
+	// that the PR lacks.
+	MissingApprovals(pr *PullRequest, q *config.TideQuery) []string
+}
+
+type aliasLoader interface {
+	LoadRepoAliases(org, repo, base string) (repoowners.RepoAliases, error)
+}
+
+// approvalAgent checks the approvals required by queries against the
+// approving reviews and the /approve comments on PRs. The members of
+// teams and the approvers of PRs at their heads are cached until the
+// next prune, so that each sync lists them at most once.
+type approvalAgent struct {
+	logger       *logrus.Entry
+	ghc          githubClient
+	owners       aliasLoader
+	changedFiles *changedFilesAgent
+
+	sync.Mutex
+	teams     map[string]sets.String
+	approvals map[changeCacheKey]sets.String
+}
+
+// MissingApprovals checks only the requirements whose paths the PR changes.
+// Requirements that cannot be checked are reported as missing so that PRs
+// never merge without the approvals.
+func (a *approvalAgent) MissingApprovals(pr *PullRequest, q *config.TideQuery) []string {
+	if len(q.RequiredApprovals) == 0 {
+		return nil
+	}
+	log := a.logger.WithFields(pr.logFields())
+	org, repo := string(pr.Repository.Owner.Login), string(pr.Repository.Name)
+	changes, changesErr := a.changedFiles.prChanges(pr)()
+	if changesErr != nil {
+		log.WithError(changesErr).Warn("Failed to get the changed files, assuming required approvals are missing.")
+	}
+
+	var approvers sets.String
+	var missing []string
+	for _, required := range q.RequiredApprovals {
+		if changesErr == nil && !changesPaths(changes, required.Paths) {
+			continue
+		}
+		if approvers == nil {
+			var err error
+			if approvers, err = a.approvers(pr); err != nil {
+				log.WithError(err).Warn("Failed to get the approvers, assuming required approvals are missing.")
+				approvers = sets.NewString()
+			}
+		}
+		members, err := a.members(org, repo, string(pr.BaseRef.Name), required)
+		if err != nil {
+			log.WithError(err).Warnf("Failed to get the members of %s, assuming the approval is missing.", required.Approvers())
+		}
+		if !approvers.HasAny(members.UnsortedList()...) {
+			missing = append(missing, fmt.Sprintf("%s by %s", strings.Join(required.Paths, ", "), required.Approvers()))
+		}
+	}
+	return missing
+}
+
+// changesPaths determines if any of the files matches any of the patterns.
+func changesPaths(files, patterns []string) bool {
+	for _, p := range patterns {
+		// Patterns are validated with the configuration.
+		pattern, err := gitattributes.ParsePattern(p)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if pattern.Match(file) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// approvers returns the logins that approved the PR, counted the way the
+// approve plugin counts them: /approve comments and approving reviews
+// approve, while /approve cancel comments and reviews that request
+// changes withdraw an earlier approval.
+func (a *approvalAgent) approvers(pr *PullRequest) (sets.String, error) {
+	org, repo, number := string(pr.Repository.Owner.Login), string(pr.Repository.Name), int(pr.Number)
+	key := changeCacheKey{org: org, repo: repo, number: number, sha: string(pr.HeadRefOID)}
+	a.Lock()
+	approvers, ok := a.approvals[key]
+	a.Unlock()
+	if ok {
+		return approvers, nil
+	}
+
+	comments, err := a.ghc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("error listing comments: %v", err)
+	}
+	reviews, err := a.ghc.ListReviews(org, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("error listing reviews: %v", err)
+	}
+
+	type vote struct {
+		login   string
+		approve bool
+		at      time.Time
+	}
+	var votes []vote
+	for _, comment := range comments {
+		for _, match := range approveRe.FindAllStringSubmatch(comment.Body, -1) {
+			votes = append(votes, vote{
+				login:   github.NormLogin(comment.User.Login),
+				approve: !strings.EqualFold(match[1], "cancel"),
+				at:      comment.CreatedAt,
+			})
+		}
+	}
+	for _, review := range reviews {
+		switch review.State {
+		case github.ReviewStateApproved, github.ReviewStateChangesRequested:
+			votes = append(votes, vote{
+				login:   github.NormLogin(review.User.Login),
+				approve: review.State == github.ReviewStateApproved,
+				at:      review.SubmittedAt,
+			})
+		}
+	}
+	sort.SliceStable(votes, func(i, j int) bool { return votes[i].at.Before(votes[j].at) })
+
+	approvers = sets.NewString()
+	for _, v := range votes {
+		if v.approve {
+			approvers.Insert(v.login)
+		} else {
+			approvers.Delete(v.login)
+		}
+	}
+	a.Lock()
+	a.approvals[key] = approvers
+	a.Unlock()
+	return approvers, nil
+}
+
+// members returns the normalized logins of the team or the alias that
+// may give the approval.
+func (a *approvalAgent) members(org, repo, branch string, required config.TideRequiredApproval) (sets.String, error) {
+	if required.Alias != "" {
+		aliases, err := a.owners.LoadRepoAliases(org, repo, branch)
+		if err != nil {
+			return sets.NewString(), fmt.Errorf("error loading OWNERS_ALIASES: %v", err)
+		}
+		return sets.NewString(aliases.ExpandAlias(required.Alias).UnsortedList()...), nil
+	}
+
+	key := org + "/" + required.Team
+	a.Lock()
+	members, ok := a.teams[key]
+	a.Unlock()
+	if ok {
+		return members, nil
+	}
+	teams, err := a.ghc.ListTeams(org)
+	if err != nil {
+		return sets.NewString(), fmt.Errorf("error listing teams: %v", err)
+	}
+	for _, team := range teams {
+		if team.Slug != required.Team {
+			continue
+		}
+		teamMembers, err := a.ghc.ListTeamMembers(team.ID, github.RoleAll)
+		if err != nil {
+			return sets.NewString(), fmt.Errorf("error listing the members of %s: %v", key, err)
+		}
+		members = sets.NewString()
+		for _, member := range teamMembers {
+			members.Insert(github.NormLogin(member.Login))
+		}
+		a.Lock()
+		a.teams[key] = members
+		a.Unlock()
+		return members, nil
+	}
+	return sets.NewString(), fmt.Errorf("team %s does not exist", key)
+}
+
+// prune forgets the members of teams and the approvers of PRs so that
+// they are listed again.
+func (a *approvalAgent) prune() {
+	a.Lock()
+	defer a.Unlock()
+	a.teams = make(map[string]sets.String)
+	a.approvals = make(map[changeCacheKey]sets.String)
+}
+
+// lacksApprovals determines if the PR lacks approvals required by every
+// query that it matches. Without a checker approvals are not required.
+func lacksApprovals(ac approvalChecker, queries config.TideQueries, pr *PullRequest) bool {
+	if ac == nil {
+		return false
+	}
+	required := false
+	for _, q := range queries {
+		if len(q.RequiredApprovals) > 0 {
+			required = true
+		}
+	}
+	if !required {
+		return false
+	}
+	for _, q := range queries {
+		if _, diff := requirementDiff(pr, &q, queryOnly{}, nil); diff > 0 {
+			continue
+		}
+		if len(ac.MissingApprovals(pr, &q)) == 0 {
+			return false
+		}
+	}
+	return true
+}
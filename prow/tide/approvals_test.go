@@ -0,0 +1,267 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/repoowners"
+)
+
+type fakeApprovalChecker []string
+
+func (f fakeApprovalChecker) MissingApprovals(pr *PullRequest, q *config.TideQuery) []string {
+	if len(q.RequiredApprovals) == 0 {
+		return nil
+	}
+	return f
+}
+
+type fakeAliasLoader struct {
+	aliases repoowners.RepoAliases
+	err     error
+}
+
+func (f fakeAliasLoader) LoadRepoAliases(org, repo, base string) (repoowners.RepoAliases, error) {
+	return f.aliases, f.err
+}
+
+func TestMissingApprovals(t *testing.T) {
+	start := time.Now()
+	comment := func(login, body string, minutes int) github.IssueComment {
+		return github.IssueComment{
+			User:      github.User{Login: login},
+			Body:      body,
+			CreatedAt: start.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+	review := func(login string, state github.ReviewState, minutes int) github.Review {
+		return github.Review{
+			User:        github.User{Login: login},
+			State:       state,
+			SubmittedAt: start.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+	docs := config.TideRequiredApproval{Paths: []string{"docs/**"}, Team: "docs-approvers"}
+	api := config.TideRequiredApproval{Paths: []string{"*.proto", "api/**"}, Alias: "api-reviewers"}
+
+	testcases := []struct {
+		name     string
+		required []config.TideRequiredApproval
+		changes  []string
+		comments []github.IssueComment
+		reviews  []github.Review
+		aliasErr error
+
+		expected []string
+	}{
+		{
+			name:     "no paths that require approval are changed",
+			required: []config.TideRequiredApproval{docs, api},
+			changes:  []string{"main.go", "pkg/docs.go"},
+		},
+		{
+			name:     "changed paths lack approval",
+			required: []config.TideRequiredApproval{docs, api},
+			changes:  []string{"docs/tide.md", "pkg/types.proto"},
+			comments: []github.IssueComment{comment("someone", "/approve", 1)},
+			expected: []string{"docs/** by docs-approvers", "*.proto, api/** by api-reviewers"},
+		},
+		{
+			name:     "approve comment by a team member",
+			required: []config.TideRequiredApproval{docs},
+			changes:  []string{"docs/tide.md"},
+			comments: []github.IssueComment{comment("Alice", "lgtm\n/approve", 1)},
+		},
+		{
+			name:     "approving review by an alias member",
+			required: []config.TideRequiredApproval{api},
+			changes:  []string{"api/v1/types.go"},
+			reviews:  []github.Review{review("carol", github.ReviewStateApproved, 1)},
+		},
+		{
+			name:     "approval is cancelled",
+			required: []config.TideRequiredApproval{docs},
+			changes:  []string{"docs/tide.md"},
+			comments: []github.IssueComment{comment("alice", "/approve", 1), comment("alice", "/approve cancel", 2)},
+			expected: []string{"docs/** by docs-approvers"},
+		},
+		{
+			name:     "review requesting changes withdraws an approval",
+			required: []config.TideRequiredApproval{docs},
+			changes:  []string{"docs/tide.md"},
+			comments: []github.IssueComment{comment("alice", "/approve", 1)},
+			reviews:  []github.Review{review("alice", github.ReviewStateChangesRequested, 2)},
+			expected: []string{"docs/** by docs-approvers"},
+		},
+		{
+			name:     "approval after requesting changes",
+			required: []config.TideRequiredApproval{docs},
+			changes:  []string{"docs/tide.md"},
+			comments: []github.IssueComment{comment("alice", "/approve", 3)},
+			reviews:  []github.Review{review("alice", github.ReviewStateChangesRequested, 2)},
+		},
+		{
+			name:     "team does not exist",
+			required: []config.TideRequiredApproval{{Paths: []string{"docs/**"}, Team: "missing"}},
+			changes:  []string{"docs/tide.md"},
+			comments: []github.IssueComment{comment("alice", "/approve", 1)},
+			expected: []string{"docs/** by missing"},
+		},
+		{
+			name:     "aliases fail to load",
+			required: []config.TideRequiredApproval{api},
+			changes:  []string{"api/v1/types.go"},
+			reviews:  []github.Review{review("carol", github.ReviewStateApproved, 1)},
+			aliasErr: errors.New("injected error"),
+			expected: []string{"*.proto, api/** by api-reviewers"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pr := testPR("org", "repo", "master", 1, githubql.MergeableStateMergeable)
+			ghc := &fgc{
+				issueComments: map[int][]github.IssueComment{1: tc.comments},
+				reviews:       map[int][]github.Review{1: tc.reviews},
+				teams:         []github.Team{{ID: 5, Name: "Docs Approvers", Slug: "docs-approvers"}},
+				teamMembers:   map[int][]github.TeamMember{5: {{Login: "alice"}, {Login: "bob"}}},
+			}
+			agent := &approvalAgent{
+				logger: logrus.WithField("test", tc.name),
+				ghc:    ghc,
+				owners: fakeAliasLoader{
+					aliases: repoowners.RepoAliases{"api-reviewers": sets.NewString("carol")},
+					err:     tc.aliasErr,
+				},
+				changedFiles: &changedFilesAgent{
+					ghc: ghc,
+					changeCache: map[changeCacheKey][]string{
+						{org: "org", repo: "repo", number: 1, sha: "SHA"}: tc.changes,
+					},
+					nextChangeCache: make(map[changeCacheKey][]string),
+				},
+				teams:     make(map[string]sets.String),
+				approvals: make(map[changeCacheKey]sets.String),
+			}
+
+			missing := agent.MissingApprovals(&pr, &config.TideQuery{RequiredApprovals: tc.required})
+			if !reflect.DeepEqual(missing, tc.expected) {
+				t.Errorf("expected missing approvals %v, got %v", tc.expected, missing)
+			}
+		})
+	}
+}
+
+func TestApproversCache(t *testing.T) {
+	pr := testPR("org", "repo", "master", 1, githubql.MergeableStateMergeable)
+	ghc := &fgc{
+		issueComments: map[int][]github.IssueComment{1: {{User: github.User{Login: "alice"}, Body: "/approve"}}},
+	}
+	agent := &approvalAgent{
+		logger:    logrus.WithField("test", "cache"),
+		ghc:       ghc,
+		teams:     make(map[string]sets.String),
+		approvals: make(map[changeCacheKey]sets.String),
+	}
+	approve := func(step string, expectedLists int) {
+		approvers, err := agent.approvers(&pr)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step, err)
+		}
+		if !approvers.Has("alice") {
+			t.Errorf("%s: expected alice to approve, got %v", step, approvers.List())
+		}
+		if ghc.commentLists != expectedLists {
+			t.Errorf("%s: expected the comments to be listed %d times, got %d", step, expectedLists, ghc.commentLists)
+		}
+	}
+
+	approve("first check", 1)
+	approve("same head", 1)
+	pr.HeadRefOID = "new-SHA"
+	approve("new head", 2)
+	agent.prune()
+	approve("next sync", 3)
+}
+
+func TestLacksApprovals(t *testing.T) {
+	labeled := testPR("org", "repo", "master", 1, githubql.MergeableStateMergeable)
+	labeled.Labels.Nodes = append(labeled.Labels.Nodes, struct{ Name githubql.String }{Name: "lgtm"})
+	docs := []config.TideRequiredApproval{{Paths: []string{"docs/**"}, Team: "docs-approvers"}}
+
+	testcases := []struct {
+		name    string
+		queries config.TideQueries
+		ac      approvalChecker
+
+		expected bool
+	}{
+		{
+			name:    "no checker",
+			queries: config.TideQueries{{Labels: []string{"lgtm"}, RequiredApprovals: docs}},
+		},
+		{
+			name:    "no query requires approvals",
+			queries: config.TideQueries{{Labels: []string{"lgtm"}}},
+			ac:      fakeApprovalChecker{"docs/** by docs-approvers"},
+		},
+		{
+			name:     "matching query lacks approvals",
+			queries:  config.TideQueries{{Labels: []string{"lgtm"}, RequiredApprovals: docs}},
+			ac:       fakeApprovalChecker{"docs/** by docs-approvers"},
+			expected: true,
+		},
+		{
+			name:    "approvals are granted",
+			queries: config.TideQueries{{Labels: []string{"lgtm"}, RequiredApprovals: docs}},
+			ac:      fakeApprovalChecker{},
+		},
+		{
+			name: "another matching query requires no approvals",
+			queries: config.TideQueries{
+				{Labels: []string{"lgtm"}, RequiredApprovals: docs},
+				{Labels: []string{"lgtm"}},
+			},
+			ac: fakeApprovalChecker{"docs/** by docs-approvers"},
+		},
+		{
+			name: "the query without approvals does not match",
+			queries: config.TideQueries{
+				{Labels: []string{"lgtm"}, RequiredApprovals: docs},
+				{Labels: []string{"approved"}},
+			},
+			ac:       fakeApprovalChecker{"docs/** by docs-approvers"},
+			expected: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := lacksApprovals(tc.ac, tc.queries, &labeled); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"fmt"
+	"sync"
+
+	githubql "github.com/shurcooL/githubv4"
+)
+
+// freshBaseAgent holds PRs whose heads are too far behind their base
+// branches to merge on the status contexts that no presubmit reports. Other
+// systems test the heads of PRs, so they tested the base that the head is
+// built on, while Tide only counts ProwJobs that tested the current base and
+// triggers them again once the base moves. Comparisons are cached by both
+// heads until the next prune, so that each sync compares a PR at most once.
+type freshBaseAgent struct {
+	ghc githubClient
+
+	sync.Mutex
+	behind map[baseCacheKey]int
+	// nextBehind caches the comparisons of this sync for the next one. It
+	// becomes behind when prune is called at the end of each sync.
+	nextBehind map[baseCacheKey]int
+	// stale holds the reasons why PRs were left out of the pools of this
+	// sync by PR key, so that the status controller can show them.
+	stale map[string]string
+}
+
+type baseCacheKey struct {
+	org, repo  string
+	base, head string
+}
+
+func newFreshBaseAgent(ghc githubClient) *freshBaseAgent {
+	return &freshBaseAgent{
+		ghc:        ghc,
+		behind:     make(map[baseCacheKey]int),
+		nextBehind: make(map[baseCacheKey]int),
+		stale:      make(map[string]string),
+	}
+}
+
+// commitsBehind returns how many commits of the base the head does not include.
+func (a *freshBaseAgent) commitsBehind(org, repo, base, head string) (int, error) {
+	key := baseCacheKey{org: org, repo: repo, base: base, head: head}
+	a.Lock()
+	behind, ok := a.nextBehind[key]
+	if !ok {
+		behind, ok = a.behind[key]
+	}
+	a.Unlock()
+	if !ok {
+		comparison, err := a.ghc.CompareCommits(org, repo, base, head)
+		if err != nil {
+			return 0, err
+		}
+		behind = comparison.BehindBy
+	}
+	a.Lock()
+	a.nextBehind[key] = behind
+	a.Unlock()
+	return behind, nil
+}
+
+// staleBase determines whether the PR has to be rebased before it merges:
+// whether its head is more than maxBehind commits behind the head of the
+// branch while the PR passes required contexts that no presubmit reports,
+// as checked by isProwContext. PRs that cannot be compared are held too.
+func (a *freshBaseAgent) staleBase(sp *subpool, pr *PullRequest, contexts []Context, maxBehind int, isProwContext func(string) bool) bool {
+	external := false
+	for _, ctx := range contexts {
+		name := string(ctx.Context)
+		if ctx.State == githubql.StatusStateSuccess && name != statusContext && !isProwContext(name) && !sp.cc.IsOptional(name) {
+			external = true
+			break
+		}
+	}
+	if !external {
+		return false
+	}
+
+	var reason string
+	behind, err := a.commitsBehind(sp.org, sp.repo, sp.sha, string(pr.HeadRefOID))
+	if err != nil {
+		sp.log.WithFields(pr.logFields()).WithError(err).Warn("Failed to compare the PR with its base branch, holding it.")
+		reason = fmt.Sprintf(" Failed to compare with %s.", sp.branch)
+	} else if behind > maxBehind {
+		reason = fmt.Sprintf(" %d commits behind %s, needs a rebase.", behind, sp.branch)
+	} else {
+		return false
+	}
+	a.Lock()
+	a.stale[prKey(pr)] = reason
+	a.Unlock()
+	return true
+}
+
+// staleReasons returns why PRs were left out of the pools of this sync.
+func (a *freshBaseAgent) staleReasons() map[string]string {
+	a.Lock()
+	defer a.Unlock()
+	reasons := make(map[string]string, len(a.stale))
+	for key, reason := range a.stale {
+		reasons[key] = reason
+	}
+	return reasons
+}
+
+// prune keeps the comparisons of this sync for the next one and forgets
+// the PRs that were held.
+func (a *freshBaseAgent) prune() {
+	a.Lock()
+	defer a.Unlock()
+	a.behind = a.nextBehind
+	a.nextBehind = make(map[baseCacheKey]int)
+	a.stale = make(map[string]string)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"testing"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestStaleBase(t *testing.T) {
+	testcases := []struct {
+		name     string
+		contexts []Context
+		behind   map[string]int
+
+		expectedStale  bool
+		expectedReason string
+	}{
+		{
+			name: "only prow and tide contexts",
+			contexts: []Context{
+				{Context: "prow-job", State: githubql.StatusStateSuccess},
+				{Context: githubql.String(statusContext), State: githubql.StatusStateSuccess},
+			},
+			behind: map[string]int{"SHA": 10},
+		},
+		{
+			name:     "optional external context",
+			contexts: []Context{{Context: "optional", State: githubql.StatusStateSuccess}},
+			behind:   map[string]int{"SHA": 10},
+		},
+		{
+			name:     "external context close to the base",
+			contexts: []Context{{Context: "external", State: githubql.StatusStateSuccess}},
+			behind:   map[string]int{"SHA": 3},
+		},
+		{
+			name:           "external context far behind the base",
+			contexts:       []Context{{Context: "external", State: githubql.StatusStateSuccess}},
+			behind:         map[string]int{"SHA": 4},
+			expectedStale:  true,
+			expectedReason: " 4 commits behind master, needs a rebase.",
+		},
+		{
+			name:           "failed comparison holds the PR",
+			contexts:       []Context{{Context: "external", State: githubql.StatusStateSuccess}},
+			expectedStale:  true,
+			expectedReason: " Failed to compare with master.",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			agent := newFreshBaseAgent(&fgc{behind: tc.behind})
+			sp := &subpool{
+				log:    logrus.WithField("component", "tide"),
+				org:    "org",
+				repo:   "repo",
+				branch: "master",
+				sha:    "master-sha",
+				cc:     &config.TideContextPolicy{OptionalContexts: []string{"optional"}},
+			}
+			pr := testPR("org", "repo", "master", 1, githubql.MergeableStateMergeable)
+			isProwContext := func(context string) bool { return context == "prow-job" }
+
+			if stale := agent.staleBase(sp, &pr, tc.contexts, 3, isProwContext); stale != tc.expectedStale {
+				t.Errorf("expected stale %t, got %t", tc.expectedStale, stale)
+			}
+			if reason := agent.staleReasons()[prKey(&pr)]; reason != tc.expectedReason {
+				t.Errorf("expected reason %q, got %q", tc.expectedReason, reason)
+			}
+		})
+	}
+}
+
+func TestCommitsBehindCache(t *testing.T) {
+	ghc := &fgc{behind: map[string]int{"head": 2}}
+	agent := newFreshBaseAgent(ghc)
+	syncs := []struct {
+		base string
+
+		expectedComparisons int
+	}{
+		{base: "base", expectedComparisons: 1},
+		{base: "base", expectedComparisons: 1},
+		{base: "moved", expectedComparisons: 2},
+	}
+	for i, s := range syncs {
+		for j := 0; j < 2; j++ {
+			if behind, err := agent.commitsBehind("org", "repo", s.base, "head"); err != nil || behind != 2 {
+				t.Errorf("sync %d: expected 2 commits behind, got %d and error %v", i, behind, err)
+			}
+		}
+		if ghc.comparisons != s.expectedComparisons {
+			t.Errorf("sync %d: expected %d comparisons, got %d", i, s.expectedComparisons, ghc.comparisons)
+		}
+		agent.prune()
+	}
+}
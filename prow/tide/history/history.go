@@ -20,8 +20,13 @@ package history
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"os"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,6 +38,9 @@ import (
 // Mock out time for unit testing.
 var now = time.Now
 
+// objectName is the name of the object that records are persisted in.
+const objectName = "history.json"
+
 // History uses a `*recordLog` per pool to store a record of recent actions that
 // Tide has taken. Using a log per pool ensure that history is retained
 // for inactive pools even if other pools are very active.
@@ -41,6 +49,11 @@ type History struct {
 	sync.Mutex
 
 	logSizeLimit int
+
+	// storage persists the records if set, dirty tracks whether
+	// records changed since they were last persisted.
+	storage Storage
+	dirty   bool
 }
 
 // Record is an entry describing one action that Tide has taken (e.g. TRIGGER or MERGE).
@@ -60,6 +73,32 @@ func New(maxRecordsPerKey int) *History {
 	}
 }
 
+// NewPersisted creates a History like New that starts out with the records
+// persisted in the storage and persists its records there when flushed.
+func NewPersisted(maxRecordsPerKey int, storage Storage) (*History, error) {
+	h := New(maxRecordsPerKey)
+	h.storage = storage
+	raw, err := storage.Read(objectName)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", objectName, err)
+	}
+	var records map[string][]*Record
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return nil, fmt.Errorf("error unmarshaling %s: %v", objectName, err)
+	}
+	for poolKey, recs := range records {
+		log := newRecordLog(h.logSizeLimit)
+		// Records are persisted newest first.
+		for i := len(recs) - 1; i >= 0; i-- {
+			log.add(recs[i])
+		}
+		h.logs[poolKey] = log
+	}
+	return h, nil
+}
+
 // Record appends an entry to the recordlog specified by the poolKey.
 func (h *History) Record(poolKey, action, baseSHA, err string, targets []prowapi.Pull) {
 	t := now()
@@ -77,11 +116,100 @@ func (h *History) Record(poolKey, action, baseSHA, err string, targets []prowapi
 		Target:  targets,
 		Err:     err,
 	})
+	h.dirty = true
+}
+
+// Targeted determines if the pool has a record of the action
+// that targeted the PR at the given SHA.
+func (h *History) Targeted(poolKey, action string, number int, sha string) bool {
+	h.Lock()
+	defer h.Unlock()
+	log, ok := h.logs[poolKey]
+	if !ok {
+		return false
+	}
+	for _, rec := range log.toSlice() {
+		if rec.Action != action {
+			continue
+		}
+		for _, pr := range rec.Target {
+			if pr.Number == number && pr.SHA == sha {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Prune drops the records that are older than the retention. Records
+// are only limited in number if the retention is not positive.
+func (h *History) Prune(retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	cutoff := now().Add(-retention)
+
+	h.Lock()
+	defer h.Unlock()
+	for poolKey, log := range h.logs {
+		if !log.prune(cutoff) {
+			continue
+		}
+		h.dirty = true
+		if len(log.buff) == 0 {
+			delete(h.logs, poolKey)
+		}
+	}
+}
+
+// Flush persists the records if they changed since they were last
+// persisted. It does nothing for a History that is not persisted.
+func (h *History) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	if h.storage == nil || !h.dirty {
+		return nil
+	}
+
+	records := make(map[string][]*Record, len(h.logs))
+	for key, log := range h.logs {
+		records[key] = log.toSlice()
+	}
+	raw, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("error marshaling records: %v", err)
+	}
+	if err := h.storage.Write(objectName, raw); err != nil {
+		return fmt.Errorf("error writing %s: %v", objectName, err)
+	}
+	h.dirty = false
+	return nil
 }
 
 // ServeHTTP serves a JSON mapping from pool key -> sorted records for the pool.
+// The records can be selected with the parameters that ParseQuery reads.
 func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	b, err := json.Marshal(h.AllRecords())
+	q, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res := map[string][]*Record{}
+	for poolKey, records := range h.AllRecords() {
+		if !q.MatchesPool(poolKey) {
+			continue
+		}
+		var matching []*Record
+		for _, rec := range records {
+			if q.Matches(*rec) {
+				matching = append(matching, rec)
+			}
+		}
+		if len(matching) > 0 {
+			res[poolKey] = matching
+		}
+	}
+	b, err := json.Marshal(res)
 	if err != nil {
 		logrus.WithError(err).Error("Encoding JSON history.")
 		b = []byte("{}")
@@ -103,6 +231,65 @@ func (h *History) AllRecords() map[string][]*Record {
 	return res
 }
 
+// Query selects the records of a repo, of a PR or of a time range.
+// The zero Query selects all records.
+type Query struct {
+	// Repo is the org/repo whose pools are selected.
+	Repo string
+	// PR is the number of the PR that selected records target.
+	PR int
+	// From and To bound the times of selected records, if set.
+	From time.Time
+	To   time.Time
+}
+
+// ParseQuery reads a query such as ?repo=org/repo&pr=123&from=2019-02-01T00:00:00Z,
+// where from and to are RFC 3339 times.
+func ParseQuery(values url.Values) (Query, error) {
+	q := Query{Repo: values.Get("repo")}
+	if pr := values.Get("pr"); pr != "" {
+		number, err := strconv.Atoi(pr)
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid pr %q: %v", pr, err)
+		}
+		q.PR = number
+	}
+	for param, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := values.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return Query{}, fmt.Errorf("invalid %s %q: %v", param, v, err)
+			}
+			*t = parsed
+		}
+	}
+	return q, nil
+}
+
+// MatchesPool determines if the query selects records of the pool.
+func (q Query) MatchesPool(poolKey string) bool {
+	return q.Repo == "" || strings.SplitN(poolKey, ":", 2)[0] == q.Repo
+}
+
+// Matches determines if the query selects the record of a selected pool.
+func (q Query) Matches(rec Record) bool {
+	if !q.From.IsZero() && rec.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && rec.Time.After(q.To) {
+		return false
+	}
+	if q.PR == 0 {
+		return true
+	}
+	for _, pr := range rec.Target {
+		if pr.Number == q.PR {
+			return true
+		}
+	}
+	return false
+}
+
 // recordLog is a space efficient, limited size, append only list.
 type recordLog struct {
 	buff  []*Record
@@ -135,6 +322,25 @@ func (rl *recordLog) add(rec *Record) {
 	}
 }
 
+// prune drops the records older than the cutoff and
+// reports whether there were any.
+func (rl *recordLog) prune(cutoff time.Time) bool {
+	records := rl.toSlice()
+	keep := len(records)
+	for keep > 0 && records[keep-1].Time.Before(cutoff) {
+		keep--
+	}
+	if keep == len(records) {
+		return false
+	}
+
+	rl.buff, rl.head, rl.cachedSlice = nil, -1, nil
+	for i := keep - 1; i >= 0; i-- {
+		rl.add(records[i])
+	}
+	return true
+}
+
 func (rl *recordLog) toSlice() []*Record {
 	if rl.cachedSlice != nil {
 		return rl.cachedSlice
@@ -19,6 +19,9 @@ package history
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -119,3 +122,247 @@ func TestHistory(t *testing.T) {
 		t.Logf("strs equal: %v.", string(es) == string(gs))
 	}
 }
+
+type fakeStorage struct {
+	objects map[string][]byte
+	writes  int
+}
+
+func (fs *fakeStorage) Read(name string) ([]byte, error) {
+	content, ok := fs.objects[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return content, nil
+}
+
+func (fs *fakeStorage) Write(name string, content []byte) error {
+	fs.objects[name] = content
+	fs.writes++
+	return nil
+}
+
+func TestPersistence(t *testing.T) {
+	var nowTime = time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)
+	oldNow := now
+	now = func() time.Time { return nowTime }
+	defer func() { now = oldNow }()
+
+	storage := &fakeStorage{objects: map[string][]byte{}}
+	hist, err := NewPersisted(2, storage)
+	if err != nil {
+		t.Fatalf("Error creating history from empty storage: %v", err)
+	}
+	if err := hist.Flush(); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+	if storage.writes != 0 {
+		t.Errorf("Expected no write without records, got %d.", storage.writes)
+	}
+
+	for i := 1; i <= 3; i++ {
+		nowTime = nowTime.Add(time.Minute)
+		hist.Record("pool A", "MERGE", "sha", "", []prowapi.Pull{{Number: i}})
+	}
+	hist.Record("pool B", "TRIGGER", "sha", "", []prowapi.Pull{{Number: 4}})
+	if err := hist.Flush(); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+	if err := hist.Flush(); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+	if storage.writes != 1 {
+		t.Errorf("Expected a single write for unchanged records, got %d.", storage.writes)
+	}
+
+	restored, err := NewPersisted(2, storage)
+	if err != nil {
+		t.Fatalf("Error restoring history: %v", err)
+	}
+	if expected, got := hist.AllRecords(), restored.AllRecords(); !reflect.DeepEqual(got, expected) {
+		es, _ := json.Marshal(expected)
+		gs, _ := json.Marshal(got)
+		t.Errorf("Expected restored history \n%s, but got \n%s.", es, gs)
+	}
+
+	// The restored logs keep their size limit.
+	restored.Record("pool A", "MERGE", "sha", "", []prowapi.Pull{{Number: 5}})
+	if records := restored.AllRecords()["pool A"]; len(records) != 2 || records[0].Target[0].Number != 5 || records[1].Target[0].Number != 3 {
+		t.Errorf("Expected records for PRs 5 and 3, got %+v.", records)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	var nowTime = time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)
+	oldNow := now
+	now = func() time.Time { return nowTime }
+	defer func() { now = oldNow }()
+
+	hist := New(10)
+	hist.Record("pool A", "MERGE", "sha", "", []prowapi.Pull{{Number: 1}})
+	hist.Record("pool B", "MERGE", "sha", "", []prowapi.Pull{{Number: 2}})
+	nowTime = nowTime.Add(2 * time.Hour)
+	hist.Record("pool B", "MERGE", "sha", "", []prowapi.Pull{{Number: 3}})
+	nowTime = nowTime.Add(30 * time.Minute)
+
+	hist.Prune(0)
+	if records := hist.AllRecords(); len(records) != 2 || len(records["pool B"]) != 2 {
+		t.Errorf("Expected no record to be pruned without retention, got %+v.", records)
+	}
+
+	hist.Prune(time.Hour)
+	records := hist.AllRecords()
+	if _, ok := records["pool A"]; ok {
+		t.Errorf("Expected pool A without recent records to be dropped, got %+v.", records["pool A"])
+	}
+	if len(records["pool B"]) != 1 || records["pool B"][0].Target[0].Number != 3 {
+		t.Errorf("Expected only the record for PR 3 in pool B, got %+v.", records["pool B"])
+	}
+}
+
+func TestTargeted(t *testing.T) {
+	hist := New(10)
+	hist.Record("pool A", "REMOVE_CULPRIT", "sha", "", []prowapi.Pull{{Number: 1, SHA: "head-1"}, {Number: 2, SHA: "head-2"}})
+	hist.Record("pool A", "MERGE", "sha", "", []prowapi.Pull{{Number: 3, SHA: "head-3"}})
+
+	testcases := []struct {
+		name     string
+		pool     string
+		action   string
+		number   int
+		sha      string
+		expected bool
+	}{
+		{name: "targeted PR", pool: "pool A", action: "REMOVE_CULPRIT", number: 2, sha: "head-2", expected: true},
+		{name: "PR at another SHA", pool: "pool A", action: "REMOVE_CULPRIT", number: 2, sha: "head-2-updated"},
+		{name: "PR targeted by another action", pool: "pool A", action: "REMOVE_CULPRIT", number: 3, sha: "head-3"},
+		{name: "other pool", pool: "pool B", action: "REMOVE_CULPRIT", number: 1, sha: "head-1"},
+	}
+	for _, tc := range testcases {
+		if actual := hist.Targeted(tc.pool, tc.action, tc.number, tc.sha); actual != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	base := time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC)
+	record := Record{
+		Time:   base,
+		Action: "MERGE_BATCH",
+		Target: []prowapi.Pull{{Number: 1}, {Number: 2}},
+	}
+	testcases := []struct {
+		name   string
+		values url.Values
+		pool   string
+
+		expectedErr   bool
+		expectedMatch bool
+	}{
+		{
+			name:          "empty query matches everything",
+			pool:          "org/repo:master",
+			expectedMatch: true,
+		},
+		{
+			name:          "repo matches pools of all branches",
+			values:        url.Values{"repo": {"org/repo"}},
+			pool:          "org/repo:release-1.0",
+			expectedMatch: true,
+		},
+		{
+			name:   "other repo does not match",
+			values: url.Values{"repo": {"org/repo"}},
+			pool:   "org/repo-other:master",
+		},
+		{
+			name:          "targeted PR matches",
+			values:        url.Values{"pr": {"2"}},
+			pool:          "org/repo:master",
+			expectedMatch: true,
+		},
+		{
+			name:   "untargeted PR does not match",
+			values: url.Values{"pr": {"3"}},
+			pool:   "org/repo:master",
+		},
+		{
+			name:          "record within time range matches",
+			values:        url.Values{"from": {"2019-01-31T00:00:00Z"}, "to": {"2019-02-02T00:00:00Z"}},
+			pool:          "org/repo:master",
+			expectedMatch: true,
+		},
+		{
+			name:   "record before time range does not match",
+			values: url.Values{"from": {"2019-02-01T01:00:00Z"}},
+			pool:   "org/repo:master",
+		},
+		{
+			name:   "record after time range does not match",
+			values: url.Values{"to": {"2019-01-31T00:00:00Z"}},
+			pool:   "org/repo:master",
+		},
+		{
+			name:        "invalid PR",
+			values:      url.Values{"pr": {"one"}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid time",
+			values:      url.Values{"from": {"yesterday"}},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		q, err := ParseQuery(tc.values)
+		if err != nil {
+			if !tc.expectedErr {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if tc.expectedErr {
+			t.Errorf("%s: expected an error, got query %+v", tc.name, q)
+			continue
+		}
+		if match := q.MatchesPool(tc.pool) && q.Matches(record); match != tc.expectedMatch {
+			t.Errorf("%s: expected match %v, got %v", tc.name, tc.expectedMatch, match)
+		}
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := NewStorage(dir, "")
+	if err != nil {
+		t.Fatalf("Error creating storage: %v", err)
+	}
+	if _, err := storage.Read("history.json"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error reading a missing object, got %v.", err)
+	}
+	for _, content := range []string{"first", "second"} {
+		if err := storage.Write("history.json", []byte(content)); err != nil {
+			t.Fatalf("Error writing: %v", err)
+		}
+		read, err := storage.Read("history.json")
+		if err != nil {
+			t.Fatalf("Error reading: %v", err)
+		}
+		if string(read) != content {
+			t.Errorf("Expected to read %q, got %q.", content, string(read))
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Error listing directory: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected no temporary files to be left behind, got %d files.", len(files))
+	}
+}
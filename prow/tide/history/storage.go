@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// Storage reads and writes the objects that Tide persists its history
+// and pools in, so that they survive restarts.
+type Storage interface {
+	// Read returns the content of the named object. The error satisfies
+	// os.IsNotExist if the object was never written.
+	Read(name string) ([]byte, error)
+	// Write replaces the content of the named object.
+	Write(name string, content []byte) error
+}
+
+// NewStorage creates the storage at a location that is either a GCS URI
+// like gs://bucket/path or a local directory.
+func NewStorage(location, gcsCredentialsFile string) (Storage, error) {
+	if !strings.HasPrefix(location, "gs://") {
+		if err := os.MkdirAll(location, 0755); err != nil {
+			return nil, fmt.Errorf("error creating %s: %v", location, err)
+		}
+		return &fileStorage{dir: location}, nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(location, "gs://"), "/", 2)
+	if parts[0] == "" {
+		return nil, fmt.Errorf("%s does not name a bucket", location)
+	}
+	var opts []option.ClientOption
+	if gcsCredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(gcsCredentialsFile))
+	}
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GCS client: %v", err)
+	}
+	gs := &gcsStorage{bucket: client.Bucket(parts[0])}
+	if len(parts) == 2 {
+		gs.prefix = strings.Trim(parts[1], "/")
+	}
+	return gs, nil
+}
+
+// fileStorage stores objects as files in a directory.
+type fileStorage struct {
+	dir string
+}
+
+func (fs *fileStorage) Read(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(fs.dir, name))
+}
+
+// Write replaces the file by renaming a temporary file over it so
+// that a crash in the middle of a write does not lose the old content.
+func (fs *fileStorage) Write(name string, content []byte) error {
+	tmp, err := ioutil.TempFile(fs.dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(fs.dir, name))
+}
+
+// gcsStorage stores objects in a GCS bucket under a prefix.
+type gcsStorage struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+func (gs *gcsStorage) object(name string) *storage.ObjectHandle {
+	if gs.prefix != "" {
+		name = gs.prefix + "/" + name
+	}
+	return gs.bucket.Object(name)
+}
+
+func (gs *gcsStorage) Read(name string) ([]byte, error) {
+	r, err := gs.object(name).NewReader(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return nil, os.ErrNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (gs *gcsStorage) Write(name string, content []byte) error {
+	w := gs.object(name).NewWriter(context.Background())
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+// MergeCommitData is what the templates of merge commits are executed with.
+type MergeCommitData struct {
+	Org    string
+	Repo   string
+	Number int
+	Title  string
+	// Body is the body of the PR without the stripped sections.
+	Body   string
+	Author string
+	Labels []string
+	// Reviewers are the logins that approved the PR in a review, in the
+	// order they first approved it.
+	Reviewers []string
+}
+
+// Section returns the trimmed text under a heading of the body, up to the
+// next heading. Both Markdown headings like "## Notes" and lines in bold
+// like "**Special notes for your reviewer**:" are headings. The heading is
+// matched case-insensitively and without the trailing colon.
+func (d MergeCommitData) Section(heading string) string {
+	var lines []string
+	in := false
+	for _, line := range strings.Split(d.Body, "\n") {
+		if title, ok := headingOf(line); ok {
+			if in {
+				break
+			}
+			in = strings.EqualFold(title, strings.TrimSpace(heading))
+			continue
+		}
+		if in {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// headingOf returns the title of a heading line.
+func headingOf(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "#"):
+		line = strings.TrimLeft(line, "#")
+	case strings.HasPrefix(line, "**") && strings.HasSuffix(strings.TrimSuffix(line, ":"), "**"):
+		line = strings.Trim(line, "*:")
+	default:
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), ":")), true
+}
+
+// stripSections removes every section from its start marker to the next end
+// marker. Sections whose end marker is missing are kept.
+func stripSections(body string, sections []config.TideStripSection) string {
+	for _, section := range sections {
+		var stripped strings.Builder
+		rest := body
+		for {
+			start := strings.Index(rest, section.Start)
+			if start == -1 {
+				break
+			}
+			end := strings.Index(rest[start+len(section.Start):], section.End)
+			if end == -1 {
+				break
+			}
+			stripped.WriteString(rest[:start])
+			rest = rest[start+len(section.Start)+end+len(section.End):]
+		}
+		stripped.WriteString(rest)
+		body = stripped.String()
+	}
+	return strings.TrimSpace(body)
+}
+
+// mergeCommit returns the title and the message of the merge commit of a
+// PR. Either is empty, so that GitHub uses its default, if its template is
+// not configured or fails to execute. The body of the PR is only fetched
+// here rather than with the PRs of the pool, as it is only needed for the
+// PRs that merge.
+func (c *Controller) mergeCommit(templ config.TideMergeCommitTemplate, pr *PullRequest, log *logrus.Entry) (string, string) {
+	if templ.Title == nil && templ.Body == nil {
+		return "", ""
+	}
+	data := MergeCommitData{
+		Org:    string(pr.Repository.Owner.Login),
+		Repo:   string(pr.Repository.Name),
+		Number: int(pr.Number),
+		Title:  string(pr.Title),
+		Author: string(pr.Author.Login),
+		Labels: prLabels(*pr),
+	}
+	if full, err := c.ghc.GetPullRequest(data.Org, data.Repo, data.Number); err != nil {
+		log.WithError(err).Warn("Failed to get the PR for the merge commit, it will have no body.")
+	} else {
+		data.Body = stripSections(full.Body, templ.StripSections)
+	}
+	reviews, err := c.ghc.ListReviews(data.Org, data.Repo, data.Number)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list the reviews for the merge commit, it will have no reviewers.")
+	}
+	reviewers := sets.NewString()
+	for _, review := range reviews {
+		if review.State == github.ReviewStateApproved && !reviewers.Has(review.User.Login) {
+			reviewers.Insert(review.User.Login)
+			data.Reviewers = append(data.Reviewers, review.User.Login)
+		}
+	}
+
+	execute := func(t *template.Template) string {
+		if t == nil {
+			return ""
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			log.WithError(err).Errorf("Failed to execute the %s template, using the default.", t.Name())
+			return ""
+		}
+		return b.String()
+	}
+	return strings.TrimSpace(execute(templ.Title)), execute(templ.Body)
+}
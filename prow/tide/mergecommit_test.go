@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"testing"
+	"text/template"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+const testPRBody = `**What this PR does / why we need it**:
+Adds merge commit templates.
+
+<!-- Delete this comment. -->
+**Special notes for your reviewer**:
+None.
+
+**Does this PR introduce a user-facing change?**:
+` + "```release-note" + `
+Tide supports merge commit templates.
+` + "```" + `
+
+## Notes
+
+Thanks!`
+
+func TestStripSections(t *testing.T) {
+	testcases := []struct {
+		name     string
+		body     string
+		sections []config.TideStripSection
+
+		expected string
+	}{
+		{
+			name:     "nothing to strip",
+			body:     "Fixes the flake.\n",
+			sections: []config.TideStripSection{{Start: "<!--", End: "-->"}},
+			expected: "Fixes the flake.",
+		},
+		{
+			name:     "comments are stripped",
+			body:     "<!-- Describe the change. -->Fixes the flake.<!-- Thanks! -->",
+			sections: []config.TideStripSection{{Start: "<!--", End: "-->"}},
+			expected: "Fixes the flake.",
+		},
+		{
+			name: "release note block is stripped",
+			body: "Fixes the flake.\n```release-note\nNONE\n```\nThanks!",
+			sections: []config.TideStripSection{
+				{Start: "```release-note", End: "```"},
+			},
+			expected: "Fixes the flake.\n\nThanks!",
+		},
+		{
+			name:     "unterminated section is kept",
+			body:     "Fixes the flake. <!-- Thanks!",
+			sections: []config.TideStripSection{{Start: "<!--", End: "-->"}},
+			expected: "Fixes the flake. <!-- Thanks!",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := stripSections(tc.body, tc.sections); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMergeCommitDataSection(t *testing.T) {
+	data := MergeCommitData{Body: testPRBody}
+	testcases := []struct {
+		heading  string
+		expected string
+	}{
+		{heading: "What this PR does / why we need it", expected: "Adds merge commit templates.\n\n<!-- Delete this comment. -->"},
+		{heading: "special notes for your reviewer", expected: "None."},
+		{heading: "Notes", expected: "Thanks!"},
+		{heading: "Missing", expected: ""},
+	}
+	for _, tc := range testcases {
+		if actual := data.Section(tc.heading); actual != tc.expected {
+			t.Errorf("expected section %q to be %q, got %q", tc.heading, tc.expected, actual)
+		}
+	}
+}
+
+func TestMergeCommit(t *testing.T) {
+	pr := testPR("org", "repo", "master", 5, githubql.MergeableStateMergeable)
+	pr.Title = "Add merge commit templates"
+	pr.Author.Login = "alice"
+	pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: "kind/feature"})
+	ghc := &fgc{
+		bodies: map[int]string{5: testPRBody},
+		reviews: map[int][]github.Review{5: {
+			{User: github.User{Login: "carol"}, State: github.ReviewStateApproved},
+			{User: github.User{Login: "bob"}, State: github.ReviewStateApproved},
+			{User: github.User{Login: "dave"}, State: github.ReviewStateCommented},
+			{User: github.User{Login: "carol"}, State: github.ReviewStateApproved},
+		}},
+	}
+	c := &Controller{ghc: ghc}
+
+	testcases := []struct {
+		name  string
+		title string
+		body  string
+		strip []config.TideStripSection
+
+		expectedTitle   string
+		expectedMessage string
+	}{
+		{
+			name: "no templates",
+		},
+		{
+			name:          "only title",
+			title:         "{{ .Title }} (#{{ .Number }})\n",
+			expectedTitle: "Add merge commit templates (#5)",
+		},
+		{
+			name:  "body with sections, labels and reviewers",
+			body:  "{{ .Section \"What this PR does / why we need it\" }}\n\nAuthor: {{ .Author }}\nLabels: {{ range .Labels }}{{ . }} {{ end }}\nReviewed-by: {{ range .Reviewers }}{{ . }} {{ end }}",
+			strip: []config.TideStripSection{{Start: "<!--", End: "-->"}},
+
+			expectedMessage: "Adds merge commit templates.\n\nAuthor: alice\nLabels: kind/feature \nReviewed-by: carol bob ",
+		},
+		{
+			name:  "failing template",
+			title: "{{ .Missing }}",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			templ := config.TideMergeCommitTemplate{StripSections: tc.strip}
+			if tc.title != "" {
+				templ.Title = template.Must(template.New("CommitTitle").Parse(tc.title))
+			}
+			if tc.body != "" {
+				templ.Body = template.Must(template.New("CommitBody").Parse(tc.body))
+			}
+			title, message := c.mergeCommit(templ, &pr, logrus.WithField("test", tc.name))
+			if title != tc.expectedTitle {
+				t.Errorf("expected title %q, got %q", tc.expectedTitle, title)
+			}
+			if message != tc.expectedMessage {
+				t.Errorf("expected message %q, got %q", tc.expectedMessage, message)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+// poolTracker keeps the state that the metrics spanning several syncs are
+// computed from: when PRs entered the pool, how often they were tested and
+// which batches were already counted. The state is persisted with the pools
+// if Tide has a storage. Otherwise PRs that were pooled when Tide started
+// count as entering the pool then.
+type poolTracker struct {
+	sync.Mutex
+	// entered maps the keys of pooled PRs to when they entered the pool.
+	entered map[string]time.Time
+	// runs maps the keys of pooled PRs to the most times any presubmit ran
+	// on their heads.
+	runs map[string]int
+	// batches maps pools to the completed batches that were counted.
+	batches map[string]sets.String
+}
+
+func newPoolTracker() *poolTracker {
+	return &poolTracker{
+		entered: make(map[string]time.Time),
+		runs:    make(map[string]int),
+		batches: make(map[string]sets.String),
+	}
+}
+
+// trackerObjectName is the name of the object that the state of the
+// pool tracker is persisted in.
+const trackerObjectName = "pooltracker.json"
+
+// poolTrackerState is the persisted state of a poolTracker.
+type poolTrackerState struct {
+	Entered map[string]time.Time `json:"entered,omitempty"`
+	Runs    map[string]int       `json:"runs,omitempty"`
+	Batches map[string][]string  `json:"batches,omitempty"`
+}
+
+// MarshalJSON encodes the state of the tracker.
+func (t *poolTracker) MarshalJSON() ([]byte, error) {
+	t.Lock()
+	defer t.Unlock()
+	state := poolTrackerState{
+		Entered: t.entered,
+		Runs:    t.runs,
+		Batches: make(map[string][]string, len(t.batches)),
+	}
+	for pool, batches := range t.batches {
+		state.Batches[pool] = batches.List()
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON restores the state of the tracker.
+func (t *poolTracker) UnmarshalJSON(raw []byte) error {
+	var state poolTrackerState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return err
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.entered = make(map[string]time.Time, len(state.Entered))
+	for key, start := range state.Entered {
+		t.entered[key] = start
+	}
+	t.runs = make(map[string]int, len(state.Runs))
+	for key, n := range state.Runs {
+		t.runs[key] = n
+	}
+	t.batches = make(map[string]sets.String, len(state.Batches))
+	for pool, batches := range state.Batches {
+		t.batches[pool] = sets.NewString(batches...)
+	}
+	return nil
+}
+
+// update records the PRs that entered the pool and forgets those that left,
+// along with how often their presubmits ran.
+func (t *poolTracker) update(prs map[string]PullRequest, pjs []prowapi.ProwJob, now time.Time) {
+	runs := make(map[string]map[string]int)
+	for _, pj := range pjs {
+		if pj.Spec.Type != prowapi.PresubmitJob || pj.Spec.Refs == nil || len(pj.Spec.Refs.Pulls) == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s#%d", pj.Spec.Refs.Org, pj.Spec.Refs.Repo, pj.Spec.Refs.Pulls[0].Number)
+		if pr, ok := prs[key]; !ok || string(pr.HeadRefOID) != pj.Spec.Refs.Pulls[0].SHA {
+			continue
+		}
+		if runs[key] == nil {
+			runs[key] = make(map[string]int)
+		}
+		runs[key][pj.Spec.Context]++
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	entered := make(map[string]time.Time, len(prs))
+	t.runs = make(map[string]int, len(prs))
+	for key := range prs {
+		if start, ok := t.entered[key]; ok {
+			entered[key] = start
+		} else {
+			entered[key] = now
+		}
+		for _, n := range runs[key] {
+			if n > t.runs[key] {
+				t.runs[key] = n
+			}
+		}
+	}
+	t.entered = entered
+}
+
+// merged observes how long the merged PRs were in the pool and how often
+// they were retested.
+func (t *poolTracker) merged(sp subpool, prs []PullRequest, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	for _, pr := range prs {
+		key := prKey(&pr)
+		if start, ok := t.entered[key]; ok {
+			tideMetrics.poolTime.WithLabelValues(sp.org, sp.repo).Observe(now.Sub(start).Seconds())
+		}
+		retests := 0
+		if t.runs[key] > 1 {
+			retests = t.runs[key] - 1
+		}
+		tideMetrics.retests.WithLabelValues(sp.org, sp.repo).Observe(float64(retests))
+	}
+}
+
+// countBatches counts the batches of the subpool that completed since the
+// last sync by their result.
+func (t *poolTracker) countBatches(sp subpool, batches []batchResult) {
+	completed := sets.NewString()
+	t.Lock()
+	defer t.Unlock()
+	counted := t.batches[poolKey(sp.org, sp.repo, sp.branch)]
+	for _, batch := range batches {
+		if batch.state == pendingState {
+			continue
+		}
+		var pulls []string
+		for _, pr := range batch.prs {
+			pulls = append(pulls, fmt.Sprintf("%d:%s", pr.Number, pr.HeadRefOID))
+		}
+		sort.Strings(pulls)
+		key := fmt.Sprintf("%s%v", sp.sha, pulls)
+		completed.Insert(key)
+		if counted.Has(key) {
+			continue
+		}
+		tideMetrics.batchResults.WithLabelValues(sp.org, sp.repo, string(batch.state)).Inc()
+	}
+	// Only batches that still test the current base can complete again.
+	t.batches[poolKey(sp.org, sp.repo, sp.branch)] = completed
+}
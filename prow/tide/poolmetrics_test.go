@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	githubql "github.com/shurcooL/githubv4"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+)
+
+func TestPoolTrackerUpdate(t *testing.T) {
+	start := time.Now()
+	pooled := testPR("org", "repo", "master", 1, githubql.MergeableStateMergeable)
+	entering := testPR("org", "repo", "master", 2, githubql.MergeableStateMergeable)
+	leaving := testPR("org", "repo", "master", 3, githubql.MergeableStateMergeable)
+	run := func(number int, sha, context string) prowapi.ProwJob {
+		return prowapi.ProwJob{Spec: prowapi.ProwJobSpec{
+			Type:    prowapi.PresubmitJob,
+			Context: context,
+			Refs: &prowapi.Refs{
+				Org:   "org",
+				Repo:  "repo",
+				Pulls: []prowapi.Pull{{Number: number, SHA: sha}},
+			},
+		}}
+	}
+
+	tracker := newPoolTracker()
+	tracker.entered = map[string]time.Time{
+		prKey(&pooled):  start,
+		prKey(&leaving): start,
+	}
+	pjs := []prowapi.ProwJob{
+		run(1, "SHA", "unit"),
+		run(1, "SHA", "unit"),
+		run(1, "SHA", "unit"),
+		run(1, "SHA", "e2e"),
+		run(1, "old", "e2e"),
+		run(2, "SHA", "unit"),
+		{Spec: prowapi.ProwJobSpec{Type: prowapi.BatchJob, Context: "unit", Refs: &prowapi.Refs{
+			Org:   "org",
+			Repo:  "repo",
+			Pulls: []prowapi.Pull{{Number: 2, SHA: "SHA"}, {Number: 1, SHA: "SHA"}},
+		}}},
+	}
+	now := start.Add(time.Hour)
+	tracker.update(byRepoAndNumber([]PullRequest{pooled, entering}), pjs, now)
+
+	expectedEntered := map[string]time.Time{
+		prKey(&pooled):   start,
+		prKey(&entering): now,
+	}
+	if !reflect.DeepEqual(tracker.entered, expectedEntered) {
+		t.Errorf("expected PRs to have entered the pool at %v, got %v", expectedEntered, tracker.entered)
+	}
+	expectedRuns := map[string]int{
+		prKey(&pooled):   3,
+		prKey(&entering): 1,
+	}
+	if !reflect.DeepEqual(tracker.runs, expectedRuns) {
+		t.Errorf("expected presubmit runs %v, got %v", expectedRuns, tracker.runs)
+	}
+}
+
+func TestPoolTrackerCountBatches(t *testing.T) {
+	counter := func(result string) float64 {
+		var m dto.Metric
+		if err := tideMetrics.batchResults.WithLabelValues("org", "batches", result).Write(&m); err != nil {
+			t.Fatalf("failed to read counter: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	prs := []PullRequest{
+		testPR("org", "batches", "master", 1, githubql.MergeableStateMergeable),
+		testPR("org", "batches", "master", 2, githubql.MergeableStateMergeable),
+		testPR("org", "batches", "master", 3, githubql.MergeableStateMergeable),
+	}
+	sp := subpool{org: "org", repo: "batches", branch: "master", sha: "base"}
+	tracker := newPoolTracker()
+
+	syncs := []struct {
+		sha     string
+		restart bool
+		batches []batchResult
+
+		expectedSuccesses float64
+		expectedFailures  float64
+	}{
+		{
+			sha:     "base",
+			batches: []batchResult{{prs: prs[:2], state: pendingState}},
+		},
+		{
+			sha: "base",
+			batches: []batchResult{
+				{prs: prs[:2], state: failureState},
+				{prs: prs[1:], state: successState},
+			},
+			expectedSuccesses: 1,
+			expectedFailures:  1,
+		},
+		{
+			sha:     "base",
+			restart: true,
+			batches: []batchResult{
+				{prs: prs[:2], state: failureState},
+				{prs: []PullRequest{prs[2], prs[1]}, state: successState},
+			},
+			expectedSuccesses: 1,
+			expectedFailures:  1,
+		},
+		{
+			sha:               "merged",
+			batches:           []batchResult{{prs: prs[:1], state: successState}},
+			expectedSuccesses: 2,
+			expectedFailures:  1,
+		},
+	}
+	for i, s := range syncs {
+		if s.restart {
+			raw, err := json.Marshal(tracker)
+			if err != nil {
+				t.Fatalf("sync %d: failed to marshal the tracker: %v", i, err)
+			}
+			tracker = newPoolTracker()
+			if err := json.Unmarshal(raw, tracker); err != nil {
+				t.Fatalf("sync %d: failed to unmarshal the tracker: %v", i, err)
+			}
+		}
+		sp.sha = s.sha
+		tracker.countBatches(sp, s.batches)
+		if successes, failures := counter("success"), counter("failure"); successes != s.expectedSuccesses || failures != s.expectedFailures {
+			t.Errorf("sync %d: expected %v successful and %v failed batches, got %v and %v", i, s.expectedSuccesses, s.expectedFailures, successes, failures)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/tide/blockers"
+	"k8s.io/test-infra/prow/tide/history"
+)
+
+// SimulationState is the recorded state that a simulation runs against.
+type SimulationState struct {
+	// PullRequests are the open PRs, in the form Tide serves them in pools.
+	PullRequests []PullRequest `json:"pullRequests"`
+	// ProwJobs are the ProwJobs in the cluster.
+	ProwJobs []prowapi.ProwJob `json:"prowJobs,omitempty"`
+	// BranchHeads maps org/repo:branch to the SHA of the branch.
+	BranchHeads map[string]string `json:"branchHeads"`
+	// ChangedFiles maps org/repo#number to the files a PR changes. They
+	// are only needed for presubmits that run_if_changed.
+	ChangedFiles map[string][]string `json:"changedFiles,omitempty"`
+}
+
+// LoadSimulationState reads the recorded state from a YAML or JSON file.
+// ProwJobs may also be read from a separate ProwJobList, like the output
+// of `kubectl get prowjobs -o yaml`, which replaces those in the state.
+func LoadSimulationState(statePath, prowJobsPath string) (*SimulationState, error) {
+	raw, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", statePath, err)
+	}
+	var state SimulationState
+	if err := yaml.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("error unmarshaling %s: %v", statePath, err)
+	}
+	if prowJobsPath != "" {
+		raw, err := ioutil.ReadFile(prowJobsPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", prowJobsPath, err)
+		}
+		var pjs prowapi.ProwJobList
+		if err := yaml.Unmarshal(raw, &pjs); err != nil {
+			return nil, fmt.Errorf("error unmarshaling %s: %v", prowJobsPath, err)
+		}
+		state.ProwJobs = pjs.Items
+	}
+	return &state, nil
+}
+
+// Simulation is the outcome of a simulated sync.
+type Simulation struct {
+	// Pools hold the action Tide would take on every pool.
+	Pools []Pool
+	// Excluded maps org/repo#number to the reason a PR is in no pool.
+	Excluded map[string]string
+}
+
+// ExcludedPRs lists the keys of the excluded PRs in order.
+func (s *Simulation) ExcludedPRs() []string {
+	keys := make([]string, 0, len(s.Excluded))
+	for key := range s.Excluded {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Simulate runs a sync against the recorded state instead of GitHub and
+// the cluster. Nothing is merged, triggered or commented on. Blocking
+// issues are not searched for, and the review approval and the approvals
+// of teams required by queries are assumed to be granted as they are not
+// part of the recorded state.
+func Simulate(cfg config.Getter, state *SimulationState, logger *logrus.Entry) (*Simulation, error) {
+	if logger == nil {
+		logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+	ghc := &simulatedGitHub{state: state}
+	c := &Controller{
+		logger:        logger.WithField("controller", "simulation"),
+		ghc:           ghc,
+		prowJobClient: simulatedProwJobs{},
+		config:        cfg,
+		changedFiles: &changedFilesAgent{
+			ghc:             ghc,
+			nextChangeCache: make(map[changeCacheKey][]string),
+		},
+		History: history.New(1000),
+	}
+
+	queryMap := cfg().Tide.Queries.QueryMap()
+	prs := make(map[string]PullRequest)
+	for _, pr := range state.PullRequests {
+		if matchesQuery(queryMap, &pr) {
+			prs[prKey(&pr)] = pr
+		}
+	}
+	pools, err := c.syncPools(prs, state.ProwJobs, blockers.Blockers{})
+	if err != nil {
+		return nil, err
+	}
+
+	pooled := make(map[string]bool)
+	for _, pool := range pools {
+		for _, list := range [][]PullRequest{pool.SuccessPRs, pool.PendingPRs, pool.MissingPRs} {
+			for _, pr := range list {
+				pooled[prKey(&pr)] = true
+			}
+		}
+	}
+	excluded := make(map[string]string)
+	for _, pr := range state.PullRequests {
+		if !pooled[prKey(&pr)] {
+			excluded[prKey(&pr)] = exclusionReason(cfg, queryMap, &pr)
+		}
+	}
+	return &Simulation{Pools: pools, Excluded: excluded}, nil
+}
+
+// matchesQuery determines if a query for the repo of the PR would find
+// it when searching GitHub.
+func matchesQuery(queryMap *config.QueryMap, pr *PullRequest) bool {
+	for _, q := range queryMap.ForRepo(string(pr.Repository.Owner.Login), string(pr.Repository.Name)) {
+		if _, diff := requirementDiff(pr, &q, queryOnly{}, nil); diff == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// exclusionReason explains why a PR is in no pool, the same way the
+// Tide status context on the PR would.
+func exclusionReason(cfg config.Getter, queryMap *config.QueryMap, pr *PullRequest) string {
+	if len(queryMap.ForRepo(string(pr.Repository.Owner.Login), string(pr.Repository.Name))) == 0 {
+		return "No Tide query covers the repository."
+	}
+	if pr.Mergeable == githubql.MergeableStateConflicting {
+		return "Not mergeable. PR has a merge conflict."
+	}
+	cc, err := cfg().GetTideContextPolicy(string(pr.Repository.Owner.Login), string(pr.Repository.Name), string(pr.BaseRef.Name))
+	if err != nil {
+		return fmt.Sprintf("Failed to get the context policy: %v", err)
+	}
+	_, desc := expectedStatus(queryMap, pr, nil, cc, nil, "", "")
+	return desc
+}
+
+// simulatedGitHub serves the recorded state and ignores all changes.
+type simulatedGitHub struct {
+	state *SimulationState
+}
+
+func (s *simulatedGitHub) CreateStatus(org, repo, ref string, status github.Status) error {
+	return nil
+}
+
+func (s *simulatedGitHub) GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus, error) {
+	return nil, fmt.Errorf("the statuses of %s/%s@%s are not in the recorded commits of the PR", org, repo, ref)
+}
+
+func (s *simulatedGitHub) GetPullRequest(org, repo string, number int) (*github.PullRequest, error) {
+	return nil, errors.New("the bodies of PRs are not recorded")
+}
+
+func (s *simulatedGitHub) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	files, ok := s.state.ChangedFiles[fmt.Sprintf("%s/%s#%d", org, repo, number)]
+	if !ok {
+		return nil, fmt.Errorf("the changed files of %s/%s#%d are not recorded", org, repo, number)
+	}
+	changes := make([]github.PullRequestChange, 0, len(files))
+	for _, file := range files {
+		changes = append(changes, github.PullRequestChange{Filename: file})
+	}
+	return changes, nil
+}
+
+func (s *simulatedGitHub) GetRef(org, repo, ref string) (string, error) {
+	branch := strings.TrimPrefix(ref, "heads/")
+	sha, ok := s.state.BranchHeads[fmt.Sprintf("%s/%s:%s", org, repo, branch)]
+	if !ok {
+		return "", fmt.Errorf("the head of %s/%s:%s is not recorded", org, repo, branch)
+	}
+	return sha, nil
+}
+
+func (s *simulatedGitHub) CompareCommits(org, repo, base, head string) (github.CommitComparison, error) {
+	return github.CommitComparison{}, fmt.Errorf("the comparison of %s and %s in %s/%s is not recorded", base, head, org, repo)
+}
+
+func (s *simulatedGitHub) Merge(org, repo string, number int, details github.MergeDetails) error {
+	return nil
+}
+
+func (s *simulatedGitHub) Query(context.Context, interface{}, map[string]interface{}) error {
+	return errors.New("queries are not supported when simulating")
+}
+
+func (s *simulatedGitHub) CreateComment(org, repo string, number int, comment string) error {
+	return nil
+}
+
+func (s *simulatedGitHub) AddLabel(org, repo string, number int, label string) error {
+	return nil
+}
+
+func (s *simulatedGitHub) ListIssueComments(org, repo string, number int) ([]github.IssueComment, error) {
+	return nil, errors.New("comments are not recorded")
+}
+
+func (s *simulatedGitHub) ListReviews(org, repo string, number int) ([]github.Review, error) {
+	return nil, errors.New("reviews are not recorded")
+}
+
+func (s *simulatedGitHub) ListTeams(org string) ([]github.Team, error) {
+	return nil, errors.New("teams are not recorded")
+}
+
+func (s *simulatedGitHub) ListTeamMembers(id int, role string) ([]github.TeamMember, error) {
+	return nil, errors.New("teams are not recorded")
+}
+
+// simulatedProwJobs ignores the ProwJobs Tide creates.
+type simulatedProwJobs struct{}
+
+func (simulatedProwJobs) Create(pj *prowapi.ProwJob) (*prowapi.ProwJob, error) {
+	return pj, nil
+}
+
+func (simulatedProwJobs) List(opts metav1.ListOptions) (*prowapi.ProwJobList, error) {
+	return &prowapi.ProwJobList{}, nil
+}
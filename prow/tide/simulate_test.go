@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	githubql "github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+)
+
+func TestSimulate(t *testing.T) {
+	labeled := func(pr PullRequest, labels ...string) PullRequest {
+		for _, label := range labels {
+			pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: githubql.String(label)})
+		}
+		return pr
+	}
+	ready := labeled(testPR("org", "repo", "master", 1, githubql.MergeableStateMergeable), "lgtm")
+	readyToo := labeled(testPR("org", "repo", "master", 2, githubql.MergeableStateMergeable), "lgtm")
+	conflicting := labeled(testPR("org", "repo", "master", 3, githubql.MergeableStateConflicting), "lgtm")
+	unlabeled := testPR("org", "repo", "master", 4, githubql.MergeableStateMergeable)
+	uncovered := labeled(testPR("org", "other", "master", 5, githubql.MergeableStateMergeable), "lgtm")
+
+	testcases := []struct {
+		name   string
+		prs    []PullRequest
+		heads  map[string]string
+		policy *config.TideOrgContextPolicy
+
+		expectedPools    []Pool
+		expectedExcluded map[string]string
+		expectedErr      bool
+	}{
+		{
+			name:  "passing PR is merged and the rest are explained",
+			prs:   []PullRequest{readyToo, ready, conflicting, unlabeled, uncovered},
+			heads: map[string]string{"org/repo:master": "master-sha"},
+			expectedPools: []Pool{{
+				Org:        "org",
+				Repo:       "repo",
+				Branch:     "master",
+				SuccessPRs: []PullRequest{ready, readyToo},
+				Action:     Merge,
+				Target:     []PullRequest{ready},
+			}},
+			expectedExcluded: map[string]string{
+				"org/repo#3":  "Not mergeable. PR has a merge conflict.",
+				"org/repo#4":  "Not mergeable. Needs lgtm label.",
+				"org/other#5": "No Tide query covers the repository.",
+			},
+		},
+		{
+			name:  "PR failing a required context",
+			prs:   []PullRequest{ready},
+			heads: map[string]string{"org/repo:master": "master-sha"},
+			policy: &config.TideOrgContextPolicy{
+				TideContextPolicy: config.TideContextPolicy{RequiredContexts: []string{"e2e"}},
+			},
+			expectedPools: []Pool{},
+			expectedExcluded: map[string]string{
+				"org/repo#1": "Not mergeable. Job e2e has not succeeded.",
+			},
+		},
+		{
+			name:        "branch head is not recorded",
+			prs:         []PullRequest{ready},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ProwConfig: config.ProwConfig{
+					Tide: config.Tide{
+						Queries: []config.TideQuery{{
+							Repos:  []string{"org/repo"},
+							Labels: []string{"lgtm"},
+						}},
+						MaxGoroutines: 4,
+					},
+				},
+			}
+			if tc.policy != nil {
+				cfg.Tide.ContextOptions.Orgs = map[string]config.TideOrgContextPolicy{"org": *tc.policy}
+			}
+			state := &SimulationState{PullRequests: tc.prs, BranchHeads: tc.heads}
+			sim, err := Simulate(func() *config.Config { return cfg }, state, logrus.WithField("test", tc.name))
+			if err != nil {
+				if !tc.expectedErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if tc.expectedErr {
+				t.Fatal("expected an error, got none")
+			}
+			if !reflect.DeepEqual(sim.Pools, tc.expectedPools) {
+				t.Errorf("expected pools %#v, got %#v", tc.expectedPools, sim.Pools)
+			}
+			if !reflect.DeepEqual(sim.Excluded, tc.expectedExcluded) {
+				t.Errorf("expected excluded PRs %v, got %v", tc.expectedExcluded, sim.Excluded)
+			}
+		})
+	}
+}
+
+func TestLoadSimulationState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "simulation")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	state := `pullRequests:
+- Number: 1
+  HeadRefOID: abc
+  BaseRef:
+    Name: master
+  Repository:
+    Name: repo
+    NameWithOwner: org/repo
+    Owner:
+      Login: org
+prowJobs:
+- metadata:
+    name: replaced
+branchHeads:
+  org/repo:master: master-sha
+`
+	pjs := `apiVersion: v1
+kind: List
+items:
+- metadata:
+    name: recorded
+  spec:
+    job: pull-unit
+`
+	statePath := filepath.Join(dir, "state.yaml")
+	pjsPath := filepath.Join(dir, "prowjobs.yaml")
+	if err := ioutil.WriteFile(statePath, []byte(state), 0644); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+	if err := ioutil.WriteFile(pjsPath, []byte(pjs), 0644); err != nil {
+		t.Fatalf("failed to write prowjobs: %v", err)
+	}
+
+	loaded, err := LoadSimulationState(statePath, "")
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if len(loaded.PullRequests) != 1 || prKey(&loaded.PullRequests[0]) != "org/repo#1" || loaded.PullRequests[0].BaseRef.Name != "master" {
+		t.Errorf("unexpected PRs: %#v", loaded.PullRequests)
+	}
+	if loaded.BranchHeads["org/repo:master"] != "master-sha" {
+		t.Errorf("unexpected branch heads: %v", loaded.BranchHeads)
+	}
+	if len(loaded.ProwJobs) != 1 || loaded.ProwJobs[0].Name != "replaced" {
+		t.Errorf("unexpected prowjobs: %#v", loaded.ProwJobs)
+	}
+
+	loaded, err = LoadSimulationState(statePath, pjsPath)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if len(loaded.ProwJobs) != 1 || loaded.ProwJobs[0].Name != "recorded" || loaded.ProwJobs[0].Spec.Job != "pull-unit" {
+		t.Errorf("expected prowjobs to be replaced, got %#v", loaded.ProwJobs)
+	}
+}
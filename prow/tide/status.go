@@ -40,12 +40,16 @@ const (
 	// The '%s' field is populated with the reason why the PR is not in a
 	// tide pool or the empty string if the reason is unknown. See requirementDiff.
 	statusNotInPool = "Not mergeable.%s"
+	// statusHeld is a format string used when a PR is in a tide pool but its
+	// merge is held. The '%s' field is populated with the reason.
+	statusHeld = "In merge pool, but held: %s"
 )
 
 type statusController struct {
-	logger *logrus.Entry
-	config config.Getter
-	ghc    githubClient
+	logger    *logrus.Entry
+	config    config.Getter
+	ghc       githubClient
+	approvals approvalChecker
 
 	// newPoolPending is a size 1 chan that signals that the main Tide loop has
 	// updated the 'poolPRs' field with a freshly updated pool.
@@ -70,6 +74,9 @@ type statusController struct {
 
 	sync.Mutex
 	poolPRs map[string]PullRequest
+	// staleBases holds why PRs were left out of the pool for being too far
+	// behind their branches, by PR key.
+	staleBases map[string]string
 }
 
 func (sc *statusController) shutdown() {
@@ -89,7 +96,7 @@ func (sc *statusController) shutdown() {
 // Note: an empty diff can be returned if the reason that the PR does not match
 // the TideQuery is unknown. This can happen if this function's logic
 // does not match GitHub's and does not indicate that the PR matches the query.
-func requirementDiff(pr *PullRequest, q *config.TideQuery, cc contextChecker) (string, int) {
+func requirementDiff(pr *PullRequest, q *config.TideQuery, cc contextChecker, ac approvalChecker) (string, int) {
 	const maxLabelChars = 50
 	var desc string
 	var diff int
@@ -184,6 +191,17 @@ func requirementDiff(pr *PullRequest, q *config.TideQuery, cc contextChecker) (s
 		}
 	}
 
+	// Checking approvals may cost API tokens, so they are only checked if a
+	// checker is provided and the PR meets the other requirements above.
+	var missingApprovals []string
+	if ac != nil && desc == "" {
+		missingApprovals = ac.MissingApprovals(pr, q)
+	}
+	diff += len(missingApprovals)
+	if len(missingApprovals) > 0 {
+		desc = fmt.Sprintf(" Needs approval of %s.", strings.Join(missingApprovals, "; "))
+	}
+
 	// fixing label issues takes precedence over status contexts
 	var contexts []string
 	for _, commit := range pr.Commits.Nodes {
@@ -215,19 +233,28 @@ func requirementDiff(pr *PullRequest, q *config.TideQuery, cc contextChecker) (s
 // in order to generate a diff for the status description. We choose the query
 // for the repo that the PR is closest to meeting (as determined by the number
 // of unmet/violated requirements).
-func expectedStatus(queryMap *config.QueryMap, pr *PullRequest, pool map[string]PullRequest, cc contextChecker) (string, string) {
+// The hold is the reason the merge of the PR is held by a merge window or a
+// code freeze, if any, and staleBase the reason the PR was left out of the
+// pool for being too far behind its branch, if any.
+func expectedStatus(queryMap *config.QueryMap, pr *PullRequest, pool map[string]PullRequest, cc contextChecker, ac approvalChecker, hold, staleBase string) (string, string) {
 	if _, ok := pool[prKey(pr)]; !ok {
 		minDiffCount := -1
 		var minDiff string
 		for _, q := range queryMap.ForRepo(string(pr.Repository.Owner.Login), string(pr.Repository.Name)) {
-			diff, diffCount := requirementDiff(pr, &q, cc)
+			diff, diffCount := requirementDiff(pr, &q, cc, ac)
 			if minDiffCount == -1 || diffCount < minDiffCount {
 				minDiffCount = diffCount
 				minDiff = diff
 			}
 		}
+		if minDiff == "" {
+			minDiff = staleBase
+		}
 		return github.StatusPending, fmt.Sprintf(statusNotInPool, minDiff)
 	}
+	if hold != "" {
+		return github.StatusPending, fmt.Sprintf(statusHeld, hold)
+	}
 	return github.StatusSuccess, statusInPool
 }
 
@@ -253,7 +280,7 @@ func targetURL(c config.Getter, pr *PullRequest, log *logrus.Entry) string {
 	return link
 }
 
-func (sc *statusController) setStatuses(all []PullRequest, pool map[string]PullRequest) {
+func (sc *statusController) setStatuses(all []PullRequest, pool map[string]PullRequest, staleBases map[string]string) {
 	// queryMap caches which queries match a repo.
 	// Make a new one each sync loop as queries will change.
 	queryMap := sc.config().Tide.Queries.QueryMap()
@@ -276,7 +303,13 @@ func (sc *statusController) setStatuses(all []PullRequest, pool map[string]PullR
 			return
 		}
 
-		wantState, wantDesc := expectedStatus(queryMap, pr, pool, cr)
+		hold := sc.config().Tide.MergeHold(
+			string(pr.Repository.Owner.Login),
+			string(pr.Repository.Name),
+			string(pr.BaseRef.Name),
+			prLabels(*pr),
+			time.Now())
+		wantState, wantDesc := expectedStatus(queryMap, pr, pool, cr, sc.approvals, hold, staleBases[prKey(pr)])
 		var actualState githubql.StatusState
 		var actualDesc string
 		for _, ctx := range contexts {
@@ -348,9 +381,9 @@ func (sc *statusController) waitSync() {
 		select {
 		case <-wait:
 			sc.Lock()
-			pool := sc.poolPRs
+			pool, staleBases := sc.poolPRs, sc.staleBases
 			sc.Unlock()
-			sc.sync(pool)
+			sc.sync(pool, staleBases)
 			return
 		case more := <-sc.newPoolPending:
 			if !more {
@@ -360,7 +393,7 @@ func (sc *statusController) waitSync() {
 	}
 }
 
-func (sc *statusController) sync(pool map[string]PullRequest) {
+func (sc *statusController) sync(pool map[string]PullRequest, staleBases map[string]string) {
 	sc.lastSyncStart = time.Now()
 	defer func() {
 		duration := time.Since(sc.lastSyncStart)
@@ -368,7 +401,7 @@ func (sc *statusController) sync(pool map[string]PullRequest) {
 		tideMetrics.statusUpdateDuration.Set(duration.Seconds())
 	}()
 
-	sc.setStatuses(sc.search(), pool)
+	sc.setStatuses(sc.search(), pool, staleBases)
 }
 
 func (sc *statusController) search() []PullRequest {
@@ -43,6 +43,9 @@ func TestExpectedStatus(t *testing.T) {
 		milestone       string
 		contexts        []Context
 		inPool          bool
+		hold            string
+		staleBase       string
+		missingApproval []string
 
 		state string
 		desc  string
@@ -54,6 +57,32 @@ func TestExpectedStatus(t *testing.T) {
 			state: github.StatusSuccess,
 			desc:  statusInPool,
 		},
+		{
+			name:   "in pool but held",
+			inPool: true,
+			hold:   "v1.14 code freeze until Mar 26 00:00 PDT.",
+
+			state: github.StatusPending,
+			desc:  fmt.Sprintf(statusHeld, "v1.14 code freeze until Mar 26 00:00 PDT."),
+		},
+		{
+			name:            "missing approvals",
+			labels:          neededLabels,
+			milestone:       "v1.0",
+			missingApproval: []string{"docs/** by docs-approvers", "api/** by api-reviewers"},
+
+			state: github.StatusPending,
+			desc:  fmt.Sprintf(statusNotInPool, " Needs approval of docs/** by docs-approvers; api/** by api-reviewers."),
+		},
+		{
+			name:            "missing labels take precedence over approvals",
+			labels:          neededLabels[:2],
+			milestone:       "v1.0",
+			missingApproval: []string{"docs/** by docs-approvers"},
+
+			state: github.StatusPending,
+			desc:  fmt.Sprintf(statusNotInPool, " Needs need-a-very-super-duper-extra-not-short-at-all-label-name label."),
+		},
 		{
 			name:      "check truncation of label list",
 			milestone: "v1.0",
@@ -141,6 +170,27 @@ func TestExpectedStatus(t *testing.T) {
 			state: github.StatusPending,
 			desc:  fmt.Sprintf(statusNotInPool, ""),
 		},
+		{
+			name:      "stale base",
+			labels:    neededLabels,
+			milestone: "v1.0",
+			contexts:  []Context{{Context: githubql.String("job-name"), State: githubql.StatusStateSuccess}},
+			inPool:    false,
+			staleBase: " 12 commits behind master, needs a rebase.",
+
+			state: github.StatusPending,
+			desc:  fmt.Sprintf(statusNotInPool, " 12 commits behind master, needs a rebase."),
+		},
+		{
+			name:      "unmet requirements take precedence over a stale base",
+			labels:    []string{"need-a-very-super-duper-extra-not-short-at-all-label-name"},
+			milestone: "v1.0",
+			inPool:    false,
+			staleBase: " 12 commits behind master, needs a rebase.",
+
+			state: github.StatusPending,
+			desc:  fmt.Sprintf(statusNotInPool, " Needs need-1, need-2 labels."),
+		},
 		{
 			name:      "single bad context",
 			labels:    neededLabels,
@@ -214,6 +264,10 @@ func TestExpectedStatus(t *testing.T) {
 				Labels:           neededLabels,
 				MissingLabels:    forbiddenLabels,
 				Milestone:        "v1.0",
+				RequiredApprovals: []config.TideRequiredApproval{{
+					Paths: []string{"docs/**"},
+					Team:  "docs-approvers",
+				}},
 			},
 			secondQuery,
 		}.QueryMap()
@@ -254,7 +308,9 @@ func TestExpectedStatus(t *testing.T) {
 			pool = map[string]PullRequest{"#0": {}}
 		}
 
-		state, desc := expectedStatus(queriesByRepo, &pr, pool, &config.TideContextPolicy{})
+		ac := fakeApprovalChecker(tc.missingApproval)
+
+		state, desc := expectedStatus(queriesByRepo, &pr, pool, &config.TideContextPolicy{}, ac, tc.hold, tc.staleBase)
 		if state != tc.state {
 			t.Errorf("Expected status state %q, but got %q.", string(tc.state), string(state))
 		}
@@ -371,7 +427,7 @@ func TestSetStatuses(t *testing.T) {
 		}
 
 		sc := &statusController{ghc: fc, config: ca.Config, logger: log}
-		sc.setStatuses([]PullRequest{pr}, pool)
+		sc.setStatuses([]PullRequest{pr}, pool, nil)
 		if str, err := log.String(); err != nil {
 			t.Fatalf("For case %s: failed to get log output: %v", tc.name, err)
 		} else if str != initialLog {
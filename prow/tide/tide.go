@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"sort"
 	"strings"
 	"sync"
@@ -39,9 +40,11 @@ import (
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowv1 "k8s.io/test-infra/prow/client/clientset/versioned/typed/prowjobs/v1"
 	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/errorutil"
 	"k8s.io/test-infra/prow/git"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/pjutil"
+	"k8s.io/test-infra/prow/repoowners"
 	"k8s.io/test-infra/prow/tide/blockers"
 	"k8s.io/test-infra/prow/tide/history"
 )
@@ -54,10 +57,18 @@ type prowJobClient interface {
 type githubClient interface {
 	CreateStatus(string, string, string, github.Status) error
 	GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus, error)
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
 	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
 	GetRef(string, string, string) (string, error)
+	CompareCommits(org, repo, base, head string) (github.CommitComparison, error)
 	Merge(string, string, int, github.MergeDetails) error
 	Query(context.Context, interface{}, map[string]interface{}) error
+	CreateComment(org, repo string, number int, comment string) error
+	AddLabel(org, repo string, number int, label string) error
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+	ListReviews(org, repo string, number int) ([]github.Review, error)
+	ListTeams(org string) ([]github.Team, error)
+	ListTeamMembers(id int, role string) ([]github.TeamMember, error)
 }
 
 type contextChecker interface {
@@ -67,6 +78,13 @@ type contextChecker interface {
 	MissingRequiredContexts([]string) []string
 }
 
+// queryOnly is a contextChecker that ignores all contexts so that
+// requirementDiff compares PRs only to the search criteria of queries.
+type queryOnly struct{}
+
+func (queryOnly) IsOptional(string) bool                    { return true }
+func (queryOnly) MissingRequiredContexts([]string) []string { return nil }
+
 // Controller knows how to sync PRs and PJs.
 type Controller struct {
 	logger        *logrus.Entry
@@ -83,10 +101,21 @@ type Controller struct {
 	// changedFiles caches the names of files changed by PRs.
 	// Cache entries expire if they are not used during a sync loop.
 	changedFiles *changedFilesAgent
+	// approvals checks the approvals that queries require, if set.
+	approvals *approvalAgent
+	// tracker keeps the state of the metrics that span syncs, if set.
+	tracker *poolTracker
+	// freshBases holds PRs that are too far behind their base branches, if set.
+	freshBases *freshBaseAgent
 
 	History *history.History
+	// storage persists the pools and the history across restarts, if set.
+	storage history.Storage
 }
 
+// poolsObjectName is the name of the object that pools are persisted in.
+const poolsObjectName = "pools.json"
+
 // Action represents what actions the controller can take. It will take
 // exactly one action each sync.
 type Action string
@@ -99,15 +128,21 @@ const (
 	Merge               = "MERGE"
 	MergeBatch          = "MERGE_BATCH"
 	PoolBlocked         = "BLOCKED"
+	// Bisect triggers the halves of failed batches.
+	Bisect = "BISECT"
+	// RemoveCulprit removes PRs that failed a batch on their own from the pool.
+	RemoveCulprit = "REMOVE_CULPRIT"
 )
 
 // recordableActions is the subset of actions that we keep historical record of.
 // Ignore idle actions to avoid flooding the records with useless data.
 var recordableActions = map[Action]bool{
-	Trigger:      true,
-	TriggerBatch: true,
-	Merge:        true,
-	MergeBatch:   true,
+	Trigger:       true,
+	TriggerBatch:  true,
+	Merge:         true,
+	MergeBatch:    true,
+	Bisect:        true,
+	RemoveCulprit: true,
 }
 
 // Pool represents information about a tide pool. There is one for every
@@ -120,6 +155,7 @@ type Pool struct {
 	// PRs with passing tests, pending tests, and missing or failed tests.
 	// Note that these results are rolled up. If all tests for a PR are passing
 	// except for one pending, it will be in PendingPRs.
+	// Each list is in the order Tide picks PRs in, see Tide.Priority.
 	SuccessPRs []PullRequest
 	PendingPRs []PullRequest
 	MissingPRs []PullRequest
@@ -132,6 +168,9 @@ type Pool struct {
 	Target   []PullRequest
 	Blockers []blockers.Blocker
 	Error    string
+	// HoldReason explains why merges of some or all of the PRs are held by
+	// a merge window or a code freeze. Held PRs are neither merged nor tested.
+	HoldReason string
 }
 
 // Prometheus Metrics
@@ -142,6 +181,13 @@ var (
 		updateTime *prometheus.GaugeVec
 		merges     *prometheus.HistogramVec
 
+		// Per repo
+		poolTime     *prometheus.HistogramVec
+		batchSizes   *prometheus.HistogramVec
+		batchResults *prometheus.CounterVec
+		retests      *prometheus.HistogramVec
+		subpoolSync  *prometheus.HistogramVec
+
 		// Singleton
 		syncDuration         prometheus.Gauge
 		statusUpdateDuration prometheus.Gauge
@@ -173,6 +219,47 @@ var (
 			"branch",
 		}),
 
+		poolTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pooltime",
+			Help:    "Histogram of the seconds from PRs entering the pool to their merge.",
+			Buckets: prometheus.ExponentialBuckets(60, 2, 14),
+		}, []string{
+			"org",
+			"repo",
+		}),
+		batchSizes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "batchsize",
+			Help:    "Histogram of triggered batches where values are the number of PRs tested together.",
+			Buckets: []float64{2, 3, 4, 5, 7, 10, 15, 25},
+		}, []string{
+			"org",
+			"repo",
+		}),
+		batchResults: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "batchresults",
+			Help: "Number of completed batches by result, either success or failure.",
+		}, []string{
+			"org",
+			"repo",
+			"result",
+		}),
+		retests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "retests",
+			Help:    "Histogram of merged PRs where values are the number of times their presubmits were retested.",
+			Buckets: []float64{0, 1, 2, 3, 5, 8, 13, 21},
+		}, []string{
+			"org",
+			"repo",
+		}),
+		subpoolSync: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "subpoolsyncdur",
+			Help:    "Histogram of the seconds taken to sync each subpool.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}, []string{
+			"org",
+			"repo",
+		}),
+
 		syncDuration: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: "syncdur",
 			Help: "The duration of the last loop of the sync controller.",
@@ -189,18 +276,66 @@ func init() {
 	prometheus.MustRegister(tideMetrics.pooledPRs)
 	prometheus.MustRegister(tideMetrics.updateTime)
 	prometheus.MustRegister(tideMetrics.merges)
+	prometheus.MustRegister(tideMetrics.poolTime)
+	prometheus.MustRegister(tideMetrics.batchSizes)
+	prometheus.MustRegister(tideMetrics.batchResults)
+	prometheus.MustRegister(tideMetrics.retests)
+	prometheus.MustRegister(tideMetrics.subpoolSync)
 	prometheus.MustRegister(tideMetrics.syncDuration)
 	prometheus.MustRegister(tideMetrics.statusUpdateDuration)
 }
 
-// NewController makes a Controller out of the given clients.
-func NewController(ghcSync, ghcStatus *github.Client, prowJobClient prowv1.ProwJobInterface, cfg config.Getter, gc *git.Client, logger *logrus.Entry) *Controller {
+// NewController makes a Controller out of the given clients. The pools, the
+// history and the state of the pool metrics are persisted to the storage
+// unless it is nil.
+func NewController(ghcSync, ghcStatus *github.Client, prowJobClient prowv1.ProwJobInterface, cfg config.Getter, gc *git.Client, storage history.Storage, logger *logrus.Entry) (*Controller, error) {
 	if logger == nil {
 		logger = logrus.NewEntry(logrus.StandardLogger())
 	}
+	hist := history.New(1000)
+	var pools []Pool
+	tracker := newPoolTracker()
+	if storage != nil {
+		var err error
+		if hist, err = history.NewPersisted(1000, storage); err != nil {
+			return nil, fmt.Errorf("error loading history: %v", err)
+		}
+		raw, err := storage.Read(poolsObjectName)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error reading %s: %v", poolsObjectName, err)
+		} else if err == nil {
+			if err := json.Unmarshal(raw, &pools); err != nil {
+				return nil, fmt.Errorf("error unmarshaling %s: %v", poolsObjectName, err)
+			}
+		}
+		raw, err = storage.Read(trackerObjectName)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error reading %s: %v", trackerObjectName, err)
+		} else if err == nil {
+			if err := json.Unmarshal(raw, tracker); err != nil {
+				return nil, fmt.Errorf("error unmarshaling %s: %v", trackerObjectName, err)
+			}
+		}
+	}
+	changedFiles := &changedFilesAgent{
+		ghc:             ghcSync,
+		nextChangeCache: make(map[changeCacheKey][]string),
+	}
+	// Only aliases are loaded, which neither the OWNERS format nor the
+	// collaborators of repos affect.
+	noop := func(org, repo string) bool { return false }
+	approvals := &approvalAgent{
+		logger:       logger.WithField("controller", "approvals"),
+		ghc:          ghcSync,
+		owners:       repoowners.NewClient(gc, ghcSync, noop, noop, func() config.OwnersDirBlacklist { return cfg().OwnersDirBlacklist }),
+		changedFiles: changedFiles,
+		teams:        make(map[string]sets.String),
+		approvals:    make(map[changeCacheKey]sets.String),
+	}
 	sc := &statusController{
 		logger:         logger.WithField("controller", "status-update"),
 		ghc:            ghcStatus,
+		approvals:      approvals,
 		config:         cfg,
 		newPoolPending: make(chan bool, 1),
 		shutDown:       make(chan bool),
@@ -216,12 +351,14 @@ func NewController(ghcSync, ghcStatus *github.Client, prowJobClient prowv1.ProwJ
 		config:        cfg,
 		gc:            gc,
 		sc:            sc,
-		changedFiles: &changedFilesAgent{
-			ghc:             ghcSync,
-			nextChangeCache: make(map[changeCacheKey][]string),
-		},
-		History: history.New(1000),
-	}
+		pools:         pools,
+		changedFiles:  changedFiles,
+		approvals:     approvals,
+		tracker:       tracker,
+		freshBases:    newFreshBaseAgent(ghcSync),
+		History:       hist,
+		storage:       storage,
+	}, nil
 }
 
 // Shutdown signals the statusController to stop working and waits for it to
@@ -272,6 +409,12 @@ func (c *Controller) Sync() error {
 		tideMetrics.syncDuration.Set(duration.Seconds())
 	}()
 	defer c.changedFiles.prune()
+	if c.approvals != nil {
+		defer c.approvals.prune()
+	}
+	if c.freshBases != nil {
+		defer c.freshBases.prune()
+	}
 
 	ctx := context.Background()
 	c.logger.Debug("Building tide pool.")
@@ -316,21 +459,44 @@ func (c *Controller) Sync() error {
 			}
 		}
 	}
+	pools, err := c.syncPools(prs, pjs, blocks)
+	if err != nil {
+		return err
+	}
+	c.m.Lock()
+	c.pools = pools
+	c.m.Unlock()
+
+	c.History.Prune(c.config().Tide.HistoryRetention)
+	return c.persist(pools)
+}
+
+// syncPools divides the PRs into pools, filters out the PRs that cannot
+// merge, and takes an action on every pool.
+func (c *Controller) syncPools(prs map[string]PullRequest, pjs []prowapi.ProwJob, blocks blockers.Blockers) ([]Pool, error) {
 	// Partition PRs into subpools and filter out non-pool PRs.
 	rawPools, err := c.dividePool(prs, pjs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	filteredPools := c.filterSubpools(c.config().Tide.MaxGoroutines, rawPools)
+	if c.tracker != nil {
+		c.tracker.update(poolPRMap(filteredPools), pjs, time.Now())
+	}
 
-	// Notify statusController about the new pool.
-	c.sc.Lock()
-	c.sc.poolPRs = poolPRMap(filteredPools)
-	select {
-	case c.sc.newPoolPending <- true:
-	default:
+	// Notify statusController about the new pool, unless simulating.
+	if c.sc != nil {
+		c.sc.Lock()
+		c.sc.poolPRs = poolPRMap(filteredPools)
+		if c.freshBases != nil {
+			c.sc.staleBases = c.freshBases.staleReasons()
+		}
+		select {
+		case c.sc.newPoolPending <- true:
+		default:
+		}
+		c.sc.Unlock()
 	}
-	c.sc.Unlock()
 
 	// Sync subpools in parallel.
 	poolChan := make(chan Pool, len(filteredPools))
@@ -352,9 +518,35 @@ func (c *Controller) Sync() error {
 		pools = append(pools, pool)
 	}
 	sortPools(pools)
-	c.m.Lock()
-	defer c.m.Unlock()
-	c.pools = pools
+	return pools, nil
+}
+
+// persist writes the history and the pools to the storage, if any, so
+// that they are served right away when Tide restarts.
+func (c *Controller) persist(pools []Pool) error {
+	if err := c.History.Flush(); err != nil {
+		return fmt.Errorf("error persisting history: %v", err)
+	}
+	if c.storage == nil {
+		return nil
+	}
+	raw, err := json.Marshal(pools)
+	if err != nil {
+		return fmt.Errorf("error marshaling pools: %v", err)
+	}
+	if err := c.storage.Write(poolsObjectName, raw); err != nil {
+		return fmt.Errorf("error writing %s: %v", poolsObjectName, err)
+	}
+	if c.tracker == nil {
+		return nil
+	}
+	raw, err = json.Marshal(c.tracker)
+	if err != nil {
+		return fmt.Errorf("error marshaling the pool tracker: %v", err)
+	}
+	if err := c.storage.Write(trackerObjectName, raw); err != nil {
+		return fmt.Errorf("error writing %s: %v", trackerObjectName, err)
+	}
 	return nil
 }
 
@@ -435,6 +627,18 @@ func (c *Controller) initSubpoolData(sp *subpool) error {
 	if err != nil {
 		return fmt.Errorf("error setting up context checker: %v", err)
 	}
+	for _, q := range c.config().Tide.Queries {
+		if q.ForRepo(sp.org, sp.repo) {
+			sp.queries = append(sp.queries, q)
+		}
+	}
+	if c.approvals != nil {
+		sp.approvals = c.approvals
+	}
+	if max := c.config().Tide.MaxCommitsBehind; max > 0 && c.freshBases != nil {
+		sp.freshBases = c.freshBases
+		sp.maxCommitsBehind = max
+	}
 	return nil
 }
 
@@ -465,6 +669,9 @@ func filterSubpool(ghc githubClient, sp *subpool) *subpool {
 //   status is preventing merge. Required ProwJob statuses are allowed to be
 //   'pending' because this prevents kicking PRs from the pool when Tide is
 //   retesting them.)
+// - Lack approvals required by the queries they match.
+// - Are too far behind the branch for the required status contexts that no
+//   ProwJob reports, if configured.
 func filterPR(ghc githubClient, sp *subpool, pr *PullRequest) bool {
 	log := sp.log.WithFields(pr.logFields())
 	// Skip PRs that are known to be unmergeable.
@@ -493,6 +700,14 @@ func filterPR(ghc githubClient, sp *subpool, pr *PullRequest) bool {
 			return true
 		}
 	}
+	if lacksApprovals(sp.approvals, sp.queries, pr) {
+		log.Debug("filtering out PR as it lacks required approvals")
+		return true
+	}
+	if sp.freshBases != nil && sp.freshBases.staleBase(sp, pr, contexts, sp.maxCommitsBehind, presubmitsHaveContext) {
+		log.Debug("filtering out PR as it is too far behind the branch for the contexts that no presubmit reports")
+		return true
+	}
 
 	return false
 }
@@ -517,7 +732,7 @@ const (
 )
 
 func toSimpleState(s prowapi.ProwJobState) simpleState {
-	if s == prowapi.TriggeredState || s == prowapi.PendingState {
+	if s == prowapi.WaitingState || s == prowapi.TriggeredState || s == prowapi.PendingState {
 		return pendingState
 	} else if s == prowapi.SuccessState {
 		return successState
@@ -565,33 +780,137 @@ func unsuccessfulContexts(contexts []Context, cc contextChecker, log *logrus.Ent
 	return failed
 }
 
-func pickSmallestPassingNumber(log *logrus.Entry, ghc githubClient, prs []PullRequest, cc contextChecker) (bool, PullRequest) {
-	smallestNumber := -1
-	var smallestPR PullRequest
-	for _, pr := range prs {
-		if smallestNumber != -1 && int(pr.Number) >= smallestNumber {
-			continue
-		}
+// pickHighestPriorityPR picks the passing PR that Tide merges or tests first,
+// which is the one with the highest priority and, among those, the oldest.
+func pickHighestPriorityPR(log *logrus.Entry, ghc githubClient, prs []PullRequest, cc contextChecker, tide *config.Tide) (bool, PullRequest) {
+	sorted := make([]PullRequest, len(prs))
+	copy(sorted, prs)
+	sortByPriority(sorted, tide)
+	for _, pr := range sorted {
 		if len(pr.Commits.Nodes) < 1 {
 			continue
 		}
 		if !isPassingTests(log, ghc, pr, cc) {
 			continue
 		}
-		smallestNumber = int(pr.Number)
-		smallestPR = pr
+		return true, pr
+	}
+	return false, PullRequest{}
+}
+
+// sortByPriority orders the PRs by decreasing priority and orders PRs of
+// the same priority by increasing number.
+func sortByPriority(prs []PullRequest, tide *config.Tide) {
+	priorities := make(map[githubql.Int]int, len(prs))
+	for _, pr := range prs {
+		priorities[pr.Number] = prPriority(pr, tide)
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		if pi, pj := priorities[prs[i].Number], priorities[prs[j].Number]; pi != pj {
+			return pi > pj
+		}
+		return prs[i].Number < prs[j].Number
+	})
+}
+
+func prPriority(pr PullRequest, tide *config.Tide) int {
+	return tide.PriorityOf(prLabels(pr))
+}
+
+func prLabels(pr PullRequest) []string {
+	labels := make([]string, 0, len(pr.Labels.Nodes))
+	for _, label := range pr.Labels.Nodes {
+		labels = append(labels, string(label.Name))
 	}
-	return smallestNumber > -1, smallestPR
+	return labels
 }
 
 // accumulateBatch returns a list of PRs that can be merged after passing batch
 // testing, if any exist. It also returns a list of PRs currently being batch
 // tested.
 func accumulateBatch(presubmits map[int][]config.Presubmit, prs []PullRequest, pjs []prowapi.ProwJob, log *logrus.Entry) ([]PullRequest, []PullRequest) {
+	var pendingBatch, successBatch []PullRequest
+	for _, batch := range accumulateBatches(presubmits, prs, pjs, log) {
+		switch batch.state {
+		// Currently we only consider 1 pending batch and 1 success batch at a time.
+		// If more are somehow present they will be ignored.
+		case pendingState:
+			pendingBatch = batch.prs
+		case successState:
+			successBatch = batch.prs
+		}
+	}
+	return successBatch, pendingBatch
+}
+
+// accumulatePipeline returns the largest batch of the speculative batches that
+// can be merged after passing batch testing, if any, and the largest batch that
+// is still being tested. Batches that include all PRs of a failed batch are
+// built on that failure and are ignored whatever their state. A passing batch
+// is not merged while a pending batch includes all of its PRs: merging would
+// move the base and invalidate the larger batch, which tests more PRs at once.
+func accumulatePipeline(presubmits map[int][]config.Presubmit, prs []PullRequest, pjs []prowapi.ProwJob, log *logrus.Entry) ([]PullRequest, []PullRequest) {
+	batches := accumulateBatches(presubmits, prs, pjs, log)
+	var failed []sets.Int
+	for _, batch := range batches {
+		if batch.state == failureState {
+			failed = append(failed, sets.NewInt(prNumbers(batch.prs)...))
+		}
+	}
+
+	var pendingBatch, successBatch []PullRequest
+	var pending []sets.Int
+	for _, batch := range batches {
+		numbers := sets.NewInt(prNumbers(batch.prs)...)
+		builtOnFailure := false
+		for _, f := range failed {
+			if numbers.Len() > f.Len() && numbers.IsSuperset(f) {
+				builtOnFailure = true
+				break
+			}
+		}
+		if builtOnFailure {
+			log.WithField("batch", prNumbers(batch.prs)).Debug("speculative batch dropped, it is built on a failed batch")
+			continue
+		}
+		switch batch.state {
+		case pendingState:
+			pending = append(pending, numbers)
+			if len(batch.prs) > len(pendingBatch) {
+				pendingBatch = batch.prs
+			}
+		case successState:
+			if len(batch.prs) > len(successBatch) {
+				successBatch = batch.prs
+			}
+		}
+	}
+	passing := sets.NewInt(prNumbers(successBatch)...)
+	for _, p := range pending {
+		if passing.Len() > 0 && p.Len() > passing.Len() && p.IsSuperset(passing) {
+			log.WithFields(logrus.Fields{
+				"batch-passing": passing.List(),
+				"batch-pending": p.List(),
+			}).Debug("holding the passing batch until a larger batch that includes it completes")
+			return nil, pendingBatch
+		}
+	}
+	return successBatch, pendingBatch
+}
+
+// batchResult is the overall state of the jobs testing a batch.
+type batchResult struct {
+	prs   []PullRequest
+	state simpleState
+}
+
+// accumulateBatches returns the overall state of every batch that is tested
+// against the current heads of its PRs.
+func accumulateBatches(presubmits map[int][]config.Presubmit, prs []PullRequest, pjs []prowapi.ProwJob, log *logrus.Entry) []batchResult {
 	log.Debug("accumulating PRs for batch testing")
 	if len(presubmits) == 0 {
 		log.Debug("no presubmits configured, no batch can be triggered")
-		return nil, nil
+		return nil
 	}
 	prNums := make(map[int]PullRequest)
 	for _, pr := range prs {
@@ -644,7 +963,7 @@ func accumulateBatch(presubmits map[int][]config.Presubmit, prs []PullRequest, p
 			states[ref].jobStates[context] = jobState
 		}
 	}
-	var pendingBatch, successBatch []PullRequest
+	var batches []batchResult
 	for ref, state := range states {
 		if !state.validPulls {
 			continue
@@ -665,16 +984,60 @@ func accumulateBatch(presubmits map[int][]config.Presubmit, prs []PullRequest, p
 				overallState = pendingState
 			}
 		}
-		switch overallState {
-		// Currently we only consider 1 pending batch and 1 success batch at a time.
-		// If more are somehow present they will be ignored.
-		case pendingState:
-			pendingBatch = state.prs
-		case successState:
-			successBatch = state.prs
+		batches = append(batches, batchResult{prs: state.prs, state: overallState})
+	}
+	return batches
+}
+
+// bisectBatches finds the culprits of failed batches, which are the PRs that
+// failed a batch on their own, and the halves of failed batches that are yet
+// to be tested to isolate them. Only the smallest failed batches are split,
+// so halves are split further until they are down to a single PR.
+func bisectBatches(batches []batchResult) ([]PullRequest, [][]PullRequest) {
+	key := func(prs []PullRequest) string {
+		numbers := prNumbers(prs)
+		sort.Ints(numbers)
+		return fmt.Sprint(numbers)
+	}
+	tested := sets.NewString()
+	var failed []sets.Int
+	for _, batch := range batches {
+		tested.Insert(key(batch.prs))
+		if batch.state == failureState {
+			failed = append(failed, sets.NewInt(prNumbers(batch.prs)...))
 		}
 	}
-	return successBatch, pendingBatch
+
+	var culprits []PullRequest
+	var halves [][]PullRequest
+	for _, batch := range batches {
+		if batch.state != failureState {
+			continue
+		}
+		if len(batch.prs) == 1 {
+			culprits = append(culprits, batch.prs[0])
+			continue
+		}
+		numbers := sets.NewInt(prNumbers(batch.prs)...)
+		smallest := true
+		for _, f := range failed {
+			if f.Len() < numbers.Len() && numbers.IsSuperset(f) {
+				smallest = false
+				break
+			}
+		}
+		if !smallest {
+			continue
+		}
+		mid := len(batch.prs) / 2
+		for _, half := range [][]PullRequest{batch.prs[:mid], batch.prs[mid:]} {
+			if k := key(half); !tested.Has(k) {
+				tested.Insert(k)
+				halves = append(halves, half)
+			}
+		}
+	}
+	return culprits, halves
 }
 
 // accumulate returns the supplied PRs sorted into three buckets based on their
@@ -738,9 +1101,15 @@ func prNumbers(prs []PullRequest) []int {
 	return nums
 }
 
-func (c *Controller) pickBatch(sp subpool, cc contextChecker) ([]PullRequest, error) {
-	// we must choose the oldest PRs for the batch
-	sort.Slice(sp.prs, func(i, j int) bool { return sp.prs[i].Number < sp.prs[j].Number })
+// maxBatchSize is the maximum number of PRs in a batch, or in each increment
+// of the batches of a merge queue.
+// TODO: Make this configurable per subpool.
+const maxBatchSize = 5
+
+// pickBatch picks up to limit PRs that pass tests and can be merged together.
+func (c *Controller) pickBatch(sp subpool, cc contextChecker, limit int) ([]PullRequest, error) {
+	// we must choose the PRs with the highest priority, then the oldest PRs, for the batch
+	sortByPriority(sp.prs, &c.config().Tide)
 
 	var candidates []PullRequest
 	for _, pr := range sp.prs {
@@ -755,6 +1124,15 @@ func (c *Controller) pickBatch(sp subpool, cc contextChecker) ([]PullRequest, er
 	}
 	sp.log.Debugf("of %d possible PRs, %d are passing tests", len(sp.prs), len(candidates))
 
+	// Without a git client, as when simulating, the candidates are assumed to
+	// merge cleanly together.
+	if c.gc == nil {
+		if len(candidates) > limit {
+			candidates = candidates[:limit]
+		}
+		return candidates, nil
+	}
+
 	r, err := c.gc.Clone(sp.org + "/" + sp.repo)
 	if err != nil {
 		return nil, err
@@ -781,8 +1159,7 @@ func (c *Controller) pickBatch(sp subpool, cc contextChecker) ([]PullRequest, er
 			return nil, err
 		} else if ok {
 			res = append(res, pr)
-			// TODO: Make this configurable per subpool.
-			if len(res) == 5 {
+			if len(res) == limit {
 				break
 			}
 		}
@@ -797,6 +1174,16 @@ func (c *Controller) mergePRs(sp subpool, prs []PullRequest) error {
 			return
 		}
 		tideMetrics.merges.WithLabelValues(sp.org, sp.repo, sp.branch).Observe(float64(len(merged)))
+		if c.tracker != nil {
+			numbers := sets.NewInt(merged...)
+			var mergedPRs []PullRequest
+			for _, pr := range prs {
+				if numbers.Has(int(pr.Number)) {
+					mergedPRs = append(mergedPRs, pr)
+				}
+			}
+			c.tracker.merged(sp, mergedPRs, time.Now())
+		}
 	}()
 
 	augmentError := func(e error, pr PullRequest) error {
@@ -816,6 +1203,7 @@ func (c *Controller) mergePRs(sp subpool, prs []PullRequest) error {
 		backoff := time.Second * 4
 		log := log.WithFields(pr.logFields())
 		mergeMethod := c.config().Tide.MergeMethod(sp.org, sp.repo)
+		commitTitle, commitMessage := c.mergeCommit(c.config().Tide.MergeCommitTemplate(sp.org, sp.repo), &pr, log)
 		if squashLabel := c.config().Tide.SquashLabel; squashLabel != "" {
 			for _, prlabel := range pr.Labels.Nodes {
 				if string(prlabel.Name) == squashLabel {
@@ -826,8 +1214,10 @@ func (c *Controller) mergePRs(sp subpool, prs []PullRequest) error {
 		}
 		for retry := 0; retry < maxRetries; retry++ {
 			if err := c.ghc.Merge(sp.org, sp.repo, int(pr.Number), github.MergeDetails{
-				SHA:         string(pr.HeadRefOID),
-				MergeMethod: string(mergeMethod),
+				SHA:           string(pr.HeadRefOID),
+				MergeMethod:   string(mergeMethod),
+				CommitTitle:   commitTitle,
+				CommitMessage: commitMessage,
 			}); err != nil {
 				// TODO: Add a config option to abort batches if a PR in the batch
 				// cannot be merged for any reason. This would skip merging
@@ -896,6 +1286,12 @@ func (c *Controller) mergePRs(sp subpool, prs []PullRequest) error {
 }
 
 func (c *Controller) trigger(sp subpool, presubmits map[int][]config.Presubmit, prs []PullRequest) error {
+	return c.triggerJobs(sp, presubmits, prs, len(prs) > 1)
+}
+
+// triggerJobs triggers the presubmits required by the PRs, as batch jobs if
+// batch is set, which allows testing a single PR without reporting to it.
+func (c *Controller) triggerJobs(sp subpool, presubmits map[int][]config.Presubmit, prs []PullRequest, batch bool) error {
 	refs := prowapi.Refs{
 		Org:     sp.org,
 		Repo:    sp.repo,
@@ -924,10 +1320,10 @@ func (c *Controller) trigger(sp subpool, presubmits map[int][]config.Presubmit,
 			}
 			triggeredContexts.Insert(string(ps.Context))
 			var spec prowapi.ProwJobSpec
-			if len(prs) == 1 {
-				spec = pjutil.PresubmitSpec(ps, refs)
-			} else {
+			if batch {
 				spec = pjutil.BatchSpec(ps, refs)
+			} else {
+				spec = pjutil.PresubmitSpec(ps, refs)
 			}
 			pj := pjutil.NewProwJob(spec, ps.Labels)
 			start := time.Now()
@@ -941,15 +1337,31 @@ func (c *Controller) trigger(sp subpool, presubmits map[int][]config.Presubmit,
 	return nil
 }
 
-func (c *Controller) takeAction(sp subpool, batchPending, successes, pendings, nones, batchMerges []PullRequest) (Action, []PullRequest, error) {
+func (c *Controller) takeAction(sp subpool, batchPending, successes, pendings, nones, batchMerges, culprits []PullRequest, bisections [][]PullRequest) (Action, []PullRequest, error) {
 	// Merge the batch!
 	if len(batchMerges) > 0 {
 		return MergeBatch, batchMerges, c.mergePRs(sp, batchMerges)
 	}
+	// Remove the PRs that failed batches from the pool, or keep looking for
+	// them before merging anything invalidates the failed batches.
+	if len(culprits) > 0 {
+		removed, err := c.removeCulprits(sp, culprits)
+		return RemoveCulprit, removed, err
+	}
+	if len(bisections) > 0 {
+		var targets []PullRequest
+		for _, half := range bisections {
+			targets = append(targets, half...)
+			if err := c.triggerJobs(sp, sp.presubmits, half, true); err != nil {
+				return Bisect, targets, err
+			}
+		}
+		return Bisect, targets, nil
+	}
 	// Do not merge PRs while waiting for a batch to complete. We don't want to
 	// invalidate the old batch result.
 	if len(successes) > 0 && len(batchPending) == 0 {
-		if ok, pr := pickSmallestPassingNumber(sp.log, c.ghc, successes, sp.cc); ok {
+		if ok, pr := pickHighestPriorityPR(sp.log, c.ghc, successes, sp.cc, &c.config().Tide); ok {
 			return Merge, []PullRequest{pr}, c.mergePRs(sp, []PullRequest{pr})
 		}
 	}
@@ -959,23 +1371,74 @@ func (c *Controller) takeAction(sp subpool, batchPending, successes, pendings, n
 	}
 	// If we have no serial jobs pending or successful, trigger one.
 	if len(nones) > 0 && len(pendings) == 0 && len(successes) == 0 {
-		if ok, pr := pickSmallestPassingNumber(sp.log, c.ghc, nones, sp.cc); ok {
+		if ok, pr := pickHighestPriorityPR(sp.log, c.ghc, nones, sp.cc, &c.config().Tide); ok {
 			return Trigger, []PullRequest{pr}, c.trigger(sp, sp.presubmits, []PullRequest{pr})
 		}
 	}
-	// If we have no batch, trigger one.
+	// If we have no batch, trigger one, or a pipeline of them in merge queue mode.
 	if len(sp.prs) > 1 && len(batchPending) == 0 {
-		batch, err := c.pickBatch(sp, sp.cc)
+		n := c.config().Tide.SpeculativeBatchCount(sp.org, sp.repo)
+		batch, err := c.pickBatch(sp, sp.cc, n*maxBatchSize)
 		if err != nil {
 			return Wait, nil, err
 		}
 		if len(batch) > 1 {
-			return TriggerBatch, batch, c.trigger(sp, sp.presubmits, batch)
+			for _, size := range speculativeBatchSizes(len(batch), n) {
+				if err := c.trigger(sp, sp.presubmits, batch[:size]); err != nil {
+					return TriggerBatch, batch, err
+				}
+				tideMetrics.batchSizes.WithLabelValues(sp.org, sp.repo).Observe(float64(size))
+			}
+			return TriggerBatch, batch, nil
 		}
 	}
 	return Wait, nil, nil
 }
 
+// speculativeBatchSizes splits a batch into up to n batches that each extend
+// the previous one with roughly the same number of PRs, returning their sizes.
+// The last batch is the whole batch and every batch holds at least two PRs.
+func speculativeBatchSizes(batchSize, n int) []int {
+	var sizes []int
+	for i := 1; i <= n; i++ {
+		size := (i*batchSize + n - 1) / n
+		if size < 2 || (len(sizes) > 0 && sizes[len(sizes)-1] == size) {
+			continue
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes
+}
+
+// removeCulprits comments on the PRs that failed a batch on their own and
+// labels them if configured. It returns the PRs that were told about it,
+// which are left out of the pool until their heads change once the action
+// is recorded. Only PRs that were both commented on and labeled count, so
+// that the others are picked up again next sync.
+func (c *Controller) removeCulprits(sp subpool, culprits []PullRequest) ([]PullRequest, error) {
+	label := c.config().Tide.BatchBisection.Label
+	var removed []PullRequest
+	var errs []error
+	for _, pr := range culprits {
+		comment := fmt.Sprintf("This PR failed the tests of a batch on its own against %s at %s, so Tide removed it from the merge pool until it is updated.", sp.branch, sp.sha)
+		if label != "" {
+			comment += fmt.Sprintf(" Remove the `%s` label to put it back in the pool.", label)
+		}
+		if err := c.ghc.CreateComment(sp.org, sp.repo, int(pr.Number), comment); err != nil {
+			errs = append(errs, fmt.Errorf("failed commenting on #%d: %v", int(pr.Number), err))
+			continue
+		}
+		if label != "" {
+			if err := c.ghc.AddLabel(sp.org, sp.repo, int(pr.Number), label); err != nil {
+				errs = append(errs, fmt.Errorf("failed labeling #%d: %v", int(pr.Number), err))
+				continue
+			}
+		}
+		removed = append(removed, pr)
+	}
+	return removed, errorutil.NewAggregate(errs...)
+}
+
 // changedFilesAgent queries and caches the names of files changed by PRs.
 // Cache entries expire if they are not used during a sync loop.
 type changedFilesAgent struct {
@@ -1074,10 +1537,52 @@ func (c *Controller) presubmitsByPull(sp *subpool) (map[int][]config.Presubmit,
 	return presubmits, nil
 }
 
+// withoutCulprits returns the PRs of the subpool that Tide did not
+// remove from the pool for failing a batch at their current heads.
+func (c *Controller) withoutCulprits(sp subpool) []PullRequest {
+	var prs []PullRequest
+	for _, pr := range sp.prs {
+		if c.History.Targeted(poolKey(sp.org, sp.repo, sp.branch), RemoveCulprit, int(pr.Number), string(pr.HeadRefOID)) {
+			sp.log.WithFields(pr.logFields()).Debug("Leaving out PR that failed a batch.")
+			continue
+		}
+		prs = append(prs, pr)
+	}
+	return prs
+}
+
 func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, error) {
 	sp.log.Infof("Syncing subpool: %d PRs, %d PJs.", len(sp.prs), len(sp.pjs))
+	start := time.Now()
+	defer func() {
+		tideMetrics.subpoolSync.WithLabelValues(sp.org, sp.repo).Observe(time.Since(start).Seconds())
+	}()
+	bisection := c.config().Tide.BatchBisection != nil
+	if bisection {
+		sp.prs = c.withoutCulprits(sp)
+	}
 	successes, pendings, nones := accumulate(sp.presubmits, sp.prs, sp.pjs, sp.log)
-	batchMerge, batchPending := accumulateBatch(sp.presubmits, sp.prs, sp.pjs, sp.log)
+	var batchMerge, batchPending []PullRequest
+	if c.config().Tide.SpeculativeBatchCount(sp.org, sp.repo) > 1 {
+		batchMerge, batchPending = accumulatePipeline(sp.presubmits, sp.prs, sp.pjs, sp.log)
+	} else {
+		batchMerge, batchPending = accumulateBatch(sp.presubmits, sp.prs, sp.pjs, sp.log)
+	}
+	var culprits []PullRequest
+	var bisections [][]PullRequest
+	if bisection || c.tracker != nil {
+		batches := accumulateBatches(sp.presubmits, sp.prs, sp.pjs, sp.log)
+		if bisection {
+			culprits, bisections = bisectBatches(batches)
+		}
+		if c.tracker != nil {
+			c.tracker.countBatches(sp, batches)
+		}
+	}
+	// Show the PRs of the pool in the order they are picked in.
+	for _, prs := range [][]PullRequest{successes, pendings, nones} {
+		sortByPriority(prs, &c.config().Tide)
+	}
 	sp.log.WithFields(logrus.Fields{
 		"prs-passing":   prNumbers(successes),
 		"prs-pending":   prNumbers(pendings),
@@ -1086,18 +1591,39 @@ func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, e
 		"batch-pending": prNumbers(batchPending),
 	}).Info("Subpool accumulated.")
 
+	// Held PRs stay in the pool, but are left out of the action.
+	free, holdReason := heldPRs(sp, &c.config().Tide, time.Now())
+	actionable := sp
+	actSuccesses, actPendings, actNones := successes, pendings, nones
+	actBatchMerge, actBatchPending := batchMerge, batchPending
+	if holdReason != "" {
+		actionable.prs = free
+		actSuccesses, actPendings, actNones = onlyPRs(successes, free), onlyPRs(pendings, free), onlyPRs(nones, free)
+		actBatchMerge, actBatchPending = freeBatch(batchMerge, free), freeBatch(batchPending, free)
+		var freeBisections [][]PullRequest
+		for _, half := range bisections {
+			if freeBatch(half, free) != nil {
+				freeBisections = append(freeBisections, half)
+			}
+		}
+		bisections = freeBisections
+		sp.log.WithField("reason", holdReason).Infof("Holding %d of %d PRs.", len(sp.prs)-len(free), len(sp.prs))
+	}
+
 	var act Action
 	var targets []PullRequest
 	var err error
 	var errorString string
-	if len(blocks) > 0 {
+	if len(blocks) > 0 || (holdReason != "" && len(free) == 0) {
 		act = PoolBlocked
 	} else {
-		act, targets, err = c.takeAction(sp, batchPending, successes, pendings, nones, batchMerge)
+		act, targets, err = c.takeAction(actionable, actBatchPending, actSuccesses, actPendings, actNones, actBatchMerge, culprits, bisections)
 		if err != nil {
 			errorString = err.Error()
 		}
-		if recordableActions[act] {
+		// Culprits that could not be told about their removal are
+		// not recorded, so that they are not left out of the pool.
+		if recordableActions[act] && (act != RemoveCulprit || len(targets) > 0) {
 			c.History.Record(
 				poolKey(sp.org, sp.repo, sp.branch),
 				string(act),
@@ -1125,14 +1651,56 @@ func (c *Controller) syncSubpool(sp subpool, blocks []blockers.Blocker) (Pool, e
 
 			BatchPending: batchPending,
 
-			Action:   act,
-			Target:   targets,
-			Blockers: blocks,
-			Error:    errorString,
+			Action:     act,
+			Target:     targets,
+			Blockers:   blocks,
+			Error:      errorString,
+			HoldReason: holdReason,
 		},
 		err
 }
 
+// heldPRs returns the PRs of the subpool that may merge at the given time
+// and, if merges of any other PRs are held by a merge window or a code
+// freeze, the reason the first of them is held.
+func heldPRs(sp subpool, tide *config.Tide, now time.Time) ([]PullRequest, string) {
+	var free []PullRequest
+	var reason string
+	for _, pr := range sp.prs {
+		if hold := tide.MergeHold(sp.org, sp.repo, sp.branch, prLabels(pr), now); hold != "" {
+			if reason == "" {
+				reason = hold
+			}
+			continue
+		}
+		free = append(free, pr)
+	}
+	return free, reason
+}
+
+// onlyPRs returns the PRs that are also in allowed, in order.
+func onlyPRs(prs, allowed []PullRequest) []PullRequest {
+	keys := sets.NewString()
+	for i := range allowed {
+		keys.Insert(prKey(&allowed[i]))
+	}
+	var res []PullRequest
+	for _, pr := range prs {
+		if keys.Has(prKey(&pr)) {
+			res = append(res, pr)
+		}
+	}
+	return res
+}
+
+// freeBatch returns the batch if none of its PRs are held, nil otherwise.
+func freeBatch(batch, free []PullRequest) []PullRequest {
+	if len(onlyPRs(batch, free)) != len(batch) {
+		return nil
+	}
+	return batch
+}
+
 func prMeta(prs ...PullRequest) []prowapi.Pull {
 	var res []prowapi.Pull
 	for _, pr := range prs {
@@ -1180,6 +1748,14 @@ type subpool struct {
 
 	cc         contextChecker
 	presubmits map[int][]config.Presubmit
+	// queries are the queries for the repo, whose required approvals are
+	// checked by approvals if set.
+	queries   config.TideQueries
+	approvals approvalChecker
+	// freshBases holds PRs that are more than maxCommitsBehind commits
+	// behind the branch, if set.
+	freshBases       *freshBaseAgent
+	maxCommitsBehind int
 }
 
 func poolKey(org, repo, branch string) string {
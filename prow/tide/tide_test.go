@@ -25,6 +25,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	githubql "github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
@@ -247,6 +248,441 @@ func TestAccumulateBatch(t *testing.T) {
 	}
 }
 
+func TestAccumulatePipeline(t *testing.T) {
+	presubmits := map[int][]config.Presubmit{
+		1: {{Reporter: config.Reporter{Context: "foo"}}},
+		2: {{Reporter: config.Reporter{Context: "foo"}}},
+		3: {{Reporter: config.Reporter{Context: "foo"}}},
+		4: {{Reporter: config.Reporter{Context: "foo"}}},
+	}
+	pulls := []int{1, 2, 3, 4}
+	type batch struct {
+		prs   []int
+		state prowapi.ProwJobState
+	}
+	tests := []struct {
+		name    string
+		batches []batch
+
+		merges  []int
+		pending []int
+	}{
+		{
+			name: "no batches running",
+		},
+		{
+			name: "whole pipeline pending",
+			batches: []batch{
+				{prs: []int{1, 2}, state: prowapi.PendingState},
+				{prs: []int{1, 2, 3}, state: prowapi.PendingState},
+				{prs: []int{1, 2, 3, 4}, state: prowapi.PendingState},
+			},
+			pending: []int{1, 2, 3, 4},
+		},
+		{
+			name: "successful prefix waits for longer pending batches",
+			batches: []batch{
+				{prs: []int{1, 2}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3}, state: prowapi.PendingState},
+				{prs: []int{1, 2, 3, 4}, state: prowapi.PendingState},
+			},
+			pending: []int{1, 2, 3, 4},
+		},
+		{
+			name: "longest successful batch waits for the pending batch including it",
+			batches: []batch{
+				{prs: []int{1, 2}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3, 4}, state: prowapi.PendingState},
+			},
+			pending: []int{1, 2, 3, 4},
+		},
+		{
+			name: "longest successful batch merges once the pipeline completes",
+			batches: []batch{
+				{prs: []int{1, 2}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3, 4}, state: prowapi.SuccessState},
+			},
+			merges: []int{1, 2, 3, 4},
+		},
+		{
+			name: "successful prefix merges once the longer batch fails",
+			batches: []batch{
+				{prs: []int{1, 2}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3}, state: prowapi.FailureState},
+				{prs: []int{1, 2, 3, 4}, state: prowapi.PendingState},
+			},
+			merges: []int{1, 2},
+		},
+		{
+			name: "batches built on a failure are dropped",
+			batches: []batch{
+				{prs: []int{1, 2}, state: prowapi.FailureState},
+				{prs: []int{1, 2, 3}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3, 4}, state: prowapi.PendingState},
+			},
+		},
+		{
+			name: "failure late in the pipeline keeps the prefix",
+			batches: []batch{
+				{prs: []int{1, 2}, state: prowapi.SuccessState},
+				{prs: []int{1, 2, 3}, state: prowapi.FailureState},
+				{prs: []int{1, 2, 3, 4}, state: prowapi.SuccessState},
+			},
+			merges: []int{1, 2},
+		},
+	}
+	for _, test := range tests {
+		var prs []PullRequest
+		for _, number := range pulls {
+			prs = append(prs, PullRequest{
+				Number:     githubql.Int(number),
+				HeadRefOID: githubql.String(fmt.Sprintf("sha-%d", number)),
+			})
+		}
+		var pjs []prowapi.ProwJob
+		for _, b := range test.batches {
+			pj := prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Job:     "foo",
+					Context: "foo",
+					Type:    prowapi.BatchJob,
+					Refs:    new(prowapi.Refs),
+				},
+				Status: prowapi.ProwJobStatus{State: b.state},
+			}
+			for _, number := range b.prs {
+				pj.Spec.Refs.Pulls = append(pj.Spec.Refs.Pulls, prowapi.Pull{
+					Number: number,
+					SHA:    fmt.Sprintf("sha-%d", number),
+				})
+			}
+			pjs = append(pjs, pj)
+		}
+		merges, pending := accumulatePipeline(presubmits, prs, pjs, logrus.NewEntry(logrus.New()))
+		testPullsMatchList(t, test.name+" merges", merges, test.merges)
+		testPullsMatchList(t, test.name+" pending", pending, test.pending)
+	}
+}
+
+func TestBisectBatches(t *testing.T) {
+	type batch struct {
+		prs   []int
+		state simpleState
+	}
+	tests := []struct {
+		name    string
+		batches []batch
+
+		culprits []int
+		halves   [][]int
+	}{
+		{
+			name: "no failed batches",
+			batches: []batch{
+				{prs: []int{1, 2, 3, 4}, state: pendingState},
+				{prs: []int{5, 6}, state: successState},
+			},
+		},
+		{
+			name: "failed batch is split in halves",
+			batches: []batch{
+				{prs: []int{1, 2, 3, 4, 5}, state: failureState},
+			},
+			halves: [][]int{{1, 2}, {3, 4, 5}},
+		},
+		{
+			name: "only untested halves are triggered",
+			batches: []batch{
+				{prs: []int{1, 2, 3, 4}, state: failureState},
+				{prs: []int{1, 2}, state: pendingState},
+			},
+			halves: [][]int{{3, 4}},
+		},
+		{
+			name: "failed half is split further",
+			batches: []batch{
+				{prs: []int{1, 2, 3, 4}, state: failureState},
+				{prs: []int{1, 2}, state: successState},
+				{prs: []int{3, 4}, state: failureState},
+			},
+			halves: [][]int{{3}, {4}},
+		},
+		{
+			name: "PR that fails on its own is the culprit",
+			batches: []batch{
+				{prs: []int{1, 2, 3, 4}, state: failureState},
+				{prs: []int{1, 2}, state: successState},
+				{prs: []int{3, 4}, state: failureState},
+				{prs: []int{3}, state: successState},
+				{prs: []int{4}, state: failureState},
+			},
+			culprits: []int{4},
+		},
+		{
+			name: "passing halves end the bisection",
+			batches: []batch{
+				{prs: []int{1, 2, 3, 4}, state: failureState},
+				{prs: []int{1, 2}, state: successState},
+				{prs: []int{3, 4}, state: successState},
+			},
+		},
+	}
+	for _, test := range tests {
+		var batches []batchResult
+		for _, b := range test.batches {
+			result := batchResult{state: b.state}
+			for _, number := range b.prs {
+				result.prs = append(result.prs, PullRequest{Number: githubql.Int(number)})
+			}
+			batches = append(batches, result)
+		}
+		culprits, halves := bisectBatches(batches)
+		testPullsMatchList(t, test.name+" culprits", culprits, test.culprits)
+		var halfNumbers [][]int
+		for _, half := range halves {
+			halfNumbers = append(halfNumbers, prNumbers(half))
+		}
+		if !reflect.DeepEqual(halfNumbers, test.halves) {
+			t.Errorf("%s: expected halves %v, got %v", test.name, test.halves, halfNumbers)
+		}
+	}
+}
+
+func TestRemoveCulprits(t *testing.T) {
+	ca := &config.Agent{}
+	ca.Set(&config.Config{ProwConfig: config.ProwConfig{Tide: config.Tide{
+		BatchBisection: &config.TideBatchBisection{Label: "do-not-merge/batch-failure"},
+	}}})
+	ghc := &fgc{labelErrs: map[int]error{3: errors.New("injected label error")}}
+	c := &Controller{
+		logger:  logrus.WithField("controller", "tide"),
+		config:  ca.Config,
+		ghc:     ghc,
+		History: history.New(10),
+	}
+	sp := subpool{
+		log:    logrus.WithField("component", "tide"),
+		org:    "o",
+		repo:   "r",
+		branch: "master",
+		sha:    "master-sha",
+	}
+	for _, number := range []int{1, 2, 3} {
+		var pr PullRequest
+		pr.Number = githubql.Int(number)
+		pr.HeadRefOID = githubql.String(fmt.Sprintf("sha-%d", number))
+		sp.prs = append(sp.prs, pr)
+	}
+
+	act, targets, err := c.takeAction(sp, nil, nil, nil, nil, nil, sp.prs[1:], nil)
+	if err == nil {
+		t.Error("Expected an error labeling #3.")
+	}
+	if act != RemoveCulprit {
+		t.Errorf("Wrong action. Got %v, wanted %v.", act, RemoveCulprit)
+	}
+	testPullsMatchList(t, "removed culprits", targets, []int{2})
+	if len(ghc.comments[2]) != 1 {
+		t.Errorf("Expected a comment on the culprit, got %v.", ghc.comments)
+	}
+	if !reflect.DeepEqual(ghc.labels, map[int][]string{2: {"do-not-merge/batch-failure"}}) {
+		t.Errorf("Expected the culprit to be labeled, got %v.", ghc.labels)
+	}
+
+	c.History.Record(poolKey(sp.org, sp.repo, sp.branch), string(act), sp.sha, "", prMeta(targets...))
+	testPullsMatchList(t, "culprit left out", c.withoutCulprits(sp), []int{1, 3})
+	sp.prs[1].HeadRefOID = "sha-2-fixed"
+	testPullsMatchList(t, "updated culprit back in pool", c.withoutCulprits(sp), []int{1, 2, 3})
+}
+
+func TestSyncSubpoolHold(t *testing.T) {
+	now := time.Now()
+	testcases := []struct {
+		name            string
+		exceptionLabels []string
+
+		expectedAction  Action
+		expectedTargets []int
+	}{
+		{
+			name:           "all PRs held",
+			expectedAction: PoolBlocked,
+		},
+		{
+			name:            "PR with an exception label merges",
+			exceptionLabels: []string{"cherry-pick-approved"},
+			expectedAction:  Merge,
+			expectedTargets: []int{2},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := &config.Agent{}
+			ca.Set(&config.Config{ProwConfig: config.ProwConfig{Tide: config.Tide{
+				CodeFreezes: []config.TideCodeFreeze{{
+					Name:            "Code freeze",
+					Start:           now.Add(-time.Hour),
+					End:             now.Add(time.Hour),
+					ExceptionLabels: tc.exceptionLabels,
+				}},
+			}}})
+			ghc := &fgc{}
+			c := &Controller{
+				logger:  logrus.WithField("controller", "tide"),
+				config:  ca.Config,
+				ghc:     ghc,
+				History: history.New(10),
+			}
+			sp := subpool{
+				log:    logrus.WithField("component", "tide"),
+				org:    "o",
+				repo:   "r",
+				branch: "master",
+				sha:    "master-sha",
+				cc:     &config.TideContextPolicy{},
+			}
+			for _, number := range []int{1, 2} {
+				pr := testPR("o", "r", "master", number, githubql.MergeableStateMergeable)
+				if number == 2 {
+					pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: "cherry-pick-approved"})
+				}
+				sp.prs = append(sp.prs, pr)
+			}
+
+			pool, err := c.syncSubpool(sp, nil)
+			if err != nil {
+				t.Fatalf("Error syncing subpool: %v", err)
+			}
+			if pool.Action != tc.expectedAction {
+				t.Errorf("Wrong action. Got %v, wanted %v.", pool.Action, tc.expectedAction)
+			}
+			if pool.HoldReason == "" {
+				t.Error("Expected a hold reason.")
+			}
+			testPullsMatchList(t, "passing PRs", pool.SuccessPRs, []int{1, 2})
+			testPullsMatchList(t, "targets", pool.Target, tc.expectedTargets)
+			if ghc.merged != len(tc.expectedTargets) {
+				t.Errorf("Expected %d merges, got %d.", len(tc.expectedTargets), ghc.merged)
+			}
+		})
+	}
+}
+
+func TestSyncSubpoolPipeline(t *testing.T) {
+	testcases := []struct {
+		name    string
+		batches map[prowapi.ProwJobState][]int
+
+		expectedAction  Action
+		expectedTargets []int
+	}{
+		{
+			name: "passing batch waits while a larger batch including it is pending",
+			batches: map[prowapi.ProwJobState][]int{
+				prowapi.SuccessState: {1, 2},
+				prowapi.PendingState: {1, 2, 3},
+			},
+			expectedAction: Wait,
+		},
+		{
+			name: "passing batch merges once the larger batch fails",
+			batches: map[prowapi.ProwJobState][]int{
+				prowapi.SuccessState: {1, 2},
+				prowapi.FailureState: {1, 2, 3},
+			},
+			expectedAction:  MergeBatch,
+			expectedTargets: []int{1, 2},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := &config.Agent{}
+			ca.Set(&config.Config{ProwConfig: config.ProwConfig{Tide: config.Tide{
+				SpeculativeBatches: map[string]int{"o/r": 2},
+			}}})
+			ghc := &fgc{}
+			c := &Controller{
+				logger:  logrus.WithField("controller", "tide"),
+				config:  ca.Config,
+				ghc:     ghc,
+				History: history.New(10),
+			}
+			sp := subpool{
+				log:        logrus.WithField("component", "tide"),
+				org:        "o",
+				repo:       "r",
+				branch:     "master",
+				sha:        "master-sha",
+				cc:         &config.TideContextPolicy{},
+				presubmits: map[int][]config.Presubmit{},
+			}
+			for _, number := range []int{1, 2, 3} {
+				sp.prs = append(sp.prs, testPR("o", "r", "master", number, githubql.MergeableStateMergeable))
+				sp.presubmits[number] = []config.Presubmit{{Reporter: config.Reporter{Context: "foo"}}}
+				// Keep the PRs from being tested on their own.
+				sp.pjs = append(sp.pjs, prowapi.ProwJob{
+					Spec: prowapi.ProwJobSpec{
+						Type:    prowapi.PresubmitJob,
+						Context: "foo",
+						Refs: &prowapi.Refs{
+							BaseSHA: "master-sha",
+							Pulls:   []prowapi.Pull{{Number: number, SHA: "SHA"}},
+						},
+					},
+					Status: prowapi.ProwJobStatus{State: prowapi.PendingState},
+				})
+			}
+			for state, numbers := range tc.batches {
+				pj := prowapi.ProwJob{
+					Spec: prowapi.ProwJobSpec{
+						Type:    prowapi.BatchJob,
+						Context: "foo",
+						Refs:    &prowapi.Refs{BaseSHA: "master-sha"},
+					},
+					Status: prowapi.ProwJobStatus{State: state},
+				}
+				for _, number := range numbers {
+					pj.Spec.Refs.Pulls = append(pj.Spec.Refs.Pulls, prowapi.Pull{Number: number, SHA: "SHA"})
+				}
+				sp.pjs = append(sp.pjs, pj)
+			}
+
+			pool, err := c.syncSubpool(sp, nil)
+			if err != nil {
+				t.Fatalf("Error syncing subpool: %v", err)
+			}
+			if pool.Action != tc.expectedAction {
+				t.Errorf("Wrong action. Got %v, wanted %v.", pool.Action, tc.expectedAction)
+			}
+			testPullsMatchList(t, "targets", pool.Target, tc.expectedTargets)
+			if ghc.merged != len(tc.expectedTargets) {
+				t.Errorf("Expected %d merges, got %d.", len(tc.expectedTargets), ghc.merged)
+			}
+		})
+	}
+}
+
+func TestSpeculativeBatchSizes(t *testing.T) {
+	tests := []struct {
+		batchSize int
+		n         int
+		expected  []int
+	}{
+		{batchSize: 5, n: 1, expected: []int{5}},
+		{batchSize: 15, n: 3, expected: []int{5, 10, 15}},
+		{batchSize: 7, n: 3, expected: []int{3, 5, 7}},
+		{batchSize: 3, n: 3, expected: []int{2, 3}},
+		{batchSize: 2, n: 4, expected: []int{2}},
+	}
+	for _, test := range tests {
+		if got := speculativeBatchSizes(test.batchSize, test.n); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("splitting %d PRs into %d batches: expected sizes %v, got %v", test.batchSize, test.n, test.expected, got)
+		}
+	}
+}
+
 func TestAccumulate(t *testing.T) {
 	jobSet := []config.Presubmit{
 		{
@@ -475,15 +911,37 @@ type fgc struct {
 	refs      map[string]string
 	merged    int
 	setStatus bool
+	comments  map[int][]string
+	labels    map[int][]string
+	labelErrs map[int]error
+
+	bodies        map[int]string
+	issueComments map[int][]github.IssueComment
+	commentLists  int
+	reviews       map[int][]github.Review
+	teams         []github.Team
+	teamMembers   map[int][]github.TeamMember
 
 	expectedSHA    string
 	combinedStatus map[string]string
+	// behind holds how many commits PR heads are behind, by head.
+	behind      map[string]int
+	comparisons int
 }
 
 func (f *fgc) GetRef(o, r, ref string) (string, error) {
 	return f.refs[o+"/"+r+" "+ref], nil
 }
 
+func (f *fgc) CompareCommits(org, repo, base, head string) (github.CommitComparison, error) {
+	f.comparisons++
+	behind, ok := f.behind[head]
+	if !ok {
+		return github.CommitComparison{}, fmt.Errorf("unknown head %s", head)
+	}
+	return github.CommitComparison{Status: "behind", BehindBy: behind}, nil
+}
+
 func (f *fgc) Query(ctx context.Context, q interface{}, vars map[string]interface{}) error {
 	sq, ok := q.(*searchQuery)
 	if !ok {
@@ -508,6 +966,25 @@ func (f *fgc) Merge(org, repo string, number int, details github.MergeDetails) e
 	return nil
 }
 
+func (f *fgc) CreateComment(org, repo string, number int, comment string) error {
+	if f.comments == nil {
+		f.comments = map[int][]string{}
+	}
+	f.comments[number] = append(f.comments[number], comment)
+	return nil
+}
+
+func (f *fgc) AddLabel(org, repo string, number int, label string) error {
+	if err := f.labelErrs[number]; err != nil {
+		return err
+	}
+	if f.labels == nil {
+		f.labels = map[int][]string{}
+	}
+	f.labels[number] = append(f.labels[number], label)
+	return nil
+}
+
 func (f *fgc) CreateStatus(org, repo, ref string, s github.Status) error {
 	switch s.State {
 	case github.StatusSuccess, github.StatusError, github.StatusPending, github.StatusFailure:
@@ -531,6 +1008,10 @@ func (f *fgc) GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus,
 		nil
 }
 
+func (f *fgc) GetPullRequest(org, repo string, number int) (*github.PullRequest, error) {
+	return &github.PullRequest{Number: number, Body: f.bodies[number]}, nil
+}
+
 func (f *fgc) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
 	if number != 100 {
 		return nil, nil
@@ -543,6 +1024,23 @@ func (f *fgc) GetPullRequestChanges(org, repo string, number int) ([]github.Pull
 		nil
 }
 
+func (f *fgc) ListIssueComments(org, repo string, number int) ([]github.IssueComment, error) {
+	f.commentLists++
+	return f.issueComments[number], nil
+}
+
+func (f *fgc) ListReviews(org, repo string, number int) ([]github.Review, error) {
+	return f.reviews[number], nil
+}
+
+func (f *fgc) ListTeams(org string) ([]github.Team, error) {
+	return f.teams, nil
+}
+
+func (f *fgc) ListTeamMembers(id int, role string) ([]github.TeamMember, error) {
+	return f.teamMembers[id], nil
+}
+
 // TestDividePool ensures that subpools returned by dividePool satisfy a few
 // important invariants.
 func TestDividePool(t *testing.T) {
@@ -798,7 +1296,7 @@ func TestPickBatch(t *testing.T) {
 		gc:     gc,
 		config: ca.Config,
 	}
-	prs, err := c.pickBatch(sp, &config.TideContextPolicy{})
+	prs, err := c.pickBatch(sp, &config.TideContextPolicy{}, maxBatchSize)
 	if err != nil {
 		t.Fatalf("Error from pickBatch: %v", err)
 	}
@@ -818,6 +1316,100 @@ func TestPickBatch(t *testing.T) {
 	}
 }
 
+func TestPickHighestPriorityPR(t *testing.T) {
+	tide := &config.Tide{
+		Priority: []config.TidePriority{
+			{Labels: []string{"kind/revert"}, Weight: 10},
+			{Labels: []string{"priority/important-soon"}, Weight: 5},
+			{Labels: []string{"do-not-rush"}, Weight: -1},
+		},
+	}
+	type testPR struct {
+		number  int
+		labels  []string
+		success bool
+	}
+	testcases := []struct {
+		name string
+		prs  []testPR
+
+		expected int
+	}{
+		{
+			name: "without priorities the oldest PR is picked",
+			prs: []testPR{
+				{number: 3, success: true},
+				{number: 1, success: true},
+				{number: 2, success: true},
+			},
+			expected: 1,
+		},
+		{
+			name: "the PR with the highest priority is picked",
+			prs: []testPR{
+				{number: 1, success: true},
+				{number: 4, labels: []string{"priority/important-soon"}, success: true},
+				{number: 3, labels: []string{"kind/revert"}, success: true},
+				{number: 2, labels: []string{"kind/revert"}, success: true},
+			},
+			expected: 2,
+		},
+		{
+			name: "failing PRs are skipped regardless of priority",
+			prs: []testPR{
+				{number: 1, success: true},
+				{number: 2, labels: []string{"kind/revert"}},
+				{number: 3, labels: []string{"priority/important-soon"}, success: true},
+			},
+			expected: 3,
+		},
+		{
+			name: "negative priorities are picked last",
+			prs: []testPR{
+				{number: 1, labels: []string{"do-not-rush"}, success: true},
+				{number: 2, success: true},
+			},
+			expected: 2,
+		},
+		{
+			name: "no passing PR",
+			prs: []testPR{
+				{number: 1, labels: []string{"kind/revert"}},
+			},
+			expected: -1,
+		},
+	}
+	for _, tc := range testcases {
+		var prs []PullRequest
+		for _, testpr := range tc.prs {
+			oid := githubql.String(fmt.Sprintf("sha-%d", testpr.number))
+			var pr PullRequest
+			pr.Number = githubql.Int(testpr.number)
+			pr.HeadRefOID = oid
+			pr.Commits.Nodes = []struct {
+				Commit Commit
+			}{{Commit: Commit{OID: oid}}}
+			state := githubql.StatusStateFailure
+			if testpr.success {
+				state = githubql.StatusStateSuccess
+			}
+			pr.Commits.Nodes[0].Commit.Status.Contexts = []Context{{Context: "test", State: state}}
+			for _, label := range testpr.labels {
+				pr.Labels.Nodes = append(pr.Labels.Nodes, struct{ Name githubql.String }{Name: githubql.String(label)})
+			}
+			prs = append(prs, pr)
+		}
+		ok, pr := pickHighestPriorityPR(logrus.WithField("test", tc.name), &fgc{}, prs, &config.TideContextPolicy{}, tide)
+		actual := -1
+		if ok {
+			actual = int(pr.Number)
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected PR %d to be picked, got %d", tc.name, tc.expected, actual)
+		}
+	}
+}
+
 func TestTakeAction(t *testing.T) {
 	// PRs 0-9 exist. All are mergable, and all are passing tests.
 	testcases := []struct {
@@ -829,6 +1421,8 @@ func TestTakeAction(t *testing.T) {
 		nones        []int
 		batchMerges  []int
 		presubmits   map[int][]config.Presubmit
+		// speculativeBatches enables the merge queue mode if set.
+		speculativeBatches int
 
 		merged           int
 		triggered        int
@@ -926,6 +1520,27 @@ func TestTakeAction(t *testing.T) {
 			triggeredBatches: 1,
 			action:           TriggerBatch,
 		},
+		{
+			name: "no pending batch, merge queue should trigger speculative batches",
+
+			batchPending: false,
+			successes:    []int{},
+			pendings:     []int{0},
+			nones:        []int{1, 2, 3, 4, 5, 6},
+			batchMerges:  []int{},
+			presubmits: map[int][]config.Presubmit{
+				100: {
+					{Reporter: config.Reporter{Context: "foo"}},
+					{Reporter: config.Reporter{Context: "if-changed"}},
+				},
+			},
+			speculativeBatches: 3,
+
+			merged:           0,
+			triggered:        3,
+			triggeredBatches: 3,
+			action:           TriggerBatch,
+		},
 		{
 			name: "one PR, should not trigger batch",
 
@@ -1051,6 +1666,9 @@ func TestTakeAction(t *testing.T) {
 		); err != nil {
 			t.Fatalf("failed to set presubmits: %v", err)
 		}
+		if tc.speculativeBatches > 0 {
+			cfg.Tide.SpeculativeBatches = map[string]int{"o/r": tc.speculativeBatches}
+		}
 		ca.Set(cfg)
 		if len(tc.presubmits) > 0 {
 			for i := 0; i <= 8; i++ {
@@ -1117,7 +1735,7 @@ func TestTakeAction(t *testing.T) {
 			batchPending = []PullRequest{{}}
 		}
 		t.Logf("Test case: %s", tc.name)
-		if act, _, err := c.takeAction(sp, batchPending, genPulls(tc.successes), genPulls(tc.pendings), genPulls(tc.nones), genPulls(tc.batchMerges)); err != nil {
+		if act, _, err := c.takeAction(sp, batchPending, genPulls(tc.successes), genPulls(tc.pendings), genPulls(tc.nones), genPulls(tc.batchMerges), nil, nil); err != nil {
 			t.Errorf("Error in takeAction: %v", err)
 			continue
 		} else if act != tc.action {
@@ -1449,8 +2067,11 @@ func TestFilterSubpool(t *testing.T) {
 	tcs := []struct {
 		name string
 
-		prs         []pr
-		expectedPRs []int // Empty indicates no subpool should be returned.
+		prs []pr
+		// commitsBehind holds how many commits the heads of PRs are
+		// behind the branch, and holds PRs more than 5 behind if set.
+		commitsBehind map[int]int
+		expectedPRs   []int // Empty indicates no subpool should be returned.
 	}{
 		{
 			name: "one mergeable passing PR (omitting optional context)",
@@ -1708,6 +2329,49 @@ func TestFilterSubpool(t *testing.T) {
 			},
 			expectedPRs: []int{1, 2},
 		},
+		{
+			name: "PR too far behind for external contexts",
+			prs: []pr{
+				{
+					number:    1,
+					mergeable: true,
+					contexts: []Context{
+						{
+							Context: githubql.String("pj-a"),
+							State:   githubql.StatusStateSuccess,
+						},
+						{
+							Context: githubql.String("pj-b"),
+							State:   githubql.StatusStateSuccess,
+						},
+						{
+							Context: githubql.String("other-a"),
+							State:   githubql.StatusStateSuccess,
+						},
+					},
+				},
+				{
+					number:    2,
+					mergeable: true,
+					contexts: []Context{
+						{
+							Context: githubql.String("pj-a"),
+							State:   githubql.StatusStateSuccess,
+						},
+						{
+							Context: githubql.String("pj-b"),
+							State:   githubql.StatusStateSuccess,
+						},
+						{
+							Context: githubql.String("other-a"),
+							State:   githubql.StatusStateSuccess,
+						},
+					},
+				},
+			},
+			commitsBehind: map[int]int{1: 10, 2: 5},
+			expectedPRs:   []int{2},
+		},
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
@@ -1719,9 +2383,19 @@ func TestFilterSubpool(t *testing.T) {
 				cc:         cc,
 				log:        logrus.WithFields(logrus.Fields{"org": "org", "repo": "repo", "branch": "branch"}),
 			}
+			if tc.commitsBehind != nil {
+				ghc := &fgc{behind: map[string]int{}}
+				for number, behind := range tc.commitsBehind {
+					ghc.behind[fmt.Sprintf("head-%d", number)] = behind
+				}
+				sp.freshBases = newFreshBaseAgent(ghc)
+				sp.maxCommitsBehind = 5
+			}
 			for _, pull := range tc.prs {
+				head := githubql.String(fmt.Sprintf("head-%d", pull.number))
 				pr := PullRequest{
-					Number: githubql.Int(pull.number),
+					Number:     githubql.Int(pull.number),
+					HeadRefOID: head,
 				}
 				pr.Commits.Nodes = []struct{ Commit Commit }{
 					{
@@ -1729,6 +2403,7 @@ func TestFilterSubpool(t *testing.T) {
 							Status: struct{ Contexts []Context }{
 								Contexts: pull.contexts,
 							},
+							OID: head,
 						},
 					},
 				}
@@ -0,0 +1,196 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reporter contains helpers for reporting prowjob state
+// transitions as CloudEvents to HTTP endpoints.
+package reporter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/errorutil"
+)
+
+const (
+	reporterName = "webhookreporter"
+
+	// EventSource is the source of the events sent by the reporter.
+	EventSource = "/prow/crier"
+	// EventTypePrefix prefixes the state of the prowjob in the type
+	// of the events sent by the reporter.
+	EventTypePrefix = "io.k8s.prow.prowjob."
+	// SignatureHeader holds the HMAC-SHA256 signature of the event.
+	SignatureHeader = "X-Prow-Signature"
+
+	contentType = "application/cloudevents+json"
+	maxRetries  = 5
+)
+
+// Event is a CloudEvents envelope in the structured JSON format
+// that carries a prowjob.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	ID              string          `json:"id"`
+	Time            string          `json:"time"`
+	Subject         string          `json:"subject"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            prowapi.ProwJob `json:"data"`
+}
+
+// Client is a reporter client fed to crier controller
+type Client struct {
+	config     config.Getter
+	httpClient *http.Client
+	readSecret func(path string) ([]byte, error)
+
+	initialBackoff time.Duration
+}
+
+// NewReporter creates a new webhook reporter
+func NewReporter(cfg config.Getter) *Client {
+	return &Client{
+		config:         cfg,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		readSecret:     ioutil.ReadFile,
+		initialBackoff: time.Second,
+	}
+}
+
+// GetName returns the name of the reporter
+func (c *Client) GetName() string {
+	return reporterName
+}
+
+// ShouldReport tells if a prowjob should be reported by this reporter
+func (c *Client) ShouldReport(pj *prowapi.ProwJob) bool {
+	return len(c.endpointsFor(pj)) > 0
+}
+
+// Report sends an event for the current state of the prowjob to
+// every endpoint that selects it. Delivery is retried with backoff,
+// and an error is returned if any endpoint could not be reached.
+// Events are identified by the prowjob and its state, so receivers
+// can drop events that are delivered twice.
+func (c *Client) Report(pj *prowapi.ProwJob) error {
+	payload, err := json.Marshal(newEvent(pj, time.Now()))
+	if err != nil {
+		return fmt.Errorf("could not marshal event: %v", err)
+	}
+
+	var errs []error
+	for _, endpoint := range c.endpointsFor(pj) {
+		if err := c.send(endpoint, payload); err != nil {
+			errs = append(errs, fmt.Errorf("failed to report to endpoint %q: %v", endpoint.Name, err))
+		}
+	}
+	return errorutil.NewAggregate(errs...)
+}
+
+func (c *Client) endpointsFor(pj *prowapi.ProwJob) []config.WebhookEndpoint {
+	var endpoints []config.WebhookEndpoint
+	for _, endpoint := range c.config().WebhookReporter.Endpoints {
+		if endpoint.Selects(pj) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+func newEvent(pj *prowapi.ProwJob, now time.Time) Event {
+	return Event{
+		SpecVersion:     "1.0",
+		Type:            EventTypePrefix + string(pj.Status.State),
+		Source:          EventSource,
+		ID:              fmt.Sprintf("%s-%s", pj.ObjectMeta.Name, pj.Status.State),
+		Time:            now.UTC().Format(time.RFC3339),
+		Subject:         pj.Spec.Job,
+		DataContentType: "application/json",
+		Data:            *pj,
+	}
+}
+
+// Sign returns the signature of the payload that is sent in the
+// SignatureHeader of the request.
+func Sign(payload, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Client) send(endpoint config.WebhookEndpoint, payload []byte) error {
+	var signature string
+	if endpoint.HMACSecretFile != "" {
+		key, err := c.readSecret(endpoint.HMACSecretFile)
+		if err != nil {
+			return fmt.Errorf("could not read hmac secret: %v", err)
+		}
+		signature = Sign(payload, bytes.TrimSpace(key))
+	}
+
+	var err error
+	backoff := c.initialBackoff
+	for retries := 0; retries < maxRetries; retries++ {
+		if retries > 0 {
+			logrus.WithError(err).WithField("endpoint", endpoint.Name).Debugf("Retrying in %v.", backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		if retry, err = c.post(endpoint.URL, signature, payload); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post sends the payload and reports whether a failure is transient.
+func (c *Client) post(url, signature string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if signature != "" {
+		req.Header.Set(SignatureHeader, signature)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	err = fmt.Errorf("response %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
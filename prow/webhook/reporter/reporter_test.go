@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/config"
+)
+
+// receiver records the events POSTed to it, failing the
+// first failures requests with the given status code.
+type receiver struct {
+	sync.Mutex
+	status   int
+	failures int
+
+	requests   int
+	events     []Event
+	signatures []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	r.requests++
+	if r.failures > 0 {
+		r.failures--
+		http.Error(w, "try again", r.status)
+		return
+	}
+	if ct := req.Header.Get("Content-Type"); ct != contentType {
+		http.Error(w, "bad content type "+ct, http.StatusBadRequest)
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if sig := req.Header.Get(SignatureHeader); sig != "" && sig != Sign(body, []byte("secret")) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+	r.events = append(r.events, event)
+	r.signatures = append(r.signatures, req.Header.Get(SignatureHeader))
+}
+
+func newTestReporter(endpoints ...config.WebhookEndpoint) *Client {
+	c := NewReporter(func() *config.Config {
+		return &config.Config{
+			ProwConfig: config.ProwConfig{
+				WebhookReporter: config.WebhookReporter{Endpoints: endpoints},
+			},
+		}
+	})
+	c.initialBackoff = 0
+	c.readSecret = func(path string) ([]byte, error) {
+		if path != "/etc/hmac" {
+			return nil, errors.New("no such secret")
+		}
+		return []byte("secret\n"), nil
+	}
+	return c
+}
+
+func testJob(state prowapi.ProwJobState) *prowapi.ProwJob {
+	return &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "abc",
+			Labels: map[string]string{"release": "true"},
+		},
+		Spec: prowapi.ProwJobSpec{
+			Job:  "ci-release",
+			Type: prowapi.PeriodicJob,
+		},
+		Status: prowapi.ProwJobStatus{State: state},
+	}
+}
+
+func TestShouldReport(t *testing.T) {
+	release, err := labels.Parse("release=true")
+	if err != nil {
+		t.Fatalf("failed to parse label selector: %v", err)
+	}
+	other, err := labels.Parse("release=false")
+	if err != nil {
+		t.Fatalf("failed to parse label selector: %v", err)
+	}
+
+	var testCases = []struct {
+		name      string
+		endpoints []config.WebhookEndpoint
+		expected  bool
+	}{
+		{
+			name: "no endpoints",
+		},
+		{
+			name:      "endpoint without selectors",
+			endpoints: []config.WebhookEndpoint{{Name: "all"}},
+			expected:  true,
+		},
+		{
+			name:      "endpoint for other job types",
+			endpoints: []config.WebhookEndpoint{{Name: "presubmits", JobTypesToReport: []prowapi.ProwJobType{prowapi.PresubmitJob}}},
+		},
+		{
+			name:      "endpoint for other states",
+			endpoints: []config.WebhookEndpoint{{Name: "failures", JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState}}},
+		},
+		{
+			name:      "endpoint for other labels",
+			endpoints: []config.WebhookEndpoint{{Name: "other", LabelSelector: other}},
+		},
+		{
+			name: "one of several endpoints selects the job",
+			endpoints: []config.WebhookEndpoint{
+				{Name: "other", LabelSelector: other},
+				{Name: "release", LabelSelector: release, JobTypesToReport: []prowapi.ProwJobType{prowapi.PeriodicJob}},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := newTestReporter(tc.endpoints...).ShouldReport(testJob(prowapi.SuccessState)); actual != tc.expected {
+				t.Errorf("expected ShouldReport to return %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		secretFile string
+		status     int
+		failures   int
+
+		expectErr       bool
+		expectRequests  int
+		expectSignature bool
+	}{
+		{
+			name:           "event is delivered",
+			expectRequests: 1,
+		},
+		{
+			name:            "event is signed",
+			secretFile:      "/etc/hmac",
+			expectRequests:  1,
+			expectSignature: true,
+		},
+		{
+			name:       "missing secret fails the report",
+			secretFile: "/etc/missing",
+			expectErr:  true,
+		},
+		{
+			name:           "server errors are retried",
+			status:         http.StatusServiceUnavailable,
+			failures:       2,
+			expectRequests: 3,
+		},
+		{
+			name:           "retries are bounded",
+			status:         http.StatusInternalServerError,
+			failures:       maxRetries,
+			expectErr:      true,
+			expectRequests: maxRetries,
+		},
+		{
+			name:           "client errors are not retried",
+			status:         http.StatusBadRequest,
+			failures:       1,
+			expectErr:      true,
+			expectRequests: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &receiver{status: tc.status, failures: tc.failures}
+			server := httptest.NewServer(r)
+			defer server.Close()
+
+			c := newTestReporter(config.WebhookEndpoint{Name: "test", URL: server.URL, HMACSecretFile: tc.secretFile})
+			err := c.Report(testJob(prowapi.FailureState))
+			if err != nil && !tc.expectErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectErr {
+				t.Fatal("expected an error but got none")
+			}
+			if r.requests != tc.expectRequests {
+				t.Errorf("expected %d requests, got %d", tc.expectRequests, r.requests)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			if len(r.events) != 1 {
+				t.Fatalf("expected one event, got %d", len(r.events))
+			}
+			event := r.events[0]
+			if event.SpecVersion != "1.0" || event.Type != "io.k8s.prow.prowjob.failure" || event.ID != "abc-failure" || event.Subject != "ci-release" {
+				t.Errorf("unexpected event envelope: %+v", event)
+			}
+			if event.Data.Status.State != prowapi.FailureState {
+				t.Errorf("expected event data to hold the prowjob, got %+v", event.Data)
+			}
+			if signed := r.signatures[0] != ""; signed != tc.expectSignature {
+				t.Errorf("expected signed event to be %v, got signature %q", tc.expectSignature, r.signatures[0])
+			}
+		})
+	}
+}